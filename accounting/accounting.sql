@@ -0,0 +1,20 @@
+-- the last trade of /api/v3/myTrades imported into binance.spot_fill (userstream/userstream.sql) by accounting.ImportTrades
+CREATE TABLE IF NOT EXISTS binance.account_trade_import (
+    symbol        text PRIMARY KEY,
+    last_trade_id int8 NOT NULL,
+    updated_at    timestamptz NOT NULL DEFAULT now()
+);
+
+-- successful deposits and completed withdrawals of the account
+CREATE TABLE IF NOT EXISTS binance.account_transfer (
+    transfer_id   text NOT NULL,
+    withdrawal    bool NOT NULL,
+    asset         text NOT NULL,
+    amount        float8 NOT NULL,
+    fee           float8 NOT NULL DEFAULT 0, -- of the withdrawal, it is paid on top of the amount
+    transfer_time int8 NOT NULL, -- insert time of deposits, apply time of withdrawals
+    tx_id         text NOT NULL DEFAULT '',
+    created_at    timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (transfer_id, withdrawal)
+);
+CREATE INDEX IF NOT EXISTS account_transfer_time ON binance.account_transfer (transfer_time);
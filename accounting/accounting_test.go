@@ -0,0 +1,197 @@
+package accounting
+
+import (
+	"bytes"
+	"context"
+	"github.com/okharch/binance/orders"
+	"github.com/okharch/binance/orders/stub"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fixedPrices are prices of the assets in USDT which do not change over time
+type fixedPrices map[string]float64
+
+func (p fixedPrices) Price(ctx context.Context, asset, quote string, t int64) (float64, bool, error) {
+	price, ok := p[asset]
+	return price, ok, nil
+}
+
+var t2022 = time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC).UnixMilli()
+
+func btc(id int64, side orders.Side, qty, price float64) Trade {
+	return Trade{Symbol: "BTCUSDT", Base: "BTC", Quote: "USDT", Id: id, Time: t2022 + id, Side: side, Qty: qty,
+		QuoteQty: qty * price}
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestLedgerMethods(t *testing.T) {
+	ctx := context.Background()
+	for _, c := range []struct {
+		method         Method
+		realised, cost float64
+		acquired       int64
+	}{
+		{FIFO, 200, 200, t2022 + 1},
+		{LIFO, 100, 100, t2022 + 2},
+		{AverageCost, 150, 150, 0},
+	} {
+		l := NewLedger(c.method, "USDT", fixedPrices{})
+		for _, trade := range []Trade{btc(1, orders.Buy, 1, 100), btc(2, orders.Buy, 1, 200), btc(3, orders.Sell, 1, 300)} {
+			if err := l.Trade(ctx, trade); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if len(l.Disposals) != 1 || !near(l.Disposals[0].Realised(), c.realised) || l.Disposals[0].Acquired != c.acquired {
+			t.Errorf("%s: unexpected disposals %+v", c.method, l.Disposals)
+		}
+		if h := l.Holdings(); len(h) != 1 || !near(h[0].Qty, 1) || !near(h[0].Cost, c.cost) {
+			t.Errorf("%s: unexpected holdings %+v", c.method, h)
+		}
+	}
+	// the sale of several lots is split into disposal per lot
+	l := NewLedger(FIFO, "USDT", fixedPrices{})
+	for _, trade := range []Trade{btc(1, orders.Buy, 1, 100), btc(2, orders.Buy, 1, 200), btc(3, orders.Sell, 1.5, 300)} {
+		l.Trade(ctx, trade)
+	}
+	if len(l.Disposals) != 2 || !near(l.Disposals[1].Qty, 0.5) || !near(l.Disposals[1].Proceeds, 150) ||
+		!near(l.Disposals[1].Cost, 100) {
+		t.Errorf("unexpected disposals %+v", l.Disposals)
+	}
+	if _, err := ParseMethod("HIFO"); err == nil {
+		t.Error("expected unknown method error")
+	}
+}
+
+func TestLedgerCommissions(t *testing.T) {
+	ctx := context.Background()
+	l := NewLedger(FIFO, "USDT", fixedPrices{"BTC": 20000, "BNB": 300})
+	buy := btc(1, orders.Buy, 1, 100)
+	buy.Commission, buy.CommissionAsset = 0.001, "BTC" // paid by the received quantity
+	sell := btc(2, orders.Sell, 0.5, 300)
+	sell.Commission, sell.CommissionAsset = 0.01, "BNB" // 3 USDT
+	deposit := Transfer{Id: "d1", Asset: "BNB", Amount: 1, Time: t2022}
+	for _, err := range []error{l.Transfer(ctx, deposit), l.Trade(ctx, buy), l.Trade(ctx, sell)} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	holdings := l.Holdings()
+	if len(holdings) != 2 || holdings[0].Asset != "BNB" || !near(holdings[0].Qty, 0.99) || !near(holdings[0].Cost, 297) ||
+		!near(holdings[1].Qty, 0.499) || !near(holdings[1].Cost, 100*0.499/0.999) {
+		t.Errorf("unexpected holdings %+v", holdings)
+	}
+	// BNB commission is disposed at its value, it is deducted from the proceeds of the sale
+	if len(l.Disposals) != 2 || l.Disposals[0].Asset != "BNB" || !near(l.Disposals[0].Realised(), 0) ||
+		!near(l.Disposals[1].Proceeds, 147) || !near(l.Disposals[1].Fee, 3) {
+		t.Errorf("unexpected disposals %+v", l.Disposals)
+	}
+
+	// ETHBTC trade disposes BTC and acquires ETH valued by BTC price
+	l = NewLedger(FIFO, "USDT", fixedPrices{"BTC": 20000})
+	l.Trade(ctx, btc(1, orders.Buy, 1, 10000))
+	l.Trade(ctx, Trade{Symbol: "ETHBTC", Base: "ETH", Quote: "BTC", Id: 2, Time: t2022 + 2, Side: orders.Buy, Qty: 10,
+		QuoteQty: 0.5, Commission: 0.0001, CommissionAsset: "BTC"})
+	if len(l.Disposals) != 1 || !near(l.Disposals[0].Qty, 0.5001) || !near(l.Disposals[0].Proceeds, 10000) {
+		t.Errorf("unexpected disposals %+v", l.Disposals)
+	}
+	if holdings := l.Holdings(); len(holdings) != 2 || holdings[1].Asset != "ETH" || !near(holdings[1].Cost, 10000) {
+		t.Errorf("unexpected holdings %+v", holdings)
+	}
+
+	// the withdrawal removes lots, its fee is realised as a loss
+	l = NewLedger(FIFO, "USDT", fixedPrices{})
+	l.Trade(ctx, btc(1, orders.Buy, 1, 100))
+	l.Transfer(ctx, Transfer{Id: "w1", Withdrawal: true, Asset: "BTC", Amount: 0.5, Fee: 0.1, Time: t2022 + 2})
+	if len(l.Disposals) != 1 || !near(l.Disposals[0].Realised(), -10) || !near(l.Holdings()[0].Cost, 40) {
+		t.Errorf("unexpected disposals %+v and holdings %+v", l.Disposals, l.Holdings())
+	}
+	// selling more than held is reported
+	l.Trade(ctx, btc(3, orders.Sell, 1, 100))
+	if len(l.Warnings) != 1 || !strings.Contains(l.Warnings[0], "missing from lots") {
+		t.Errorf("unexpected warnings %v", l.Warnings)
+	}
+}
+
+func TestReport(t *testing.T) {
+	ctx := context.Background()
+	buy := btc(1, orders.Buy, 2, 100)
+	sell := btc(2, orders.Sell, 1, 300)
+	sell.Time = time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC).UnixMilli()
+	l := NewLedger(FIFO, "USDT", fixedPrices{"BTC": 250})
+	summaries, err := Report(ctx, l, []Trade{sell, buy}, nil, time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []YearSummary{
+		{Year: 2022, Asset: "BTC", Bought: 2, BoughtCost: 200, Held: 2, CostBasis: 200, MarketValue: 500, Unrealised: 300},
+		{Year: 2023, Asset: "BTC", Sold: 1, Proceeds: 300, SoldCost: 100, Realised: 200, Held: 1, CostBasis: 100,
+			MarketValue: 250, Unrealised: 150},
+	}
+	if len(summaries) != len(expected) {
+		t.Fatalf("expected %+v, got %+v", expected, summaries)
+	}
+	for i := range expected {
+		if summaries[i] != expected[i] {
+			t.Errorf("expected %+v, got %+v", expected[i], summaries[i])
+		}
+	}
+	var b bytes.Buffer
+	if err := WriteCSV(&b, summaries); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if len(lines) != 5 || lines[1] != "2022,BTC,2,200,0,0,0,0,0,2,200,500,300" ||
+		lines[4] != "2023,TOTAL,,0,,300,100,200,0,,100,250,150" {
+		t.Errorf("unexpected csv:\n%s", b.String())
+	}
+	b.Reset()
+	if err := WriteDisposalsCSV(&b, l.Disposals); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), "2023-03-01T00:00:00Z,BTC,1,2022-06-01T00:00:00Z,300,100,0,200,BTCUSDT trade 2") {
+		t.Errorf("unexpected disposals csv:\n%s", b.String())
+	}
+}
+
+func TestFetchHistory(t *testing.T) {
+	exchange := stub.NewExchange("key", "secret")
+	defer exchange.Close()
+	client := orders.NewClient("key", "secret")
+	client.BaseURL = exchange.URL
+	ctx := context.Background()
+	exchange.SetPrice("BTCUSDT", 20000)
+	for i := 0; i < 5; i++ {
+		if _, err := client.NewOrder(ctx, orders.OrderRequest{Symbol: "BTCUSDT", Side: orders.Buy, Type: orders.Market,
+			Quantity: 0.1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer func(limit int) { tradesLimit = limit }(tradesLimit)
+	tradesLimit = 2
+	trades, err := FetchTrades(ctx, client, "BTCUSDT", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trades) != 4 || trades[0].Id != 2 || trades[3].Id != 5 || !trades[0].IsBuyer || trades[0].Price != 20000 {
+		t.Errorf("unexpected trades %+v", trades)
+	}
+
+	start := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	exchange.AddDeposit("BTC", 1, start.Add(24*time.Hour))
+	exchange.AddDeposit("ETH", 2, start.Add(200*24*time.Hour))
+	exchange.AddWithdrawal("BTC", 0.5, 0.0005, start.Add(100*24*time.Hour))
+	transfers, err := FetchTransfers(ctx, client, start, start.Add(365*24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transfers) != 3 || transfers[0].Asset != "BTC" || !transfers[1].Withdrawal || transfers[1].Fee != 0.0005 ||
+		transfers[1].Time != start.Add(100*24*time.Hour).UnixMilli() || transfers[2].Amount != 2 {
+		t.Errorf("unexpected transfers %+v", transfers)
+	}
+}
@@ -0,0 +1,203 @@
+package accounting
+
+import (
+	"context"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"github.com/okharch/binance/orders"
+	"sync"
+	"time"
+)
+
+// TradedSymbols returns the symbols imported before and those of the orders and fills
+func TradedSymbols(ctx context.Context, db *sqlx.DB) ([]string, error) {
+	var result []string
+	err := db.SelectContext(ctx, &result, `SELECT symbol FROM binance.account_trade_import
+		UNION SELECT symbol FROM binance.spot_fill
+		UNION SELECT symbol FROM binance.spot_order
+		ORDER BY 1`)
+	return result, err
+}
+
+// ImportTrades imports new trades of the symbols into binance.spot_fill, the last imported trade id of each symbol
+// is stored in binance.account_trade_import, so the fills recorded by the user data stream do not hide older trades
+func ImportTrades(ctx context.Context, db *sqlx.DB, client *orders.Client, symbols []string) (int, error) {
+	imported := 0
+	for _, symbol := range symbols {
+		var fromId int64
+		if err := db.GetContext(ctx, &fromId, `SELECT COALESCE(max(last_trade_id) + 1, 0)
+			FROM binance.account_trade_import WHERE symbol = $1`, symbol); err != nil {
+			return imported, err
+		}
+		trades, err := FetchTrades(ctx, client, symbol, fromId)
+		if len(trades) > 0 {
+			if err := saveTrades(ctx, db, symbol, trades); err != nil {
+				return imported, err
+			}
+			imported += len(trades)
+		}
+		if err != nil {
+			return imported, err
+		}
+	}
+	return imported, nil
+}
+
+func saveTrades(ctx context.Context, db *sqlx.DB, symbol string, trades []orders.Trade) error {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, t := range trades {
+		side := orders.Sell
+		if t.IsBuyer {
+			side = orders.Buy
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO binance.spot_fill (symbol, trade_id, order_id, client_order_id,
+			side, price, quantity, quote_qty, commission, commission_asset, is_maker, trade_time)
+		VALUES ($1, $2, $3, '', $4, $5, $6, $7, $8, $9, $10, $11)
+		ON CONFLICT DO NOTHING`, t.Symbol, t.Id, t.OrderId, side, t.Price, t.Qty, t.QuoteQty, t.Commission,
+			t.CommissionAsset, t.IsMaker, t.Time); err != nil {
+			return fmt.Errorf("failed to save trade %s %d: %w", t.Symbol, t.Id, err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO binance.account_trade_import (symbol, last_trade_id)
+	VALUES ($1, $2)
+	ON CONFLICT (symbol) DO UPDATE SET last_trade_id = EXCLUDED.last_trade_id, updated_at = now()`,
+		symbol, trades[len(trades)-1].Id); err != nil {
+		return fmt.Errorf("failed to save last trade of %s: %w", symbol, err)
+	}
+	return tx.Commit()
+}
+
+// transferOverlap is the period before the last stored transfer which is requested again,
+// deposits appear in the history when they are pending and become successful later
+const transferOverlap = 7 * 24 * time.Hour
+
+// ImportTransfers imports deposits and withdrawals into binance.account_transfer since the last stored one,
+// since is the start of the history when there are none
+func ImportTransfers(ctx context.Context, db *sqlx.DB, client *orders.Client, since time.Time) (int, error) {
+	var last *int64
+	if err := db.GetContext(ctx, &last, "SELECT max(transfer_time) FROM binance.account_transfer"); err != nil {
+		return 0, err
+	}
+	if last != nil {
+		if start := time.UnixMilli(*last).Add(-transferOverlap); start.After(since) {
+			since = start
+		}
+	}
+	transfers, err := FetchTransfers(ctx, client, since, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	for _, t := range transfers {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO binance.account_transfer (transfer_id, withdrawal, asset, amount,
+			fee, transfer_time, tx_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (transfer_id, withdrawal) DO UPDATE SET amount = EXCLUDED.amount, fee = EXCLUDED.fee,
+			transfer_time = EXCLUDED.transfer_time, tx_id = EXCLUDED.tx_id`,
+			t.Id, t.Withdrawal, t.Asset, t.Amount, t.Fee, t.Time, t.TxId); err != nil {
+			return 0, fmt.Errorf("failed to save transfer %s: %w", t.Id, err)
+		}
+	}
+	return len(transfers), tx.Commit()
+}
+
+// LoadHistory loads the trades of binance.spot_fill with assets of their symbols and the transfers
+func LoadHistory(ctx context.Context, db *sqlx.DB) (trades []Trade, transfers []Transfer, err error) {
+	rows, err := db.QueryContext(ctx, `SELECT f.symbol, e.base_asset, e.quote_asset, f.trade_id, f.trade_time,
+		f.side, f.quantity, f.quote_qty, f.commission, f.commission_asset
+	FROM binance.spot_fill f
+	JOIN binance.exchange_symbols e ON e.symbol = f.symbol
+	ORDER BY f.trade_time, f.trade_id`)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load trades: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var t Trade
+		if err := rows.Scan(&t.Symbol, &t.Base, &t.Quote, &t.Id, &t.Time, &t.Side, &t.Qty, &t.QuoteQty, &t.Commission,
+			&t.CommissionAsset); err != nil {
+			return nil, nil, err
+		}
+		trades = append(trades, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	rows, err = db.QueryContext(ctx, `SELECT transfer_id, withdrawal, asset, amount, fee, transfer_time, tx_id
+	FROM binance.account_transfer
+	ORDER BY transfer_time`)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load transfers: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var t Transfer
+		if err := rows.Scan(&t.Id, &t.Withdrawal, &t.Asset, &t.Amount, &t.Fee, &t.Time, &t.TxId); err != nil {
+			return nil, nil, err
+		}
+		transfers = append(transfers, t)
+	}
+	return trades, transfers, rows.Err()
+}
+
+// KlinePrices are close prices of 1m klines of binance.klines, 1d klines are used when there are no 1m ones.
+// the price of ASSETQUOTE symbol is used or the inverse of QUOTEASSET one
+type KlinePrices struct {
+	DB    *sqlx.DB
+	mu    sync.Mutex
+	cache map[string]float64 // by symbol and minute, 0 if the price is not known
+}
+
+func NewKlinePrices(db *sqlx.DB) *KlinePrices {
+	return &KlinePrices{DB: db, cache: map[string]float64{}}
+}
+
+func (p *KlinePrices) Price(ctx context.Context, asset, quote string, t int64) (float64, bool, error) {
+	price, err := p.closePrice(ctx, asset+quote, t)
+	if err != nil || price > 0 {
+		return price, price > 0, err
+	}
+	price, err = p.closePrice(ctx, quote+asset, t)
+	if err != nil || price == 0 {
+		return 0, false, err
+	}
+	return 1 / price, true, nil
+}
+
+// closePrice returns the close price of the last kline of the symbol opened before t, 0 if there is none
+func (p *KlinePrices) closePrice(ctx context.Context, symbol string, t int64) (float64, error) {
+	key := fmt.Sprintf("%s %d", symbol, t/time.Minute.Milliseconds())
+	p.mu.Lock()
+	price, ok := p.cache[key]
+	p.mu.Unlock()
+	if ok {
+		return price, nil
+	}
+	for _, k := range []struct {
+		period string
+		maxAge time.Duration
+	}{{"1m", time.Hour}, {"1d", 7 * 24 * time.Hour}} {
+		var prices []float64
+		if err := p.DB.SelectContext(ctx, &prices, `SELECT close_price FROM binance.klines
+			WHERE symbol_id = binance.get_symbol_id($1) AND period = $2 AND open_time BETWEEN $3 AND $4
+			ORDER BY open_time DESC LIMIT 1`, symbol, k.period, t-k.maxAge.Milliseconds(), t); err != nil {
+			return 0, fmt.Errorf("failed to get price of %s: %w", symbol, err)
+		}
+		if len(prices) > 0 {
+			price = prices[0]
+			break
+		}
+	}
+	p.mu.Lock()
+	p.cache[key] = price
+	p.mu.Unlock()
+	return price, nil
+}
@@ -0,0 +1,106 @@
+package accounting
+
+import (
+	"context"
+	"fmt"
+	"github.com/okharch/binance/orders"
+	"sort"
+	"time"
+)
+
+// Trade is the trade of the account, Base and Quote are the assets of its symbol
+type Trade struct {
+	Symbol          string
+	Base, Quote     string
+	Id              int64
+	Time            int64
+	Side            orders.Side
+	Qty             float64
+	QuoteQty        float64
+	Commission      float64
+	CommissionAsset string
+}
+
+// Transfer is the deposit or the withdrawal of the asset, Fee is paid on top of the withdrawn Amount
+type Transfer struct {
+	Id         string
+	Withdrawal bool
+	Asset      string
+	Amount     float64
+	Fee        float64
+	Time       int64
+	TxId       string
+}
+
+// tradesLimit is the page size of myTrades requests
+var tradesLimit = 1000
+
+// FetchTrades returns trades of the symbol starting from trade id fromId requesting them page by page
+func FetchTrades(ctx context.Context, client *orders.Client, symbol string, fromId int64) ([]orders.Trade, error) {
+	var result []orders.Trade
+	for {
+		trades, err := client.MyTrades(ctx, symbol, fromId, tradesLimit)
+		if err != nil {
+			return result, fmt.Errorf("failed to get trades of %s from %d: %w", symbol, fromId, err)
+		}
+		result = append(result, trades...)
+		if len(trades) < tradesLimit {
+			return result, nil
+		}
+		fromId = trades[len(trades)-1].Id + 1
+	}
+}
+
+// historyLimit is the page size of deposit and withdrawal history requests
+var historyLimit = 1000
+
+const withdrawalTimeLayout = "2006-01-02 15:04:05"
+
+// FetchTransfers returns successful deposits and completed withdrawals between start and end sorted by time,
+// the history is requested by windows of orders.MaxHistoryWindow
+func FetchTransfers(ctx context.Context, client *orders.Client, start, end time.Time) ([]Transfer, error) {
+	var result []Transfer
+	for from := start; from.Before(end); from = from.Add(orders.MaxHistoryWindow) {
+		to := from.Add(orders.MaxHistoryWindow - time.Millisecond)
+		if to.After(end) {
+			to = end
+		}
+		for offset := 0; ; offset += historyLimit {
+			deposits, err := client.Deposits(ctx, from, to, offset, historyLimit)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get deposits since %v: %w", from, err)
+			}
+			for _, d := range deposits {
+				if d.Status == orders.DepositSuccess {
+					result = append(result, Transfer{Id: d.Id, Asset: d.Coin, Amount: d.Amount, Time: d.InsertTime,
+						TxId: d.TxId})
+				}
+			}
+			if len(deposits) < historyLimit {
+				break
+			}
+		}
+		for offset := 0; ; offset += historyLimit {
+			withdrawals, err := client.Withdrawals(ctx, from, to, offset, historyLimit)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get withdrawals since %v: %w", from, err)
+			}
+			for _, w := range withdrawals {
+				if w.Status != orders.WithdrawalCompleted {
+					continue
+				}
+				applied, err := time.Parse(withdrawalTimeLayout, w.ApplyTime)
+				if err != nil {
+					return nil, fmt.Errorf("invalid time of withdrawal %s: %w", w.Id, err)
+				}
+				result = append(result, Transfer{Id: w.Id, Withdrawal: true, Asset: w.Coin, Amount: w.Amount,
+					Fee: w.TransactionFee, Time: applied.UnixMilli(), TxId: w.TxId})
+			}
+			if len(withdrawals) < historyLimit {
+				break
+			}
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Time < result[j].Time })
+	return result, nil
+}
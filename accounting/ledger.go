@@ -0,0 +1,257 @@
+package accounting
+
+import (
+	"context"
+	"fmt"
+	"github.com/okharch/binance/orders"
+	"sort"
+	"strings"
+	"time"
+)
+
+/*
+Ledger keeps tax lots of the assets valued in the quote currency, e.g. USDT.
+every trade is the disposal of the spent asset and the acquisition of the received one,
+the quote currency itself is cash and has no lots. the trade is valued by its quote quantity
+converted to the quote currency by Prices.
+
+the commission reduces the received quantity if it is paid in the received asset, otherwise
+the commission asset is disposed at its value and the value is added to the cost of the acquisition
+or deducted from the proceeds when the quote currency is received.
+
+deposits are acquired at their market value, withdrawals remove lots without realising P&L
+and their fee is realised as the loss of its cost.
+*/
+
+type Method string
+
+const (
+	FIFO        Method = "fifo"
+	LIFO        Method = "lifo"
+	AverageCost Method = "average"
+)
+
+func ParseMethod(s string) (Method, error) {
+	switch m := Method(strings.ToLower(s)); m {
+	case FIFO, LIFO, AverageCost:
+		return m, nil
+	}
+	return "", fmt.Errorf("unknown cost method %q, use fifo, lifo or average", s)
+}
+
+// Prices returns the price of the asset in quote currency at time t in milliseconds, ok is false if it is unknown
+type Prices interface {
+	Price(ctx context.Context, asset, quote string, t int64) (price float64, ok bool, err error)
+}
+
+// Lot is the quantity of the asset acquired at Time for Cost in quote currency
+type Lot struct {
+	Qty  float64
+	Cost float64
+	Time int64
+}
+
+type Acquisition struct {
+	Time      int64
+	Asset     string
+	Qty       float64
+	Cost      float64
+	Fee       float64 // the value of the commission in quote currency
+	Reference string
+}
+
+// Disposal is the sale of the lot, Acquired is 0 for average cost and for the quantity missing from lots
+type Disposal struct {
+	Time      int64
+	Asset     string
+	Qty       float64
+	Proceeds  float64
+	Cost      float64
+	Fee       float64
+	Acquired  int64
+	Reference string
+}
+
+func (d Disposal) Realised() float64 {
+	return d.Proceeds - d.Cost
+}
+
+type Holding struct {
+	Asset string
+	Qty   float64
+	Cost  float64
+}
+
+type Ledger struct {
+	Method       Method
+	Quote        string
+	Prices       Prices
+	Acquisitions []Acquisition
+	Disposals    []Disposal
+	// Warnings are missing prices and lots, the missing values are taken as 0
+	Warnings []string
+	lots     map[string][]Lot
+}
+
+func NewLedger(method Method, quote string, prices Prices) *Ledger {
+	return &Ledger{Method: method, Quote: quote, Prices: prices, lots: map[string][]Lot{}}
+}
+
+// qtyEpsilon is the quantity which is considered zero
+const qtyEpsilon = 1e-10
+
+// Trade records the trade, trades and transfers must be recorded in time order
+func (l *Ledger) Trade(ctx context.Context, t Trade) error {
+	value, err := l.value(ctx, t.Quote, t.QuoteQty, t.Time)
+	if err != nil {
+		return err
+	}
+	fee, err := l.value(ctx, t.CommissionAsset, t.Commission, t.Time)
+	if err != nil {
+		return err
+	}
+	spent, spentQty, received, receivedQty := t.Quote, t.QuoteQty, t.Base, t.Qty
+	if t.Side == orders.Sell {
+		spent, spentQty, received, receivedQty = t.Base, t.Qty, t.Quote, t.QuoteQty
+	}
+	ref := fmt.Sprintf("%s trade %d", t.Symbol, t.Id)
+	// extraFee is the value of the commission which is not paid by the quantities of the trade
+	var extraFee float64
+	switch {
+	case t.Commission == 0:
+	case t.CommissionAsset == received && received != l.Quote:
+		receivedQty -= t.Commission
+	case t.CommissionAsset == spent && spent != l.Quote:
+		spentQty += t.Commission
+	default:
+		extraFee = fee
+		if t.CommissionAsset != l.Quote {
+			l.dispose(t.CommissionAsset, t.Commission, fee, 0, t.Time, ref+" commission")
+		}
+	}
+	if spent != l.Quote {
+		proceeds, disposalFee := value, 0.0
+		if received == l.Quote {
+			proceeds, disposalFee = value-extraFee, fee
+		}
+		l.dispose(spent, spentQty, proceeds, disposalFee, t.Time, ref)
+	}
+	if received != l.Quote {
+		l.acquire(received, receivedQty, value+extraFee, fee, t.Time, ref)
+	}
+	return nil
+}
+
+// Transfer records the deposit or the withdrawal, the transfers of the quote currency are ignored
+func (l *Ledger) Transfer(ctx context.Context, t Transfer) error {
+	if t.Asset == l.Quote {
+		return nil
+	}
+	if !t.Withdrawal {
+		value, err := l.value(ctx, t.Asset, t.Amount, t.Time)
+		if err != nil {
+			return err
+		}
+		l.acquire(t.Asset, t.Amount, value, 0, t.Time, "deposit "+t.Id)
+		return nil
+	}
+	l.take(t.Asset, t.Amount, t.Time)
+	if t.Fee > 0 {
+		l.dispose(t.Asset, t.Fee, 0, 0, t.Time, "withdrawal fee "+t.Id)
+	}
+	return nil
+}
+
+// Holdings returns the quantities and cost basis of the assets sorted by asset
+func (l *Ledger) Holdings() []Holding {
+	var result []Holding
+	for asset, lots := range l.lots {
+		h := Holding{Asset: asset}
+		for _, lot := range lots {
+			h.Qty += lot.Qty
+			h.Cost += lot.Cost
+		}
+		if h.Qty > qtyEpsilon {
+			result = append(result, h)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Asset < result[j].Asset })
+	return result
+}
+
+// value returns the value of qty of the asset in quote currency at time t
+func (l *Ledger) value(ctx context.Context, asset string, qty float64, t int64) (float64, error) {
+	if qty == 0 || asset == l.Quote {
+		return qty, nil
+	}
+	price, ok, err := l.Prices.Price(ctx, asset, l.Quote, t)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get price of %s: %w", asset, err)
+	}
+	if !ok {
+		l.warn(t, "no price of %s in %s", asset, l.Quote)
+		return 0, nil
+	}
+	return qty * price, nil
+}
+
+func (l *Ledger) acquire(asset string, qty, cost, fee float64, t int64, ref string) {
+	l.Acquisitions = append(l.Acquisitions, Acquisition{Time: t, Asset: asset, Qty: qty, Cost: cost, Fee: fee,
+		Reference: ref})
+	lots := l.lots[asset]
+	if l.Method == AverageCost && len(lots) > 0 {
+		lots[0].Qty += qty
+		lots[0].Cost += cost
+		return
+	}
+	l.lots[asset] = append(lots, Lot{Qty: qty, Cost: cost, Time: t})
+}
+
+// dispose takes qty of the asset from the lots and records a disposal per lot, proceeds are split by quantity
+func (l *Ledger) dispose(asset string, qty, proceeds, fee float64, t int64, ref string) {
+	for i, lot := range l.take(asset, qty, t) {
+		d := Disposal{Time: t, Asset: asset, Qty: lot.Qty, Proceeds: proceeds * lot.Qty / qty, Cost: lot.Cost,
+			Acquired: lot.Time, Reference: ref}
+		if i == 0 {
+			d.Fee = fee
+		}
+		if l.Method == AverageCost {
+			d.Acquired = 0
+		}
+		l.Disposals = append(l.Disposals, d)
+	}
+}
+
+// take removes qty of the asset from the lots in the order of the method,
+// the quantity missing from the lots is returned as a lot without cost
+func (l *Ledger) take(asset string, qty float64, t int64) (taken []Lot) {
+	lots := l.lots[asset]
+	for qty > qtyEpsilon && len(lots) > 0 {
+		i := 0
+		if l.Method == LIFO {
+			i = len(lots) - 1
+		}
+		lot := &lots[i]
+		if lot.Qty <= qty+qtyEpsilon {
+			taken = append(taken, *lot)
+			qty -= lot.Qty
+			lots = append(lots[:i], lots[i+1:]...)
+			continue
+		}
+		part := Lot{Qty: qty, Cost: lot.Cost * qty / lot.Qty, Time: lot.Time}
+		lot.Qty -= part.Qty
+		lot.Cost -= part.Cost
+		taken = append(taken, part)
+		qty = 0
+	}
+	l.lots[asset] = lots
+	if qty > qtyEpsilon {
+		l.warn(t, "%v %s is missing from lots, its cost is 0", qty, asset)
+		taken = append(taken, Lot{Qty: qty})
+	}
+	return taken
+}
+
+func (l *Ledger) warn(t int64, format string, args ...any) {
+	l.Warnings = append(l.Warnings, time.UnixMilli(t).UTC().Format(time.RFC3339)+": "+fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,194 @@
+package accounting
+
+import (
+	"context"
+	"encoding/csv"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// YearSummary is P&L of the asset in the year, the holdings are valued at the end of the year
+type YearSummary struct {
+	Year        int
+	Asset       string
+	Bought      float64
+	BoughtCost  float64
+	Sold        float64
+	Proceeds    float64
+	SoldCost    float64
+	Realised    float64
+	Fees        float64
+	Held        float64
+	CostBasis   float64
+	MarketValue float64
+	Unrealised  float64
+}
+
+// Report records trades and transfers into the ledger in time order and summarises them per year and asset,
+// the holdings of the current year are valued at now
+func Report(ctx context.Context, l *Ledger, trades []Trade, transfers []Transfer, now time.Time) ([]YearSummary, error) {
+	type event struct {
+		time     int64
+		trade    *Trade
+		transfer *Transfer
+	}
+	events := make([]event, 0, len(trades)+len(transfers))
+	for i := range trades {
+		events = append(events, event{time: trades[i].Time, trade: &trades[i]})
+	}
+	for i := range transfers {
+		events = append(events, event{time: transfers[i].Time, transfer: &transfers[i]})
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].time < events[j].time })
+	if len(events) == 0 {
+		return nil, nil
+	}
+	var result []YearSummary
+	year := time.UnixMilli(events[0].time).UTC().Year()
+	acquisitions, disposals := 0, 0
+	// closeYear summarises the records of the year since the previous one
+	closeYear := func() error {
+		end := time.Date(year+1, 1, 1, 0, 0, 0, 0, time.UTC).Add(-time.Millisecond)
+		if end.After(now) {
+			end = now
+		}
+		summary, err := l.summarise(ctx, year, l.Acquisitions[acquisitions:], l.Disposals[disposals:], end.UnixMilli())
+		if err != nil {
+			return err
+		}
+		result = append(result, summary...)
+		acquisitions, disposals = len(l.Acquisitions), len(l.Disposals)
+		year++
+		return nil
+	}
+	for _, e := range events {
+		for time.UnixMilli(e.time).UTC().Year() > year {
+			if err := closeYear(); err != nil {
+				return nil, err
+			}
+		}
+		var err error
+		if e.trade != nil {
+			err = l.Trade(ctx, *e.trade)
+		} else {
+			err = l.Transfer(ctx, *e.transfer)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	for year <= now.UTC().Year() {
+		if err := closeYear(); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// summarise returns the summaries of the assets with records or holdings sorted by asset
+func (l *Ledger) summarise(ctx context.Context, year int, acquisitions []Acquisition, disposals []Disposal,
+	end int64) ([]YearSummary, error) {
+	byAsset := map[string]*YearSummary{}
+	get := func(asset string) *YearSummary {
+		s, ok := byAsset[asset]
+		if !ok {
+			s = &YearSummary{Year: year, Asset: asset}
+			byAsset[asset] = s
+		}
+		return s
+	}
+	for _, a := range acquisitions {
+		s := get(a.Asset)
+		s.Bought += a.Qty
+		s.BoughtCost += a.Cost
+		s.Fees += a.Fee
+	}
+	for _, d := range disposals {
+		s := get(d.Asset)
+		s.Sold += d.Qty
+		s.Proceeds += d.Proceeds
+		s.SoldCost += d.Cost
+		s.Realised += d.Realised()
+		s.Fees += d.Fee
+	}
+	for _, h := range l.Holdings() {
+		s := get(h.Asset)
+		s.Held, s.CostBasis = h.Qty, h.Cost
+		value, err := l.value(ctx, h.Asset, h.Qty, end)
+		if err != nil {
+			return nil, err
+		}
+		s.MarketValue, s.Unrealised = value, value-h.Cost
+	}
+	result := make([]YearSummary, 0, len(byAsset))
+	for _, s := range byAsset {
+		result = append(result, *s)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Asset < result[j].Asset })
+	return result, nil
+}
+
+// Total is the asset of the summary row of the year in csv
+const Total = "TOTAL"
+
+// WriteCSV writes the summaries with the total of quote currency columns after each year
+func WriteCSV(w io.Writer, summaries []YearSummary) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"year", "asset", "bought", "bought_cost", "sold", "proceeds", "sold_cost", "realised",
+		"fees", "held", "cost_basis", "market_value", "unrealised"})
+	var total YearSummary
+	for i, s := range summaries {
+		writer.Write([]string{strconv.Itoa(s.Year), s.Asset, formatAmount(s.Bought), formatAmount(s.BoughtCost),
+			formatAmount(s.Sold), formatAmount(s.Proceeds), formatAmount(s.SoldCost), formatAmount(s.Realised),
+			formatAmount(s.Fees), formatAmount(s.Held), formatAmount(s.CostBasis), formatAmount(s.MarketValue),
+			formatAmount(s.Unrealised)})
+		total.BoughtCost += s.BoughtCost
+		total.Proceeds += s.Proceeds
+		total.SoldCost += s.SoldCost
+		total.Realised += s.Realised
+		total.Fees += s.Fees
+		total.CostBasis += s.CostBasis
+		total.MarketValue += s.MarketValue
+		total.Unrealised += s.Unrealised
+		if i == len(summaries)-1 || summaries[i+1].Year != s.Year {
+			writer.Write([]string{strconv.Itoa(s.Year), Total, "", formatAmount(total.BoughtCost), "",
+				formatAmount(total.Proceeds), formatAmount(total.SoldCost), formatAmount(total.Realised),
+				formatAmount(total.Fees), "", formatAmount(total.CostBasis), formatAmount(total.MarketValue),
+				formatAmount(total.Unrealised)})
+			total = YearSummary{}
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteDisposalsCSV writes the disposals of the tax lots
+func WriteDisposalsCSV(w io.Writer, disposals []Disposal) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"time", "asset", "qty", "acquired", "proceeds", "cost", "fee", "realised", "reference"})
+	for _, d := range disposals {
+		acquired := ""
+		if d.Acquired != 0 {
+			acquired = formatTime(d.Acquired)
+		}
+		writer.Write([]string{formatTime(d.Time), d.Asset, formatAmount(d.Qty), acquired, formatAmount(d.Proceeds),
+			formatAmount(d.Cost), formatAmount(d.Fee), formatAmount(d.Realised()), d.Reference})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// formatAmount rounds v to 8 decimal places like Binance does
+func formatAmount(v float64) string {
+	r, _ := strconv.ParseFloat(strconv.FormatFloat(v, 'f', 8, 64), 64)
+	if r == 0 {
+		return "0" // not -0
+	}
+	return strconv.FormatFloat(r, 'f', -1, 64)
+}
+
+func formatTime(t int64) string {
+	return time.UnixMilli(t).UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,337 @@
+package backtest
+
+import (
+	"context"
+	"errors"
+	"github.com/okharch/binance/klines"
+	"github.com/okharch/binance/sizing"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+// kLinesOf returns 1m klines with open prices, high and low are 1 above and below the open, close equals open
+func kLinesOf(prices ...float64) []klines.KLineEntry {
+	result := make([]klines.KLineEntry, len(prices))
+	for i, p := range prices {
+		openTime := int64(i) * 60000
+		result[i] = klines.KLineEntry{OpenTime: openTime, CloseTime: openTime + 59999,
+			OpenPrice: p, HighPrice: p + 1, LowPrice: p - 1, ClosePrice: p, Volume: 1}
+	}
+	return result
+}
+
+func testConfig() Config {
+	cfg := DefaultConfig()
+	cfg.InitialCash = 1000
+	cfg.Fees = Fees{Maker: 0.001, Taker: 0.002}
+	cfg.Slippage = 0.01
+	cfg.Latency = 0
+	cfg.KeepKLines = 16
+	return cfg
+}
+
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func run(t *testing.T, cfg Config, data []klines.KLineEntry, f StrategyFunc) *Result {
+	t.Helper()
+	r, err := New(cfg).Run(context.Background(), f, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestMarketOrders(t *testing.T) {
+	data := kLinesOf(100, 100, 110, 120, 120)
+	r := run(t, testConfig(), data, func(e *Engine, k klines.KLineEntry) {
+		switch k.OpenTime {
+		case 0:
+			e.Buy(5)
+		case 2 * 60000:
+			e.Sell(5)
+		}
+	})
+	if len(r.Fills) != 2 || len(r.Trades) != 1 {
+		t.Fatalf("expected 2 fills and 1 trade, got %+v %+v", r.Fills, r.Trades)
+	}
+	buy, sell := r.Fills[0], r.Fills[1]
+	if buy.Time != 60000 || !almostEqual(buy.Price, 101) || !almostEqual(buy.Fee, 101*5*0.002) {
+		t.Errorf("unexpected buy fill %+v", buy)
+	}
+	if sell.Time != 3*60000 || !almostEqual(sell.Price, 120*0.99) {
+		t.Errorf("unexpected sell fill %+v", sell)
+	}
+	trade := r.Trades[0]
+	fees := 101*5*0.002 + 118.8*5*0.002
+	if !almostEqual(trade.Fees, fees) || !almostEqual(trade.PnL, (118.8-101)*5-fees) {
+		t.Errorf("unexpected trade %+v", trade)
+	}
+	if !almostEqual(r.FinalEquity, 1000+trade.PnL) {
+		t.Errorf("expected final equity %f, got %f", 1000+trade.PnL, r.FinalEquity)
+	}
+}
+
+func TestLimitAndStopOrders(t *testing.T) {
+	// the buy limit rests at 95 and fills when the low reaches it, the stop sells when the low reaches 97
+	data := kLinesOf(100, 100, 98, 96, 100, 98, 97)
+	var buyId, stopId int
+	r := run(t, testConfig(), data, func(e *Engine, k klines.KLineEntry) {
+		if k.OpenTime == 0 {
+			buyId = e.Submit(Order{Side: Buy, Type: Limit, Quantity: 1, Price: 95})
+		}
+		if o, _ := e.Order(buyId); o.Status == Filled && stopId == 0 {
+			stopId = e.Submit(Order{Side: Sell, Type: Stop, Quantity: 1, StopPrice: 97.5})
+		}
+	})
+	if len(r.Fills) != 2 {
+		t.Fatalf("expected 2 fills, got %+v", r.Fills)
+	}
+	buy, sell := r.Fills[0], r.Fills[1]
+	if buy.Time != 3*60000 || buy.Price != 95 || !buy.Maker {
+		t.Errorf("unexpected limit fill %+v", buy)
+	}
+	// the stop is triggered on the kline opened at 98 with the low at 97
+	if sell.Time != 5*60000 || !almostEqual(sell.Price, 97.5*0.99) || sell.Maker {
+		t.Errorf("unexpected stop fill %+v", sell)
+	}
+}
+
+func TestRejectsAndLatency(t *testing.T) {
+	cfg := testConfig()
+	cfg.Latency = 90 * time.Second
+	data := kLinesOf(100, 100, 100, 100)
+	r := run(t, cfg, data, func(e *Engine, k klines.KLineEntry) {
+		if k.OpenTime == 0 {
+			e.Buy(100) // can't be afforded
+			e.Sell(1)  // nothing to sell
+			e.BuyQuote(500)
+		}
+	})
+	if r.Orders[0].Status != Rejected || r.Orders[1].Status != Rejected || r.Orders[2].Status != Filled {
+		t.Fatalf("unexpected orders %+v", r.Orders)
+	}
+	// submitted at 60000, active at 150000, so the next kline opened at 60000 is skipped
+	if fill := r.Fills[0]; fill.Time != 3*60000 || !almostEqual(fill.Price*fill.Quantity+fill.Fee, 500) {
+		t.Errorf("unexpected fill %+v", fill)
+	}
+	// the position is closed at the end
+	if len(r.Trades) != 1 || r.Equity[len(r.Equity)-1].Position != 0 {
+		t.Errorf("expected the position to be closed at the end, got %+v", r.Trades)
+	}
+}
+
+func TestPeriodClosed(t *testing.T) {
+	var closed []int64
+	prices := make([]float64, 16)
+	for i := range prices {
+		prices[i] = float64(i + 1)
+	}
+	run(t, testConfig(), kLinesOf(prices...), func(e *Engine, k klines.KLineEntry) {
+		if e.PeriodClosed(5 * time.Minute) {
+			closed = append(closed, k.OpenTime/60000)
+			// the ticker does not return the first klines of the period
+			if len(closed) < 3 {
+				return
+			}
+			kl := e.KLines(5*time.Minute, 1)
+			if kl == nil || kl[0].CloseTime != k.CloseTime {
+				t.Errorf("expected 5m kline closed by %d, got %+v", k.OpenTime, kl)
+			}
+		}
+	})
+	if len(closed) != 3 || closed[0] != 4 || closed[1] != 9 || closed[2] != 14 {
+		t.Errorf("expected 5m klines to close on 4, 9 and 14 minutes, got %v", closed)
+	}
+}
+
+func TestPeriodClosedMidPeriod(t *testing.T) {
+	prices := make([]float64, 20)
+	for i := range prices {
+		prices[i] = float64(i + 1)
+	}
+	kl := kLinesOf(prices...)
+	var closed []int64
+	strategy := StrategyFunc(func(e *Engine, k klines.KLineEntry) {
+		if !e.PeriodClosed(5 * time.Minute) {
+			return
+		}
+		closed = append(closed, k.OpenTime/60000)
+		// the ticker does not return the first 5m kline, it may be incomplete
+		if len(closed) < 2 {
+			return
+		}
+		if p := e.KLines(5*time.Minute, 1); p == nil || p[0].OpenTime != k.OpenTime-4*60000 {
+			t.Errorf("expected the complete 5m kline closed by %d, got %+v", k.OpenTime, p)
+		}
+	})
+	// the history starts in the middle of the 5m kline
+	if _, err := New(testConfig()).RunWithHistory(context.Background(), strategy, kl[2:7], kl[7:]); err != nil {
+		t.Fatal(err)
+	}
+	if len(closed) != 3 || closed[0] != 9 || closed[1] != 14 || closed[2] != 19 {
+		t.Errorf("expected 5m klines to close on 9, 14 and 19 minutes, got %v", closed)
+	}
+}
+
+func TestPeriodsAfterGaps(t *testing.T) {
+	prices := make([]float64, 30)
+	for i := range prices {
+		prices[i] = float64(i + 1)
+	}
+	kl := kLinesOf(prices...)
+	// minutes 6-8 are missing within the 5m kline, 12-17 across its end
+	data := append(append(kl[:6:6], kl[9:12]...), kl[18:]...)
+	var last []klines.KLineEntry
+	strategy := StrategyFunc(func(e *Engine, k klines.KLineEntry) {
+		last = e.KLines(5*time.Minute, 3)
+	})
+	if _, err := New(testConfig()).Run(context.Background(), strategy, data); err != nil {
+		t.Fatal(err)
+	}
+	if len(last) != 3 {
+		t.Fatalf("expected 3 5m klines, got %+v", last)
+	}
+	for i, minute := range []int64{15, 20, 25} {
+		if last[i].OpenTime != minute*60000 {
+			t.Errorf("expected 5m kline %d to open on %d minute, got %+v", i, minute, last[i])
+		}
+	}
+	if last[0].Volume != 2 || last[0].OpenPrice != 19 || last[2].Volume != 5 {
+		t.Errorf("expected the kline after the gap to aggregate 2 klines, got %+v", last)
+	}
+
+	// the weekly klines start on Monday
+	monday := time.Date(2023, 2, 27, 0, 0, 0, 0, time.UTC).UnixMilli()
+	data = nil
+	for day := 0; day < 21; day += 3 {
+		openTime := monday + int64(day)*24*time.Hour.Milliseconds()
+		data = append(data, klines.KLineEntry{OpenTime: openTime, CloseTime: openTime + 59999, OpenPrice: 1,
+			HighPrice: 1, LowPrice: 1, ClosePrice: 1, Volume: 1})
+	}
+	strategy = StrategyFunc(func(e *Engine, k klines.KLineEntry) {
+		last = e.KLines(7*24*time.Hour, 1)
+	})
+	if _, err := New(testConfig()).Run(context.Background(), strategy, data); err != nil {
+		t.Fatal(err)
+	}
+	week := 7 * 24 * time.Hour.Milliseconds()
+	if len(last) != 1 || last[0].OpenTime != monday+2*week || last[0].Volume != 2 {
+		t.Errorf("expected the third week to open on Monday %d, got %+v", monday+2*week, last)
+	}
+}
+
+func TestReadKLinesCSV(t *testing.T) {
+	data := `open_time,open,high,low,close,volume,close_time,quote_volume,count,taker_buy_volume,taker_buy_quote_volume,ignore
+1735689600000000,93576.00,93610.93,93537.50,93610.93,8.21827,1735689659999999,768978.05,1236,4.07,381113.69,0
+1735689660000,93610.93,93652.00,93606.15,93651.99,12.5,1735689719999,1170000.5,1500,6.1,571000.1,0
+`
+	kLines, err := ReadKLinesCSV(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kLines) != 2 {
+		t.Fatalf("expected 2 klines, got %d", len(kLines))
+	}
+	k := kLines[0]
+	if k.OpenTime != 1735689600000 || k.CloseTime != 1735689659999 || k.HighPrice != 93610.93 || k.NumTrades != 1236 ||
+		k.TakerBuyQuoteAssetVolume != 381113.69 {
+		t.Errorf("unexpected kline %+v", k)
+	}
+	if kLines[1].OpenTime != 1735689660000 {
+		t.Errorf("unexpected open time %d", kLines[1].OpenTime)
+	}
+}
+
+func TestLiveStepping(t *testing.T) {
+	data := kLinesOf(100, 100, 100, 110, 120)
+	buy := StrategyFunc(func(e *Engine, k klines.KLineEntry) {
+		if k.OpenTime == 2*60000 {
+			e.Buy(2)
+			e.Submit(Order{Side: Sell, Type: Limit, Quantity: 2, Price: 115})
+		}
+	})
+	e := New(testConfig())
+	e.Start(data[:2])
+	e.Step(buy, data[2])
+	e.FillAtQuotes(e.Time(), 99.5, 100.5)
+	u := e.TakeUpdates()
+	if len(u.Orders) != 2 || u.Orders[0].Status != Filled || u.Orders[1].Status != Pending {
+		t.Fatalf("unexpected orders %+v", u.Orders)
+	}
+	if len(u.Fills) != 1 || u.Fills[0].Price != 100.5 || u.Fills[0].Maker {
+		t.Fatalf("expected the buy to be filled at the ask, got %+v", u.Fills)
+	}
+	if u = e.TakeUpdates(); len(u.Orders)+len(u.Fills)+len(u.Equity) != 0 {
+		t.Errorf("expected no updates, got %+v", u)
+	}
+
+	// restart: the state is restored into a new engine which fills the resting limit order
+	state := e.State()
+	restored := New(testConfig())
+	restored.Start(data[:3])
+	restored.Restore(state)
+	if restored.Position() != 2 || !almostEqual(restored.Cash(), e.Cash()) || len(restored.OpenOrders()) != 1 {
+		t.Fatalf("unexpected restored state %+v", restored.State())
+	}
+	restored.Step(buy, data[3])
+	restored.Step(buy, data[4])
+	u = restored.TakeUpdates()
+	if len(u.Fills) != 1 || u.Fills[0].Price != 115 || !u.Fills[0].Maker || len(u.Trades) != 1 {
+		t.Fatalf("expected the limit sell to be filled at 115, got %+v", u)
+	}
+	if id := restored.Buy(1); id != state.NextId {
+		t.Errorf("expected order id %d after restore, got %d", state.NextId, id)
+	}
+}
+
+func TestOrderCheck(t *testing.T) {
+	data := kLinesOf(100, 100, 100)
+	e := New(testConfig())
+	e.SetOrderCheck(func(e *Engine, o Order) error {
+		if o.Side == Buy && o.Quantity*e.Price() > 150 {
+			return errors.New("position limit")
+		}
+		return nil
+	})
+	e.Start(nil)
+	var ids []int
+	strategy := StrategyFunc(func(e *Engine, k klines.KLineEntry) {
+		if k.OpenTime == 0 {
+			ids = append(ids, e.Buy(2), e.Buy(1))
+		}
+	})
+	for _, k := range data {
+		e.Step(strategy, k)
+	}
+	rejected, _ := e.Order(ids[0])
+	filled, _ := e.Order(ids[1])
+	if rejected.Status != Rejected || rejected.Reason != "position limit" || filled.Status != Filled {
+		t.Errorf("unexpected orders %+v %+v", rejected, filled)
+	}
+}
+
+func TestSize(t *testing.T) {
+	e := New(testConfig())
+	e.Start(nil)
+	rules := sizing.Rules{StepSize: 0.01, MinNotional: 10}
+	var sizes []float64
+	var errs []error
+	strategy := StrategyFunc(func(e *Engine, k klines.KLineEntry) {
+		if k.OpenTime == 0 {
+			q, err := e.Size(sizing.FixedFractional{Fraction: 0.5}, time.Minute, rules)
+			sizes, errs = append(sizes, q), append(errs, err)
+			buy(e, time.Minute, 1, sizing.FixedFractional{Fraction: 2}, rules)
+		}
+	})
+	for _, k := range kLinesOf(30, 30, 30) {
+		e.Step(strategy, k)
+	}
+	// the fraction over 1 is limited by the cash reduced by the fee and slippage
+	if sizes[0] != 16.66 || errs[0] != nil || e.Position() != 32.93 {
+		t.Errorf("unexpected size %v, %v, position %v", sizes[0], errs[0], e.Position())
+	}
+}
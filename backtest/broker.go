@@ -0,0 +1,263 @@
+package backtest
+
+import (
+	"github.com/okharch/binance/klines"
+	"math"
+	"math/rand"
+)
+
+// quantities below epsilon are treated as zero
+const epsilon = 1e-12
+
+type pendingOrder struct {
+	*Order
+	// resting limit orders are in the order book, they fill at their price as makers
+	resting bool
+}
+
+// broker simulates order execution on 1m klines and keeps the portfolio of one symbol
+type broker struct {
+	cfg     Config
+	rnd     *rand.Rand     // jitter of order latency
+	orders  map[int]*Order // submitted orders by id
+	nextId  int
+	pending []pendingOrder
+	updated []*Order // orders changed since the last takeUpdates
+
+	cash      float64
+	position  float64
+	avgPrice  float64 // average entry price of the position
+	entryFees float64 // fees paid for the open position
+	entryTime int64   // when the position was opened from flat
+
+	fills  []Fill
+	trades []Trade
+}
+
+func newBroker(cfg Config) *broker {
+	b := &broker{cfg: cfg, cash: cfg.InitialCash, orders: map[int]*Order{}, nextId: 1}
+	if cfg.Jitter > 0 {
+		b.rnd = rand.New(rand.NewSource(cfg.Seed))
+	}
+	return b
+}
+
+// submit registers the order submitted at time now, it is active after the latency,
+// the order is rejected with reason if it is not empty
+func (b *broker) submit(o Order, now int64, reason string) int {
+	o.Id = b.nextId
+	b.nextId++
+	o.SubmitTime = now
+	o.ActiveTime = now + b.cfg.Latency.Milliseconds()
+	if b.rnd != nil {
+		o.ActiveTime += b.rnd.Int63n(b.cfg.Jitter.Milliseconds() + 1)
+	}
+	o.Status = Pending
+	order := &o
+	b.orders[o.Id] = order
+	b.updated = append(b.updated, order)
+	if reason == "" {
+		reason = validate(order)
+	}
+	if reason != "" {
+		b.reject(order, reason)
+		return o.Id
+	}
+	b.pending = append(b.pending, pendingOrder{Order: order})
+	return o.Id
+}
+
+func validate(o *Order) string {
+	switch {
+	case o.Quantity < 0 || o.QuoteQuantity < 0:
+		return "negative quantity"
+	case o.Quantity == 0 && o.QuoteQuantity == 0:
+		return "zero quantity"
+	case o.QuoteQuantity > 0 && (o.Type != Market || o.Side != Buy):
+		return "quote quantity is supported by market buy orders only"
+	case o.Type == Limit && o.Price <= 0:
+		return "limit order without price"
+	case o.Type == Stop && o.StopPrice <= 0:
+		return "stop order without stop price"
+	}
+	return ""
+}
+
+func (b *broker) reject(o *Order, reason string) {
+	o.Status = Rejected
+	o.Reason = reason
+	b.updated = append(b.updated, o)
+}
+
+func (b *broker) cancel(id int) bool {
+	for i, p := range b.pending {
+		if p.Id == id {
+			p.Status = Cancelled
+			b.updated = append(b.updated, p.Order)
+			b.pending = append(b.pending[:i], b.pending[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// process fills active pending orders which are reached by the kline in the order of submission
+func (b *broker) process(k *klines.KLineEntry) {
+	remaining := b.pending[:0]
+	for _, p := range b.pending {
+		if k.OpenTime < p.ActiveTime {
+			remaining = append(remaining, p)
+			continue
+		}
+		price, maker, ok := b.match(p, k)
+		if !ok {
+			// limit order which is not marketable on activation rests in the book
+			p.resting = true
+			remaining = append(remaining, p)
+			continue
+		}
+		b.execute(p.Order, k.OpenTime, price, maker)
+	}
+	b.pending = remaining
+}
+
+// processQuotes fills active pending orders which are marketable at the best bid and ask at time
+func (b *broker) processQuotes(time int64, bid, ask float64) {
+	remaining := b.pending[:0]
+	for _, p := range b.pending {
+		price, ok := 0.0, false
+		if time >= p.ActiveTime {
+			price, ok = matchQuote(p, bid, ask)
+		}
+		if !ok {
+			remaining = append(remaining, p)
+			continue
+		}
+		b.execute(p.Order, time, price, false)
+	}
+	b.pending = remaining
+}
+
+// matchQuote returns the price the order takes liquidity at: the ask for buys and the bid for sells
+func matchQuote(p pendingOrder, bid, ask float64) (float64, bool) {
+	price := ask
+	if p.Side == Sell {
+		price = bid
+	}
+	switch p.Type {
+	case Market:
+		return price, true
+	case Limit:
+		return price, p.Side == Buy && price <= p.Price || p.Side == Sell && price >= p.Price
+	case Stop:
+		return price, p.Side == Buy && price >= p.StopPrice || p.Side == Sell && price <= p.StopPrice
+	}
+	return 0, false
+}
+
+// match returns the fill price of the order on the kline
+func (b *broker) match(p pendingOrder, k *klines.KLineEntry) (price float64, maker bool, ok bool) {
+	slip := 1 + b.cfg.Slippage
+	if p.Side == Sell {
+		slip = 1 - b.cfg.Slippage
+	}
+	switch p.Type {
+	case Market:
+		return k.OpenPrice * slip, false, true
+	case Stop:
+		if p.Side == Buy && k.HighPrice >= p.StopPrice {
+			return math.Max(k.OpenPrice, p.StopPrice) * slip, false, true
+		}
+		if p.Side == Sell && k.LowPrice <= p.StopPrice {
+			return math.Min(k.OpenPrice, p.StopPrice) * slip, false, true
+		}
+	case Limit:
+		// the order which is marketable on arrival takes liquidity at the open price
+		if !p.resting {
+			if p.Side == Buy && k.OpenPrice <= p.Price || p.Side == Sell && k.OpenPrice >= p.Price {
+				return k.OpenPrice, false, true
+			}
+		}
+		if p.Side == Buy && k.LowPrice <= p.Price || p.Side == Sell && k.HighPrice >= p.Price {
+			return p.Price, true, true
+		}
+	}
+	return 0, false, false
+}
+
+// execute fills the order at price and updates the portfolio, the order is rejected if it can't be afforded
+func (b *broker) execute(o *Order, time int64, price float64, maker bool) {
+	rate := b.cfg.Fees.Rate(maker)
+	qty := o.Quantity
+	if o.Side == Buy {
+		if o.QuoteQuantity > 0 {
+			qty = o.QuoteQuantity / (price * (1 + rate))
+		}
+		cost := price * qty
+		fee := cost * rate
+		if cost+fee > b.cash*(1+epsilon) {
+			b.reject(o, "insufficient cash")
+			return
+		}
+		b.cash = math.Max(b.cash-cost-fee, 0)
+		if b.position < epsilon {
+			b.entryTime = time
+		}
+		b.avgPrice = (b.avgPrice*b.position + cost) / (b.position + qty)
+		b.position += qty
+		b.entryFees += fee
+		b.fill(o, time, price, qty, fee, maker)
+		return
+	}
+	if qty > b.position*(1+epsilon) {
+		b.reject(o, "insufficient position")
+		return
+	}
+	qty = math.Min(qty, b.position)
+	proceeds := price * qty
+	fee := proceeds * rate
+	share := qty / b.position
+	entryFees := b.entryFees * share
+	b.cash += proceeds - fee
+	b.trades = append(b.trades, Trade{
+		EntryTime:  b.entryTime,
+		ExitTime:   time,
+		EntryPrice: b.avgPrice,
+		ExitPrice:  price,
+		Quantity:   qty,
+		Fees:       entryFees + fee,
+		PnL:        (price-b.avgPrice)*qty - entryFees - fee,
+	})
+	b.entryFees -= entryFees
+	b.position -= qty
+	if b.position < epsilon {
+		b.position, b.avgPrice, b.entryFees = 0, 0, 0
+	}
+	b.fill(o, time, price, qty, fee, maker)
+}
+
+// liquidate sells the whole position at price by market order submitted at time
+func (b *broker) liquidate(time int64, price float64) {
+	o := &Order{Id: b.nextId, Side: Sell, Type: Market, Quantity: b.position, SubmitTime: time, ActiveTime: time}
+	b.nextId++
+	b.orders[o.Id] = o
+	b.execute(o, time, price, false)
+}
+
+func (b *broker) fill(o *Order, time int64, price, qty, fee float64, maker bool) {
+	o.Status = Filled
+	b.updated = append(b.updated, o)
+	b.fills = append(b.fills, Fill{
+		OrderId:  o.Id,
+		Time:     time,
+		Side:     o.Side,
+		Price:    price,
+		Quantity: qty,
+		Fee:      fee,
+		Maker:    maker,
+	})
+}
+
+func (b *broker) equity(price float64) float64 {
+	return b.cash + b.position*price
+}
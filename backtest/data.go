@@ -0,0 +1,109 @@
+package backtest
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"github.com/okharch/binance/indicators"
+	"github.com/okharch/binance/klines"
+	"io"
+	"os"
+	"strconv"
+)
+
+// LoadKLinesFromDB loads 1m klines of the symbol from binance.klines
+func LoadKLinesFromDB(db *sqlx.DB, symbolId int32, startTime, endTime int64) ([]klines.KLineEntry, error) {
+	kd, err := klines.FetchKLineDataFromDB(db, symbolId, startTime, endTime)
+	if err != nil {
+		return nil, err
+	}
+	return kd.Data, nil
+}
+
+// LoadSymbolKLinesFromDB loads 1m klines of the symbol by its name, e.g. BTCUSDT
+func LoadSymbolKLinesFromDB(ctx context.Context, db *sqlx.DB, symbol string, startTime, endTime int64) ([]klines.KLineEntry, error) {
+	var symbolId int32
+	if err := db.GetContext(ctx, &symbolId, "SELECT binance.get_symbol_id($1)", symbol); err != nil {
+		return nil, fmt.Errorf("unknown symbol %s: %w", symbol, err)
+	}
+	return LoadKLinesFromDB(db, symbolId, startTime, endTime)
+}
+
+// LoadSignals loads signals of the indicator on the period index of ticker.PeriodMinutes stored by signal generation
+func LoadSignals(ctx context.Context, db *sqlx.DB, symbolId, indicatorId int32, period int8, startTime, endTime int64) (
+	signals []indicators.IndicatorSignal, err error) {
+	err = db.SelectContext(ctx, &signals, `
+		SELECT open_time, symbol_id, indicator_id, side, period, volume
+		FROM binance.indicator_signal
+		WHERE symbol_id = $1 AND indicator_id = $2 AND period = $3 AND open_time BETWEEN $4 AND $5
+		ORDER BY open_time`, symbolId, indicatorId, period, startTime, endTime)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load signals of indicator %d: %w", indicatorId, err)
+	}
+	return
+}
+
+// LoadKLinesCSV loads 1m klines from csv file exported by Binance (data.binance.vision):
+// open time, open, high, low, close, volume, close time, quote volume, trades, taker buy base and quote volume.
+// header line is skipped, times in microseconds are converted to milliseconds
+func LoadKLinesCSV(fileName string) ([]klines.KLineEntry, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	result, err := ReadKLinesCSV(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fileName, err)
+	}
+	return result, nil
+}
+
+// ReadKLinesCSV reads klines in the format of LoadKLinesCSV
+func ReadKLinesCSV(r io.Reader) (result []klines.KLineEntry, err error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return result, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) < 11 {
+			return nil, fmt.Errorf("line %d: expected at least 11 fields, got %d", line, len(record))
+		}
+		if line == 1 {
+			if _, err := strconv.ParseInt(record[0], 10, 64); err != nil {
+				continue // header
+			}
+		}
+		var k klines.KLineEntry
+		var ints [3]int64
+		var floats [8]float64
+		for i, idx := range []int{0, 6, 8} {
+			if ints[i], err = strconv.ParseInt(record[idx], 10, 64); err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+		}
+		for i, idx := range []int{1, 2, 3, 4, 5, 7, 9, 10} {
+			if floats[i], err = strconv.ParseFloat(record[idx], 64); err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+		}
+		k.OpenTime, k.CloseTime, k.NumTrades = toMillis(ints[0]), toMillis(ints[1]), ints[2]
+		k.OpenPrice, k.HighPrice, k.LowPrice, k.ClosePrice, k.Volume = floats[0], floats[1], floats[2], floats[3], floats[4]
+		k.QuoteAssetVolume, k.TakerBuyBaseAssetVolume, k.TakerBuyQuoteAssetVolume = floats[5], floats[6], floats[7]
+		result = append(result, k)
+	}
+}
+
+// toMillis converts microsecond timestamps used by Binance exports since 2025 to milliseconds
+func toMillis(t int64) int64 {
+	if t > 1e14 {
+		return t / 1000
+	}
+	return t
+}
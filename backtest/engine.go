@@ -0,0 +1,378 @@
+package backtest
+
+import (
+	"context"
+	"errors"
+	"github.com/okharch/binance/klines"
+	"github.com/okharch/binance/sizing"
+	"github.com/okharch/binance/ticker"
+	"sort"
+	"time"
+)
+
+/*
+Event-driven backtesting engine.
+1m klines are replayed through a streaming ticker, so a strategy sees klines of all the ticker periods
+exactly as signal generation does. on every 1m kline the engine:
+
+	1. fills pending orders which are active (submitted before the kline plus latency) against its prices
+	2. pushes the kline to the ticker
+	3. calls the strategy, orders it submits are filled on the following klines
+	4. records equity once per Config.EquityInterval
+
+the portfolio is a spot account of one symbol: cash in quote asset and a long position.
+*/
+
+// Config of the simulation
+type Config struct {
+	InitialCash float64 `json:"initial_cash"` // in quote asset
+	Fees        Fees    `json:"fees"`
+	// Slippage is adverse price change of market and stop fills, 0.0005 is 5 bps
+	Slippage float64 `json:"slippage"`
+	// Latency between submitting the order and its arrival to the exchange
+	Latency time.Duration `json:"latency"`
+	// Jitter is the maximal random delay added to the latency of each order, it is seeded by Seed
+	Jitter time.Duration `json:"jitter,omitempty"`
+	Seed   int64         `json:"seed,omitempty"`
+	// EquityInterval is resolution of the equity curve
+	EquityInterval time.Duration `json:"equity_interval"`
+	// CloseAtEnd sells the position at the close of the last kline, so it is included in trades
+	CloseAtEnd bool `json:"close_at_end"`
+	// KeepKLines is the number of klines of each period kept by the ticker, ticker.KLinesToKeep() if 0
+	KeepKLines int `json:"-"`
+}
+
+// DefaultConfig returns 10000 of quote asset, Binance fees without BNB, 5 bps slippage and hourly equity
+func DefaultConfig() Config {
+	return Config{
+		InitialCash:    10000,
+		Fees:           BinanceFees(false),
+		Slippage:       0.0005,
+		Latency:        100 * time.Millisecond,
+		EquityInterval: time.Hour,
+		CloseAtEnd:     true,
+	}
+}
+
+// EquityPoint is the state of the portfolio at Time
+type EquityPoint struct {
+	Time     int64   `json:"time"`
+	Equity   float64 `json:"equity"`
+	Cash     float64 `json:"cash"`
+	Position float64 `json:"position"`
+	Price    float64 `json:"price"`
+}
+
+// Result of the backtest
+type Result struct {
+	Config      Config        `json:"config"`
+	Start       int64         `json:"start"` // open time of the first kline
+	End         int64         `json:"end"`   // close time of the last kline
+	FinalEquity float64       `json:"final_equity"`
+	Orders      []Order       `json:"orders"`
+	Fills       []Fill        `json:"fills"`
+	Trades      []Trade       `json:"trades"`
+	Equity      []EquityPoint `json:"equity"`
+}
+
+// Strategy reacts to 1m klines by submitting orders to the engine
+type Strategy interface {
+	// OnKLine is called after the kline is pushed to the ticker, e.KLines returns klines up to it
+	OnKLine(e *Engine, kLine klines.KLineEntry)
+}
+
+// Engine runs a strategy on historical klines, its methods are used by strategies
+type Engine struct {
+	cfg    Config
+	ticker *ticker.Ticker
+	broker *broker
+	kLine  klines.KLineEntry // the current 1m kline
+	equity []EquityPoint
+	// Step was called, start is open time of the first kline passed to it
+	stepped bool
+	start   int64
+	check   OrderCheck
+}
+
+// OrderCheck is called for every submitted order, the order is rejected with the error as the reason
+type OrderCheck func(e *Engine, o Order) error
+
+func New(cfg Config) *Engine {
+	if cfg.EquityInterval <= 0 {
+		cfg.EquityInterval = time.Hour
+	}
+	if cfg.KeepKLines <= 0 {
+		cfg.KeepKLines = ticker.KLinesToKeep()
+	}
+	return &Engine{cfg: cfg}
+}
+
+// Run replays 1m klines ordered by open time through the strategy
+func (e *Engine) Run(ctx context.Context, strategy Strategy, data []klines.KLineEntry) (*Result, error) {
+	return e.RunWithHistory(ctx, strategy, nil, data)
+}
+
+// RunWithHistory pushes history klines preceding data to the ticker without trading,
+// so indicators of the strategy are ready from the first kline of data
+func (e *Engine) RunWithHistory(ctx context.Context, strategy Strategy, history, data []klines.KLineEntry) (*Result, error) {
+	if len(data) == 0 {
+		return nil, errors.New("no klines to backtest")
+	}
+	e.Start(history)
+	for i := range data {
+		if i%1024 == 0 && ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		e.Step(strategy, data[i])
+	}
+	if e.cfg.CloseAtEnd && e.broker.position > 0 {
+		// the last kline has no successor, so the position is sold at its close
+		e.broker.liquidate(e.Time(), e.kLine.ClosePrice*(1-e.cfg.Slippage))
+	}
+	e.recordEquity(e.Time())
+	return e.Result(), nil
+}
+
+// Start resets the engine and pushes history klines to the ticker without trading,
+// then the following klines are passed to Step one by one
+func (e *Engine) Start(history []klines.KLineEntry) {
+	e.ticker = ticker.NewStreamingTicker(e.cfg.KeepKLines)
+	for i := range history {
+		e.ticker.Push(history[i])
+	}
+	e.broker = newBroker(e.cfg)
+	e.equity = nil
+	e.stepped = false
+	e.kLine = klines.KLineEntry{}
+	if len(history) > 0 {
+		e.kLine = history[len(history)-1]
+	}
+}
+
+// Step processes the next 1m kline: fills pending orders against it, pushes it to the ticker and calls the strategy
+func (e *Engine) Step(strategy Strategy, k klines.KLineEntry) {
+	interval := e.cfg.EquityInterval.Milliseconds()
+	if !e.stepped {
+		e.stepped = true
+		e.start = k.OpenTime
+		e.equity = append(e.equity, EquityPoint{Time: k.OpenTime, Equity: e.broker.equity(k.OpenPrice),
+			Cash: e.broker.cash, Position: e.broker.position, Price: k.OpenPrice})
+	} else if k.OpenTime/interval != e.kLine.OpenTime/interval {
+		e.recordEquity(e.kLine.CloseTime + 1)
+	}
+	e.broker.process(&k)
+	e.ticker.Push(k)
+	e.kLine = k
+	strategy.OnKLine(e, k)
+}
+
+// FillAtQuotes fills active pending orders which are marketable at the best bid and ask at time,
+// it is used by live trading to fill orders before the next kline
+func (e *Engine) FillAtQuotes(time int64, bid, ask float64) {
+	e.broker.processQuotes(time, bid, ask)
+}
+
+// Result returns the result of the klines stepped so far
+func (e *Engine) Result() *Result {
+	result := &Result{
+		Config:      e.cfg,
+		Start:       e.start,
+		End:         e.kLine.CloseTime,
+		FinalEquity: e.Equity(),
+		Fills:       e.broker.fills,
+		Trades:      e.broker.trades,
+		Equity:      e.equity,
+	}
+	result.Orders = make([]Order, 0, len(e.broker.orders))
+	for _, o := range e.broker.orders {
+		result.Orders = append(result.Orders, *o)
+	}
+	sort.Slice(result.Orders, func(i, j int) bool { return result.Orders[i].Id < result.Orders[j].Id })
+	return result
+}
+
+// Updates are records changed since the previous TakeUpdates
+type Updates struct {
+	Orders []Order
+	Fills  []Fill
+	Trades []Trade
+	Equity []EquityPoint
+}
+
+// TakeUpdates returns changes since the previous call and removes them from the engine,
+// so a long running live engine does not accumulate them. the result of the engine is not complete after it.
+func (e *Engine) TakeUpdates() Updates {
+	b := e.broker
+	u := Updates{Fills: b.fills, Trades: b.trades, Equity: e.equity}
+	seen := map[int]bool{}
+	for _, o := range b.updated {
+		if seen[o.Id] {
+			continue
+		}
+		seen[o.Id] = true
+		u.Orders = append(u.Orders, *o)
+		if o.Status != Pending {
+			delete(b.orders, o.Id)
+		}
+	}
+	sort.Slice(u.Orders, func(i, j int) bool { return u.Orders[i].Id < u.Orders[j].Id })
+	b.updated, b.fills, b.trades, e.equity = nil, nil, nil, nil
+	return u
+}
+
+// State is the portfolio and pending orders of the engine, it is persisted by live trading
+type State struct {
+	Cash      float64 `json:"cash"`
+	Position  float64 `json:"position"`
+	AvgPrice  float64 `json:"avg_price"`
+	EntryFees float64 `json:"entry_fees"`
+	EntryTime int64   `json:"entry_time"`
+	NextId    int     `json:"next_id"` // id of the next order
+	Pending   []Order `json:"pending"`
+}
+
+func (e *Engine) State() State {
+	b := e.broker
+	return State{
+		Cash:      b.cash,
+		Position:  b.position,
+		AvgPrice:  b.avgPrice,
+		EntryFees: b.entryFees,
+		EntryTime: b.entryTime,
+		NextId:    b.nextId,
+		Pending:   e.OpenOrders(),
+	}
+}
+
+// Restore sets the state saved by State after Start
+func (e *Engine) Restore(s State) {
+	b := e.broker
+	b.cash, b.position, b.avgPrice, b.entryFees, b.entryTime = s.Cash, s.Position, s.AvgPrice, s.EntryFees, s.EntryTime
+	if s.NextId > b.nextId {
+		b.nextId = s.NextId
+	}
+	b.pending = b.pending[:0]
+	for i := range s.Pending {
+		o := s.Pending[i]
+		b.orders[o.Id] = &o
+		// limit orders were in the book before the restart
+		b.pending = append(b.pending, pendingOrder{Order: &o, resting: o.Type == Limit})
+	}
+}
+
+func (e *Engine) recordEquity(time int64) {
+	e.equity = append(e.equity, EquityPoint{
+		Time:     time,
+		Equity:   e.Equity(),
+		Cash:     e.broker.cash,
+		Position: e.broker.position,
+		Price:    e.kLine.ClosePrice,
+	})
+}
+
+// SetOrderCheck sets the check of the orders submitted after it, e.g. by the risk manager
+func (e *Engine) SetOrderCheck(check OrderCheck) {
+	e.check = check
+}
+
+// Submit submits the order at the close of the current kline and returns its id
+func (e *Engine) Submit(o Order) int {
+	var reason string
+	if e.check != nil {
+		if err := e.check(e, o); err != nil {
+			reason = err.Error()
+		}
+	}
+	return e.broker.submit(o, e.Time(), reason)
+}
+
+// Buy submits market order to buy quantity
+func (e *Engine) Buy(quantity float64) int {
+	return e.Submit(Order{Side: Buy, Type: Market, Quantity: quantity})
+}
+
+// BuyQuote submits market order to buy for quote amount
+func (e *Engine) BuyQuote(quote float64) int {
+	return e.Submit(Order{Side: Buy, Type: Market, QuoteQuantity: quote})
+}
+
+// Sell submits market order to sell quantity
+func (e *Engine) Sell(quantity float64) int {
+	return e.Submit(Order{Side: Sell, Type: Market, Quantity: quantity})
+}
+
+// Size returns the quantity of the sizing policy at the current kline, klines of the period are passed to it.
+// the cash is reduced by taker fee and slippage, so the market order of the quantity can be afforded
+func (e *Engine) Size(sizer sizing.Sizer, period time.Duration, rules sizing.Rules) (float64, error) {
+	in := sizing.Input{Equity: e.Equity(), Price: e.Price(),
+		Cash: e.Cash() / (1 + e.cfg.Fees.Rate(false) + e.cfg.Slippage)}
+	if n := sizer.KLines(); n > 0 {
+		in.KLines = e.KLines(period, n)
+	}
+	return sizing.Quantity(sizer, in, rules)
+}
+
+// Cancel cancels pending order, false if it is not pending
+func (e *Engine) Cancel(id int) bool {
+	return e.broker.cancel(id)
+}
+
+// Order returns the order by id, orders taken by TakeUpdates are available while they are pending
+func (e *Engine) Order(id int) (Order, bool) {
+	o, ok := e.broker.orders[id]
+	if !ok {
+		return Order{}, false
+	}
+	return *o, true
+}
+
+// OpenOrders returns pending orders
+func (e *Engine) OpenOrders() []Order {
+	result := make([]Order, len(e.broker.pending))
+	for i, p := range e.broker.pending {
+		result[i] = *p.Order
+	}
+	return result
+}
+
+// Cash returns free quote asset
+func (e *Engine) Cash() float64 {
+	return e.broker.cash
+}
+
+// Position returns quantity of base asset
+func (e *Engine) Position() float64 {
+	return e.broker.position
+}
+
+// AvgPrice returns the average entry price of the position
+func (e *Engine) AvgPrice() float64 {
+	return e.broker.avgPrice
+}
+
+// Price returns the close price of the current kline
+func (e *Engine) Price() float64 {
+	return e.kLine.ClosePrice
+}
+
+// Equity returns cash plus the position valued at the current price
+func (e *Engine) Equity() float64 {
+	return e.broker.equity(e.kLine.ClosePrice)
+}
+
+// Time returns the close time of the current kline in milliseconds
+func (e *Engine) Time() int64 {
+	return e.kLine.CloseTime + 1
+}
+
+// KLines returns n klines of the period up to the current kline, the last one may be incomplete
+func (e *Engine) KLines(period time.Duration, n int) []klines.KLineEntry {
+	return e.ticker.KLines(period, n)
+}
+
+// PeriodClosed returns true if the current kline completes the kline of the period,
+// the klines of the period are aligned by klines.PeriodStart like the ones of the ticker
+func (e *Engine) PeriodClosed(period time.Duration) bool {
+	closeTime := e.kLine.CloseTime + 1
+	return period >= time.Minute && klines.PeriodStart(closeTime, period) == closeTime
+}
@@ -0,0 +1,27 @@
+package backtest
+
+// Fees are fee rates of the exchange, 0.001 is 0.1%.
+// fees are charged in quote asset, paying them with BNB is simulated by the discount
+type Fees struct {
+	Maker       float64 `json:"maker"`
+	Taker       float64 `json:"taker"`
+	BNBDiscount float64 `json:"bnb_discount"`
+	PayWithBNB  bool    `json:"pay_with_bnb"`
+}
+
+// BinanceFees returns regular Binance spot fees: 0.1% maker and taker, 25% off when paid with BNB
+func BinanceFees(payWithBNB bool) Fees {
+	return Fees{Maker: 0.001, Taker: 0.001, BNBDiscount: 0.25, PayWithBNB: payWithBNB}
+}
+
+// Rate returns fee rate of maker or taker fill
+func (f Fees) Rate(maker bool) float64 {
+	rate := f.Taker
+	if maker {
+		rate = f.Maker
+	}
+	if f.PayWithBNB {
+		rate *= 1 - f.BNBDiscount
+	}
+	return rate
+}
@@ -0,0 +1,214 @@
+package backtest
+
+import (
+	"math"
+	"time"
+)
+
+const year = 365 * 24 * time.Hour // crypto markets trade every day
+
+// MonthlyReturn is return of the equity over the calendar month (UTC)
+type MonthlyReturn struct {
+	Month  string  `json:"month"` // YYYY-MM
+	Return float64 `json:"return"`
+}
+
+// Metrics are performance metrics of the backtest, returns and drawdowns are fractions (0.1 is 10%)
+type Metrics struct {
+	Start               int64   `json:"start"`
+	End                 int64   `json:"end"`
+	InitialEquity       float64 `json:"initial_equity"`
+	FinalEquity         float64 `json:"final_equity"`
+	TotalReturn         float64 `json:"total_return"`
+	AnnualReturn        float64 `json:"annual_return"`
+	AnnualVolatility    float64 `json:"annual_volatility"`
+	Sharpe              float64 `json:"sharpe"`
+	Sortino             float64 `json:"sortino"`
+	MaxDrawdown         float64 `json:"max_drawdown"`
+	MaxDrawdownDuration float64 `json:"max_drawdown_days"` // the longest time below the previous peak in days
+	Exposure            float64 `json:"exposure"`          // part of the time with open position
+
+	Trades       int      `json:"trades"`
+	BreakEven    int      `json:"break_even"` // trades with zero pnl, they are neither wins nor losses
+	WinRate      float64  `json:"win_rate"`
+	ProfitFactor *float64 `json:"profit_factor"` // gross profit / gross loss, nil without losses
+	AvgTrade     float64  `json:"avg_trade"`     // average pnl in quote asset
+	AvgReturn    float64  `json:"avg_return"`    // average return of trades
+	AvgWin       float64  `json:"avg_win"`
+	AvgLoss      float64  `json:"avg_loss"`
+
+	Monthly []MonthlyReturn `json:"monthly"`
+}
+
+// Metrics returns performance metrics of the result
+func (r *Result) Metrics() Metrics {
+	m := Metrics{Start: r.Start, End: r.End, InitialEquity: r.Config.InitialCash, FinalEquity: r.FinalEquity}
+	if m.InitialEquity > 0 {
+		m.TotalReturn = m.FinalEquity/m.InitialEquity - 1
+	}
+	if span := time.Duration(r.End-r.Start) * time.Millisecond; span > 0 && m.FinalEquity > 0 && m.InitialEquity > 0 {
+		m.AnnualReturn = math.Pow(m.FinalEquity/m.InitialEquity, float64(year)/float64(span)) - 1
+	}
+	m.AnnualVolatility, m.Sharpe, m.Sortino = riskRatios(r.Equity)
+	m.MaxDrawdown, m.MaxDrawdownDuration = drawdown(r.Equity)
+	m.Exposure = exposure(r.Fills, r.Start, r.End)
+	m.tradeStats(r.Trades)
+	m.Monthly = monthlyReturns(r.Equity)
+	return m
+}
+
+// riskRatios returns annualised volatility, Sharpe and Sortino ratios of equity returns between points
+func riskRatios(equity []EquityPoint) (volatility, sharpe, sortino float64) {
+	if len(equity) < 3 {
+		return
+	}
+	returns := make([]float64, 0, len(equity)-1)
+	var mean float64
+	for i := 1; i < len(equity); i++ {
+		if equity[i-1].Equity <= 0 {
+			continue
+		}
+		ret := equity[i].Equity/equity[i-1].Equity - 1
+		returns = append(returns, ret)
+		mean += ret
+	}
+	if len(returns) < 2 {
+		return
+	}
+	n := float64(len(returns))
+	mean /= n
+	var variance, downside float64
+	for _, ret := range returns {
+		variance += (ret - mean) * (ret - mean)
+		if ret < 0 {
+			downside += ret * ret
+		}
+	}
+	std := math.Sqrt(variance / (n - 1))
+	downsideDev := math.Sqrt(downside / n)
+	// the points are recorded once per interval, the last one may be closer to the previous
+	interval := float64(equity[len(equity)-2].Time-equity[0].Time) / float64(len(equity)-2)
+	periodsPerYear := float64(year.Milliseconds()) / interval
+	annual := math.Sqrt(periodsPerYear)
+	volatility = std * annual
+	if std > 0 {
+		sharpe = mean / std * annual
+	}
+	if downsideDev > 0 {
+		sortino = mean / downsideDev * annual
+	}
+	return
+}
+
+// drawdown returns the maximal drawdown and the longest drawdown duration in days
+func drawdown(equity []EquityPoint) (maxDrawdown, maxDays float64) {
+	if len(equity) == 0 {
+		return
+	}
+	peak, peakTime := equity[0].Equity, equity[0].Time
+	for _, p := range equity {
+		if p.Equity >= peak {
+			peak, peakTime = p.Equity, p.Time
+			continue
+		}
+		if dd := 1 - p.Equity/peak; dd > maxDrawdown {
+			maxDrawdown = dd
+		}
+		if days := float64(p.Time-peakTime) / float64(24*time.Hour.Milliseconds()); days > maxDays {
+			maxDays = days
+		}
+	}
+	return
+}
+
+// exposure returns the part of the time from start to end when the position was open
+func exposure(fills []Fill, start, end int64) float64 {
+	if end <= start {
+		return 0
+	}
+	var position float64
+	var opened, inPosition int64
+	for _, f := range fills {
+		wasOpen := position > epsilon
+		if f.Side == Buy {
+			position += f.Quantity
+		} else {
+			position -= f.Quantity
+		}
+		isOpen := position > epsilon
+		switch {
+		case !wasOpen && isOpen:
+			opened = f.Time
+		case wasOpen && !isOpen:
+			inPosition += f.Time - opened
+		}
+	}
+	if position > epsilon {
+		inPosition += end - opened
+	}
+	return float64(inPosition) / float64(end-start)
+}
+
+func (m *Metrics) tradeStats(trades []Trade) {
+	m.Trades = len(trades)
+	if len(trades) == 0 {
+		return
+	}
+	var wins, losses int
+	var profit, loss, returns float64
+	for _, t := range trades {
+		returns += t.Return()
+		switch {
+		case t.PnL > 0:
+			wins++
+			profit += t.PnL
+		case t.PnL < 0:
+			losses++
+			loss -= t.PnL
+		default:
+			m.BreakEven++
+		}
+	}
+	n := float64(len(trades))
+	m.WinRate = float64(wins) / n
+	m.AvgTrade = (profit - loss) / n
+	m.AvgReturn = returns / n
+	if wins > 0 {
+		m.AvgWin = profit / float64(wins)
+	}
+	if losses > 0 {
+		m.AvgLoss = -loss / float64(losses)
+	}
+	if loss > 0 {
+		profitFactor := profit / loss
+		m.ProfitFactor = &profitFactor
+	}
+}
+
+// monthlyReturns returns equity change of each calendar month relative to the end of the previous one
+func monthlyReturns(equity []EquityPoint) (result []MonthlyReturn) {
+	if len(equity) == 0 {
+		return nil
+	}
+	month := func(t int64) string {
+		return time.UnixMilli(t).UTC().Format("2006-01")
+	}
+	ret := func(last, base float64) float64 {
+		if base <= 0 {
+			return 0
+		}
+		return last/base - 1
+	}
+	base := equity[0].Equity
+	current, last := month(equity[0].Time), base
+	for _, p := range equity[1:] {
+		// the point is recorded at the end of the interval, so it belongs to the month of the previous millisecond
+		m := month(p.Time - 1)
+		if m != current {
+			result = append(result, MonthlyReturn{current, ret(last, base)})
+			current, base = m, last
+		}
+		last = p.Equity
+	}
+	return append(result, MonthlyReturn{current, ret(last, base)})
+}
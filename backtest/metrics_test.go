@@ -0,0 +1,106 @@
+package backtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testResult() *Result {
+	day := int64(24 * time.Hour / time.Millisecond)
+	start := time.Date(2023, 1, 30, 0, 0, 0, 0, time.UTC).UnixMilli()
+	// daily equity: up to 1100, down to 990 (-10%), recovery to 1210
+	values := []float64{1000, 1100, 1045, 990, 1100, 1210}
+	r := &Result{Config: Config{InitialCash: 1000}, Start: start, End: start + 5*day, FinalEquity: 1210}
+	for i, v := range values {
+		r.Equity = append(r.Equity, EquityPoint{Time: start + int64(i)*day, Equity: v})
+	}
+	r.Fills = []Fill{
+		{Time: start, Side: Buy, Quantity: 1},
+		{Time: start + day, Side: Sell, Quantity: 1},
+		{Time: start + 3*day, Side: Buy, Quantity: 2},
+	}
+	r.Trades = []Trade{
+		{EntryPrice: 100, Quantity: 1, PnL: 30},
+		{EntryPrice: 100, Quantity: 1, PnL: -10},
+		{EntryPrice: 100, Quantity: 2, PnL: 20},
+	}
+	return r
+}
+
+func TestMetrics(t *testing.T) {
+	m := testResult().Metrics()
+	if !almostEqual(m.TotalReturn, 0.21) {
+		t.Errorf("expected total return 0.21, got %f", m.TotalReturn)
+	}
+	if !almostEqual(m.MaxDrawdown, 0.1) || !almostEqual(m.MaxDrawdownDuration, 2) {
+		t.Errorf("expected 10%% drawdown for 2 days, got %f for %f", m.MaxDrawdown, m.MaxDrawdownDuration)
+	}
+	// in position the first day and from the third to the end
+	if !almostEqual(m.Exposure, 3.0/5) {
+		t.Errorf("expected exposure 0.6, got %f", m.Exposure)
+	}
+	if m.Trades != 3 || !almostEqual(m.WinRate, 2.0/3) || m.ProfitFactor == nil || !almostEqual(*m.ProfitFactor, 5) || !almostEqual(m.AvgTrade, 40.0/3) {
+		t.Errorf("unexpected trade stats %+v", m)
+	}
+	if !almostEqual(m.AvgWin, 25) || !almostEqual(m.AvgLoss, -10) || !almostEqual(m.AvgReturn, (0.3-0.1+0.1)/3) {
+		t.Errorf("unexpected average trades %+v", m)
+	}
+	if m.Sharpe <= 0 || m.Sortino <= m.Sharpe || m.AnnualReturn <= m.TotalReturn {
+		t.Errorf("unexpected ratios %+v", m)
+	}
+	// the point at midnight of February 1 closes January at 1045
+	if len(m.Monthly) != 2 || m.Monthly[0].Month != "2023-01" || !almostEqual(m.Monthly[0].Return, 0.045) ||
+		!almostEqual(m.Monthly[1].Return, 1210.0/1045-1) {
+		t.Errorf("unexpected monthly returns %+v", m.Monthly)
+	}
+}
+
+func TestTradeStatsWithoutLosses(t *testing.T) {
+	var m Metrics
+	m.tradeStats([]Trade{
+		{EntryPrice: 100, Quantity: 1, PnL: 30},
+		{EntryPrice: 100, Quantity: 1, PnL: 0},
+	})
+	if m.BreakEven != 1 || !almostEqual(m.WinRate, 0.5) || m.AvgLoss != 0 {
+		t.Errorf("expected the break-even trade to be neither a win nor a loss, got %+v", m)
+	}
+	if m.ProfitFactor != nil {
+		t.Errorf("expected no profit factor without losses, got %v", *m.ProfitFactor)
+	}
+	var buf bytes.Buffer
+	if err := NewReport("no losses", &Result{Config: Config{InitialCash: 1000}, Trades: []Trade{{PnL: 30}}}).
+		WriteHTML(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "no losses</td>") {
+		t.Errorf("expected the report to show no losses, got %s", buf.String())
+	}
+}
+
+func TestReport(t *testing.T) {
+	rep := NewReport("MAC 10,20 1h", testResult())
+	var buf bytes.Buffer
+	if err := rep.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var decoded Report
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Metrics.Trades != 3 || len(decoded.Trades) != 3 {
+		t.Errorf("unexpected json report %s", buf.String())
+	}
+	buf.Reset()
+	if err := rep.WriteHTML(&buf); err != nil {
+		t.Fatal(err)
+	}
+	html := buf.String()
+	for _, s := range []string{"<title>MAC 10,20 1h</title>", "21.00%", "2023-02", "<polyline"} {
+		if !strings.Contains(html, s) {
+			t.Errorf("html report does not contain %q", s)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package backtest
+
+import (
+	"context"
+	"errors"
+	"github.com/okharch/binance/klines"
+	"math"
+	"math/rand"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+)
+
+/*
+Monte Carlo robustness analysis.
+a single backtest is one path of many possible ones, the analysis builds distributions of return and drawdown:
+
+	bootstrap - trades are drawn with replacement, so lucky trades may be missing or repeated
+	shuffle   - the order of trades is permuted, the return is the same but drawdowns differ
+	delay     - the backtest is rerun with random delays of orders, which shows dependence on exact entries
+
+returns of trades are taken relative to the equity before them, so the resampled paths are compounded.
+*/
+
+type MonteCarloConfig struct {
+	Runs int   `json:"runs"`
+	Seed int64 `json:"seed"`
+	// Ruin is the part of the initial equity below which the run is ruined, 0.5 means 50% loss
+	Ruin float64 `json:"ruin"`
+}
+
+func DefaultMonteCarloConfig() MonteCarloConfig {
+	return MonteCarloConfig{Runs: 1000, Seed: 1, Ruin: 0.5}
+}
+
+// Distribution of the values over the runs
+type Distribution struct {
+	Mean float64 `json:"mean"`
+	Std  float64 `json:"std"`
+	P5   float64 `json:"p5"`
+	P25  float64 `json:"p25"`
+	P50  float64 `json:"p50"`
+	P75  float64 `json:"p75"`
+	P95  float64 `json:"p95"`
+}
+
+func NewDistribution(values []float64) Distribution {
+	if len(values) == 0 {
+		return Distribution{}
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	var d Distribution
+	for _, v := range sorted {
+		d.Mean += v
+	}
+	d.Mean /= float64(len(sorted))
+	if len(sorted) > 1 {
+		for _, v := range sorted {
+			d.Std += (v - d.Mean) * (v - d.Mean)
+		}
+		d.Std = math.Sqrt(d.Std / float64(len(sorted)-1))
+	}
+	d.P5, d.P25, d.P50 = percentile(sorted, 0.05), percentile(sorted, 0.25), percentile(sorted, 0.5)
+	d.P75, d.P95 = percentile(sorted, 0.75), percentile(sorted, 0.95)
+	return d
+}
+
+// percentile of sorted values with linear interpolation
+func percentile(sorted []float64, p float64) float64 {
+	pos := p * float64(len(sorted)-1)
+	i := int(pos)
+	if i+1 >= len(sorted) {
+		return sorted[len(sorted)-1]
+	}
+	return sorted[i] + (sorted[i+1]-sorted[i])*(pos-float64(i))
+}
+
+// MonteCarlo is the result of one method of the analysis
+type MonteCarlo struct {
+	Method      string       `json:"method"`
+	Runs        int          `json:"runs"`
+	Return      Distribution `json:"return"`
+	MaxDrawdown Distribution `json:"max_drawdown"`
+	// RuinProbability is the part of runs where equity fell below Ruin of the initial one
+	RuinProbability float64 `json:"ruin_probability"`
+}
+
+// tradeReturns returns pnl of the trades relative to the equity before them
+func tradeReturns(initialEquity float64, trades []Trade) []float64 {
+	returns := make([]float64, 0, len(trades))
+	equity := initialEquity
+	for _, t := range trades {
+		if equity <= 0 {
+			break
+		}
+		returns = append(returns, t.PnL/equity)
+		equity += t.PnL
+	}
+	return returns
+}
+
+// pathStats compounds returns and returns the total return, the maximal drawdown and whether it was ruined
+func pathStats(returns []float64, ruin float64) (total, maxDrawdown float64, ruined bool) {
+	equity, peak := 1.0, 1.0
+	for _, r := range returns {
+		equity *= 1 + r
+		if equity > peak {
+			peak = equity
+		}
+		if dd := 1 - equity/peak; dd > maxDrawdown {
+			maxDrawdown = dd
+		}
+		if equity < ruin {
+			ruined = true
+		}
+	}
+	return equity - 1, maxDrawdown, ruined
+}
+
+func newMonteCarlo(method string, totals, drawdowns []float64, ruined int) MonteCarlo {
+	return MonteCarlo{
+		Method:          method,
+		Runs:            len(totals),
+		Return:          NewDistribution(totals),
+		MaxDrawdown:     NewDistribution(drawdowns),
+		RuinProbability: float64(ruined) / float64(len(totals)),
+	}
+}
+
+// resample runs the analysis of trade returns, draw fills the path of the run from returns
+func resample(method string, returns []float64, cfg MonteCarloConfig, draw func(rnd *rand.Rand, path, returns []float64)) MonteCarlo {
+	rnd := rand.New(rand.NewSource(cfg.Seed))
+	path := make([]float64, len(returns))
+	totals := make([]float64, cfg.Runs)
+	drawdowns := make([]float64, cfg.Runs)
+	ruined := 0
+	for i := 0; i < cfg.Runs; i++ {
+		draw(rnd, path, returns)
+		var r bool
+		totals[i], drawdowns[i], r = pathStats(path, cfg.Ruin)
+		if r {
+			ruined++
+		}
+	}
+	return newMonteCarlo(method, totals, drawdowns, ruined)
+}
+
+// MonteCarlo runs bootstrap and shuffle analysis of the trades, nil if there are less than 2 trades
+func (r *Result) MonteCarlo(cfg MonteCarloConfig) []MonteCarlo {
+	returns := tradeReturns(r.Config.InitialCash, r.Trades)
+	if len(returns) < 2 || cfg.Runs <= 0 {
+		return nil
+	}
+	bootstrap := resample("bootstrap", returns, cfg, func(rnd *rand.Rand, path, returns []float64) {
+		for i := range path {
+			path[i] = returns[rnd.Intn(len(returns))]
+		}
+	})
+	shuffle := resample("shuffle", returns, cfg, func(rnd *rand.Rand, path, returns []float64) {
+		copy(path, returns)
+		rnd.Shuffle(len(path), func(i, j int) { path[i], path[j] = path[j], path[i] })
+	})
+	return []MonteCarlo{bootstrap, shuffle}
+}
+
+// DelayMonteCarlo reruns the backtest cfg.Runs times with orders delayed randomly up to maxDelay.
+// newStrategy returns a fresh strategy for each run, the runs use all CPUs
+func DelayMonteCarlo(ctx context.Context, btConfig Config, newStrategy func() Strategy, data []klines.KLineEntry,
+	cfg MonteCarloConfig, maxDelay time.Duration) (MonteCarlo, error) {
+	if cfg.Runs <= 0 {
+		return MonteCarlo{}, errors.New("no runs")
+	}
+	totals := make([]float64, cfg.Runs)
+	drawdowns := make([]float64, cfg.Runs)
+	ruins := make([]bool, cfg.Runs)
+	errs := make([]error, cfg.Runs)
+	runs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < runtime.NumCPU(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range runs {
+				c := btConfig
+				c.Jitter, c.Seed = maxDelay, cfg.Seed+int64(i)
+				r, err := New(c).Run(ctx, newStrategy(), data)
+				if err != nil {
+					errs[i] = err
+					continue
+				}
+				m := r.Metrics()
+				totals[i], drawdowns[i] = m.TotalReturn, m.MaxDrawdown
+				for _, p := range r.Equity {
+					if p.Equity < cfg.Ruin*c.InitialCash {
+						ruins[i] = true
+						break
+					}
+				}
+			}
+		}()
+	}
+feed:
+	for i := 0; i < cfg.Runs; i++ {
+		select {
+		case <-ctx.Done():
+			break feed
+		case runs <- i:
+		}
+	}
+	close(runs)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return MonteCarlo{}, err
+	}
+	ruined := 0
+	for i, err := range errs {
+		if err != nil {
+			return MonteCarlo{}, err
+		}
+		if ruins[i] {
+			ruined++
+		}
+	}
+	return newMonteCarlo("delay", totals, drawdowns, ruined), nil
+}
@@ -0,0 +1,89 @@
+package backtest
+
+import (
+	"context"
+	"github.com/okharch/binance/indicators"
+	"github.com/okharch/binance/klines"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestNewDistribution(t *testing.T) {
+	values := make([]float64, 101)
+	for i := range values {
+		values[100-i] = float64(i)
+	}
+	d := NewDistribution(values)
+	if d.Mean != 50 || d.P5 != 5 || d.P25 != 25 || d.P50 != 50 || d.P75 != 75 || d.P95 != 95 {
+		t.Errorf("unexpected distribution %+v", d)
+	}
+}
+
+func TestMonteCarlo(t *testing.T) {
+	r := &Result{Config: Config{InitialCash: 1000}, Trades: []Trade{
+		{PnL: 100}, {PnL: -220}, {PnL: 50}, {PnL: 300}, {PnL: -400},
+	}}
+	mc := r.MonteCarlo(MonteCarloConfig{Runs: 500, Seed: 7, Ruin: 0.7})
+	if len(mc) != 2 || mc[0].Method != "bootstrap" || mc[1].Method != "shuffle" {
+		t.Fatalf("unexpected analysis %+v", mc)
+	}
+	// shuffled returns are compounded to the same total, 1000 -> 830
+	shuffle := mc[1]
+	if math.Abs(shuffle.Return.P5-(-0.17)) > 1e-9 || math.Abs(shuffle.Return.P95-(-0.17)) > 1e-9 {
+		t.Errorf("expected shuffled return -17%%, got %+v", shuffle.Return)
+	}
+	if shuffle.MaxDrawdown.P5 >= shuffle.MaxDrawdown.P95 {
+		t.Errorf("expected different drawdowns of shuffled paths, got %+v", shuffle.MaxDrawdown)
+	}
+	bootstrap := mc[0]
+	if bootstrap.Return.Std == 0 || bootstrap.RuinProbability == 0 || bootstrap.RuinProbability == 1 {
+		t.Errorf("unexpected bootstrap %+v", bootstrap)
+	}
+	if again := r.MonteCarlo(MonteCarloConfig{Runs: 500, Seed: 7, Ruin: 0.7}); again[0] != bootstrap {
+		t.Errorf("bootstrap is not reproducible with the same seed")
+	}
+}
+
+func TestDelayMonteCarlo(t *testing.T) {
+	prices := make([]float64, 120)
+	for i := range prices {
+		prices[i] = 100 + float64(i%20)
+	}
+	newStrategy := func() Strategy {
+		return StrategyFunc(func(e *Engine, k klines.KLineEntry) {
+			switch k.OpenTime / 60000 % 20 {
+			case 0:
+				e.BuyQuote(e.Cash())
+			case 10:
+				e.Sell(e.Position())
+			}
+		})
+	}
+	mc, err := DelayMonteCarlo(context.Background(), testConfig(), newStrategy, kLinesOf(prices...),
+		MonteCarloConfig{Runs: 16, Seed: 1, Ruin: 0.5}, 5*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the later the entry, the higher the price, so delays reduce the return
+	if mc.Runs != 16 || mc.Return.P5 >= mc.Return.P95 {
+		t.Errorf("unexpected delay analysis %+v", mc)
+	}
+}
+
+func TestStoredSignalStrategy(t *testing.T) {
+	signals := []indicators.IndicatorSignal{
+		{OpenTime: 60000, Side: "BUY", Period: 0},
+		{OpenTime: 2 * 60000, Side: "BUY", Period: 0},
+		{OpenTime: 3 * 60000, Side: "SELL", Period: 0},
+	}
+	s, err := NewStoredSignalStrategy(signals)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := run(t, testConfig(), kLinesOf(100, 100, 102, 104, 106, 108), s.OnKLine)
+	// BUY is final at the close of its kline, so it is filled at the open of the kline after it
+	if len(r.Fills) != 2 || r.Fills[0].Time != 2*60000 || r.Fills[1].Time != 4*60000 {
+		t.Errorf("unexpected fills %+v", r.Fills)
+	}
+}
@@ -0,0 +1,316 @@
+package optimize
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/okharch/binance/backtest"
+	"github.com/okharch/binance/indicators"
+	"github.com/okharch/binance/klines"
+	"math"
+	"math/rand"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+)
+
+/*
+Walk-forward optimisation of indicator strategy params.
+the klines are split into rolling windows: each in-sample window is followed by out-of-sample one,
+the next window starts OutOfSample later:
+
+	|---- in-sample ----|-- oos --|
+	          |---- in-sample ----|-- oos --|
+
+every candidate params set is backtested on both parts of every window, the best in-sample one is chosen
+for the out-of-sample part, so the chained out-of-sample results are what could have been traded.
+out-of-sample scores of every candidate show how stable it is across the windows.
+*/
+
+// Search selects candidate params from the indicator grid
+type Search int
+
+const (
+	GridSearch   Search = iota // all the grid
+	RandomSearch               // Config.Samples random params sets of the grid
+)
+
+// Objective scores backtest metrics, higher is better
+type Objective func(m backtest.Metrics) float64
+
+// Sharpe is the default objective
+func Sharpe(m backtest.Metrics) float64 {
+	return m.Sharpe
+}
+
+type Config struct {
+	Indicator   string
+	Period      time.Duration // period of klines the indicator is calculated on
+	InSample    time.Duration
+	OutOfSample time.Duration
+	Objective   Objective // Sharpe if nil
+	Search      Search
+	Samples     int             // number of params sets of RandomSearch
+	Seed        int64           // seed of RandomSearch
+	Workers     int             // runtime.NumCPU() if 0
+	Backtest    backtest.Config // backtest.DefaultConfig() if InitialCash is 0
+}
+
+// WindowResult is the params chosen in-sample and their metrics
+type WindowResult struct {
+	InSampleStart    int64            `json:"in_sample_start"`
+	OutOfSampleStart int64            `json:"out_of_sample_start"`
+	End              int64            `json:"end"`
+	Params           []int            `json:"params"`
+	InSample         backtest.Metrics `json:"in_sample"`
+	OutOfSample      backtest.Metrics `json:"out_of_sample"`
+}
+
+// ParamStats is performance of the params set across the windows
+type ParamStats struct {
+	Params           []int   `json:"params"`
+	Chosen           int     `json:"chosen"`          // windows the params were the best in-sample
+	InSampleScore    float64 `json:"in_sample_score"` // mean
+	OutOfSampleScore float64 `json:"oos_score"`       // mean
+	OutOfSampleStd   float64 `json:"oos_score_std"`   // lower is more stable
+	PositiveWindows  float64 `json:"oos_positive"`    // part of out-of-sample windows with positive return
+	Efficiency       float64 `json:"efficiency"`      // out-of-sample score / in-sample score
+	OutOfSampleRet   float64 `json:"oos_return"`      // compounded out-of-sample return
+	scores           [2][]float64
+}
+
+type Result struct {
+	Indicator string         `json:"indicator"`
+	Period    string         `json:"period"`
+	Windows   []WindowResult `json:"windows"`
+	// Params are sorted by mean out-of-sample score
+	Params []ParamStats `json:"params"`
+	// OutOfSampleReturn is compounded return of the chosen params over out-of-sample windows
+	OutOfSampleReturn float64 `json:"oos_return"`
+}
+
+type window struct {
+	inStart, outStart, end int // indexes of the klines
+}
+
+type job struct {
+	window, candidate int
+	outOfSample       bool
+}
+
+type jobResult struct {
+	job
+	metrics backtest.Metrics
+	err     error
+}
+
+// Run runs walk-forward optimisation on 1m klines ordered by open time using cfg.Workers goroutines
+func Run(ctx context.Context, cfg Config, data []klines.KLineEntry) (*Result, error) {
+	ind, ok := indicators.Lookup(cfg.Indicator)
+	if !ok {
+		return nil, fmt.Errorf("unknown indicator %s", cfg.Indicator)
+	}
+	if cfg.Objective == nil {
+		cfg.Objective = Sharpe
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = runtime.NumCPU()
+	}
+	if cfg.Backtest.InitialCash == 0 {
+		cfg.Backtest = backtest.DefaultConfig()
+	}
+	candidates := Candidates(ind, cfg.Search, cfg.Samples, cfg.Seed)
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no params to optimise for %s", cfg.Indicator)
+	}
+	windows := splitWindows(data, cfg.InSample, cfg.OutOfSample)
+	if len(windows) == 0 {
+		return nil, errors.New("not enough klines for a single in-sample and out-of-sample window")
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	jobs := make(chan job)
+	results := make(chan jobResult)
+	var wg sync.WaitGroup
+	for i := 0; i < cfg.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				m, err := runJob(runCtx, cfg, data, windows[j.window], candidates[j.candidate], j.outOfSample)
+				select {
+				case results <- jobResult{j, m, err}:
+				case <-runCtx.Done():
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		defer close(jobs)
+		for w := range windows {
+			for c := range candidates {
+				for _, oos := range []bool{false, true} {
+					select {
+					case jobs <- job{w, c, oos}:
+					case <-runCtx.Done():
+						return
+					}
+				}
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// metrics[window][candidate][in-sample, out-of-sample]
+	metrics := make([][][2]backtest.Metrics, len(windows))
+	for w := range metrics {
+		metrics[w] = make([][2]backtest.Metrics, len(candidates))
+	}
+	var err error
+	for r := range results {
+		if r.err != nil && err == nil {
+			err = fmt.Errorf("backtest of %v failed: %w", candidates[r.candidate], r.err)
+			cancel()
+		}
+		if r.outOfSample {
+			metrics[r.window][r.candidate][1] = r.metrics
+		} else {
+			metrics[r.window][r.candidate][0] = r.metrics
+		}
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return summarize(cfg, data, windows, candidates, metrics), nil
+}
+
+func runJob(ctx context.Context, cfg Config, data []klines.KLineEntry, w window, params []int, outOfSample bool) (backtest.Metrics, error) {
+	strategy, err := backtest.NewSignalStrategy(cfg.Indicator, params, cfg.Period)
+	if err != nil {
+		return backtest.Metrics{}, err
+	}
+	start, end := w.inStart, w.outStart
+	if outOfSample {
+		start, end = w.outStart, w.end
+	}
+	historyStart := start - strategy.HistoryMinutes()
+	if historyStart < 0 {
+		historyStart = 0
+	}
+	r, err := backtest.New(cfg.Backtest).RunWithHistory(ctx, strategy, data[historyStart:start], data[start:end])
+	if err != nil {
+		return backtest.Metrics{}, err
+	}
+	return r.Metrics(), nil
+}
+
+// Candidates returns params sets of the indicator grid to be optimised
+func Candidates(ind indicators.Indicator, search Search, samples int, seed int64) [][]int {
+	grid := ind.Grid()
+	if search != RandomSearch || samples <= 0 || samples >= len(grid) {
+		return grid
+	}
+	rnd := rand.New(rand.NewSource(seed))
+	rnd.Shuffle(len(grid), func(i, j int) { grid[i], grid[j] = grid[j], grid[i] })
+	return grid[:samples]
+}
+
+// splitWindows returns rolling windows of klines by open time
+func splitWindows(data []klines.KLineEntry, inSample, outOfSample time.Duration) (result []window) {
+	if len(data) == 0 || inSample <= 0 || outOfSample <= 0 {
+		return nil
+	}
+	index := func(t int64) int {
+		return sort.Search(len(data), func(i int) bool { return data[i].OpenTime >= t })
+	}
+	last := data[len(data)-1].OpenTime
+	for start := data[0].OpenTime; start+(inSample+outOfSample).Milliseconds() <= last+60000; start += outOfSample.Milliseconds() {
+		outStart := start + inSample.Milliseconds()
+		w := window{index(start), index(outStart), index(outStart + outOfSample.Milliseconds())}
+		if w.inStart < w.outStart && w.outStart < w.end {
+			result = append(result, w)
+		}
+	}
+	return
+}
+
+func summarize(cfg Config, data []klines.KLineEntry, windows []window, candidates [][]int, metrics [][][2]backtest.Metrics) *Result {
+	result := &Result{Indicator: cfg.Indicator, Period: klines.Duration2Period(cfg.Period), OutOfSampleReturn: 1}
+	stats := make([]ParamStats, len(candidates))
+	for c := range candidates {
+		stats[c] = ParamStats{Params: candidates[c], OutOfSampleRet: 1}
+	}
+	for w, win := range windows {
+		best := 0
+		for c := range candidates {
+			m := metrics[w][c]
+			s := &stats[c]
+			s.scores[0] = append(s.scores[0], cfg.Objective(m[0]))
+			s.scores[1] = append(s.scores[1], cfg.Objective(m[1]))
+			if m[1].TotalReturn > 0 {
+				s.PositiveWindows++
+			}
+			s.OutOfSampleRet *= 1 + m[1].TotalReturn
+			if cfg.Objective(m[0]) > cfg.Objective(metrics[w][best][0]) {
+				best = c
+			}
+		}
+		stats[best].Chosen++
+		result.OutOfSampleReturn *= 1 + metrics[w][best][1].TotalReturn
+		result.Windows = append(result.Windows, WindowResult{
+			InSampleStart:    data[win.inStart].OpenTime,
+			OutOfSampleStart: data[win.outStart].OpenTime,
+			End:              data[win.end-1].CloseTime,
+			Params:           candidates[best],
+			InSample:         metrics[w][best][0],
+			OutOfSample:      metrics[w][best][1],
+		})
+	}
+	result.OutOfSampleReturn--
+	n := float64(len(windows))
+	for c := range stats {
+		s := &stats[c]
+		s.InSampleScore = mean(s.scores[0])
+		s.OutOfSampleScore = mean(s.scores[1])
+		s.OutOfSampleStd = std(s.scores[1], s.OutOfSampleScore)
+		s.PositiveWindows /= n
+		s.OutOfSampleRet--
+		if s.InSampleScore != 0 {
+			s.Efficiency = s.OutOfSampleScore / s.InSampleScore
+		}
+	}
+	sort.SliceStable(stats, func(i, j int) bool { return stats[i].OutOfSampleScore > stats[j].OutOfSampleScore })
+	result.Params = stats
+	return result
+}
+
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+func std(values []float64, mean float64) float64 {
+	if len(values) < 2 {
+		return 0
+	}
+	var sum float64
+	for _, v := range values {
+		sum += (v - mean) * (v - mean)
+	}
+	return math.Sqrt(sum / float64(len(values)-1))
+}
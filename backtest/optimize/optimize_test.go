@@ -0,0 +1,99 @@
+package optimize
+
+import (
+	"context"
+	"errors"
+	"github.com/okharch/binance/indicators"
+	"github.com/okharch/binance/klines"
+	"math"
+	"testing"
+	"time"
+)
+
+func init() {
+	// momentum: buy when the close is above the close lookback klines ago, sell when below
+	indicators.Register(indicators.Indicator{
+		Type:        indicators.IndicatorTypeComposite,
+		Name:        "TestMomentum",
+		Params:      []indicators.Param{{Name: "lookback", Min: 2, Max: 4, Step: 1}},
+		KLinesCount: indicators.KLinesPlus(0, 1),
+		Factory: indicators.FuncFactory(func(kLines []klines.KLineEntry, params []int) indicators.TradeSignal {
+			n := len(kLines)
+			switch {
+			case kLines[n-1].ClosePrice > kLines[n-1-params[0]].ClosePrice:
+				return indicators.TradeBuy
+			case kLines[n-1].ClosePrice < kLines[n-1-params[0]].ClosePrice:
+				return indicators.TradeSell
+			}
+			return indicators.TradeNone
+		}),
+	})
+}
+
+// sine prices with 10 hours cycle over the days
+func testKLines(days int) []klines.KLineEntry {
+	n := days * 1440
+	result := make([]klines.KLineEntry, n)
+	for i := range result {
+		price := 100 + 10*math.Sin(float64(i)/600*2*math.Pi)
+		openTime := int64(i) * 60000
+		result[i] = klines.KLineEntry{OpenTime: openTime, CloseTime: openTime + 59999,
+			OpenPrice: price, HighPrice: price + 0.1, LowPrice: price - 0.1, ClosePrice: price, Volume: 1}
+	}
+	return result
+}
+
+func testConfig() Config {
+	return Config{
+		Indicator:   "TestMomentum",
+		Period:      time.Hour,
+		InSample:    24 * time.Hour,
+		OutOfSample: 12 * time.Hour,
+		Workers:     4,
+	}
+}
+
+func TestRun(t *testing.T) {
+	r, err := Run(context.Background(), testConfig(), testKLines(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Windows) != 4 || len(r.Params) != 3 {
+		t.Fatalf("expected 4 windows and 3 params sets, got %d and %d", len(r.Windows), len(r.Params))
+	}
+	chosen := 0
+	for i, p := range r.Params {
+		chosen += p.Chosen
+		if i > 0 && p.OutOfSampleScore > r.Params[i-1].OutOfSampleScore {
+			t.Errorf("params are not sorted by out-of-sample score: %+v", r.Params)
+		}
+	}
+	if chosen != len(r.Windows) {
+		t.Errorf("expected params chosen for every window, got %d", chosen)
+	}
+	w := r.Windows[1]
+	if w.InSampleStart != 12*3600000 || w.OutOfSampleStart != 36*3600000 || w.End != 48*3600000-1 {
+		t.Errorf("unexpected window %+v", w)
+	}
+	if w.OutOfSample.Trades == 0 {
+		t.Errorf("expected out-of-sample trades in window %+v", w)
+	}
+}
+
+func TestRunCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Run(ctx, testConfig(), testKLines(3)); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestCandidates(t *testing.T) {
+	ind, _ := indicators.Lookup("TestMomentum")
+	if c := Candidates(ind, RandomSearch, 2, 1); len(c) != 2 {
+		t.Errorf("expected 2 random candidates, got %v", c)
+	}
+	if c := Candidates(ind, GridSearch, 2, 1); len(c) != 3 {
+		t.Errorf("expected the whole grid, got %v", c)
+	}
+}
@@ -0,0 +1,152 @@
+package backtest
+
+import "fmt"
+
+type Side int8
+
+const (
+	Buy Side = iota
+	Sell
+)
+
+func (s Side) String() string {
+	if s == Buy {
+		return "BUY"
+	}
+	return "SELL"
+}
+
+func (s Side) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *Side) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "BUY":
+		*s = Buy
+	case "SELL":
+		*s = Sell
+	default:
+		return fmt.Errorf("invalid side %s", text)
+	}
+	return nil
+}
+
+type OrderType int8
+
+const (
+	Market OrderType = iota
+	Limit
+	Stop // market order triggered when the price reaches StopPrice
+)
+
+func (t OrderType) String() string {
+	switch t {
+	case Market:
+		return "MARKET"
+	case Limit:
+		return "LIMIT"
+	case Stop:
+		return "STOP"
+	}
+	return fmt.Sprintf("OrderType(%d)", int(t))
+}
+
+func (t OrderType) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+func (t *OrderType) UnmarshalText(text []byte) error {
+	for _, ot := range []OrderType{Market, Limit, Stop} {
+		if ot.String() == string(text) {
+			*t = ot
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid order type %s", text)
+}
+
+type OrderStatus int8
+
+const (
+	Pending OrderStatus = iota
+	Filled
+	Cancelled
+	Rejected
+)
+
+func (s OrderStatus) String() string {
+	switch s {
+	case Pending:
+		return "PENDING"
+	case Filled:
+		return "FILLED"
+	case Cancelled:
+		return "CANCELLED"
+	case Rejected:
+		return "REJECTED"
+	}
+	return fmt.Sprintf("OrderStatus(%d)", int(s))
+}
+
+func (s OrderStatus) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *OrderStatus) UnmarshalText(text []byte) error {
+	for _, os := range []OrderStatus{Pending, Filled, Cancelled, Rejected} {
+		if os.String() == string(text) {
+			*s = os
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid order status %s", text)
+}
+
+// Order is a simulated spot order.
+// market buy orders may set QuoteQuantity instead of Quantity to spend that amount of quote asset
+type Order struct {
+	Id            int         `json:"id"`
+	Side          Side        `json:"side"`
+	Type          OrderType   `json:"type"`
+	Quantity      float64     `json:"quantity"`
+	QuoteQuantity float64     `json:"quote_quantity,omitempty"`
+	Price         float64     `json:"price,omitempty"`      // limit price
+	StopPrice     float64     `json:"stop_price,omitempty"` // trigger price of stop orders
+	SubmitTime    int64       `json:"submit_time"`
+	ActiveTime    int64       `json:"active_time"` // SubmitTime plus latency, the order is not filled before it
+	Status        OrderStatus `json:"status"`
+	Reason        string      `json:"reason,omitempty"` // why the order was rejected
+}
+
+// Fill is an execution of the order
+type Fill struct {
+	OrderId  int     `json:"order_id"`
+	Time     int64   `json:"time"`
+	Side     Side    `json:"side"`
+	Price    float64 `json:"price"`
+	Quantity float64 `json:"quantity"`
+	Fee      float64 `json:"fee"` // in quote asset
+	Maker    bool    `json:"maker"`
+}
+
+// Trade is a closed part of the position: buys at average EntryPrice sold at ExitPrice.
+// PnL includes fees of both sides
+type Trade struct {
+	EntryTime  int64   `json:"entry_time"`
+	ExitTime   int64   `json:"exit_time"`
+	EntryPrice float64 `json:"entry_price"`
+	ExitPrice  float64 `json:"exit_price"`
+	Quantity   float64 `json:"quantity"`
+	Fees       float64 `json:"fees"`
+	PnL        float64 `json:"pnl"`
+}
+
+// Return of the trade relative to its cost
+func (t Trade) Return() float64 {
+	cost := t.EntryPrice * t.Quantity
+	if cost == 0 {
+		return 0
+	}
+	return t.PnL / cost
+}
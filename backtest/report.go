@@ -0,0 +1,164 @@
+package backtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+)
+
+// Report of the backtest with metrics, it is written as json or self-contained html page
+type Report struct {
+	Title   string  `json:"title"`
+	Config  Config  `json:"config"`
+	Metrics Metrics `json:"metrics"`
+	// MonteCarlo contains bootstrap and shuffle analysis of the trades, delay analysis may be appended to it
+	MonteCarlo []MonteCarlo `json:"monte_carlo,omitempty"`
+	Trades     []Trade      `json:"trades"`
+	equity     []EquityPoint
+}
+
+func NewReport(title string, r *Result) *Report {
+	return &Report{Title: title, Config: r.Config, Metrics: r.Metrics(), Trades: r.Trades, equity: r.Equity,
+		MonteCarlo: r.MonteCarlo(DefaultMonteCarloConfig())}
+}
+
+func (rep *Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(rep)
+}
+
+func (rep *Report) WriteHTML(w io.Writer) error {
+	return reportTemplate.Execute(w, rep)
+}
+
+const chartWidth, chartHeight = 1000, 240
+
+// EquityChart returns svg polyline points of the equity curve
+func (rep *Report) EquityChart() string {
+	values := make([]float64, len(rep.equity))
+	for i, p := range rep.equity {
+		values[i] = p.Equity
+	}
+	return rep.polyline(values)
+}
+
+// DrawdownChart returns svg polyline points of the drawdown, 0 at the top
+func (rep *Report) DrawdownChart() string {
+	values := make([]float64, len(rep.equity))
+	var peak float64
+	for i, p := range rep.equity {
+		if p.Equity > peak {
+			peak = p.Equity
+		}
+		if peak > 0 {
+			values[i] = p.Equity/peak - 1
+		}
+	}
+	return rep.polyline(values)
+}
+
+// polyline scales values by time of the equity points into the chart box
+func (rep *Report) polyline(values []float64) string {
+	if len(values) < 2 {
+		return ""
+	}
+	minV, maxV := values[0], values[0]
+	for _, v := range values {
+		if v < minV {
+			minV = v
+		}
+		if v > maxV {
+			maxV = v
+		}
+	}
+	if maxV == minV {
+		maxV = minV + 1
+	}
+	t0, t1 := rep.equity[0].Time, rep.equity[len(rep.equity)-1].Time
+	if t1 == t0 {
+		t1 = t0 + 1
+	}
+	var sb strings.Builder
+	for i, v := range values {
+		x := float64(rep.equity[i].Time-t0) / float64(t1-t0) * chartWidth
+		y := (maxV - v) / (maxV - minV) * chartHeight
+		fmt.Fprintf(&sb, "%.1f,%.1f ", x, y)
+	}
+	return sb.String()
+}
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"pct": func(v float64) string { return fmt.Sprintf("%.2f%%", v*100) },
+	"num": func(v float64) string { return fmt.Sprintf("%.2f", v) },
+	"factor": func(v *float64) string {
+		if v == nil {
+			return "no losses"
+		}
+		return fmt.Sprintf("%.2f", *v)
+	},
+	"date": func(ms int64) string {
+		return time.UnixMilli(ms).UTC().Format("2006-01-02 15:04")
+	},
+	"width":  func() int { return chartWidth },
+	"height": func() int { return chartHeight },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+td, th { border: 1px solid #ccc; padding: 4px 8px; text-align: right; }
+th { background: #f4f4f4; }
+svg { border: 1px solid #ccc; margin-bottom: 2em; }
+.neg { color: #c00; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{with .Metrics}}
+<p>{{date .Start}} &ndash; {{date .End}} UTC</p>
+<table>
+<tr><th>Initial equity</th><td>{{num .InitialEquity}}</td><th>Final equity</th><td>{{num .FinalEquity}}</td></tr>
+<tr><th>Total return</th><td>{{pct .TotalReturn}}</td><th>Annual return</th><td>{{pct .AnnualReturn}}</td></tr>
+<tr><th>Sharpe</th><td>{{num .Sharpe}}</td><th>Sortino</th><td>{{num .Sortino}}</td></tr>
+<tr><th>Annual volatility</th><td>{{pct .AnnualVolatility}}</td><th>Exposure</th><td>{{pct .Exposure}}</td></tr>
+<tr><th>Max drawdown</th><td>{{pct .MaxDrawdown}}</td><th>Max drawdown duration</th><td>{{num .MaxDrawdownDuration}} days</td></tr>
+<tr><th>Trades</th><td>{{.Trades}} ({{.BreakEven}} break-even)</td><th>Win rate</th><td>{{pct .WinRate}}</td></tr>
+<tr><th>Profit factor</th><td>{{factor .ProfitFactor}}</td><th>Average trade</th><td>{{num .AvgTrade}} ({{pct .AvgReturn}})</td></tr>
+<tr><th>Average win</th><td>{{num .AvgWin}}</td><th>Average loss</th><td>{{num .AvgLoss}}</td></tr>
+</table>
+{{end}}
+<h2>Equity</h2>
+<svg width="{{width}}" height="{{height}}" viewBox="0 0 {{width}} {{height}}" preserveAspectRatio="none">
+<polyline fill="none" stroke="#1565c0" stroke-width="1.5" points="{{.EquityChart}}"/>
+</svg>
+<h2>Drawdown</h2>
+<svg width="{{width}}" height="{{height}}" viewBox="0 0 {{width}} {{height}}" preserveAspectRatio="none">
+<polyline fill="none" stroke="#c62828" stroke-width="1.5" points="{{.DrawdownChart}}"/>
+</svg>
+{{if .MonteCarlo}}
+<h2>Monte Carlo</h2>
+<table>
+<tr><th>Method</th><th>Runs</th><th>Return p5</th><th>Return p50</th><th>Return p95</th><th>Max drawdown p50</th><th>Max drawdown p95</th><th>Ruin probability</th></tr>
+{{range .MonteCarlo}}<tr><td>{{.Method}}</td><td>{{.Runs}}</td><td>{{pct .Return.P5}}</td><td>{{pct .Return.P50}}</td><td>{{pct .Return.P95}}</td><td>{{pct .MaxDrawdown.P50}}</td><td>{{pct .MaxDrawdown.P95}}</td><td>{{pct .RuinProbability}}</td></tr>
+{{end}}</table>
+{{end}}
+<h2>Monthly returns</h2>
+<table>
+<tr><th>Month</th><th>Return</th></tr>
+{{range .Metrics.Monthly}}<tr><td>{{.Month}}</td><td{{if lt .Return 0.0}} class="neg"{{end}}>{{pct .Return}}</td></tr>
+{{end}}</table>
+<h2>Trades</h2>
+<table>
+<tr><th>Entry</th><th>Exit</th><th>Entry price</th><th>Exit price</th><th>Quantity</th><th>Fees</th><th>PnL</th><th>Return</th></tr>
+{{range .Trades}}<tr><td>{{date .EntryTime}}</td><td>{{date .ExitTime}}</td><td>{{.EntryPrice}}</td><td>{{.ExitPrice}}</td><td>{{.Quantity}}</td><td>{{num .Fees}}</td><td{{if lt .PnL 0.0}} class="neg"{{end}}>{{num .PnL}}</td><td>{{pct .Return}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
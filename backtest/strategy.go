@@ -0,0 +1,158 @@
+package backtest
+
+import (
+	"errors"
+	"fmt"
+	"github.com/okharch/binance/indicators"
+	"github.com/okharch/binance/klines"
+	"github.com/okharch/binance/sizing"
+	"github.com/okharch/binance/ticker"
+	"time"
+)
+
+// SignalStrategy trades signals of a registered indicator on klines of the period:
+// it buys for Fraction of cash or the quantity of Sizer if it is set on BUY when flat
+// and sells the whole position on SELL
+type SignalStrategy struct {
+	Period   time.Duration
+	Fraction float64
+	Sizer    sizing.Sizer
+	Rules    sizing.Rules // of the symbol, used with Sizer
+
+	kLinesCount int
+	signal      indicators.SignalFunc
+	source      indicators.SourceSignalFunc
+}
+
+// NewSignalStrategy returns strategy of the indicator with params calculated on completed klines of the period
+func NewSignalStrategy(name string, params []int, period time.Duration) (*SignalStrategy, error) {
+	ind, ok := indicators.Lookup(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown indicator %s", name)
+	}
+	if len(params) != len(ind.Params) {
+		return nil, fmt.Errorf("indicator %s expects %d params, got %d", name, len(ind.Params), len(params))
+	}
+	if !tickerPeriod(period) {
+		return nil, fmt.Errorf("period %v is not aggregated by the ticker", period)
+	}
+	s := &SignalStrategy{Period: period, Fraction: 1}
+	if ind.SourceFactory != nil {
+		s.source = ind.SourceFactory(params)
+	} else {
+		s.kLinesCount = ind.KLinesCount(params)
+		s.signal = ind.Factory(params)
+	}
+	return s, nil
+}
+
+// HistoryMinutes returns the number of 1m klines required before the first signal
+func (s *SignalStrategy) HistoryMinutes() int {
+	kLinesCount := s.kLinesCount
+	if s.source != nil {
+		// klines used by the source are not known in advance
+		kLinesCount = ticker.KLinesToKeep()
+	}
+	// the ticker does not return the first n+1 klines of the period
+	return (kLinesCount + 2) * int(s.Period/time.Minute)
+}
+
+func tickerPeriod(period time.Duration) bool {
+	for _, pm := range ticker.PeriodMinutes {
+		if time.Duration(pm)*time.Minute == period {
+			return true
+		}
+	}
+	return false
+}
+
+// Signal returns the indicator signal at the current kline of the engine
+func (s *SignalStrategy) Signal(e *Engine) indicators.TradeSignal {
+	if s.source != nil {
+		return s.source(e, s.Period)
+	}
+	kLines := e.KLines(s.Period, s.kLinesCount)
+	if kLines == nil {
+		return indicators.TradeNone
+	}
+	return s.signal(kLines)
+}
+
+func (s *SignalStrategy) OnKLine(e *Engine, _ klines.KLineEntry) {
+	if !e.PeriodClosed(s.Period) || len(e.OpenOrders()) > 0 {
+		return
+	}
+	switch s.Signal(e) {
+	case indicators.TradeBuy:
+		if e.Position() == 0 {
+			buy(e, s.Period, s.Fraction, s.Sizer, s.Rules)
+		}
+	case indicators.TradeSell:
+		if e.Position() > 0 {
+			e.Sell(e.Position())
+		}
+	}
+}
+
+// buy buys for fraction of cash or the quantity of the sizer if it is not nil,
+// nothing is bought if the quantity is too small for the rules or there are not enough klines yet
+func buy(e *Engine, period time.Duration, fraction float64, sizer sizing.Sizer, rules sizing.Rules) {
+	if sizer == nil {
+		e.BuyQuote(e.Cash() * fraction)
+		return
+	}
+	if q, err := e.Size(sizer, period, rules); err == nil && q > 0 {
+		e.Buy(q)
+	}
+}
+
+// StrategyFunc adapts a function to Strategy
+type StrategyFunc func(e *Engine, kLine klines.KLineEntry)
+
+func (f StrategyFunc) OnKLine(e *Engine, kLine klines.KLineEntry) {
+	f(e, kLine)
+}
+
+// StoredSignalStrategy trades signals stored in binance.indicator_signal like SignalStrategy does.
+// the signal is acted upon at the close of its period kline, when it is final
+type StoredSignalStrategy struct {
+	Period   time.Duration
+	Fraction float64
+	Sizer    sizing.Sizer
+	Rules    sizing.Rules
+	sides    map[int64]string // by close time of the period kline
+}
+
+// NewStoredSignalStrategy returns strategy of the signals of one indicator and period
+func NewStoredSignalStrategy(signals []indicators.IndicatorSignal) (*StoredSignalStrategy, error) {
+	s := &StoredSignalStrategy{Fraction: 1, sides: make(map[int64]string, len(signals))}
+	for _, signal := range signals {
+		if int(signal.Period) >= len(ticker.PeriodMinutes) {
+			return nil, fmt.Errorf("invalid period %d of signal", signal.Period)
+		}
+		period := time.Duration(ticker.PeriodMinutes[signal.Period]) * time.Minute
+		if s.Period != 0 && s.Period != period {
+			return nil, errors.New("signals of several periods")
+		}
+		s.Period = period
+		s.sides[signal.OpenTime+period.Milliseconds()] = signal.Side
+	}
+	return s, nil
+}
+
+func (s *StoredSignalStrategy) OnKLine(e *Engine, _ klines.KLineEntry) {
+	side, ok := s.sides[e.Time()]
+	if !ok || len(e.OpenOrders()) > 0 {
+		return
+	}
+	switch side {
+	case indicators.TradeBuy.String():
+		if e.Position() == 0 {
+			buy(e, s.Period, s.Fraction, s.Sizer, s.Rules)
+		}
+	case indicators.TradeSell.String():
+		if e.Position() > 0 {
+			e.Sell(e.Position())
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+	"github.com/okharch/binance/backtest"
+	"github.com/okharch/binance/indicators"
+	_ "github.com/okharch/binance/indicators/all"
+	"github.com/okharch/binance/klines"
+	"github.com/okharch/binance/sizing"
+	"github.com/okharch/binance/ticker"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// backtest runs signal strategy of a registered indicator on 1m klines from binance.klines or Binance csv export:
+//
+//	backtest -indicator MAC -params 10,20 -period 1h -symbol BTCUSDT -from 2023-01-01 -out result.json
+//	backtest -indicator RSI -params 14 -period 4h -csv BTCUSDT-1m-2023-01.csv -html report.html -mc-delay 5m
+//	backtest -indicator MAC -params 10,20 -period 1h -symbol BTCUSDT -stored
+//	backtest -indicator MAC -params 10,20 -period 1h -symbol BTCUSDT -sizing risk:0.01,14,2
+func main() {
+	log.SetFlags(log.LstdFlags | log.Lshortfile)
+	cfg := backtest.DefaultConfig()
+	indicator := flag.String("indicator", "", "name of the registered indicator")
+	paramsFlag := flag.String("params", "", "comma separated indicator params")
+	periodFlag := flag.String("period", "1h", "period of klines the indicator is calculated on")
+	symbol := flag.String("symbol", "", "symbol to load 1m klines of from the database")
+	csvFile := flag.String("csv", "", "csv file with 1m klines exported by Binance")
+	from := flag.String("from", "", "start date YYYY-MM-DD, the last 30 days by default")
+	to := flag.String("to", "", "end date YYYY-MM-DD, now by default")
+	out := flag.String("out", "", "file to write the result json to")
+	jsonReport := flag.String("json", "", "file to write the metrics report json to")
+	htmlReport := flag.String("html", "", "file to write the html report with charts to")
+	flag.Float64Var(&cfg.InitialCash, "cash", cfg.InitialCash, "initial cash in quote asset")
+	flag.Float64Var(&cfg.Slippage, "slippage", cfg.Slippage, "slippage of market orders, 0.0005 is 5 bps")
+	flag.DurationVar(&cfg.Latency, "latency", cfg.Latency, "order latency")
+	flag.BoolVar(&cfg.Fees.PayWithBNB, "bnb", false, "pay fees with BNB")
+	sizingFlag := flag.String("sizing", "", "position sizing: fixed:fraction, risk:fraction,atr_period,atr_multiple, "+
+		"kelly:win_rate,payoff,scale,cap or volatility:target,lookback,max_fraction, all the cash by default")
+	stored := flag.Bool("stored", false, "trade signals stored in binance.indicator_signal instead of calculating them")
+	mc := backtest.DefaultMonteCarloConfig()
+	flag.IntVar(&mc.Runs, "mc-runs", mc.Runs, "number of Monte Carlo runs of trades resampling")
+	flag.Float64Var(&mc.Ruin, "mc-ruin", mc.Ruin, "part of the initial equity below which Monte Carlo run is ruined")
+	mcDelay := flag.Duration("mc-delay", 0, "maximal random delay of orders of Monte Carlo reruns, no reruns if 0")
+	mcDelayRuns := flag.Int("mc-delay-runs", 100, "number of Monte Carlo reruns with delayed orders")
+	flag.Parse()
+
+	params, err := parseParams(*paramsFlag)
+	if err != nil {
+		log.Fatal(err)
+	}
+	period, err := klines.Period2Duration(*periodFlag)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var sizer sizing.Sizer
+	if *sizingFlag != "" {
+		if sizer, err = sizing.Parse(*sizingFlag); err != nil {
+			log.Fatal(err)
+		}
+	}
+	// the filters of the symbol are applied to the sizes if the klines are loaded from the database
+	var rules sizing.Rules
+	newStrategy := func() backtest.Strategy {
+		strategy, err := backtest.NewSignalStrategy(*indicator, params, period)
+		if err != nil {
+			log.Fatal(err)
+		}
+		strategy.Sizer, strategy.Rules = sizer, rules
+		return strategy
+	}
+	newStrategy() // validate the params before loading data
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		cancel()
+	}()
+
+	var data []klines.KLineEntry
+	if *csvFile != "" {
+		data, err = backtest.LoadKLinesCSV(*csvFile)
+	} else {
+		var stamps [2]int64
+		if stamps, err = timeRange(*from, *to); err != nil {
+			log.Fatal(err)
+		}
+		var db *sqlx.DB
+		if db, err = connect(*symbol); err != nil {
+			log.Fatal(err)
+		}
+		defer db.Close()
+		if sizer != nil {
+			if rules, err = sizing.LoadRules(ctx, db, *symbol, true); err != nil {
+				log.Fatal(err)
+			}
+		}
+		data, err = backtest.LoadSymbolKLinesFromDB(ctx, db, *symbol, stamps[0], stamps[1])
+		if err == nil && *stored {
+			var strategy *backtest.StoredSignalStrategy
+			if strategy, err = storedStrategy(ctx, db, *symbol, *indicator, params, period, stamps); err != nil {
+				log.Fatalf("failed to load signals: %v", err)
+			}
+			strategy.Sizer, strategy.Rules = sizer, rules
+			newStrategy = func() backtest.Strategy { return strategy }
+		}
+	}
+	if err != nil {
+		log.Fatalf("failed to load klines: %v", err)
+	}
+
+	result, err := backtest.New(cfg).Run(ctx, newStrategy(), data)
+	if err != nil {
+		log.Fatalf("backtest failed: %v", err)
+	}
+	report := backtest.NewReport(fmt.Sprintf("%s %s %s", *indicator, *paramsFlag, *periodFlag), result)
+	report.MonteCarlo = result.MonteCarlo(mc)
+	if *mcDelay > 0 {
+		delayConfig := mc
+		delayConfig.Runs = *mcDelayRuns
+		delayed, err := backtest.DelayMonteCarlo(ctx, cfg, newStrategy, data, delayConfig, *mcDelay)
+		if err != nil {
+			log.Fatalf("monte carlo with delays failed: %v", err)
+		}
+		report.MonteCarlo = append(report.MonteCarlo, delayed)
+	}
+	for _, a := range report.MonteCarlo {
+		log.Printf("monte carlo %s: return p5 %.2f%% p50 %.2f%%, max drawdown p95 %.2f%%, ruin %.2f%%",
+			a.Method, a.Return.P5*100, a.Return.P50*100, a.MaxDrawdown.P95*100, a.RuinProbability*100)
+	}
+	m := report.Metrics
+	log.Printf("%d klines, %d trades, return %.2f%%, sharpe %.2f, max drawdown %.2f%%, win rate %.2f%%",
+		len(data), m.Trades, m.TotalReturn*100, m.Sharpe, m.MaxDrawdown*100, m.WinRate*100)
+	if *out != "" {
+		b, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := os.WriteFile(*out, b, 0644); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *jsonReport != "" {
+		if err := writeFile(*jsonReport, report.WriteJSON); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *htmlReport != "" {
+		if err := writeFile(*htmlReport, report.WriteHTML); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+func writeFile(fileName string, write func(w io.Writer) error) error {
+	f, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func parseParams(s string) (params []int, err error) {
+	if s == "" {
+		return nil, nil
+	}
+	for _, p := range strings.Split(s, ",") {
+		v, err := strconv.Atoi(strings.TrimSpace(p))
+		if err != nil {
+			return nil, fmt.Errorf("invalid param %q: %w", p, err)
+		}
+		params = append(params, v)
+	}
+	return params, nil
+}
+
+// timeRange returns start and end timestamps of the dates, the last 30 days by default
+func timeRange(from, to string) (stamps [2]int64, err error) {
+	endTime := time.Now()
+	startTime := endTime.AddDate(0, 0, -30)
+	if to != "" {
+		if endTime, err = time.Parse("2006-01-02", to); err != nil {
+			return
+		}
+	}
+	if from != "" {
+		if startTime, err = time.Parse("2006-01-02", from); err != nil {
+			return
+		}
+	}
+	return [2]int64{startTime.UnixMilli(), endTime.UnixMilli()}, nil
+}
+
+func connect(symbol string) (*sqlx.DB, error) {
+	if symbol == "" {
+		return nil, fmt.Errorf("either -symbol or -csv is required")
+	}
+	dbURL := os.Getenv("TBOTS_DB")
+	if dbURL == "" {
+		return nil, fmt.Errorf("TBOTS_DB environment variable is not set")
+	}
+	return sqlx.Connect("postgres", dbURL)
+}
+
+// storedStrategy returns strategy of signals generated for the indicator with params on the period
+func storedStrategy(ctx context.Context, db *sqlx.DB, symbol, indicator string, params []int, period time.Duration,
+	stamps [2]int64) (*backtest.StoredSignalStrategy, error) {
+	catalogue := indicators.NewCatalogue(db)
+	if err := catalogue.Load(ctx); err != nil {
+		return nil, err
+	}
+	indicatorId, ok := catalogue.Id(indicator, params)
+	if !ok {
+		return nil, fmt.Errorf("%s%v is not in the indicator catalogue", indicator, params)
+	}
+	periodIndex := -1
+	for i, pm := range ticker.PeriodMinutes {
+		if time.Duration(pm)*time.Minute == period {
+			periodIndex = i
+		}
+	}
+	if periodIndex < 0 {
+		return nil, fmt.Errorf("signals are not generated for period %v", period)
+	}
+	var symbolId int32
+	if err := db.GetContext(ctx, &symbolId, "SELECT binance.get_symbol_id($1)", symbol); err != nil {
+		return nil, fmt.Errorf("unknown symbol %s: %w", symbol, err)
+	}
+	signals, err := backtest.LoadSignals(ctx, db, symbolId, indicatorId, int8(periodIndex), stamps[0], stamps[1])
+	if err != nil {
+		return nil, err
+	}
+	return backtest.NewStoredSignalStrategy(signals)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+	"github.com/okharch/binance/evaluation"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+func main() {
+	log.SetFlags(log.LstdFlags | log.Lshortfile)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dbURL := os.Getenv("TBOTS_DB")
+	if dbURL == "" {
+		log.Fatal("TBOTS_DB environment variable is not set")
+	}
+	db, err := sqlx.Connect("postgres", dbURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		log.Println("Received termination signal, cancelling context")
+		cancel()
+	}()
+
+	var symbols []int32
+	if err := db.SelectContext(ctx, &symbols, "SELECT DISTINCT symbol_id FROM binance.indicator_signal"); err != nil {
+		log.Fatalf("Failed to fetch symbols: %v", err)
+	}
+	for _, symbolId := range symbols {
+		results, err := evaluation.EvaluateSymbol(ctx, db, symbolId, evaluation.DefaultHorizons)
+		if err != nil {
+			log.Printf("failed to evaluate signals of symbol %d: %v", symbolId, err)
+			if ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+		rankings := evaluation.Aggregate(results, evaluation.DefaultHorizons)
+		if err := evaluation.SaveRankings(ctx, db, symbolId, rankings); err != nil {
+			log.Printf("failed to save rankings of symbol %d: %v", symbolId, err)
+			continue
+		}
+		log.Printf("symbol %d: %d signals evaluated, %d rankings saved", symbolId, len(results), len(rankings))
+	}
+	log.Println("evaluate_signals: exit gracefully")
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+	"github.com/okharch/binance/exchange_info"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// exchange_info imports symbols from /api/v3/exchangeInfo into binance.exchange_symbols once or every interval:
+//
+//	exchange_info
+//	exchange_info -interval 1h -unwatch-after 72h
+func main() {
+	log.SetFlags(log.LstdFlags | log.Lshortfile)
+	interval := flag.Duration("interval", 0, "import every interval until terminated, once if 0")
+	unwatchAfter := flag.Duration("unwatch-after", 7*24*time.Hour,
+		"remove symbols from watch_symbols after they are not trading for this duration, delisted ones at once")
+	flag.Parse()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dbURL := os.Getenv("TBOTS_DB")
+	if dbURL == "" {
+		log.Fatal("TBOTS_DB environment variable is not set")
+	}
+	db, err := sqlx.Connect("postgres", dbURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		log.Println("Received termination signal, cancelling context")
+		cancel()
+	}()
+
+	cache := exchange_info.NewCache()
+	if *interval > 0 {
+		cache.Run(ctx, db, *interval, *unwatchAfter)
+		return
+	}
+	result, err := cache.Refresh(ctx, db, *unwatchAfter)
+	if err != nil {
+		log.Fatalf("Failed to import exchange info: %v", err)
+	}
+	exchange_info.LogResult(result)
+}
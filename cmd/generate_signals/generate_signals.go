@@ -3,15 +3,21 @@ package main
 import (
 	"context"
 	"errors"
+	"expvar"
+	"flag"
 	"fmt"
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
-	"github.com/okharch/binance/klines"
+	"github.com/okharch/binance/indicators/composite"
+	"github.com/okharch/binance/pipeline"
 	"github.com/okharch/binance/ticker"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
+	"runtime"
 	"syscall"
+	"time"
 )
 
 func GetDB(envVarName string) (*sqlx.DB, error) {
@@ -30,6 +36,12 @@ func GetDB(envVarName string) (*sqlx.DB, error) {
 }
 
 func main() {
+	full := flag.Bool("full", false, "ignore checkpoints and generate signals for all the klines")
+	workers := flag.Int("workers", runtime.NumCPU(), "number of symbols processed concurrently")
+	metricsAddr := flag.String("metrics", "", "address to serve progress metrics at /debug/vars, e.g. :8080")
+	reportInterval := flag.Duration("report", time.Minute, "interval of progress logging")
+	flag.Parse()
+
 	// Connect to the database.
 	db, err := GetDB("TBOTS_DB")
 	if err != nil {
@@ -49,32 +61,52 @@ func main() {
 		cancel()
 	}()
 
+	// Register composite signal rules if they are configured.
+	if rulesFile := os.Getenv("COMPOSITE_RULES"); rulesFile != "" {
+		rules, err := composite.LoadRules(rulesFile)
+		if err != nil {
+			log.Fatalf("failed to load composite rules: %v", err)
+		}
+		if err := composite.Register(rules); err != nil {
+			log.Fatalf("failed to register composite rules: %v", err)
+		}
+	}
+
 	// Fetch the symbols from the watch_symbols table.
 	symbols, err := fetchSymbols(ctx, db)
 	if err != nil {
 		log.Fatalf("failed to fetch symbols: %v", err)
 	}
 
-	// For each symbol, fetch 1-minute klines and generate signals.
-	for _, symbol := range symbols {
-		// Fetch 1-minute klines for the symbol.
-		kLineData, err := klines.FetchKLineDataFromDBSincePeriodsBefore(db, symbol, int64(ticker.GetMaxMinutes()))
-		if err != nil {
-			log.Printf("failed to fetch klines for symbol %s: %v", symbol, err)
-			continue
-		}
+	catalogue, err := ticker.GetCatalogue(ctx, db)
+	if err != nil {
+		log.Fatalf("failed to load indicator catalogue: %v", err)
+	}
 
-		// Create a ticker using the 1-minute klines.
-		t := ticker.NewTicker(kLineData.Data)
-		ticks := t.GetTicksChannel(ctx)
-		for tick := range ticks {
-			log.Printf("generating signals for %d: %d", symbol, tick.OpenTime)
-			// Generate signals using the ticker.
-			if err := t.GenerateSignals(ctx, db, symbol); err != nil {
-				log.Printf("failed to generate signals for symbol %s: %v", symbol, err)
-				continue
+	stats := &pipeline.Stats{}
+	expvar.Publish("generate_signals", expvar.Func(func() any { return stats.Snapshot() }))
+	if *metricsAddr != "" {
+		// expvar serves the counters at /debug/vars
+		go func() {
+			if err := http.ListenAndServe(*metricsAddr, nil); err != nil {
+				log.Printf("metrics server failed: %v", err)
 			}
-		}
+		}()
+	}
+	reportCtx, stopReport := context.WithCancel(ctx)
+	go stats.Report(reportCtx, *reportInterval, len(symbols))
+
+	// Stream 1-minute klines of the symbols through the workers and generate signals.
+	cfg := pipeline.DefaultConfig()
+	cfg.Full = *full
+	cfg.Workers = *workers
+	err = pipeline.Run(ctx, pipeline.DBStore{DB: db}, catalogue, symbols, cfg, stats)
+	stopReport()
+	s := stats.Snapshot()
+	log.Printf("processed %d symbols, %d klines, inserted %d signals and %d indicator values", s.Symbols, s.KLines,
+		s.Inserted, s.Values)
+	if err != nil && !errors.Is(err, context.Canceled) {
+		log.Fatalf("failed to generate signals: %v", err)
 	}
 }
 
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+	"github.com/okharch/binance/backtest"
+	"github.com/okharch/binance/backtest/optimize"
+	_ "github.com/okharch/binance/indicators/all"
+	"github.com/okharch/binance/klines"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// optimize runs walk-forward optimisation of the indicator params:
+//
+//	optimize -indicator MAC -period 1h -symbol BTCUSDT -from 2022-01-01 -is 2160h -oos 720h -out mac.json
+//	optimize -indicator RSI -period 4h -csv BTCUSDT-1m-2023.csv -random 50
+func main() {
+	log.SetFlags(log.LstdFlags | log.Lshortfile)
+	cfg := optimize.Config{Backtest: backtest.DefaultConfig()}
+	flag.StringVar(&cfg.Indicator, "indicator", "", "name of the registered indicator")
+	periodFlag := flag.String("period", "1h", "period of klines the indicator is calculated on")
+	flag.DurationVar(&cfg.InSample, "is", 90*24*time.Hour, "in-sample window")
+	flag.DurationVar(&cfg.OutOfSample, "oos", 30*24*time.Hour, "out-of-sample window")
+	random := flag.Int("random", 0, "number of random params sets, the whole grid if 0")
+	flag.Int64Var(&cfg.Seed, "seed", 1, "seed of random search")
+	flag.IntVar(&cfg.Workers, "workers", 0, "number of concurrent backtests, the number of CPUs if 0")
+	symbol := flag.String("symbol", "", "symbol to load 1m klines of from the database")
+	csvFile := flag.String("csv", "", "csv file with 1m klines exported by Binance")
+	from := flag.String("from", "", "start date YYYY-MM-DD, a year ago by default")
+	to := flag.String("to", "", "end date YYYY-MM-DD, now by default")
+	out := flag.String("out", "", "file to write the result json to, stdout by default")
+	flag.Parse()
+
+	var err error
+	if cfg.Period, err = klines.Period2Duration(*periodFlag); err != nil {
+		log.Fatal(err)
+	}
+	if *random > 0 {
+		cfg.Search, cfg.Samples = optimize.RandomSearch, *random
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		cancel()
+	}()
+
+	var data []klines.KLineEntry
+	if *csvFile != "" {
+		data, err = backtest.LoadKLinesCSV(*csvFile)
+	} else {
+		data, err = loadFromDB(ctx, *symbol, *from, *to)
+	}
+	if err != nil {
+		log.Fatalf("failed to load klines: %v", err)
+	}
+
+	started := time.Now()
+	result, err := optimize.Run(ctx, cfg, data)
+	if err != nil {
+		log.Fatalf("optimisation failed: %v", err)
+	}
+	log.Printf("%d windows optimised in %v, walk-forward out-of-sample return %.2f%%",
+		len(result.Windows), time.Since(started), result.OutOfSampleReturn*100)
+	for i, p := range result.Params {
+		if i == 10 {
+			break
+		}
+		log.Printf("%v: oos score %.2f±%.2f, in-sample %.2f, positive %.0f%%, chosen %d",
+			p.Params, p.OutOfSampleScore, p.OutOfSampleStd, p.InSampleScore, p.PositiveWindows*100, p.Chosen)
+	}
+
+	b, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *out == "" {
+		fmt.Println(string(b))
+		return
+	}
+	if err := os.WriteFile(*out, b, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func loadFromDB(ctx context.Context, symbol, from, to string) ([]klines.KLineEntry, error) {
+	if symbol == "" {
+		return nil, fmt.Errorf("either -symbol or -csv is required")
+	}
+	endTime := time.Now()
+	startTime := endTime.AddDate(-1, 0, 0)
+	var err error
+	if to != "" {
+		if endTime, err = time.Parse("2006-01-02", to); err != nil {
+			return nil, err
+		}
+	}
+	if from != "" {
+		if startTime, err = time.Parse("2006-01-02", from); err != nil {
+			return nil, err
+		}
+	}
+	dbURL := os.Getenv("TBOTS_DB")
+	if dbURL == "" {
+		return nil, fmt.Errorf("TBOTS_DB environment variable is not set")
+	}
+	db, err := sqlx.Connect("postgres", dbURL)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	return backtest.LoadSymbolKLinesFromDB(ctx, db, symbol, startTime.UnixMilli(), endTime.UnixMilli())
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+	_ "github.com/okharch/binance/indicators/all"
+	"github.com/okharch/binance/klines/download"
+	"github.com/okharch/binance/paper"
+	"github.com/okharch/binance/risk"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// paper_trading forward-tests the active accounts of binance.paper_account on live klines:
+//
+//	paper_trading -book
+//
+// the orders are checked by the risk manager if -risk is set, breaches are saved into binance.risk_breach:
+//
+//	paper_trading -risk limits.json -kill-file /tmp/stop-trading -kill-db -risk-addr :8090
+//
+// POST requests of -risk-addr must be authorized by "Authorization: Bearer $TBOTS_RISK_TOKEN",
+// only the status is served without the token
+func main() {
+	log.SetFlags(log.LstdFlags | log.Lshortfile)
+	book := flag.Bool("book", false, "fill orders at the best bid and ask of binance.bid_book and binance.ask_book")
+	maxAge := flag.Duration("book-age", 0, "maximal age of the order book updates, 1m by default")
+	limitsFile := flag.String("risk", "", "json file of the risk limits, see risk.Limits")
+	killFile := flag.String("kill-file", "", "trading is stopped while the file exists")
+	killDB := flag.Bool("kill-db", false, "trading is stopped while binance.risk_kill_switch is engaged")
+	riskAddr := flag.String("risk-addr", "", "http address of the risk status and kill switch")
+	webhook := flag.String("alert-webhook", "", "url the risk breaches are posted to")
+	flag.Parse()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dbURL := os.Getenv("TBOTS_DB")
+	if dbURL == "" {
+		log.Fatal("TBOTS_DB environment variable is not set")
+	}
+	db, err := sqlx.Connect("postgres", dbURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		log.Println("Received termination signal, cancelling context")
+		cancel()
+	}()
+
+	var quotes paper.QuoteSource
+	if *book {
+		quotes = paper.BookQuotes{DB: db, MaxAge: *maxAge}
+	}
+	service, err := paper.NewService(ctx, db, quotes)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *limitsFile != "" {
+		limits, err := risk.LoadLimits(*limitsFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		alerters := []risk.Alerter{risk.DBAlerter{DB: db}}
+		if *webhook != "" {
+			alerters = append(alerters, risk.WebhookAlerter{URL: *webhook})
+		}
+		riskManager := risk.NewManager(limits, alerters...)
+		riskManager.Token = os.Getenv("TBOTS_RISK_TOKEN")
+		service.SetRisk(ctx, riskManager)
+		var switches []risk.Switch
+		if *killFile != "" {
+			switches = append(switches, risk.FileSwitch{Path: *killFile})
+		}
+		if *killDB {
+			switches = append(switches, risk.DBSwitch{DB: db})
+		}
+		if len(switches) > 0 {
+			go riskManager.Watch(ctx, 5*time.Second, switches...)
+		}
+		if *riskAddr != "" {
+			if riskManager.Token == "" {
+				log.Print("TBOTS_RISK_TOKEN environment variable is not set, the kill switch can't be used over http")
+			}
+			go func() {
+				if err := http.ListenAndServe(*riskAddr, riskManager); err != nil {
+					log.Printf("risk server failed: %v", err)
+				}
+			}()
+		}
+	}
+	messages, err := download.WatchKlines(ctx, db)
+	if err != nil {
+		log.Fatalf("Failed to watch klines: %v", err)
+	}
+	if err := service.Run(ctx, messages); err != nil && err != context.Canceled {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+	"github.com/okharch/binance/orders"
+	"github.com/okharch/binance/portfolio"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// portfolio values the balances in USDT and BTC and stores the snapshot into binance.portfolio_snapshot
+// once or every interval:
+//
+//	portfolio -interval 24h
+//	portfolio -source manual -name cold-wallet
+func main() {
+	log.SetFlags(log.LstdFlags | log.Lshortfile)
+	source := flag.String("source", "account",
+		"balances of account API of BINANCE_API_KEY, binance.account_balance (stream) or binance.portfolio_holding (manual)")
+	name := flag.String("name", "account", "name of the portfolio")
+	interval := flag.Duration("interval", 0, "take snapshot every interval until terminated, once if 0")
+	flag.Parse()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dbURL := os.Getenv("TBOTS_DB")
+	if dbURL == "" {
+		log.Fatal("TBOTS_DB environment variable is not set")
+	}
+	db, err := sqlx.Connect("postgres", dbURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		log.Println("Received termination signal, cancelling context")
+		cancel()
+	}()
+
+	var balances portfolio.Source
+	switch *source {
+	case "account":
+		client, err := orders.NewClientFromEnv()
+		if err != nil {
+			log.Fatal(err)
+		}
+		balances = portfolio.AccountBalances{Client: client}
+	case "stream":
+		balances = portfolio.StreamBalances{DB: db}
+	case "manual":
+		balances = portfolio.ManualHoldings{DB: db, Portfolio: *name}
+	default:
+		log.Fatalf("unknown source %s, use account, stream or manual", *source)
+	}
+	if *interval > 0 {
+		portfolio.Run(ctx, db, *name, balances, *interval)
+		return
+	}
+	s, err := portfolio.TakeSnapshot(ctx, db, *name, balances)
+	if err != nil {
+		log.Fatal(err)
+	}
+	portfolio.LogSnapshot(s)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+	"github.com/okharch/binance/accounting"
+	"github.com/okharch/binance/orders"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// trade_history imports trades, deposits and withdrawals of the account of BINANCE_API_KEY
+// and writes yearly P&L report of the assets in csv:
+//
+//	trade_history -method fifo -quote USDT -year 2023 -o pnl2023.csv
+//	trade_history -import=false -disposals -method lifo
+func main() {
+	log.SetFlags(log.LstdFlags | log.Lshortfile)
+	importHistory := flag.Bool("import", true, "import new trades and transfers before the report")
+	symbols := flag.String("symbols", "", "comma separated symbols to import trades of, the traded ones by default")
+	since := flag.String("since", "2017-07-01", "start date of deposits and withdrawals history")
+	method := flag.String("method", "fifo", "cost method: fifo, lifo or average")
+	quote := flag.String("quote", "USDT", "currency of the report")
+	year := flag.Int("year", 0, "year of the report, all years if 0")
+	disposals := flag.Bool("disposals", false, "write disposals of tax lots instead of yearly summary")
+	output := flag.String("o", "", "output csv file, stdout by default")
+	flag.Parse()
+
+	costMethod, err := accounting.ParseMethod(*method)
+	if err != nil {
+		log.Fatal(err)
+	}
+	start, err := time.Parse("2006-01-02", *since)
+	if err != nil {
+		log.Fatalf("invalid since date: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dbURL := os.Getenv("TBOTS_DB")
+	if dbURL == "" {
+		log.Fatal("TBOTS_DB environment variable is not set")
+	}
+	db, err := sqlx.Connect("postgres", dbURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		log.Println("Received termination signal, cancelling context")
+		cancel()
+	}()
+
+	if *importHistory {
+		client, err := orders.NewClientFromEnv()
+		if err != nil {
+			log.Fatal(err)
+		}
+		var list []string
+		if *symbols != "" {
+			list = strings.Split(strings.ToUpper(*symbols), ",")
+		} else if list, err = accounting.TradedSymbols(ctx, db); err != nil {
+			log.Fatalf("Failed to get traded symbols: %v", err)
+		}
+		n, err := accounting.ImportTrades(ctx, db, client, list)
+		if err != nil {
+			log.Fatalf("Failed to import trades: %v", err)
+		}
+		log.Printf("%d trades of %d symbols imported", n, len(list))
+		if n, err = accounting.ImportTransfers(ctx, db, client, start); err != nil {
+			log.Fatalf("Failed to import deposits and withdrawals: %v", err)
+		}
+		log.Printf("%d deposits and withdrawals imported", n)
+	}
+
+	trades, transfers, err := accounting.LoadHistory(ctx, db)
+	if err != nil {
+		log.Fatal(err)
+	}
+	ledger := accounting.NewLedger(costMethod, strings.ToUpper(*quote), accounting.NewKlinePrices(db))
+	summaries, err := accounting.Report(ctx, ledger, trades, transfers, time.Now())
+	if err != nil {
+		log.Fatalf("Failed to calculate P&L: %v", err)
+	}
+	for _, w := range ledger.Warnings {
+		log.Print(w)
+	}
+
+	var out io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		out = f
+	}
+	if *disposals {
+		var list []accounting.Disposal
+		for _, d := range ledger.Disposals {
+			if *year == 0 || time.UnixMilli(d.Time).UTC().Year() == *year {
+				list = append(list, d)
+			}
+		}
+		err = accounting.WriteDisposalsCSV(out, list)
+	} else {
+		var list []accounting.YearSummary
+		for _, s := range summaries {
+			if *year == 0 || s.Year == *year {
+				list = append(list, s)
+			}
+		}
+		err = accounting.WriteCSV(out, list)
+	}
+	if err != nil {
+		log.Fatalf("Failed to write report: %v", err)
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+	"github.com/okharch/binance/accounting"
+	"github.com/okharch/binance/orders"
+	"github.com/okharch/binance/risk"
+	"github.com/okharch/binance/userstream"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// user_stream records balances, order updates and fills of the account of BINANCE_API_KEY
+// into the tables of userstream/userstream.sql and binance.spot_order:
+//
+//	user_stream -prefix bot1
+//
+// the orders of the manager are checked by the risk manager if -risk is set, the exposure of the live book
+// is updated from the balances of the account, the open orders are cancelled when the kill switch is engaged:
+//
+//	user_stream -risk limits.json -kill-file /tmp/stop-trading -kill-db -risk-addr :8091
+//
+// POST requests of -risk-addr must be authorized by "Authorization: Bearer $TBOTS_RISK_TOKEN",
+// only the status is served without the token
+func main() {
+	log.SetFlags(log.LstdFlags | log.Lshortfile)
+	prefix := flag.String("prefix", "stream", "prefix of client order ids of the orders manager")
+	limitsFile := flag.String("risk", "", "json file of the risk limits, see risk.Limits")
+	quote := flag.String("quote", "USDT", "quote asset the exposure and equity of the account are valued in")
+	killFile := flag.String("kill-file", "", "trading is stopped while the file exists")
+	killDB := flag.Bool("kill-db", false, "trading is stopped while binance.risk_kill_switch is engaged")
+	riskAddr := flag.String("risk-addr", "", "http address of the risk status and kill switch")
+	webhook := flag.String("alert-webhook", "", "url the risk breaches are posted to")
+	flag.Parse()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dbURL := os.Getenv("TBOTS_DB")
+	if dbURL == "" {
+		log.Fatal("TBOTS_DB environment variable is not set")
+	}
+	db, err := sqlx.Connect("postgres", dbURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+	client, err := orders.NewClientFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		log.Println("Received termination signal, cancelling context")
+		cancel()
+	}()
+
+	var manager *orders.Manager
+	if *limitsFile == "" {
+		manager = orders.NewManager(client, orders.DBStore{DB: db}, *prefix)
+	} else {
+		limits, err := risk.LoadLimits(*limitsFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		alerters := []risk.Alerter{risk.DBAlerter{DB: db}}
+		if *webhook != "" {
+			alerters = append(alerters, risk.WebhookAlerter{URL: *webhook})
+		}
+		riskManager := risk.NewManager(limits, alerters...)
+		riskManager.Token = os.Getenv("TBOTS_RISK_TOKEN")
+		prices := accounting.NewKlinePrices(db)
+		price := func(symbol string) float64 {
+			asset := strings.TrimSuffix(symbol, *quote)
+			p, _, err := prices.Price(ctx, asset, *quote, time.Now().UnixMilli())
+			if err != nil {
+				log.Print(err)
+			}
+			return p
+		}
+		var book *risk.LiveBook
+		manager, book, err = risk.NewLiveManager(riskManager, "live", client, orders.DBStore{DB: db}, *prefix, *quote,
+			price)
+		if err != nil {
+			log.Fatal(err)
+		}
+		go book.Run(ctx, time.Minute)
+		var switches []risk.Switch
+		if *killFile != "" {
+			switches = append(switches, risk.FileSwitch{Path: *killFile})
+		}
+		if *killDB {
+			switches = append(switches, risk.DBSwitch{DB: db})
+		}
+		if len(switches) > 0 {
+			go riskManager.Watch(ctx, 5*time.Second, switches...)
+		}
+		if *riskAddr != "" {
+			if riskManager.Token == "" {
+				log.Print("TBOTS_RISK_TOKEN environment variable is not set, the kill switch can't be used over http")
+			}
+			go func() {
+				if err := http.ListenAndServe(*riskAddr, riskManager); err != nil {
+					log.Printf("risk server failed: %v", err)
+				}
+			}()
+		}
+	}
+	stream := userstream.NewStream(client, userstream.DBHandler{DB: db, Orders: manager})
+	// the order updates missed while the stream was disconnected are recovered from the exchange
+	stream.OnConnect = manager.Reconcile
+	stream.Run(ctx)
+}
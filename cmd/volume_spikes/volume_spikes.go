@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+	"github.com/okharch/binance/indicators"
+	"github.com/okharch/binance/indicators/volume"
+	"github.com/okharch/binance/klines"
+	"github.com/okharch/binance/klines/download"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// periods to watch for volume spikes
+var spikePeriods = []string{"5m", "15m", "1h"}
+
+func insertSpike(db *sqlx.DB, symbol, period string, spike volume.Spike) error {
+	tradeSignal := "SELL"
+	if spike.Signal == indicators.TradeBuy {
+		tradeSignal = "BUY"
+	}
+	_, err := db.Exec(`
+		INSERT INTO binance.volume_spikes (symbol, period, open_time, close_price, volume, avg_volume, trade_signal, price_vol_window)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT DO NOTHING`,
+		symbol, period, spike.OpenTime, spike.ClosePrice, spike.Volume, spike.AvgVolume, tradeSignal, spike.PriceVolWindow)
+	return err
+}
+
+func main() {
+	log.SetFlags(log.LstdFlags | log.Lshortfile)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dbURL := os.Getenv("TBOTS_DB")
+	if dbURL == "" {
+		log.Fatal("TBOTS_DB environment variable is not set")
+	}
+	db, err := sqlx.Connect("postgres", dbURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		log.Println("Received termination signal, cancelling context")
+		cancel()
+	}()
+
+	messages, err := download.WatchKlines(ctx, db)
+	if err != nil {
+		log.Fatalf("Failed to watch klines: %v", err)
+	}
+	// detectors by symbol for each of spikePeriods
+	detectors := make(map[string][]*volume.SpikeDetector)
+	for msg := range messages {
+		km, err := download.ParseKlineMessage(msg)
+		if err != nil {
+			log.Print(err)
+			continue
+		}
+		if !km.Closed {
+			continue
+		}
+		symbolDetectors, ok := detectors[km.Symbol]
+		if !ok {
+			for _, period := range spikePeriods {
+				d, _ := klines.Period2Duration(period)
+				symbolDetectors = append(symbolDetectors, volume.NewSpikeDetector(d, volume.DefaultSpikeParams()))
+			}
+			detectors[km.Symbol] = symbolDetectors
+		}
+		for _, d := range symbolDetectors {
+			spike, ok := d.Add(km.KLine)
+			if !ok {
+				continue
+			}
+			period := klines.Duration2Period(d.Period)
+			log.Printf("volume spike %s %s at %v: %s", km.Symbol, period,
+				time.UnixMilli(spike.OpenTime).UTC(), spike.PriceVolWindow)
+			if err := insertSpike(db, km.Symbol, period, spike); err != nil {
+				log.Printf("failed to insert volume spike: %v", err)
+			}
+		}
+	}
+	log.Println("volume_spikes: exit gracefully")
+}
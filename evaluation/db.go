@@ -0,0 +1,85 @@
+package evaluation
+
+import (
+	"context"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"github.com/okharch/binance/indicators"
+	"github.com/okharch/binance/klines"
+	"github.com/okharch/binance/ticker"
+	"time"
+)
+
+func fetchSignals(ctx context.Context, db *sqlx.DB, symbolId int32) (signals []indicators.IndicatorSignal, err error) {
+	err = db.SelectContext(ctx, &signals, `
+		SELECT open_time, symbol_id, indicator_id, side, period, volume
+		FROM binance.indicator_signal
+		WHERE symbol_id = $1
+		ORDER BY open_time`, symbolId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch signals of symbol %d: %w", symbolId, err)
+	}
+	return
+}
+
+// EvaluateSymbol evaluates all stored signals of the symbol
+func EvaluateSymbol(ctx context.Context, db *sqlx.DB, symbolId int32, horizons []time.Duration) ([]SignalResult, error) {
+	signals, err := fetchSignals(ctx, db, symbolId)
+	if err != nil || len(signals) == 0 {
+		return nil, err
+	}
+	var maxHorizon time.Duration
+	for _, h := range horizons {
+		if h > maxHorizon {
+			maxHorizon = h
+		}
+	}
+	maxPeriod := time.Duration(ticker.PeriodMinutes[len(ticker.PeriodMinutes)-1]) * time.Minute
+	startTime := signals[0].OpenTime
+	endTime := signals[len(signals)-1].OpenTime + (maxPeriod + maxHorizon).Milliseconds()
+	kd, err := klines.FetchKLineDataFromDB(db, symbolId, startTime, endTime)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]SignalResult, 0, len(signals))
+	for _, signal := range signals {
+		if int(signal.Period) >= len(ticker.PeriodMinutes) {
+			continue
+		}
+		period := time.Duration(ticker.PeriodMinutes[signal.Period]) * time.Minute
+		if r, ok := EvaluateSignal(signal, period, kd.Data, horizons); ok {
+			results = append(results, r)
+		}
+	}
+	return results, ctx.Err()
+}
+
+// SaveRankings replaces rankings of the symbol
+func SaveRankings(ctx context.Context, db *sqlx.DB, symbolId int32, rankings []Ranking) error {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, `DELETE FROM binance.indicator_ranking WHERE symbol_id = $1`, symbolId); err != nil {
+		return fmt.Errorf("failed to delete rankings of symbol %d: %w", symbolId, err)
+	}
+	// insert by chunks not to exceed postgres limit on the number of query parameters
+	const chunkSize = 5000
+	for len(rankings) > 0 {
+		chunk := rankings
+		if len(chunk) > chunkSize {
+			chunk = chunk[:chunkSize]
+		}
+		rankings = rankings[len(chunk):]
+		_, err := tx.NamedExecContext(ctx, `
+			INSERT INTO binance.indicator_ranking (indicator_id, period, symbol_id, horizon_minutes,
+				signals, avg_return, std_return, hit_rate, avg_mfe, avg_mae, score)
+			VALUES (:indicator_id, :period, :symbol_id, :horizon_minutes,
+				:signals, :avg_return, :std_return, :hit_rate, :avg_mfe, :avg_mae, :score)`, chunk)
+		if err != nil {
+			return fmt.Errorf("failed to insert rankings of symbol %d: %w", symbolId, err)
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,68 @@
+package evaluation
+
+import (
+	"github.com/okharch/binance/indicators"
+	"github.com/okharch/binance/klines"
+	"math"
+	"testing"
+	"time"
+)
+
+func makeData1m(prices []float64) []klines.KLineEntry {
+	data := make([]klines.KLineEntry, len(prices))
+	for i, price := range prices {
+		openTime := int64(i) * 60000
+		data[i] = klines.KLineEntry{
+			OpenTime: openTime, CloseTime: openTime + 59999,
+			HighPrice: price + 1, LowPrice: price - 1, ClosePrice: price,
+		}
+	}
+	return data
+}
+
+func TestEvaluateSignal(t *testing.T) {
+	data := makeData1m([]float64{100, 100, 100, 110, 90, 105})
+	horizons := []time.Duration{time.Minute, 3 * time.Minute}
+	// 2m signal kline opened at 0, entry is close of the 2nd minute
+	signal := indicators.IndicatorSignal{OpenTime: 0, Side: "BUY"}
+	r, ok := EvaluateSignal(signal, 2*time.Minute, data, horizons)
+	if !ok {
+		t.Fatalf("expected signal to be evaluated")
+	}
+	expected := []float64{0, -0.1}
+	for i, v := range expected {
+		if math.Abs(r.Returns[i]-v) > 1e-9 {
+			t.Errorf("horizon %v: expected %v, got %v", horizons[i], v, r.Returns[i])
+		}
+	}
+	if math.Abs(r.MFE-0.11) > 1e-9 || math.Abs(r.MAE+0.11) > 1e-9 {
+		t.Errorf("expected MFE 0.11 and MAE -0.11, got %v and %v", r.MFE, r.MAE)
+	}
+	signal.Side = "SELL"
+	r, _ = EvaluateSignal(signal, 2*time.Minute, data, horizons)
+	if math.Abs(r.Returns[1]-0.1) > 1e-9 {
+		t.Errorf("sell: expected 0.1, got %v", r.Returns[1])
+	}
+	// not enough data for the horizon
+	if _, ok := EvaluateSignal(indicators.IndicatorSignal{OpenTime: 120000, Side: "BUY"}, 2*time.Minute, data, horizons); ok {
+		t.Errorf("expected signal not to be evaluated")
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	horizons := []time.Duration{time.Hour}
+	results := []SignalResult{
+		{Signal: indicators.IndicatorSignal{IndicatorId: 1}, Returns: []float64{0.02}},
+		{Signal: indicators.IndicatorSignal{IndicatorId: 1}, Returns: []float64{-0.01}},
+		{Signal: indicators.IndicatorSignal{IndicatorId: 2}, Returns: []float64{0.01}},
+	}
+	rankings := Aggregate(results, horizons)
+	if len(rankings) != 2 {
+		t.Fatalf("expected 2 rankings, got %d", len(rankings))
+	}
+	for _, r := range rankings {
+		if r.IndicatorId == 1 && (r.Signals != 2 || math.Abs(r.AvgReturn-0.005) > 1e-9 || r.HitRate != 0.5) {
+			t.Errorf("invalid ranking %+v", r)
+		}
+	}
+}
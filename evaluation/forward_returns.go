@@ -0,0 +1,77 @@
+package evaluation
+
+import (
+	"github.com/okharch/binance/indicators"
+	"github.com/okharch/binance/klines"
+	"sort"
+	"time"
+)
+
+/*
+Forward-return evaluation of indicator signals.
+The entry price of a signal is the close of the signal kline (open_time + period),
+the forward return at a horizon is the change of the close price after the horizon,
+signed by side, so a positive return means the signal was right.
+MFE/MAE (maximum favorable/adverse excursion) are the best and the worst
+price moves within the longest horizon.
+*/
+
+var DefaultHorizons = []time.Duration{15 * time.Minute, time.Hour, 4 * time.Hour, 24 * time.Hour}
+
+type SignalResult struct {
+	Signal  indicators.IndicatorSignal
+	Returns []float64 // by horizon
+	MFE     float64
+	MAE     float64 // negative or zero
+}
+
+// findKLine returns index of the first 1m kline opened at or after openTime
+func findKLine(data1m []klines.KLineEntry, openTime int64) int {
+	return sort.Search(len(data1m), func(i int) bool { return data1m[i].OpenTime >= openTime })
+}
+
+// EvaluateSignal calculates forward returns of the signal on period using 1m klines ordered by open time.
+// ok is false if there are no klines for the entry or the longest horizon
+func EvaluateSignal(signal indicators.IndicatorSignal, period time.Duration, data1m []klines.KLineEntry, horizons []time.Duration) (r SignalResult, ok bool) {
+	entryTime := signal.OpenTime + period.Milliseconds()
+	// the last 1m kline of the signal kline gives the entry price
+	entryIdx := findKLine(data1m, entryTime) - 1
+	if entryIdx < 0 || entryIdx >= len(data1m) || data1m[entryIdx].CloseTime+1 != entryTime {
+		return
+	}
+	entry := data1m[entryIdx].ClosePrice
+	if entry <= 0 {
+		return
+	}
+	sign := 1.0
+	if signal.Side == indicators.TradeSell.String() {
+		sign = -1
+	}
+	r.Signal = signal
+	r.Returns = make([]float64, len(horizons))
+	var maxHorizon time.Duration
+	for i, h := range horizons {
+		idx := findKLine(data1m, entryTime+h.Milliseconds()) - 1
+		if idx <= entryIdx || data1m[idx].CloseTime+1 != entryTime+h.Milliseconds() {
+			return r, false
+		}
+		r.Returns[i] = sign * (data1m[idx].ClosePrice/entry - 1)
+		if h > maxHorizon {
+			maxHorizon = h
+		}
+	}
+	endTime := entryTime + maxHorizon.Milliseconds()
+	for i := entryIdx + 1; i < len(data1m) && data1m[i].OpenTime < endTime; i++ {
+		up, down := data1m[i].HighPrice/entry-1, data1m[i].LowPrice/entry-1
+		if sign < 0 {
+			up, down = -down, -up
+		}
+		if up > r.MFE {
+			r.MFE = up
+		}
+		if down < r.MAE {
+			r.MAE = down
+		}
+	}
+	return r, true
+}
@@ -0,0 +1,28 @@
+-- forward-return evaluation of indicator signals, see evaluation package
+CREATE TABLE IF NOT EXISTS binance.indicator_ranking (
+    indicator_id INT NOT NULL, -- binance.indicator_catalogue.indicator_id
+    period SMALLINT NOT NULL, -- index of ticker.PeriodMinutes
+    symbol_id INT NOT NULL,
+    horizon_minutes INT NOT NULL, -- forward return horizon
+    signals INT NOT NULL, -- number of evaluated signals
+    avg_return FLOAT8 NOT NULL, -- average return signed by side
+    std_return FLOAT8 NOT NULL,
+    hit_rate FLOAT8 NOT NULL, -- share of signals with positive return
+    avg_mfe FLOAT8 NOT NULL, -- average maximum favorable excursion within the longest horizon
+    avg_mae FLOAT8 NOT NULL, -- average maximum adverse excursion within the longest horizon
+    score FLOAT8 NOT NULL, -- t-statistic of average return
+    evaluated_at timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (indicator_id, period, symbol_id, horizon_minutes)
+);
+COMMENT ON TABLE binance.indicator_ranking IS 'Forward returns of indicator signals aggregated by indicator, period and symbol.';
+
+-- the best indicator settings across symbols, e.g. for 4h horizon
+CREATE OR REPLACE VIEW binance.indicator_ranking_summary AS
+SELECT r.indicator_id, c.indicator, c.params, r.period, r.horizon_minutes,
+       sum(r.signals) AS signals,
+       sum(r.avg_return * r.signals) / sum(r.signals) AS avg_return,
+       sum(r.hit_rate * r.signals) / sum(r.signals) AS hit_rate,
+       avg(r.score) AS avg_score
+FROM binance.indicator_ranking r
+JOIN binance.indicator_catalogue c USING (indicator_id)
+GROUP BY r.indicator_id, c.indicator, c.params, r.period, r.horizon_minutes;
@@ -0,0 +1,79 @@
+package evaluation
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// RankingKey groups signal results
+type RankingKey struct {
+	IndicatorId int32
+	Period      int8
+	SymbolId    int32
+}
+
+// Ranking is aggregated evaluation of signals of the indicator on period and symbol at the horizon
+type Ranking struct {
+	IndicatorId    int32   `db:"indicator_id"`
+	Period         int8    `db:"period"`
+	SymbolId       int32   `db:"symbol_id"`
+	HorizonMinutes int     `db:"horizon_minutes"`
+	Signals        int     `db:"signals"`
+	AvgReturn      float64 `db:"avg_return"`
+	StdReturn      float64 `db:"std_return"`
+	HitRate        float64 `db:"hit_rate"` // share of signals with positive return
+	AvgMFE         float64 `db:"avg_mfe"`
+	AvgMAE         float64 `db:"avg_mae"`
+	Score          float64 `db:"score"` // t-statistic of the average return: avg/std*sqrt(n)
+}
+
+// Aggregate aggregates results by indicator_id/period/symbol for each horizon,
+// rankings are ordered by score, the best first
+func Aggregate(results []SignalResult, horizons []time.Duration) []Ranking {
+	groups := make(map[RankingKey][]SignalResult)
+	for _, r := range results {
+		key := RankingKey{r.Signal.IndicatorId, r.Signal.Period, r.Signal.SymbolId}
+		groups[key] = append(groups[key], r)
+	}
+	var rankings []Ranking
+	for key, group := range groups {
+		n := float64(len(group))
+		var sumMFE, sumMAE float64
+		for _, r := range group {
+			sumMFE += r.MFE
+			sumMAE += r.MAE
+		}
+		for i, h := range horizons {
+			var sum, sumSq, hits float64
+			for _, r := range group {
+				v := r.Returns[i]
+				sum += v
+				sumSq += v * v
+				if v > 0 {
+					hits++
+				}
+			}
+			avg := sum / n
+			std := math.Sqrt(math.Max(sumSq/n-avg*avg, 0))
+			ranking := Ranking{
+				IndicatorId:    key.IndicatorId,
+				Period:         key.Period,
+				SymbolId:       key.SymbolId,
+				HorizonMinutes: int(h / time.Minute),
+				Signals:        len(group),
+				AvgReturn:      avg,
+				StdReturn:      std,
+				HitRate:        hits / n,
+				AvgMFE:         sumMFE / n,
+				AvgMAE:         sumMAE / n,
+			}
+			if std > 0 {
+				ranking.Score = avg / std * math.Sqrt(n)
+			}
+			rankings = append(rankings, ranking)
+		}
+	}
+	sort.Slice(rankings, func(i, j int) bool { return rankings[i].Score > rankings[j].Score })
+	return rankings
+}
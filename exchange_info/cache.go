@@ -0,0 +1,107 @@
+package exchange_info
+
+import (
+	"context"
+	"github.com/jmoiron/sqlx"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Cache is the in-memory trading rules of the symbols, it is safe for concurrent use
+type Cache struct {
+	mu         sync.RWMutex
+	symbols    map[string]*Symbol
+	serverTime int64
+}
+
+func NewCache() *Cache {
+	return &Cache{symbols: map[string]*Symbol{}}
+}
+
+// Set replaces the symbols of the cache, the symbols must not be changed after it
+func (c *Cache) Set(info *ExchangeInfo) {
+	symbols := make(map[string]*Symbol, len(info.Symbols))
+	for _, s := range info.Symbols {
+		symbols[s.Symbol] = s
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.symbols, c.serverTime = symbols, info.ServerTime
+}
+
+// Symbol returns the trading rules of the symbol, it is the signature of Validator.Lookup
+func (c *Cache) Symbol(symbol string) (*Symbol, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	s, ok := c.symbols[symbol]
+	return s, ok
+}
+
+// Symbols returns the symbols sorted by name, trading ones only if trading is true
+func (c *Cache) Symbols(trading bool) []*Symbol {
+	c.mu.RLock()
+	result := make([]*Symbol, 0, len(c.symbols))
+	for _, s := range c.symbols {
+		if !trading || s.Trading() {
+			result = append(result, s)
+		}
+	}
+	c.mu.RUnlock()
+	sort.Slice(result, func(i, j int) bool { return result[i].Symbol < result[j].Symbol })
+	return result
+}
+
+// ServerTime returns the server time of the exchange info the cache was set by
+func (c *Cache) ServerTime() int64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.serverTime
+}
+
+// Refresh fetches exchange info, imports it into the database and sets the cache
+func (c *Cache) Refresh(ctx context.Context, db *sqlx.DB, unwatchAfter time.Duration) (ImportResult, error) {
+	info, err := Fetch(ctx, db)
+	if err != nil {
+		return ImportResult{}, err
+	}
+	result, err := Import(ctx, db, info, unwatchAfter)
+	if err != nil {
+		return result, err
+	}
+	c.Set(info)
+	return result, nil
+}
+
+// Run refreshes the cache every interval until ctx is cancelled, the errors are logged
+func (c *Cache) Run(ctx context.Context, db *sqlx.DB, interval, unwatchAfter time.Duration) {
+	for {
+		result, err := c.Refresh(ctx, db, unwatchAfter)
+		if err != nil {
+			log.Printf("failed to refresh exchange info: %v", err)
+		} else {
+			LogResult(result)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// LogResult logs status changes and unwatched symbols of the import
+func LogResult(result ImportResult) {
+	for _, c := range result.Changes {
+		if c.From == "" {
+			log.Printf("new symbol %s %s", c.Symbol, c.To)
+		} else {
+			log.Printf("symbol %s: %s -> %s", c.Symbol, c.From, c.To)
+		}
+	}
+	if len(result.Unwatched) > 0 {
+		log.Printf("symbols removed from watch_symbols: %v", result.Unwatched)
+	}
+	log.Printf("%d symbols imported, %d status changes", result.Symbols, len(result.Changes))
+}
@@ -0,0 +1,86 @@
+package exchange_info
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"github.com/okharch/binance/request"
+)
+
+const ExchangeInfoURL = "https://api.binance.com/api/v3/exchangeInfo"
+
+// ExchangeInfo is the response of /api/v3/exchangeInfo
+type ExchangeInfo struct {
+	Timezone   string    `json:"timezone"`
+	ServerTime int64     `json:"serverTime"`
+	Symbols    []*Symbol `json:"symbols"`
+}
+
+// Symbol is the trading rules of the symbol
+type Symbol struct {
+	Symbol                         string   `json:"symbol"`
+	Status                         string   `json:"status"` // TRADING, BREAK...
+	BaseAsset                      string   `json:"baseAsset"`
+	BaseAssetPrecision             int      `json:"baseAssetPrecision"`
+	QuoteAsset                     string   `json:"quoteAsset"`
+	QuotePrecision                 int      `json:"quotePrecision"`
+	QuoteAssetPrecision            int      `json:"quoteAssetPrecision"`
+	BaseCommissionPrecision        int      `json:"baseCommissionPrecision"`
+	QuoteCommissionPrecision       int      `json:"quoteCommissionPrecision"`
+	OrderTypes                     []string `json:"orderTypes"`
+	IcebergAllowed                 bool     `json:"icebergAllowed"`
+	OcoAllowed                     bool     `json:"ocoAllowed"`
+	IsSpotTradingAllowed           bool     `json:"isSpotTradingAllowed"`
+	IsMarginTradingAllowed         bool     `json:"isMarginTradingAllowed"`
+	Permissions                    []string `json:"permissions"`
+	DefaultSelfTradePreventionMode string   `json:"defaultSelfTradePreventionMode"`
+	Filters                        Filters  `json:"filters"`
+}
+
+// Parse decodes the response of /api/v3/exchangeInfo
+func Parse(body []byte) (*ExchangeInfo, error) {
+	var info ExchangeInfo
+	if err := json.Unmarshal(body, &info); err != nil {
+		return nil, fmt.Errorf("failed to decode exchange info: %w", err)
+	}
+	return &info, nil
+}
+
+// Fetch requests exchange info of all the symbols through the rate limited client
+func Fetch(ctx context.Context, db *sqlx.DB) (*ExchangeInfo, error) {
+	body, err := request.GetRequest(ctx, ExchangeInfoURL, db)
+	if err != nil {
+		return nil, err
+	}
+	if body == nil {
+		return nil, errors.New("exchange info request was cancelled")
+	}
+	return Parse(body)
+}
+
+// Symbol returns the trading rules of the symbol
+func (info *ExchangeInfo) Symbol(symbol string) (*Symbol, bool) {
+	for _, s := range info.Symbols {
+		if s.Symbol == symbol {
+			return s, true
+		}
+	}
+	return nil, false
+}
+
+// Trading is true if orders of the symbol are accepted
+func (s *Symbol) Trading() bool {
+	return s.Status == "TRADING"
+}
+
+// AllowsOrderType returns true if the symbol accepts orders of the type like LIMIT or STOP_LOSS_LIMIT
+func (s *Symbol) AllowsOrderType(orderType string) bool {
+	for _, t := range s.OrderTypes {
+		if t == orderType {
+			return true
+		}
+	}
+	return false
+}
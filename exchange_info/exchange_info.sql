@@ -13,49 +13,23 @@ CREATE TABLE IF NOT EXISTS binance.exchange_symbols (
     default_self_trade_prevention_mode VARCHAR(20) NOT NULL
 );
 
-CREATE OR REPLACE PROCEDURE binance.import_exchange_symbols() LANGUAGE plpgsql AS $$
-DECLARE
-    url TEXT := 'https://api.binance.com/api/v3/exchangeInfo';
-    response http_response;
-BEGIN
-    -- Make API call to retrieve exchange info
-    response := http_get(url);
-    
-    -- Check if API response status is OK
-    IF response.status != 200 THEN
-        RAISE WARNING 'Binance fetch exchange info at % returned invalid status %, exiting', url, response.status;
-        RETURN;
-    END IF;
-    
-    -- Insert or update symbols and details into exchange_symbols table
-    INSERT INTO binance.exchange_symbols (symbol,
-        status, base_asset, base_asset_precision, quote_asset, quote_precision,
-        quote_asset_precision, base_commission_precision, quote_commission_precision,
-        default_self_trade_prevention_mode)
-    SELECT
-            e->>'symbol',
-            e->>'status',
-            e->>'baseAsset',
-            (e->>'baseAssetPrecision')::SMALLINT,
-            e->>'quoteAsset',
-            (e->>'quotePrecision')::SMALLINT,
-            (e->>'quoteAssetPrecision')::SMALLINT,
-            (e->>'baseCommissionPrecision')::SMALLINT,
-            (e->>'quoteCommissionPrecision')::SMALLINT,
-            e->>'defaultSelfTradePreventionMode'
-    FROM json_array_elements(response.content::json->'symbols') as e
-    ON CONFLICT (symbol) DO UPDATE
-        SET
-           status = EXCLUDED.status,
-           base_asset = EXCLUDED.base_asset,
-           base_asset_precision = EXCLUDED.base_asset_precision,
-           quote_asset = EXCLUDED.quote_asset,
-           quote_precision = EXCLUDED.quote_precision,
-           quote_asset_precision = EXCLUDED.quote_asset_precision,
-           base_commission_precision = EXCLUDED.base_commission_precision,
-           quote_commission_precision = EXCLUDED.quote_commission_precision,
-           default_self_trade_prevention_mode = EXCLUDED.default_self_trade_prevention_mode;
-end
-$$;
+-- the symbols are imported by exchange_info.Import, see README.md
+DROP PROCEDURE IF EXISTS binance.import_exchange_symbols();
 
-call binance.import_exchange_symbols();
\ No newline at end of file
+ALTER TABLE binance.exchange_symbols
+    ADD COLUMN IF NOT EXISTS order_types text[] NOT NULL DEFAULT '{}',
+    ADD COLUMN IF NOT EXISTS permissions text[] NOT NULL DEFAULT '{}',
+    ADD COLUMN IF NOT EXISTS oco_allowed bool NOT NULL DEFAULT false,
+    ADD COLUMN IF NOT EXISTS is_spot_trading_allowed bool NOT NULL DEFAULT false,
+    ADD COLUMN IF NOT EXISTS filters jsonb NOT NULL DEFAULT '[]', -- as they are returned by /api/v3/exchangeInfo
+    ADD COLUMN IF NOT EXISTS status_changed_at timestamptz NOT NULL DEFAULT now(),
+    ADD COLUMN IF NOT EXISTS updated_at timestamptz NOT NULL DEFAULT now();
+
+-- status changes of the symbols: TRADING -> BREAK -> DELISTED (missing from exchange info)
+CREATE TABLE IF NOT EXISTS binance.exchange_symbol_history (
+    symbol_id   int NOT NULL REFERENCES binance.exchange_symbols ON DELETE CASCADE,
+    from_status text NOT NULL, -- empty for new symbols
+    to_status   text NOT NULL,
+    changed_at  timestamptz NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS exchange_symbol_history_symbol ON binance.exchange_symbol_history (symbol_id, changed_at);
@@ -0,0 +1,193 @@
+package exchange_info
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/okharch/binance/orders"
+	"github.com/okharch/binance/orders/stub"
+	"strings"
+	"testing"
+)
+
+const testExchangeInfo = `{"timezone":"UTC","serverTime":1680000000000,"symbols":[{"symbol":"BTCUSDT","status":"TRADING",
+"baseAsset":"BTC","baseAssetPrecision":8,"quoteAsset":"USDT","quotePrecision":8,"quoteAssetPrecision":8,
+"baseCommissionPrecision":8,"quoteCommissionPrecision":8,
+"orderTypes":["LIMIT","LIMIT_MAKER","MARKET","STOP_LOSS_LIMIT","TAKE_PROFIT_LIMIT"],
+"icebergAllowed":true,"ocoAllowed":true,"isSpotTradingAllowed":true,"isMarginTradingAllowed":true,
+"filters":[
+{"filterType":"PRICE_FILTER","minPrice":"0.01000000","maxPrice":"1000000.00000000","tickSize":"0.01000000"},
+{"filterType":"LOT_SIZE","minQty":"0.00001000","maxQty":"9000.00000000","stepSize":"0.00001000"},
+{"filterType":"ICEBERG_PARTS","limit":10},
+{"filterType":"MARKET_LOT_SIZE","minQty":"0.00000000","maxQty":"100.00000000","stepSize":"0.00000000"},
+{"filterType":"TRAILING_DELTA","minTrailingAboveDelta":10,"maxTrailingAboveDelta":2000},
+{"filterType":"PERCENT_PRICE_BY_SIDE","bidMultiplierUp":"5","bidMultiplierDown":"0.2","askMultiplierUp":"5","askMultiplierDown":"0.2","avgPriceMins":5},
+{"filterType":"NOTIONAL","minNotional":"5.00000000","applyMinToMarket":true,"maxNotional":"9000000.00000000","applyMaxToMarket":false,"avgPriceMins":5},
+{"filterType":"MAX_NUM_ORDERS","maxNumOrders":200},
+{"filterType":"MAX_NUM_ALGO_ORDERS","maxNumAlgoOrders":5}],
+"permissions":["SPOT","MARGIN"],"defaultSelfTradePreventionMode":"NONE"},
+{"symbol":"OLDBTC","status":"BREAK","baseAsset":"OLD","quoteAsset":"BTC","orderTypes":["LIMIT"],"filters":[]}]}`
+
+func testSymbol(t *testing.T) *Symbol {
+	t.Helper()
+	info, err := Parse([]byte(testExchangeInfo))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, ok := info.Symbol("BTCUSDT")
+	if !ok {
+		t.Fatal("BTCUSDT is not found")
+	}
+	return s
+}
+
+func TestParseFilters(t *testing.T) {
+	s := testSymbol(t)
+	f := s.Filters
+	if f.Price == nil || f.Price.TickSize != 0.01 || f.LotSize == nil || f.LotSize.StepSize != 0.00001 ||
+		f.MarketLotSize == nil || f.MarketLotSize.MaxQty != 100 || f.Notional == nil || !f.Notional.ApplyMinToMarket ||
+		f.PercentPriceBySide == nil || f.PercentPriceBySide.AskMultiplierDown != 0.2 || f.MinNotional != nil ||
+		f.MaxNumOrders != 200 || f.MaxNumAlgoOrders != 5 || len(f.Raw) != 9 {
+		t.Errorf("unexpected filters %+v", f)
+	}
+	if s.MinNotional() != 5 || !s.AllowsOrderType("STOP_LOSS_LIMIT") || s.AllowsOrderType("STOP_LOSS") {
+		t.Errorf("unexpected rules of %s", s.Symbol)
+	}
+}
+
+func TestRounding(t *testing.T) {
+	s := testSymbol(t)
+	for _, c := range []struct{ v, expected float64 }{
+		{s.RoundPrice(27123.456), 27123.46},
+		{s.RoundPrice(27123.454), 27123.45},
+		{s.RoundQuantity(0.123456789, false), 0.12345},
+		{s.RoundQuantity(0.3, true), 0.3}, // market lot step is 0, the step of lot size is used
+		{FloorToStep(0.7, 0.1), 0.7},
+		{FloorToStep(12345, 100), 12300},
+		{RoundToStep(0.15, 0.1), 0.2},
+	} {
+		if c.v != c.expected {
+			t.Errorf("expected %v, got %v", c.expected, c.v)
+		}
+	}
+}
+
+func TestValidateOrder(t *testing.T) {
+	s := testSymbol(t)
+	limit := func(side orders.Side, quantity, price float64) orders.OrderRequest {
+		return orders.OrderRequest{Symbol: "BTCUSDT", Side: side, Type: orders.Limit, Quantity: quantity, Price: price}
+	}
+	for _, c := range []struct {
+		r          orders.OrderRequest
+		avgPrice   float64
+		openOrders int
+		filter     string
+	}{
+		{limit(orders.Buy, 0.001, 27000), 27000, 0, ""},
+		{limit(orders.Buy, 0.001, 27000.005), 27000, 0, "PRICE_FILTER"},
+		{limit(orders.Buy, 0.0000015, 27000), 27000, 0, "LOT_SIZE"},
+		{limit(orders.Buy, 0.000015, 27000), 27000, 0, "LOT_SIZE"},
+		{limit(orders.Buy, 0.0001, 27000), 27000, 0, "NOTIONAL"},
+		{limit(orders.Buy, 0.001, 140000), 27000, 0, "PERCENT_PRICE_BY_SIDE"},
+		{limit(orders.Sell, 0.001, 5000), 27000, 0, "PERCENT_PRICE_BY_SIDE"},
+		{limit(orders.Sell, 0.001, 5000), 0, 0, ""}, // the average price is not known
+		{limit(orders.Buy, 0.001, 27000), 27000, 200, "MAX_NUM_ORDERS"},
+		{orders.OrderRequest{Symbol: "BTCUSDT", Side: orders.Buy, Type: orders.Market, Quantity: 101}, 27000, 0, "MARKET_LOT_SIZE"},
+		{orders.OrderRequest{Symbol: "BTCUSDT", Side: orders.Buy, Type: orders.Market, QuoteOrderQty: 4}, 27000, 0, "NOTIONAL"},
+		{orders.OrderRequest{Symbol: "BTCUSDT", Side: orders.Buy, Type: orders.Market, Quantity: 0.0001}, 27000, 0, "NOTIONAL"},
+		{orders.OrderRequest{Symbol: "BTCUSDT", Side: orders.Sell, Type: orders.StopLossLimit, Quantity: 0.001,
+			Price: 26000, StopPrice: 26100.001}, 27000, 0, "PRICE_FILTER"},
+	} {
+		err := s.ValidateOrder(c.r, c.avgPrice, c.openOrders)
+		var validationErr *ValidationError
+		switch {
+		case c.filter == "" && err != nil:
+			t.Errorf("%+v: unexpected error %v", c.r, err)
+		case c.filter != "" && (!errors.As(err, &validationErr) || validationErr.Filter != c.filter):
+			t.Errorf("%+v: expected %s error, got %v", c.r, c.filter, err)
+		}
+	}
+	err := s.ValidateOrder(limit(orders.Buy, 0.123456, 27000), 0, 0)
+	if err == nil || !strings.Contains(err.Error(), "use 0.12345") {
+		t.Errorf("expected the error to suggest the rounded quantity, got %v", err)
+	}
+}
+
+func TestValidatorStopsOrdersBeforeSending(t *testing.T) {
+	info, err := Parse([]byte(testExchangeInfo))
+	if err != nil {
+		t.Fatal(err)
+	}
+	exchange := stub.NewExchange("key", "secret")
+	defer exchange.Close()
+	client := orders.NewClient("key", "secret")
+	client.BaseURL = exchange.URL
+	m := orders.NewManager(client, nil, "test")
+	m.SetValidator(Validator{Lookup: info.Symbol})
+	ctx := context.Background()
+	for _, r := range []orders.OrderRequest{
+		{Symbol: "BTCUSDT", Side: orders.Buy, Type: orders.Limit, Quantity: 0.0001, Price: 27000},
+		{Symbol: "OLDBTC", Side: orders.Buy, Type: orders.Limit, Quantity: 1, Price: 0.001},
+		{Symbol: "ETHUSDT", Side: orders.Buy, Type: orders.Limit, Quantity: 1, Price: 1800},
+	} {
+		if _, err := m.Place(ctx, r); err == nil {
+			t.Errorf("expected %+v to be invalid", r)
+		}
+	}
+	_, err = m.PlaceOCO(ctx, orders.OCORequest{Symbol: "BTCUSDT", Side: orders.Sell, Quantity: 0.001, Price: 28000,
+		StopPrice: 26000, StopLimitPrice: 25999.999})
+	if err == nil {
+		t.Error("expected OCO with invalid stop limit price to be invalid")
+	}
+	if n := exchange.Requests(); n != 0 {
+		t.Errorf("expected no requests to the exchange, got %d", n)
+	}
+}
+
+func TestStatusChanges(t *testing.T) {
+	info, err := Parse([]byte(testExchangeInfo))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored := map[string]string{"BTCUSDT": "TRADING", "OLDBTC": "TRADING", "GONEBTC": "BREAK", "DEADBTC": Delisted}
+	changes := statusChanges(stored, info)
+	expected := []StatusChange{{"GONEBTC", "BREAK", Delisted}, {"OLDBTC", "TRADING", "BREAK"}}
+	if len(changes) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, changes)
+	}
+	for i := range expected {
+		if changes[i] != expected[i] {
+			t.Errorf("expected %v, got %v", expected[i], changes[i])
+		}
+	}
+	if changes = statusChanges(nil, info); len(changes) != 2 || changes[0].From != "" {
+		t.Errorf("expected new symbols, got %v", changes)
+	}
+}
+
+func TestCache(t *testing.T) {
+	info, err := Parse([]byte(testExchangeInfo))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewCache()
+	if _, ok := c.Symbol("BTCUSDT"); ok {
+		t.Error("expected empty cache")
+	}
+	c.Set(info)
+	if s, ok := c.Symbol("BTCUSDT"); !ok || s.Filters.Price == nil {
+		t.Errorf("unexpected symbol %+v", s)
+	}
+	if all, trading := c.Symbols(false), c.Symbols(true); len(all) != 2 || len(trading) != 1 || c.ServerTime() != info.ServerTime {
+		t.Errorf("unexpected symbols %v %v", all, trading)
+	}
+	// filters are stored as they were received
+	b, err := json.Marshal(info.Symbols[0].Filters)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var filters Filters
+	if err := json.Unmarshal(b, &filters); err != nil || len(filters.Raw) != 9 || filters.Notional.MaxNotional != 9000000 {
+		t.Errorf("unexpected filters after round trip %s: %v", b, err)
+	}
+}
@@ -0,0 +1,166 @@
+package exchange_info
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+/*
+Filters of the symbol define valid prices and quantities of its orders.
+numbers are sent by Binance as strings, zero values mean the check is disabled.
+the filters which are not known are kept in Raw only, so they are stored along with the known ones.
+*/
+
+type PriceFilter struct {
+	MinPrice float64 `json:"minPrice,string"`
+	MaxPrice float64 `json:"maxPrice,string"`
+	TickSize float64 `json:"tickSize,string"`
+}
+
+// LotSizeFilter is LOT_SIZE of all the orders and MARKET_LOT_SIZE of market orders
+type LotSizeFilter struct {
+	MinQty   float64 `json:"minQty,string"`
+	MaxQty   float64 `json:"maxQty,string"`
+	StepSize float64 `json:"stepSize,string"`
+}
+
+// MinNotionalFilter is the minimal price * quantity of the order, market orders use average price
+type MinNotionalFilter struct {
+	MinNotional   float64 `json:"minNotional,string"`
+	ApplyToMarket bool    `json:"applyToMarket"`
+	AvgPriceMins  int     `json:"avgPriceMins"`
+}
+
+// NotionalFilter is the range of price * quantity of the order
+type NotionalFilter struct {
+	MinNotional      float64 `json:"minNotional,string"`
+	ApplyMinToMarket bool    `json:"applyMinToMarket"`
+	MaxNotional      float64 `json:"maxNotional,string"`
+	ApplyMaxToMarket bool    `json:"applyMaxToMarket"`
+	AvgPriceMins     int     `json:"avgPriceMins"`
+}
+
+// PercentPriceBySideFilter is the range of the order price relative to the average price
+type PercentPriceBySideFilter struct {
+	BidMultiplierUp   float64 `json:"bidMultiplierUp,string"`
+	BidMultiplierDown float64 `json:"bidMultiplierDown,string"`
+	AskMultiplierUp   float64 `json:"askMultiplierUp,string"`
+	AskMultiplierDown float64 `json:"askMultiplierDown,string"`
+	AvgPriceMins      int     `json:"avgPriceMins"`
+}
+
+type Filters struct {
+	Price              *PriceFilter
+	LotSize            *LotSizeFilter
+	MarketLotSize      *LotSizeFilter
+	MinNotional        *MinNotionalFilter
+	Notional           *NotionalFilter
+	PercentPriceBySide *PercentPriceBySideFilter
+	MaxNumOrders       int
+	MaxNumAlgoOrders   int
+	Raw                []json.RawMessage
+}
+
+func (f *Filters) UnmarshalJSON(data []byte) error {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*f = Filters{Raw: raw}
+	for _, r := range raw {
+		var head struct {
+			FilterType       string `json:"filterType"`
+			MaxNumOrders     int    `json:"maxNumOrders"`
+			MaxNumAlgoOrders int    `json:"maxNumAlgoOrders"`
+		}
+		if err := json.Unmarshal(r, &head); err != nil {
+			return err
+		}
+		var target any
+		switch head.FilterType {
+		case "PRICE_FILTER":
+			f.Price = &PriceFilter{}
+			target = f.Price
+		case "LOT_SIZE":
+			f.LotSize = &LotSizeFilter{}
+			target = f.LotSize
+		case "MARKET_LOT_SIZE":
+			f.MarketLotSize = &LotSizeFilter{}
+			target = f.MarketLotSize
+		case "MIN_NOTIONAL":
+			f.MinNotional = &MinNotionalFilter{}
+			target = f.MinNotional
+		case "NOTIONAL":
+			f.Notional = &NotionalFilter{}
+			target = f.Notional
+		case "PERCENT_PRICE_BY_SIDE":
+			f.PercentPriceBySide = &PercentPriceBySideFilter{}
+			target = f.PercentPriceBySide
+		case "MAX_NUM_ORDERS":
+			f.MaxNumOrders = head.MaxNumOrders
+		case "MAX_NUM_ALGO_ORDERS":
+			f.MaxNumAlgoOrders = head.MaxNumAlgoOrders
+		}
+		if target != nil {
+			if err := json.Unmarshal(r, target); err != nil {
+				return fmt.Errorf("invalid %s: %w", head.FilterType, err)
+			}
+		}
+	}
+	return nil
+}
+
+// MarshalJSON returns the filters as they were received
+func (f Filters) MarshalJSON() ([]byte, error) {
+	if f.Raw == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(f.Raw)
+}
+
+// decimals returns the number of decimal places of the step like 0.001
+func decimals(step float64) int {
+	s := strconv.FormatFloat(step, 'f', -1, 64)
+	for i := range s {
+		if s[i] == '.' {
+			return len(s) - i - 1
+		}
+	}
+	return 0
+}
+
+// roundTo rounds v to decimal places removing float noise like 0.30000000000000004
+func roundTo(v float64, decimals int) float64 {
+	r, _ := strconv.ParseFloat(strconv.FormatFloat(v, 'f', decimals, 64), 64)
+	return r
+}
+
+// epsilon is the tolerance of division by the step: 0.7 / 0.1 is 6.999999999999999 in float64
+const epsilon = 1e-9
+
+// FloorToStep rounds v down to the multiple of step
+func FloorToStep(v, step float64) float64 {
+	if step <= 0 {
+		return v
+	}
+	return roundTo(math.Floor(v/step+epsilon)*step, decimals(step))
+}
+
+// RoundToStep rounds v to the nearest multiple of step
+func RoundToStep(v, step float64) float64 {
+	if step <= 0 {
+		return v
+	}
+	return roundTo(math.Floor(v/step+0.5+epsilon)*step, decimals(step))
+}
+
+// onStep returns true if v - min is a multiple of step
+func onStep(v, min, step float64) bool {
+	if step <= 0 {
+		return true
+	}
+	n := (v - min) / step
+	return n-math.Floor(n+epsilon) < epsilon*(1+n)
+}
@@ -0,0 +1,113 @@
+package exchange_info
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"sort"
+	"time"
+)
+
+// Delisted is the status of the symbols which are missing from exchange info
+const Delisted = "DELISTED"
+
+type StatusChange struct {
+	Symbol string
+	From   string // empty for new symbols
+	To     string
+}
+
+type ImportResult struct {
+	Symbols   int
+	Changes   []StatusChange
+	Unwatched []string // symbols removed from binance.watch_symbols
+}
+
+// statusChanges compares statuses of the stored symbols with exchange info,
+// the stored symbols missing from it are delisted
+func statusChanges(stored map[string]string, info *ExchangeInfo) (changes []StatusChange) {
+	present := make(map[string]bool, len(info.Symbols))
+	for _, s := range info.Symbols {
+		present[s.Symbol] = true
+		if status, ok := stored[s.Symbol]; !ok || status != s.Status {
+			changes = append(changes, StatusChange{Symbol: s.Symbol, From: status, To: s.Status})
+		}
+	}
+	for symbol, status := range stored {
+		if !present[symbol] && status != Delisted {
+			changes = append(changes, StatusChange{Symbol: symbol, From: status, To: Delisted})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Symbol < changes[j].Symbol })
+	return changes
+}
+
+// Import upserts symbols, their filters and permissions into binance.exchange_symbols in a single transaction,
+// records status changes into binance.exchange_symbol_history and removes from binance.watch_symbols
+// delisted symbols and those which have not been trading for longer than unwatchAfter
+func Import(ctx context.Context, db *sqlx.DB, info *ExchangeInfo, unwatchAfter time.Duration) (result ImportResult, err error) {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return result, err
+	}
+	defer tx.Rollback()
+	var rows []struct {
+		Symbol string `db:"symbol"`
+		Status string `db:"status"`
+	}
+	if err = tx.SelectContext(ctx, &rows, "SELECT symbol, status FROM binance.exchange_symbols"); err != nil {
+		return result, fmt.Errorf("failed to load symbols: %w", err)
+	}
+	stored := make(map[string]string, len(rows))
+	for _, r := range rows {
+		stored[r.Symbol] = r.Status
+	}
+	for _, s := range info.Symbols {
+		filters, err := json.Marshal(s.Filters)
+		if err != nil {
+			return result, err
+		}
+		if _, err = tx.ExecContext(ctx, `INSERT INTO binance.exchange_symbols (symbol, status, base_asset,
+			base_asset_precision, quote_asset, quote_precision, quote_asset_precision, base_commission_precision,
+			quote_commission_precision, default_self_trade_prevention_mode, order_types, permissions, oco_allowed,
+			is_spot_trading_allowed, filters)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+		ON CONFLICT (symbol) DO UPDATE SET status = EXCLUDED.status, base_asset = EXCLUDED.base_asset,
+			base_asset_precision = EXCLUDED.base_asset_precision, quote_asset = EXCLUDED.quote_asset,
+			quote_precision = EXCLUDED.quote_precision, quote_asset_precision = EXCLUDED.quote_asset_precision,
+			base_commission_precision = EXCLUDED.base_commission_precision,
+			quote_commission_precision = EXCLUDED.quote_commission_precision,
+			default_self_trade_prevention_mode = EXCLUDED.default_self_trade_prevention_mode,
+			order_types = EXCLUDED.order_types, permissions = EXCLUDED.permissions, oco_allowed = EXCLUDED.oco_allowed,
+			is_spot_trading_allowed = EXCLUDED.is_spot_trading_allowed, filters = EXCLUDED.filters, updated_at = now()`,
+			s.Symbol, s.Status, s.BaseAsset, s.BaseAssetPrecision, s.QuoteAsset, s.QuotePrecision,
+			s.QuoteAssetPrecision, s.BaseCommissionPrecision, s.QuoteCommissionPrecision,
+			s.DefaultSelfTradePreventionMode, pq.StringArray(s.OrderTypes), pq.StringArray(s.Permissions), s.OcoAllowed,
+			s.IsSpotTradingAllowed, string(filters)); err != nil {
+			return result, fmt.Errorf("failed to upsert symbol %s: %w", s.Symbol, err)
+		}
+	}
+	result.Symbols = len(info.Symbols)
+	result.Changes = statusChanges(stored, info)
+	for _, c := range result.Changes {
+		if _, err = tx.ExecContext(ctx, `WITH s AS (
+			UPDATE binance.exchange_symbols SET status = $3, status_changed_at = now()
+			WHERE symbol = $1
+			RETURNING symbol_id
+		)
+		INSERT INTO binance.exchange_symbol_history (symbol_id, from_status, to_status)
+		SELECT symbol_id, $2, $3 FROM s`, c.Symbol, c.From, c.To); err != nil {
+			return result, fmt.Errorf("failed to record status of %s: %w", c.Symbol, err)
+		}
+	}
+	if err = tx.SelectContext(ctx, &result.Unwatched, `DELETE FROM binance.watch_symbols w
+		USING binance.exchange_symbols e
+		WHERE e.symbol = w.symbol AND e.status <> 'TRADING'
+			AND (e.status = $1 OR e.status_changed_at < now() - $2::interval)
+		RETURNING w.symbol`, Delisted, fmt.Sprintf("%d seconds", int64(unwatchAfter.Seconds()))); err != nil {
+		return result, fmt.Errorf("failed to unwatch symbols: %w", err)
+	}
+	return result, tx.Commit()
+}
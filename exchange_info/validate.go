@@ -0,0 +1,232 @@
+package exchange_info
+
+import (
+	"fmt"
+	"github.com/okharch/binance/orders"
+	"strconv"
+)
+
+// ValidationError describes why the order does not pass the filter of the symbol
+type ValidationError struct {
+	Symbol string
+	Filter string
+	Msg    string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s %s: %s", e.Symbol, e.Filter, e.Msg)
+}
+
+func (s *Symbol) invalid(filter, format string, args ...any) error {
+	return &ValidationError{Symbol: s.Symbol, Filter: filter, Msg: fmt.Sprintf(format, args...)}
+}
+
+func format(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// RoundPrice rounds the price to the nearest tick
+func (s *Symbol) RoundPrice(price float64) float64 {
+	if s.Filters.Price == nil {
+		return price
+	}
+	return RoundToStep(price, s.Filters.Price.TickSize)
+}
+
+// RoundQuantity rounds the quantity down to the lot step, the step of market lot is used for market orders if it is set
+func (s *Symbol) RoundQuantity(quantity float64, market bool) float64 {
+	step := 0.0
+	if s.Filters.LotSize != nil {
+		step = s.Filters.LotSize.StepSize
+	}
+	if market && s.Filters.MarketLotSize != nil && s.Filters.MarketLotSize.StepSize > 0 {
+		step = s.Filters.MarketLotSize.StepSize
+	}
+	return FloorToStep(quantity, step)
+}
+
+// MinNotional returns the minimal price * quantity of limit orders
+func (s *Symbol) MinNotional() float64 {
+	if s.Filters.Notional != nil {
+		return s.Filters.Notional.MinNotional
+	}
+	if s.Filters.MinNotional != nil {
+		return s.Filters.MinNotional.MinNotional
+	}
+	return 0
+}
+
+// ValidateOrder checks the order against the status, order types and filters of the symbol.
+// avgPrice is the current average price, it is used for market orders and PERCENT_PRICE_BY_SIDE, 0 skips the checks.
+// openOrders is the number of open orders of the symbol
+func (s *Symbol) ValidateOrder(r orders.OrderRequest, avgPrice float64, openOrders int) error {
+	if !s.Trading() {
+		return s.invalid("STATUS", "symbol is %s", s.Status)
+	}
+	if !s.AllowsOrderType(string(r.Type)) {
+		return s.invalid("ORDER_TYPES", "%s orders are not allowed", r.Type)
+	}
+	market := r.Type == orders.Market
+	if !market {
+		if err := s.validatePrice("price", r.Price); err != nil {
+			return err
+		}
+		if err := s.validatePercentPrice(r.Side, r.Price, avgPrice); err != nil {
+			return err
+		}
+	}
+	if r.Type == orders.StopLossLimit || r.Type == orders.TakeProfitLimit {
+		if err := s.validatePrice("stop price", r.StopPrice); err != nil {
+			return err
+		}
+	}
+	if r.Quantity > 0 {
+		if err := s.validateLot("LOT_SIZE", s.Filters.LotSize, r.Quantity); err != nil {
+			return err
+		}
+		if market {
+			if err := s.validateLot("MARKET_LOT_SIZE", s.Filters.MarketLotSize, r.Quantity); err != nil {
+				return err
+			}
+		}
+	}
+	notional := r.Price * r.Quantity
+	if market {
+		notional = avgPrice * r.Quantity
+		if r.QuoteOrderQty > 0 {
+			notional = r.QuoteOrderQty
+		}
+	}
+	if err := s.validateNotional(notional, market, market && notional == 0); err != nil {
+		return err
+	}
+	if max := s.Filters.MaxNumOrders; max > 0 && openOrders >= max {
+		return s.invalid("MAX_NUM_ORDERS", "%d open orders, the limit is %d", openOrders, max)
+	}
+	return nil
+}
+
+// ValidateOCO checks both orders of OCO, they count as 2 open orders
+func (s *Symbol) ValidateOCO(r orders.OCORequest, avgPrice float64, openOrders int) error {
+	if !s.OcoAllowed {
+		return s.invalid("OCO", "OCO orders are not allowed")
+	}
+	limit := orders.OrderRequest{Symbol: r.Symbol, Side: r.Side, Type: orders.LimitMaker, Quantity: r.Quantity, Price: r.Price}
+	if err := s.ValidateOrder(limit, avgPrice, openOrders+1); err != nil {
+		return err
+	}
+	stop := orders.OrderRequest{Symbol: r.Symbol, Side: r.Side, Type: orders.StopLossLimit, Quantity: r.Quantity,
+		Price: r.StopLimitPrice, StopPrice: r.StopPrice}
+	return s.ValidateOrder(stop, avgPrice, openOrders+1)
+}
+
+func (s *Symbol) validatePrice(name string, price float64) error {
+	f := s.Filters.Price
+	if price <= 0 {
+		return s.invalid("PRICE_FILTER", "%s is not set", name)
+	}
+	if f == nil {
+		return nil
+	}
+	if f.MinPrice > 0 && price < f.MinPrice {
+		return s.invalid("PRICE_FILTER", "%s %s is less than min %s", name, format(price), format(f.MinPrice))
+	}
+	if f.MaxPrice > 0 && price > f.MaxPrice {
+		return s.invalid("PRICE_FILTER", "%s %s is greater than max %s", name, format(price), format(f.MaxPrice))
+	}
+	if !onStep(price, f.MinPrice, f.TickSize) {
+		return s.invalid("PRICE_FILTER", "%s %s is not a multiple of tick size %s, use %s", name, format(price),
+			format(f.TickSize), format(s.RoundPrice(price)))
+	}
+	return nil
+}
+
+func (s *Symbol) validateLot(filter string, f *LotSizeFilter, quantity float64) error {
+	if f == nil {
+		return nil
+	}
+	if f.MinQty > 0 && quantity < f.MinQty {
+		return s.invalid(filter, "quantity %s is less than min %s", format(quantity), format(f.MinQty))
+	}
+	if f.MaxQty > 0 && quantity > f.MaxQty {
+		return s.invalid(filter, "quantity %s is greater than max %s", format(quantity), format(f.MaxQty))
+	}
+	if !onStep(quantity, f.MinQty, f.StepSize) {
+		return s.invalid(filter, "quantity %s is not a multiple of step size %s, use %s", format(quantity),
+			format(f.StepSize), format(FloorToStep(quantity, f.StepSize)))
+	}
+	return nil
+}
+
+// validateNotional checks price * quantity, unknown is true if the notional of the market order is not known
+func (s *Symbol) validateNotional(notional float64, market, unknown bool) error {
+	if unknown {
+		return nil
+	}
+	if f := s.Filters.MinNotional; f != nil && (!market || f.ApplyToMarket) && notional < f.MinNotional {
+		return s.invalid("MIN_NOTIONAL", "notional %s is less than min %s", format(notional), format(f.MinNotional))
+	}
+	if f := s.Filters.Notional; f != nil {
+		if (!market || f.ApplyMinToMarket) && notional < f.MinNotional {
+			return s.invalid("NOTIONAL", "notional %s is less than min %s", format(notional), format(f.MinNotional))
+		}
+		if (!market || f.ApplyMaxToMarket) && f.MaxNotional > 0 && notional > f.MaxNotional {
+			return s.invalid("NOTIONAL", "notional %s is greater than max %s", format(notional), format(f.MaxNotional))
+		}
+	}
+	return nil
+}
+
+func (s *Symbol) validatePercentPrice(side orders.Side, price, avgPrice float64) error {
+	f := s.Filters.PercentPriceBySide
+	if f == nil || avgPrice <= 0 {
+		return nil
+	}
+	up, down := f.BidMultiplierUp, f.BidMultiplierDown
+	if side == orders.Sell {
+		up, down = f.AskMultiplierUp, f.AskMultiplierDown
+	}
+	if up > 0 && price > avgPrice*up {
+		return s.invalid("PERCENT_PRICE_BY_SIDE", "%s price %s is greater than %s (average price %s * %s)", side,
+			format(price), format(avgPrice*up), format(avgPrice), format(up))
+	}
+	if price < avgPrice*down {
+		return s.invalid("PERCENT_PRICE_BY_SIDE", "%s price %s is less than %s (average price %s * %s)", side,
+			format(price), format(avgPrice*down), format(avgPrice), format(down))
+	}
+	return nil
+}
+
+// Validator is orders.Validator of the symbols
+type Validator struct {
+	Lookup   func(symbol string) (*Symbol, bool)
+	AvgPrice func(symbol string) float64 // the checks by the average price are skipped if it is nil
+}
+
+func (v Validator) symbol(name string) (*Symbol, float64, error) {
+	s, ok := v.Lookup(name)
+	if !ok {
+		return nil, 0, &ValidationError{Symbol: name, Filter: "SYMBOL", Msg: "unknown symbol"}
+	}
+	var avgPrice float64
+	if v.AvgPrice != nil {
+		avgPrice = v.AvgPrice(name)
+	}
+	return s, avgPrice, nil
+}
+
+func (v Validator) ValidateOrder(r orders.OrderRequest, openOrders int) error {
+	s, avgPrice, err := v.symbol(r.Symbol)
+	if err != nil {
+		return err
+	}
+	return s.ValidateOrder(r, avgPrice, openOrders)
+}
+
+func (v Validator) ValidateOCO(r orders.OCORequest, openOrders int) error {
+	s, avgPrice, err := v.symbol(r.Symbol)
+	if err != nil {
+		return err
+	}
+	return s.ValidateOCO(r, avgPrice, openOrders)
+}
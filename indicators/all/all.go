@@ -0,0 +1,12 @@
+// Package all imports all indicator packages, so they register themselves in indicators registry.
+// new indicator packages have to be added here
+package all
+
+import (
+	_ "github.com/okharch/binance/indicators/fibonacci"
+	_ "github.com/okharch/binance/indicators/ichimoku"
+	_ "github.com/okharch/binance/indicators/levels"
+	_ "github.com/okharch/binance/indicators/mac"
+	_ "github.com/okharch/binance/indicators/stochastic"
+	_ "github.com/okharch/binance/indicators/volume"
+)
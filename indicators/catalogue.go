@@ -0,0 +1,183 @@
+package indicators
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+/*
+Indicator catalogue assigns stable indicator_id to each combination of indicator and its params.
+params are stored as json object {"paramName": value, ...}, so signals can be queried by indicator settings:
+
+	select s.* from binance.indicator_signal s join binance.indicator_catalogue c using (indicator_id)
+	where c.indicator = 'RSI' and (c.params->>'period')::int = 14
+*/
+
+type CatalogueEntry struct {
+	IndicatorId   int32         `db:"indicator_id"`
+	IndicatorType IndicatorType `db:"indicator_type"`
+	Indicator     string        `db:"indicator"`
+	Params        string        `db:"params"` // json object of param names and values
+}
+
+type Catalogue struct {
+	db      *sqlx.DB
+	mu      sync.RWMutex
+	ids     map[string]int32 // by catalogueKey
+	entries map[int32]CatalogueEntry
+}
+
+func NewCatalogue(db *sqlx.DB) *Catalogue {
+	return &Catalogue{db: db, ids: map[string]int32{}, entries: map[int32]CatalogueEntry{}}
+}
+
+func catalogueKey(indicator string, params []int) string {
+	var sb strings.Builder
+	sb.WriteString(indicator)
+	for _, p := range params {
+		sb.WriteByte(':')
+		sb.WriteString(strconv.Itoa(p))
+	}
+	return sb.String()
+}
+
+// ParamsJSON returns params of the indicator as json object
+func (ind Indicator) ParamsJSON(params []int) (string, error) {
+	if len(params) != len(ind.Params) {
+		return "", fmt.Errorf("indicator %s expects %d params, got %d", ind.Name, len(ind.Params), len(params))
+	}
+	m := make(map[string]int, len(params))
+	for i, p := range ind.Params {
+		m[p.Name] = params[i]
+	}
+	b, err := json.Marshal(m)
+	return string(b), err
+}
+
+// DecodeParams returns params values of the entry in the order of the indicator params
+func (ind Indicator) DecodeParams(paramsJSON string) ([]int, error) {
+	var m map[string]int
+	if err := json.Unmarshal([]byte(paramsJSON), &m); err != nil {
+		return nil, fmt.Errorf("failed to decode %s params %s: %w", ind.Name, paramsJSON, err)
+	}
+	params := make([]int, len(ind.Params))
+	for i, p := range ind.Params {
+		v, ok := m[p.Name]
+		if !ok {
+			return nil, fmt.Errorf("missing %s param %s in %s", ind.Name, p.Name, paramsJSON)
+		}
+		params[i] = v
+	}
+	return params, nil
+}
+
+func (c *Catalogue) add(entry CatalogueEntry) error {
+	ind, ok := Lookup(entry.Indicator)
+	if !ok {
+		// indicator is not registered in this build, keep the entry to be able to decode it
+		c.entries[entry.IndicatorId] = entry
+		return nil
+	}
+	params, err := ind.DecodeParams(entry.Params)
+	if err != nil {
+		return err
+	}
+	c.ids[catalogueKey(entry.Indicator, params)] = entry.IndicatorId
+	c.entries[entry.IndicatorId] = entry
+	return nil
+}
+
+// Load loads all catalogue entries from the database
+func (c *Catalogue) Load(ctx context.Context) error {
+	var entries []CatalogueEntry
+	err := c.db.SelectContext(ctx, &entries,
+		`SELECT indicator_id, indicator_type, indicator, params FROM binance.indicator_catalogue`)
+	if err != nil {
+		return fmt.Errorf("failed to load indicator catalogue: %w", err)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, entry := range entries {
+		if err := c.add(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Register assigns ids to all the combinations of params of the indicators grids which are missing in the catalogue,
+// they are inserted into binance.indicator_catalogue unless the catalogue is created without database
+func (c *Catalogue) Register(ctx context.Context, indicators []Indicator) error {
+	var missing []CatalogueEntry
+	c.mu.RLock()
+	for _, ind := range indicators {
+		for _, params := range ind.Grid() {
+			if _, ok := c.ids[catalogueKey(ind.Name, params)]; ok {
+				continue
+			}
+			paramsJSON, err := ind.ParamsJSON(params)
+			if err != nil {
+				c.mu.RUnlock()
+				return err
+			}
+			missing = append(missing, CatalogueEntry{IndicatorType: ind.Type, Indicator: ind.Name, Params: paramsJSON})
+		}
+	}
+	c.mu.RUnlock()
+	if len(missing) == 0 {
+		return nil
+	}
+	if c.db == nil {
+		// the catalogue without database assigns ids in memory, e.g. for tests
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		for _, entry := range missing {
+			entry.IndicatorId = int32(len(c.entries) + 1)
+			if err := c.add(entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	// insert by chunks not to exceed postgres limit on the number of query parameters
+	const chunkSize = 5000
+	for len(missing) > 0 {
+		chunk := missing
+		if len(chunk) > chunkSize {
+			chunk = chunk[:chunkSize]
+		}
+		missing = missing[len(chunk):]
+		query, args, err := sqlx.Named(`
+			INSERT INTO binance.indicator_catalogue (indicator_type, indicator, params)
+			VALUES (:indicator_type, :indicator, :params)
+			ON CONFLICT (indicator, params) DO NOTHING`, chunk)
+		if err != nil {
+			return err
+		}
+		if _, err := c.db.ExecContext(ctx, c.db.Rebind(query), args...); err != nil {
+			return fmt.Errorf("failed to register indicators in catalogue: %w", err)
+		}
+	}
+	return c.Load(ctx)
+}
+
+// Id returns indicator_id of the indicator with params, ok is false if it is not in the catalogue
+func (c *Catalogue) Id(indicator string, params []int) (id int32, ok bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	id, ok = c.ids[catalogueKey(indicator, params)]
+	return
+}
+
+// Entry returns catalogue entry by indicator_id
+func (c *Catalogue) Entry(id int32) (entry CatalogueEntry, ok bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	entry, ok = c.entries[id]
+	return
+}
@@ -0,0 +1,261 @@
+package composite
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/okharch/binance/indicators"
+	"github.com/okharch/binance/klines"
+	"os"
+	"time"
+)
+
+/*
+Composite signal rules combine conditions on several indicators and periods, e.g.
+"MAC buy on 15m while RSI(1h) < 40 and volume > 2x avg":
+
+	{
+	  "name": "mac15m_rsi1h_volume",
+	  "period": "15m",
+	  "side": "BUY",
+	  "condition": {"all": [
+	    {"indicator": "MAC", "params": [20, 10], "side": "BUY"},
+	    {"value": "rsi", "params": [14], "period": "1h", "op": "<", "threshold": 40},
+	    {"value": "volume_ratio", "params": [20], "op": ">", "threshold": 2}
+	  ]}
+	}
+
+Condition kinds:
+- all/any: all (any) of nested conditions are true.
+- indicator: the registered indicator with params fired signal of the side within the last Within klines (default 1).
+- value: the value function (see values.go) compared with threshold.
+Period of a condition defaults to the period of the rule, higher period is used for confirmation.
+Each rule is registered as indicator of IndicatorTypeComposite, so its signals are generated and stored
+like the signals of other indicators.
+*/
+
+type Condition struct {
+	All []Condition `json:"all,omitempty"`
+	Any []Condition `json:"any,omitempty"`
+
+	Period string `json:"period,omitempty"`
+	Params []int  `json:"params,omitempty"`
+
+	Indicator string `json:"indicator,omitempty"`
+	Side      string `json:"side,omitempty"`
+	Within    int    `json:"within,omitempty"`
+
+	Value     string  `json:"value,omitempty"`
+	Op        string  `json:"op,omitempty"`
+	Threshold float64 `json:"threshold,omitempty"`
+}
+
+type Rule struct {
+	Name      string    `json:"name"`
+	Period    string    `json:"period"`
+	Side      string    `json:"side"`
+	Condition Condition `json:"condition"`
+}
+
+// evaluator returns true if condition holds at the current position of the source
+type evaluator func(src indicators.KLinesSource) bool
+
+func parseSide(side string) (indicators.TradeSignal, error) {
+	switch side {
+	case "BUY":
+		return indicators.TradeBuy, nil
+	case "SELL":
+		return indicators.TradeSell, nil
+	default:
+		return indicators.TradeNone, fmt.Errorf("invalid side %q, expected BUY or SELL", side)
+	}
+}
+
+// compile returns the evaluator of the condition and the maximal number of klines of a period it reads
+func compile(c Condition, period time.Duration) (evaluator, int, error) {
+	if c.Period != "" {
+		var err error
+		if period, err = klines.Period2Duration(c.Period); err != nil {
+			return nil, 0, err
+		}
+	}
+	switch {
+	case len(c.All) > 0 || len(c.Any) > 0:
+		return compileGroup(c, period)
+	case c.Indicator != "":
+		return compileSignal(c, period)
+	case c.Value != "":
+		return compileValue(c, period)
+	default:
+		return nil, 0, fmt.Errorf("empty condition")
+	}
+}
+
+func compileGroup(c Condition, period time.Duration) (evaluator, int, error) {
+	if len(c.All) > 0 && len(c.Any) > 0 {
+		return nil, 0, fmt.Errorf("condition can't have both all and any")
+	}
+	all := len(c.All) > 0
+	nested := c.All
+	if !all {
+		nested = c.Any
+	}
+	evaluators := make([]evaluator, len(nested))
+	var maxKLines int
+	for i, n := range nested {
+		var err error
+		var kLines int
+		if evaluators[i], kLines, err = compile(n, period); err != nil {
+			return nil, 0, err
+		}
+		if kLines > maxKLines {
+			maxKLines = kLines
+		}
+	}
+	return func(src indicators.KLinesSource) bool {
+		for _, e := range evaluators {
+			if e(src) != all {
+				return !all
+			}
+		}
+		return all
+	}, maxKLines, nil
+}
+
+func compileSignal(c Condition, period time.Duration) (evaluator, int, error) {
+	ind, ok := indicators.Lookup(c.Indicator)
+	if !ok {
+		return nil, 0, fmt.Errorf("unknown indicator %s", c.Indicator)
+	}
+	if ind.Factory == nil || ind.KLinesCount == nil {
+		return nil, 0, fmt.Errorf("indicator %s can't be used in composite condition", c.Indicator)
+	}
+	if len(c.Params) != len(ind.Params) {
+		return nil, 0, fmt.Errorf("indicator %s expects %d params, got %d", c.Indicator, len(ind.Params), len(c.Params))
+	}
+	side, err := parseSide(c.Side)
+	if err != nil {
+		return nil, 0, err
+	}
+	within := c.Within
+	if within <= 0 {
+		within = 1
+	}
+	n := ind.KLinesCount(c.Params)
+	signalFunc := ind.Factory(c.Params)
+	return func(src indicators.KLinesSource) bool {
+		kLines := src.KLines(period, n+within-1)
+		if kLines == nil {
+			return false
+		}
+		// check signal on each of the last within klines
+		for i := 0; i < within; i++ {
+			if signalFunc(kLines[i:i+n]) == side {
+				return true
+			}
+		}
+		return false
+	}, n + within - 1, nil
+}
+
+func compileValue(c Condition, period time.Duration) (evaluator, int, error) {
+	vf, ok := values[c.Value]
+	if !ok {
+		return nil, 0, fmt.Errorf("unknown value %s", c.Value)
+	}
+	if len(c.Params) != vf.params {
+		return nil, 0, fmt.Errorf("value %s expects %d params, got %d", c.Value, vf.params, len(c.Params))
+	}
+	var compare func(v float64) bool
+	switch c.Op {
+	case "<":
+		compare = func(v float64) bool { return v < c.Threshold }
+	case "<=":
+		compare = func(v float64) bool { return v <= c.Threshold }
+	case ">":
+		compare = func(v float64) bool { return v > c.Threshold }
+	case ">=":
+		compare = func(v float64) bool { return v >= c.Threshold }
+	default:
+		return nil, 0, fmt.Errorf("invalid op %q of value %s", c.Op, c.Value)
+	}
+	n := vf.kLinesCount(c.Params)
+	return func(src indicators.KLinesSource) bool {
+		kLines := src.KLines(period, n)
+		if kLines == nil {
+			return false
+		}
+		v, ok := vf.calculate(kLines, c.Params)
+		return ok && compare(v)
+	}, n, nil
+}
+
+// Indicator compiles the rule into indicator to be registered
+func (r Rule) Indicator() (indicators.Indicator, error) {
+	if r.Name == "" {
+		return indicators.Indicator{}, fmt.Errorf("rule name is empty")
+	}
+	period, err := klines.Period2Duration(r.Period)
+	if err != nil {
+		return indicators.Indicator{}, fmt.Errorf("rule %s: %w", r.Name, err)
+	}
+	side, err := parseSide(r.Side)
+	if err != nil {
+		return indicators.Indicator{}, fmt.Errorf("rule %s: %w", r.Name, err)
+	}
+	e, kLinesCount, err := compile(r.Condition, period)
+	if err != nil {
+		return indicators.Indicator{}, fmt.Errorf("rule %s: %w", r.Name, err)
+	}
+	return indicators.Indicator{
+		Type:    indicators.IndicatorTypeComposite,
+		Name:    r.Name,
+		Periods: []time.Duration{period},
+		// the lookback of the conditions, so the ticker keeps enough klines of every period for them
+		KLinesCount: func([]int) int { return kLinesCount },
+		SourceFactory: func([]int) indicators.SourceSignalFunc {
+			return func(src indicators.KLinesSource, _ time.Duration) indicators.TradeSignal {
+				if e(src) {
+					return side
+				}
+				return indicators.TradeNone
+			}
+		},
+	}, nil
+}
+
+// Register compiles the rules and registers them as indicators,
+// nothing is registered if a rule is invalid or its name is taken by another rule or a registered indicator
+func Register(rules []Rule) error {
+	compiled := make([]indicators.Indicator, len(rules))
+	names := make(map[string]bool, len(rules))
+	for i, r := range rules {
+		if names[r.Name] {
+			return fmt.Errorf("rule %s is defined twice", r.Name)
+		}
+		names[r.Name] = true
+		if _, ok := indicators.Lookup(r.Name); ok {
+			return fmt.Errorf("rule %s: indicator %s is already registered", r.Name, r.Name)
+		}
+		var err error
+		if compiled[i], err = r.Indicator(); err != nil {
+			return err
+		}
+	}
+	for _, ind := range compiled {
+		indicators.Register(ind)
+	}
+	return nil
+}
+
+// LoadRules loads json array of rules from the file
+func LoadRules(fileName string) ([]Rule, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	var rules []Rule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse composite rules %s: %w", fileName, err)
+	}
+	return rules, nil
+}
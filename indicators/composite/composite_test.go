@@ -0,0 +1,115 @@
+package composite
+
+import (
+	"github.com/okharch/binance/indicators"
+	_ "github.com/okharch/binance/indicators/mac"
+	"github.com/okharch/binance/klines"
+	"github.com/okharch/binance/ticker"
+	"testing"
+	"time"
+)
+
+// source with klines by period
+type testSource map[time.Duration][]klines.KLineEntry
+
+func (s testSource) KLines(period time.Duration, n int) []klines.KLineEntry {
+	kLines := s[period]
+	if len(kLines) < n {
+		return nil
+	}
+	return kLines[len(kLines)-n:]
+}
+
+func makeKLines(prices, volumes []float64) []klines.KLineEntry {
+	kLines := make([]klines.KLineEntry, len(prices))
+	for i, price := range prices {
+		kLines[i] = klines.KLineEntry{HighPrice: price, LowPrice: price, ClosePrice: price, Volume: volumes[i]}
+	}
+	return kLines
+}
+
+func TestRule(t *testing.T) {
+	src := testSource{
+		// MAC(10, 5) uses the first 10 of 11 klines, the same data as MAC buy test
+		15 * time.Minute: makeKLines(
+			[]float64{20, 21, 11, 18, 10, 10, 14, 13, 15, 18, 18},
+			[]float64{1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 3}),
+	}
+	// falling market on 1h: RSI is low
+	prices, volumes := make([]float64, 60), make([]float64, 60)
+	for i := range prices {
+		prices[i], volumes[i] = float64(100-i), 1
+	}
+	src[time.Hour] = makeKLines(prices, volumes)
+	rule := Rule{
+		Name:   "test",
+		Period: "15m",
+		Side:   "BUY",
+		Condition: Condition{All: []Condition{
+			{Indicator: "MAC", Params: []int{10, 5}, Side: "BUY"},
+			{Value: "rsi", Params: []int{5}, Period: "1h", Op: "<", Threshold: 40},
+			{Any: []Condition{
+				{Value: "volume_ratio", Params: []int{5}, Op: ">", Threshold: 2},
+				{Value: "close", Op: ">", Threshold: 100},
+			}},
+		}},
+	}
+	ind, err := rule.Indicator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// rsi(5) on 1h reads the most klines
+	if n := ind.MaxKLinesCount(); n != 51 {
+		t.Errorf("expected lookback of 51 klines, got %d", n)
+	}
+	signalFunc := ind.SourceFactory(nil)
+	if signal := signalFunc(src, 15*time.Minute); signal != indicators.TradeBuy {
+		t.Errorf("expected %v, got %v", indicators.TradeBuy, signal)
+	}
+	// volume is not above average any more
+	src[15*time.Minute][10].Volume = 1
+	if signal := signalFunc(src, 15*time.Minute); signal != indicators.TradeNone {
+		t.Errorf("expected %v, got %v", indicators.TradeNone, signal)
+	}
+}
+
+func TestInvalidRule(t *testing.T) {
+	for _, rule := range []Rule{
+		{Name: "unknown", Period: "15m", Side: "BUY", Condition: Condition{Indicator: "unknown"}},
+		{Name: "params", Period: "15m", Side: "BUY", Condition: Condition{Indicator: "MAC", Params: []int{1}, Side: "BUY"}},
+		{Name: "op", Period: "15m", Side: "BUY", Condition: Condition{Value: "close", Op: "=="}},
+		{Name: "period", Period: "7m", Side: "BUY", Condition: Condition{Value: "close", Op: ">"}},
+	} {
+		if _, err := rule.Indicator(); err == nil {
+			t.Errorf("rule %s: expected error", rule.Name)
+		}
+	}
+}
+
+func TestLookbackIsKept(t *testing.T) {
+	rule := Rule{Name: "long rsi", Period: "1h", Side: "BUY",
+		Condition: Condition{Value: "rsi", Params: []int{30}, Op: "<", Threshold: 30}}
+	if err := Register([]Rule{rule}); err != nil {
+		t.Fatal(err)
+	}
+	if n := ticker.KLinesToKeep(); n < 301 {
+		t.Errorf("expected the ticker to keep the lookback of rsi(30), got %d", n)
+	}
+}
+
+func TestRegisterDuplicates(t *testing.T) {
+	rule := func(name string) Rule {
+		return Rule{Name: name, Period: "15m", Side: "BUY", Condition: Condition{Value: "close", Op: ">", Threshold: 1}}
+	}
+	for _, rules := range [][]Rule{
+		{rule("MAC")},
+		{rule("twice"), rule("twice")},
+	} {
+		if err := Register(rules); err == nil {
+			t.Errorf("expected %s to be rejected", rules[len(rules)-1].Name)
+		}
+	}
+	if _, ok := indicators.Lookup("twice"); ok {
+		t.Error("expected no rules to be registered")
+	}
+}
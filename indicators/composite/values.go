@@ -0,0 +1,51 @@
+package composite
+
+import (
+	"github.com/okharch/binance/indicators/rsi"
+	"github.com/okharch/binance/indicators/stochastic"
+	"github.com/okharch/binance/indicators/volume"
+	"github.com/okharch/binance/klines"
+)
+
+// valueFunc calculates value of the last kline which can be compared in value conditions
+type valueFunc struct {
+	params      int
+	kLinesCount func(params []int) int
+	calculate   func(kLines []klines.KLineEntry, params []int) (float64, bool)
+}
+
+var values = map[string]valueFunc{
+	// close price of the last kline
+	"close": {0, func([]int) int { return 1 }, func(kLines []klines.KLineEntry, _ []int) (float64, bool) {
+		return kLines[len(kLines)-1].ClosePrice, true
+	}},
+	// rsi(period), smoothed over 10 periods more to be close to the value calculated on the whole history
+	"rsi": {1, func(p []int) int { return 10*p[0] + 1 }, func(kLines []klines.KLineEntry, p []int) (float64, bool) {
+		v := rsi.RSI(kLines, p[0])
+		return v, v >= 0
+	}},
+	// volume_ratio(n): volume of the last kline divided by average volume of n klines before it
+	"volume_ratio": {1, func(p []int) int { return p[0] + 1 }, func(kLines []klines.KLineEntry, p []int) (float64, bool) {
+		avg := klines.VolumeAvg(kLines[:len(kLines)-1])
+		return kLines[len(kLines)-1].Volume / avg, avg > 0
+	}},
+	// stochastic_k(kPeriod, dPeriod): slow %K
+	"stochastic_k": {2, func(p []int) int {
+		return stochastic.Params{KPeriod: p[0], DPeriod: p[1], Slowing: stochastic.DefaultSlowing}.KLinesCount()
+	}, func(kLines []klines.KLineEntry, p []int) (float64, bool) {
+		k, _ := stochastic.KD(kLines, stochastic.Params{KPeriod: p[0], DPeriod: p[1], Slowing: stochastic.DefaultSlowing})
+		if k == nil {
+			return 0, false
+		}
+		return k[1], true
+	}},
+	// williams_r(lookBack)
+	"williams_r": {1, func(p []int) int { return p[0] }, func(kLines []klines.KLineEntry, _ []int) (float64, bool) {
+		return stochastic.PercentR(kLines), true
+	}},
+	// vwap_deviation(n): relative distance of the close from rolling VWAP of n klines
+	"vwap_deviation": {1, func(p []int) int { return p[0] }, func(kLines []klines.KLineEntry, _ []int) (float64, bool) {
+		vwap := volume.VWAP(kLines)
+		return (kLines[len(kLines)-1].ClosePrice - vwap) / vwap, vwap > 0
+	}},
+}
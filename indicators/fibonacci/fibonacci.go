@@ -0,0 +1,134 @@
+package fibonacci
+
+import (
+	"fmt"
+	"github.com/okharch/binance/indicators"
+	"github.com/okharch/binance/klines"
+	"math"
+)
+
+/*
+Fibonacci retracement:
+- find the significant swing: the highest high and the lowest low of the lookback klines
+  which differ by at least MinMove (relative to the low).
+- for an upswing (low before high) retracement levels are high - ratio*(high-low),
+  extension levels are low + ratio*(high-low); for a downswing they are mirrored.
+- TradeBuy when price retraces down to a level of upswing (low within Tolerance of it) and the next kline closes up above the level.
+- TradeSell when price retraces up to a level of downswing (high within Tolerance of it) and the next kline closes down below the level.
+*/
+
+var RetracementRatios = []float64{0.236, 0.382, 0.5, 0.618, 0.786}
+var ExtensionRatios = []float64{1.272, 1.618, 2.618}
+
+// ratios retracement to which are considered for signals
+var signalRatios = []float64{0.382, 0.5, 0.618}
+
+const (
+	DefaultMinMove   = 0.03
+	DefaultTolerance = 0.002
+)
+
+type Params struct {
+	MinMove   float64 // minimal relative size of the swing
+	Tolerance float64 // relative distance from the level which counts as touching it
+}
+
+func DefaultParams() Params {
+	return Params{DefaultMinMove, DefaultTolerance}
+}
+
+type Swing struct {
+	High, Low                 float64
+	HighOpenTime, LowOpenTime int64
+}
+
+// Up returns true if swing low is before swing high
+func (s Swing) Up() bool {
+	return s.LowOpenTime < s.HighOpenTime
+}
+
+// Level returns the price of the ratio, retracement for ratio < 1, extension otherwise
+func (s Swing) Level(ratio float64) float64 {
+	move := s.High - s.Low
+	if s.Up() {
+		if ratio < 1 {
+			return s.High - ratio*move
+		}
+		return s.Low + ratio*move
+	}
+	if ratio < 1 {
+		return s.Low + ratio*move
+	}
+	return s.High - ratio*move
+}
+
+// Values returns retracement and extension levels of the swing as indicator values
+func (s Swing) Values() (result []indicators.IndicatorValue) {
+	for _, ratios := range [][]float64{RetracementRatios, ExtensionRatios} {
+		for _, ratio := range ratios {
+			result = append(result, indicators.IndicatorValue{Name: fmt.Sprintf("fib_%g", ratio), Value: s.Level(ratio)})
+		}
+	}
+	return
+}
+
+// LastSwing returns the swing between the highest high and the lowest low of kLines,
+// so the lookback defines which swing is significant.
+// ok is false if the swing is less than MinMove
+func LastSwing(kLines []klines.KLineEntry, p Params) (swing Swing, ok bool) {
+	if len(kLines) == 0 {
+		return
+	}
+	high, low := &kLines[0], &kLines[0]
+	for i := range kLines {
+		kl := &kLines[i]
+		// prefer the most recent extreme
+		if kl.HighPrice >= high.HighPrice {
+			high = kl
+		}
+		if kl.LowPrice <= low.LowPrice {
+			low = kl
+		}
+	}
+	if low.LowPrice <= 0 || (high.HighPrice-low.LowPrice)/low.LowPrice < p.MinMove {
+		return
+	}
+	return Swing{high.HighPrice, low.LowPrice, high.OpenTime, low.OpenTime}, true
+}
+
+// Signal returns retracement signal for the last kline of kLines,
+// the swing is detected on the klines before it
+func Signal(kLines []klines.KLineEntry, p Params) indicators.TradeSignal {
+	if len(kLines) < 2 {
+		return indicators.TradeNone
+	}
+	swing, ok := LastSwing(kLines[:len(kLines)-1], p)
+	if !ok {
+		return indicators.TradeNone
+	}
+	prev, last := &kLines[len(kLines)-2], &kLines[len(kLines)-1]
+	// the level is touched either by the reversal kline or by the kline before it
+	low := math.Min(prev.LowPrice, last.LowPrice)
+	high := math.Max(prev.HighPrice, last.HighPrice)
+	for _, ratio := range signalRatios {
+		level := swing.Level(ratio)
+		if swing.Up() && low <= level*(1+p.Tolerance) && low >= level*(1-p.Tolerance) &&
+			last.ClosePrice > level && last.ClosePrice > prev.ClosePrice {
+			return indicators.TradeBuy
+		}
+		if !swing.Up() && high >= level*(1-p.Tolerance) && high <= level*(1+p.Tolerance) &&
+			last.ClosePrice < level && last.ClosePrice < prev.ClosePrice {
+			return indicators.TradeSell
+		}
+	}
+	return indicators.TradeNone
+}
+
+// Fibonacci uses params[0] klines as lookback for the swing
+func Fibonacci(kLines []klines.KLineEntry, params []int) indicators.TradeSignal {
+	// the last kline is not the part of the swing
+	if n := params[0] + 1; len(kLines) > n {
+		kLines = kLines[len(kLines)-n:]
+	}
+	return Signal(kLines, DefaultParams())
+}
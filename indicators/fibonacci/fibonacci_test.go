@@ -0,0 +1,55 @@
+package fibonacci
+
+import (
+	"github.com/okharch/binance/indicators"
+	"github.com/okharch/binance/klines"
+	"math"
+	"testing"
+)
+
+func makeKLines(prices []float64) []klines.KLineEntry {
+	kLines := make([]klines.KLineEntry, len(prices))
+	for i, price := range prices {
+		kLines[i] = klines.KLineEntry{OpenTime: int64(i) * 60000, HighPrice: price, LowPrice: price, ClosePrice: price}
+	}
+	return kLines
+}
+
+func TestLastSwing(t *testing.T) {
+	// upswing from 100 to 200 and retracement
+	kLines := makeKLines([]float64{120, 100, 130, 160, 200, 180, 170, 175})
+	swing, ok := LastSwing(kLines, Params{0.03, 0.002})
+	if !ok {
+		t.Fatalf("expected swing to be found")
+	}
+	if !swing.Up() || swing.High != 200 || swing.Low != 100 {
+		t.Errorf("invalid swing %+v", swing)
+	}
+	if level := swing.Level(0.618); math.Abs(level-138.2) > 1e-9 {
+		t.Errorf("0.618 retracement: expected 138.2, got %v", level)
+	}
+	if level := swing.Level(1.618); math.Abs(level-261.8) > 1e-9 {
+		t.Errorf("1.618 extension: expected 261.8, got %v", level)
+	}
+	if values := swing.Values(); len(values) != len(RetracementRatios)+len(ExtensionRatios) || values[2].Name != "fib_0.5" {
+		t.Errorf("invalid values %+v", values)
+	}
+}
+
+func TestSignal(t *testing.T) {
+	p := Params{0.03, 0.002}
+	tests := []struct {
+		expected indicators.TradeSignal
+		prices   []float64
+	}{
+		// retraced to 0.5 level (150) and bounced
+		{indicators.TradeBuy, []float64{120, 100, 130, 160, 200, 180, 150, 149.9, 155}},
+		{indicators.TradeSell, []float64{180, 200, 170, 140, 100, 120, 150, 150.1, 145}},
+		{indicators.TradeNone, []float64{120, 100, 130, 160, 200, 180, 170, 175, 176}},
+	}
+	for _, test := range tests {
+		if signal := Signal(makeKLines(test.prices), p); signal != test.expected {
+			t.Errorf("%v: expected %v, but got %v", test.prices, test.expected, signal)
+		}
+	}
+}
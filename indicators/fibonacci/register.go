@@ -0,0 +1,14 @@
+package fibonacci
+
+import "github.com/okharch/binance/indicators"
+
+func init() {
+	// the last kline is not the part of the swing
+	indicators.Register(indicators.Indicator{
+		Type:        indicators.IndicatorTypeFibonacci,
+		Name:        "Fibonacci",
+		Params:      []indicators.Param{{Name: "lookBack", Min: 20, Max: 100, Step: 1}},
+		KLinesCount: indicators.KLinesPlus(0, 1),
+		Factory:     indicators.FuncFactory(Fibonacci),
+	})
+}
@@ -0,0 +1,114 @@
+package ichimoku
+
+import (
+	"github.com/okharch/binance/indicators"
+	"github.com/okharch/binance/klines"
+	"math"
+)
+
+/*
+Ichimoku Cloud:
+- Tenkan-sen (conversion line): (highest high + lowest low)/2 over Tenkan periods.
+- Kijun-sen (base line): (highest high + lowest low)/2 over Kijun periods.
+- Senkou Span A (leading span A): (Tenkan + Kijun)/2 displaced Displacement periods forward.
+- Senkou Span B (leading span B): (highest high + lowest low)/2 over SenkouB periods displaced Displacement periods forward.
+- Chikou Span (lagging span): close price displaced Displacement periods backward.
+Senkou spans A and B form the cloud (kumo).
+*/
+
+const (
+	DefaultTenkan       = 9
+	DefaultKijun        = 26
+	DefaultSenkouB      = 52
+	DefaultDisplacement = 26
+)
+
+type Params struct {
+	Tenkan, Kijun, SenkouB, Displacement int
+}
+
+func DefaultParams() Params {
+	return Params{DefaultTenkan, DefaultKijun, DefaultSenkouB, DefaultDisplacement}
+}
+
+// KLinesCount returns the number of klines required to calculate the cloud under the current kline
+// and the values for the previous kline, which is needed to detect crosses
+func (p Params) KLinesCount() int {
+	return p.maxPeriod() + p.Displacement + 1
+}
+
+// maxPeriod returns the longest lookback among the lines
+func (p Params) maxPeriod() int {
+	n := p.Tenkan
+	if p.Kijun > n {
+		n = p.Kijun
+	}
+	if p.SenkouB > n {
+		n = p.SenkouB
+	}
+	return n
+}
+
+// Values contains Ichimoku lines calculated on a kline.
+// SenkouA and SenkouB are the values to be plotted Displacement periods forward,
+// Chikou is the close of the kline to be plotted Displacement periods backward
+type Values struct {
+	Tenkan, Kijun, SenkouA, SenkouB, Chikou float64
+	Displacement                            int
+}
+
+// Lines returns values as indicator lines with their displacement
+func (v Values) Lines() []indicators.IndicatorValue {
+	return []indicators.IndicatorValue{
+		{Name: "tenkan", Value: v.Tenkan},
+		{Name: "kijun", Value: v.Kijun},
+		{Name: "senkou_a", Value: v.SenkouA, Shift: v.Displacement},
+		{Name: "senkou_b", Value: v.SenkouB, Shift: v.Displacement},
+		{Name: "chikou", Value: v.Chikou, Shift: -v.Displacement},
+	}
+}
+
+// CloudTop returns the upper boundary of the cloud
+func (v Values) CloudTop() float64 {
+	return math.Max(v.SenkouA, v.SenkouB)
+}
+
+// CloudBottom returns the lower boundary of the cloud
+func (v Values) CloudBottom() float64 {
+	return math.Min(v.SenkouA, v.SenkouB)
+}
+
+// midPoint returns (highest high + lowest low)/2 over the last n kLines
+func midPoint(kLines []klines.KLineEntry, n int) float64 {
+	kLines = kLines[len(kLines)-n:]
+	high, low := kLines[0].HighPrice, kLines[0].LowPrice
+	for _, kl := range kLines[1:] {
+		high = math.Max(high, kl.HighPrice)
+		low = math.Min(low, kl.LowPrice)
+	}
+	return (high + low) / 2
+}
+
+// Calculate returns Ichimoku values of the last kline.
+// ok is false if there are not enough klines
+func Calculate(kLines []klines.KLineEntry, p Params) (v Values, ok bool) {
+	if len(kLines) < p.maxPeriod() || p.Tenkan <= 0 || p.Kijun <= 0 || p.SenkouB <= 0 {
+		return
+	}
+	v.Tenkan = midPoint(kLines, p.Tenkan)
+	v.Kijun = midPoint(kLines, p.Kijun)
+	v.SenkouA = (v.Tenkan + v.Kijun) / 2
+	v.SenkouB = midPoint(kLines, p.SenkouB)
+	v.Chikou = kLines[len(kLines)-1].ClosePrice
+	v.Displacement = p.Displacement
+	return v, true
+}
+
+// Cloud returns Ichimoku values calculated Displacement klines ago,
+// their SenkouA and SenkouB are the cloud under the last kline
+func Cloud(kLines []klines.KLineEntry, p Params) (v Values, ok bool) {
+	if len(kLines) <= p.Displacement {
+		return
+	}
+	return Calculate(kLines[:len(kLines)-p.Displacement], p)
+}
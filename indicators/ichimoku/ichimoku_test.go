@@ -0,0 +1,53 @@
+package ichimoku
+
+import (
+	"github.com/okharch/binance/indicators"
+	"github.com/okharch/binance/klines"
+	"testing"
+)
+
+func makeKLines(prices []float64) []klines.KLineEntry {
+	kLines := make([]klines.KLineEntry, len(prices))
+	for i, price := range prices {
+		kLines[i] = klines.KLineEntry{HighPrice: price + 1, LowPrice: price - 1, ClosePrice: price}
+	}
+	return kLines
+}
+
+func TestCalculate(t *testing.T) {
+	p := Params{2, 3, 4, 2}
+	v, ok := Calculate(makeKLines([]float64{10, 20, 30, 40}), p)
+	if !ok {
+		t.Fatalf("expected values to be calculated")
+	}
+	// tenkan: (41+29)/2, kijun: (41+19)/2, senkou B: (41+9)/2
+	expected := Values{Tenkan: 35, Kijun: 30, SenkouA: 32.5, SenkouB: 25, Chikou: 40, Displacement: 2}
+	if v != expected {
+		t.Errorf("expected %+v, got %+v", expected, v)
+	}
+	lines := v.Lines()
+	if lines[2].Shift != 2 || lines[4].Shift != -2 {
+		t.Errorf("invalid lines displacement: %+v", lines)
+	}
+	if _, ok := Calculate(makeKLines([]float64{10, 20, 30}), p); ok {
+		t.Errorf("expected not enough klines")
+	}
+}
+
+func TestSignals(t *testing.T) {
+	p := Params{2, 3, 4, 2}
+	// flat market then breakout up through the cloud
+	kLines := makeKLines([]float64{10, 10, 10, 10, 10, 10, 10, 15})
+	if signal := CloudBreakoutSignal(kLines, p); signal != indicators.TradeBuy {
+		t.Errorf("cloud breakout: expected %v, got %v", indicators.TradeBuy, signal)
+	}
+	kLines = makeKLines([]float64{10, 10, 10, 10, 10, 10, 10, 5})
+	if signal := CloudBreakoutSignal(kLines, p); signal != indicators.TradeSell {
+		t.Errorf("cloud breakout: expected %v, got %v", indicators.TradeSell, signal)
+	}
+	// decline then sharp recovery: senkou A crosses above senkou B
+	kLines = makeKLines([]float64{20, 18, 16, 14, 12, 10, 12, 14})
+	if signal := CloudTwistSignal(kLines, p); signal != indicators.TradeBuy {
+		t.Errorf("cloud twist: expected %v, got %v", indicators.TradeBuy, signal)
+	}
+}
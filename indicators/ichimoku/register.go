@@ -0,0 +1,32 @@
+package ichimoku
+
+import "github.com/okharch/binance/indicators"
+
+func init() {
+	// SenkouB and displacement are derived from Kijun
+	params := []indicators.Param{
+		{Name: "kijun", Min: 20, Max: 30, Step: 1},
+		{Name: "tenkan", Min: 6, Max: 10, Step: 1},
+	}
+	kLinesCount := func(params []int) int {
+		return params2Ichimoku(params).KLinesCount()
+	}
+	for _, ind := range []struct {
+		indicatorType indicators.IndicatorType
+		name          string
+		indicatorFunc indicators.IndicatorFunc
+	}{
+		{indicators.IndicatorTypeIchimokuTKCross, "IchimokuTKCross", TKCross},
+		{indicators.IndicatorTypeIchimokuCloudBreakout, "IchimokuCloudBreakout", CloudBreakout},
+		{indicators.IndicatorTypeIchimokuCloudTwist, "IchimokuCloudTwist", CloudTwist},
+	} {
+		indicators.Register(indicators.Indicator{
+			Type:        ind.indicatorType,
+			Name:        ind.name,
+			Params:      params,
+			Constraints: []indicators.Constraint{indicators.LessThan(1, 0)},
+			KLinesCount: kLinesCount,
+			Factory:     indicators.FuncFactory(ind.indicatorFunc),
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package ichimoku
+
+import (
+	"github.com/okharch/binance/indicators"
+	"github.com/okharch/binance/klines"
+)
+
+/*
+Ichimoku signal rules (each one needs Params.KLinesCount() klines):
+1. TK cross: TradeBuy when Tenkan crosses above Kijun above the cloud, TradeSell when it crosses below Kijun under the cloud.
+2. Cloud breakout: TradeBuy when close crosses above the cloud top, TradeSell when it crosses below the cloud bottom.
+3. Cloud twist: TradeBuy when the leading Senkou A crosses above Senkou B, TradeSell when it crosses below.
+*/
+
+// previousAndLast calculates values and cloud for the previous and the last kline
+func previousAndLast(kLines []klines.KLineEntry, p Params) (prev, last, prevCloud, lastCloud Values, ok bool) {
+	if len(kLines) < p.KLinesCount() {
+		return
+	}
+	prevKLines := kLines[:len(kLines)-1]
+	var ok1, ok2, ok3, ok4 bool
+	prev, ok1 = Calculate(prevKLines, p)
+	last, ok2 = Calculate(kLines, p)
+	prevCloud, ok3 = Cloud(prevKLines, p)
+	lastCloud, ok4 = Cloud(kLines, p)
+	ok = ok1 && ok2 && ok3 && ok4
+	return
+}
+
+func TKCrossSignal(kLines []klines.KLineEntry, p Params) indicators.TradeSignal {
+	prev, last, _, cloud, ok := previousAndLast(kLines, p)
+	if !ok {
+		return indicators.TradeNone
+	}
+	price := kLines[len(kLines)-1].ClosePrice
+	if prev.Tenkan <= prev.Kijun && last.Tenkan > last.Kijun && price > cloud.CloudTop() {
+		return indicators.TradeBuy
+	}
+	if prev.Tenkan >= prev.Kijun && last.Tenkan < last.Kijun && price < cloud.CloudBottom() {
+		return indicators.TradeSell
+	}
+	return indicators.TradeNone
+}
+
+func CloudBreakoutSignal(kLines []klines.KLineEntry, p Params) indicators.TradeSignal {
+	_, _, prevCloud, lastCloud, ok := previousAndLast(kLines, p)
+	if !ok {
+		return indicators.TradeNone
+	}
+	prevPrice := kLines[len(kLines)-2].ClosePrice
+	price := kLines[len(kLines)-1].ClosePrice
+	if prevPrice <= prevCloud.CloudTop() && price > lastCloud.CloudTop() {
+		return indicators.TradeBuy
+	}
+	if prevPrice >= prevCloud.CloudBottom() && price < lastCloud.CloudBottom() {
+		return indicators.TradeSell
+	}
+	return indicators.TradeNone
+}
+
+func CloudTwistSignal(kLines []klines.KLineEntry, p Params) indicators.TradeSignal {
+	prev, last, _, _, ok := previousAndLast(kLines, p)
+	if !ok {
+		return indicators.TradeNone
+	}
+	if prev.SenkouA <= prev.SenkouB && last.SenkouA > last.SenkouB {
+		return indicators.TradeBuy
+	}
+	if prev.SenkouA >= prev.SenkouB && last.SenkouA < last.SenkouB {
+		return indicators.TradeSell
+	}
+	return indicators.TradeNone
+}
+
+// params2Ichimoku uses params[0] as Kijun and params[1] as Tenkan,
+// SenkouB is 2*Kijun and displacement is Kijun as in the default 9/26/52/26 settings
+func params2Ichimoku(params []int) Params {
+	return Params{params[1], params[0], 2 * params[0], params[0]}
+}
+
+func TKCross(kLines []klines.KLineEntry, params []int) indicators.TradeSignal {
+	return TKCrossSignal(kLines, params2Ichimoku(params))
+}
+
+func CloudBreakout(kLines []klines.KLineEntry, params []int) indicators.TradeSignal {
+	return CloudBreakoutSignal(kLines, params2Ichimoku(params))
+}
+
+func CloudTwist(kLines []klines.KLineEntry, params []int) indicators.TradeSignal {
+	return CloudTwistSignal(kLines, params2Ichimoku(params))
+}
@@ -0,0 +1,40 @@
+-- stable ids of indicators with particular params, see indicators/catalogue.go
+CREATE TABLE IF NOT EXISTS binance.indicator_catalogue (
+    indicator_id serial PRIMARY KEY,
+    indicator_type SMALLINT NOT NULL, -- indicators.IndicatorType
+    indicator VARCHAR(40) NOT NULL, -- registered name of the indicator, e.g. MAC
+    params JSONB NOT NULL, -- e.g. {"longTerm": 20, "shortTerm": 10}
+    created_at timestamptz NOT NULL DEFAULT now(),
+    UNIQUE (indicator, params)
+);
+COMMENT ON TABLE binance.indicator_catalogue IS 'Indicators with particular params referenced by indicator_signal.indicator_id.';
+
+CREATE TABLE IF NOT EXISTS binance.indicator_signal (
+    open_time BIGINT NOT NULL,
+    symbol_id INT NOT NULL,
+    indicator_id INT NOT NULL,
+    period SMALLINT NOT NULL, -- index of ticker.PeriodMinutes
+    volume NUMERIC(28, 8),
+    side VARCHAR(4) -- BUY or SELL
+);
+ALTER TABLE binance.indicator_signal ADD COLUMN IF NOT EXISTS side VARCHAR(4);
+
+-- migrate signals with bit packed indicator_id: sign is side, then type<<22 + longTerm<<12 + shortTerm
+-- indicator types of that time: 0 - MAC(longTerm, shortTerm)
+INSERT INTO binance.indicator_catalogue (indicator_type, indicator, params)
+SELECT DISTINCT 0, 'MAC', jsonb_build_object('longTerm', (abs(indicator_id) >> 12) & 1023, 'shortTerm', abs(indicator_id) & 4095)
+FROM binance.indicator_signal
+WHERE side IS NULL AND abs(indicator_id) >> 22 = 0
+ON CONFLICT DO NOTHING;
+
+UPDATE binance.indicator_signal s
+SET side = CASE WHEN s.indicator_id < 0 THEN 'SELL' ELSE 'BUY' END,
+    indicator_id = c.indicator_id
+FROM binance.indicator_catalogue c
+WHERE s.side IS NULL AND abs(s.indicator_id) >> 22 = 0
+  AND c.indicator = 'MAC'
+  AND c.params = jsonb_build_object('longTerm', (abs(s.indicator_id) >> 12) & 1023, 'shortTerm', abs(s.indicator_id) & 4095);
+
+-- signals of other packed indicator types were experimental, they have to be regenerated
+DELETE FROM binance.indicator_signal WHERE side IS NULL;
+ALTER TABLE binance.indicator_signal ALTER COLUMN side SET NOT NULL;
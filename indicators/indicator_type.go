@@ -6,12 +6,24 @@ const (
 	IndicatorTypeMAC IndicatorType = iota
 	IndicatorTypeMACD
 	IndicatorTypeBollinger
+	IndicatorTypeCVDDivergence
+	IndicatorTypeStochastic
+	IndicatorTypeSlowStochastic
+	IndicatorTypeWilliamsR
+	IndicatorTypeIchimokuTKCross
+	IndicatorTypeIchimokuCloudBreakout
+	IndicatorTypeIchimokuCloudTwist
+	IndicatorTypeVolumeSpike
+	IndicatorTypeSupportResistance
+	IndicatorTypeFibonacci
+	IndicatorTypeComposite
 )
 
 type IndicatorSignal struct {
 	OpenTime    int64   `db:"open_time"`
 	SymbolId    int32   `db:"symbol_id"`
-	IndicatorId int32   `db:"indicator_id"`
+	IndicatorId int32   `db:"indicator_id"` // binance.indicator_catalogue.indicator_id
+	Side        string  `db:"side"`         // BUY or SELL
 	Period      int8    `db:"period"`
 	Volume      float64 `db:"volume"`
 	VolAvg      float64 `db:"vol_avg"`
@@ -26,7 +38,38 @@ func (it IndicatorType) String() string {
 		return "MACD"
 	case IndicatorTypeBollinger:
 		return "Bollinger"
+	case IndicatorTypeCVDDivergence:
+		return "CVDDivergence"
+	case IndicatorTypeStochastic:
+		return "Stochastic"
+	case IndicatorTypeSlowStochastic:
+		return "SlowStochastic"
+	case IndicatorTypeWilliamsR:
+		return "WilliamsR"
+	case IndicatorTypeIchimokuTKCross:
+		return "IchimokuTKCross"
+	case IndicatorTypeIchimokuCloudBreakout:
+		return "IchimokuCloudBreakout"
+	case IndicatorTypeIchimokuCloudTwist:
+		return "IchimokuCloudTwist"
+	case IndicatorTypeVolumeSpike:
+		return "VolumeSpike"
+	case IndicatorTypeSupportResistance:
+		return "SupportResistance"
+	case IndicatorTypeFibonacci:
+		return "Fibonacci"
+	case IndicatorTypeComposite:
+		return "Composite"
 	default:
 		return ""
 	}
 }
+
+// IndicatorValue is one output line of multi-line indicator (e.g. Ichimoku).
+// Shift is the number of periods the value is displaced relative to the kline it is calculated on:
+// positive values are plotted forward (into the future), negative values backward.
+type IndicatorValue struct {
+	Name  string
+	Value float64
+	Shift int
+}
@@ -0,0 +1,41 @@
+package indicators
+
+import (
+	"fmt"
+	"github.com/jmoiron/sqlx"
+)
+
+// IndicatorValueRecord is a value of indicator line calculated on the kline at OpenTime
+type IndicatorValueRecord struct {
+	OpenTime  int64   `db:"open_time"`
+	SymbolId  int32   `db:"symbol_id"`
+	Period    int8    `db:"period"`
+	Indicator string  `db:"indicator"`
+	Name      string  `db:"name"`
+	Value     float64 `db:"value"`
+	Shift     int     `db:"shift"`
+}
+
+// NewIndicatorValueRecords converts indicator values calculated on the kline at openTime to records
+func NewIndicatorValueRecords(openTime int64, symbolId int32, period int8, indicator string, values []IndicatorValue) []IndicatorValueRecord {
+	result := make([]IndicatorValueRecord, len(values))
+	for i, v := range values {
+		result[i] = IndicatorValueRecord{openTime, symbolId, period, indicator, v.Name, v.Value, v.Shift}
+	}
+	return result
+}
+
+func InsertIndicatorValues(db *sqlx.DB, values []IndicatorValueRecord) error {
+	if len(values) == 0 {
+		return nil
+	}
+	_, err := db.NamedExec(`
+		INSERT INTO binance.indicator_value (open_time, symbol_id, period, indicator, name, value, shift)
+		VALUES (:open_time, :symbol_id, :period, :indicator, :name, :value, :shift)
+		ON CONFLICT (symbol_id, period, indicator, name, open_time) DO UPDATE SET value = EXCLUDED.value, shift = EXCLUDED.shift
+	`, values)
+	if err != nil {
+		return fmt.Errorf("failed to insert indicator values: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,12 @@
+-- values of multi-line indicators (Ichimoku lines, Fibonacci levels, etc.) to chart them along with klines
+CREATE TABLE IF NOT EXISTS binance.indicator_value (
+    open_time BIGINT NOT NULL, -- open time of the kline the value is calculated on
+    symbol_id INT NOT NULL,
+    period SMALLINT NOT NULL, -- index of ticker.PeriodMinutes
+    indicator VARCHAR(40) NOT NULL, -- e.g. Ichimoku, Fibonacci
+    name VARCHAR(40) NOT NULL, -- line name, e.g. senkou_a, fib_0.618
+    value FLOAT8 NOT NULL,
+    shift INT NOT NULL DEFAULT 0, -- number of periods the value is displaced when plotted
+    PRIMARY KEY (symbol_id, period, indicator, name, open_time)
+);
+COMMENT ON TABLE binance.indicator_value IS 'Values of indicator lines calculated on klines.';
@@ -0,0 +1,19 @@
+package levels
+
+import (
+	"github.com/jmoiron/sqlx"
+	"github.com/okharch/binance/klines"
+	"time"
+)
+
+// LevelsAt returns levels of symbol for the period known at the time at,
+// they are detected on lookback klines of the period before at
+func LevelsAt(db *sqlx.DB, symbolId int32, period time.Duration, at time.Time, lookback int, p Params) ([]Level, error) {
+	endTime := at.UnixMilli() - 1
+	startTime := endTime - int64(lookback)*period.Milliseconds()
+	kd, err := klines.FetchKLineDataFromDB(db, symbolId, startTime, endTime)
+	if err != nil {
+		return nil, err
+	}
+	return Detect(klines.AggregatePeriod(kd.Data, period), p), nil
+}
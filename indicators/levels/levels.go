@@ -0,0 +1,162 @@
+package levels
+
+import (
+	"github.com/okharch/binance/klines"
+	"math"
+	"sort"
+)
+
+/*
+Support and resistance levels:
+1. find swing highs and lows: a kline whose high (low) is the highest (lowest) among SwingWindow klines on each side.
+2. cluster swings whose prices are within Tolerance (relative distance) into zones.
+3. each swing in a zone is a touch, zone strength is the sum of swing volumes relative to the average volume,
+   so levels formed on heavy volume are stronger than the levels formed on thin market.
+Levels below the last close are support, above it are resistance.
+*/
+
+const (
+	DefaultSwingWindow = 5
+	DefaultTolerance   = 0.005
+	DefaultMinTouches  = 2
+)
+
+type Params struct {
+	SwingWindow int
+	Tolerance   float64 // relative price distance to merge swings into one zone
+	MinTouches  int     // minimal number of swings forming the level
+}
+
+func DefaultParams() Params {
+	return Params{DefaultSwingWindow, DefaultTolerance, DefaultMinTouches}
+}
+
+type Kind int
+
+const (
+	Support Kind = iota
+	Resistance
+)
+
+func (k Kind) String() string {
+	if k == Support {
+		return "support"
+	}
+	return "resistance"
+}
+
+type Swing struct {
+	OpenTime int64
+	Price    float64
+	Volume   float64
+	High     bool // swing high, otherwise swing low
+}
+
+type Level struct {
+	Kind                Kind
+	Price               float64 // volume weighted price of the swings
+	Low, High           float64 // zone boundaries
+	Touches             int
+	Strength            float64 // sum of swings volumes divided by average volume
+	FirstTime, LastTime int64   // open time of the first and the last touch
+}
+
+// Swings returns swing highs and lows of kLines ordered by time.
+// the last window klines can't be swings as they don't have enough klines on the right side
+func Swings(kLines []klines.KLineEntry, window int) (result []Swing) {
+	for i := window; i < len(kLines)-window; i++ {
+		kl := &kLines[i]
+		isHigh, isLow := true, true
+		for j := i - window; j <= i+window && (isHigh || isLow); j++ {
+			if j == i {
+				continue
+			}
+			if kLines[j].HighPrice >= kl.HighPrice {
+				isHigh = false
+			}
+			if kLines[j].LowPrice <= kl.LowPrice {
+				isLow = false
+			}
+		}
+		if isHigh {
+			result = append(result, Swing{kl.OpenTime, kl.HighPrice, kl.Volume, true})
+		}
+		if isLow {
+			result = append(result, Swing{kl.OpenTime, kl.LowPrice, kl.Volume, false})
+		}
+	}
+	return
+}
+
+// Detect returns support and resistance levels of kLines ordered by strength, the strongest first.
+// only klines passed are used, so detecting levels on klines before some time gives levels known at that time
+func Detect(kLines []klines.KLineEntry, p Params) []Level {
+	if len(kLines) == 0 {
+		return nil
+	}
+	swings := Swings(kLines, p.SwingWindow)
+	sort.Slice(swings, func(i, j int) bool { return swings[i].Price < swings[j].Price })
+	avgVolume := klines.VolumeAvg(kLines)
+	if avgVolume == 0 {
+		avgVolume = 1
+	}
+	lastClose := kLines[len(kLines)-1].ClosePrice
+	var result []Level
+	// swings are sorted by price, so the zone grows while the next swing is within tolerance of the zone low
+	for i := 0; i < len(swings); {
+		j := i
+		var pv, v float64
+		level := Level{Low: swings[i].Price, FirstTime: swings[i].OpenTime, LastTime: swings[i].OpenTime}
+		for ; j < len(swings) && swings[j].Price <= level.Low*(1+p.Tolerance); j++ {
+			s := &swings[j]
+			level.High = s.Price
+			level.Touches++
+			level.FirstTime = minInt64(level.FirstTime, s.OpenTime)
+			level.LastTime = maxInt64(level.LastTime, s.OpenTime)
+			// tiny positive weight not to lose swings with zero volume
+			w := math.Max(s.Volume, 1e-9)
+			pv += s.Price * w
+			v += w
+			level.Strength += s.Volume / avgVolume
+		}
+		i = j
+		if level.Touches < p.MinTouches {
+			continue
+		}
+		level.Price = pv / v
+		if level.Price > lastClose {
+			level.Kind = Resistance
+		}
+		result = append(result, level)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Strength > result[j].Strength })
+	return result
+}
+
+// Nearest returns the nearest support below and resistance above the price, nil if there is none
+func Nearest(levels []Level, price float64) (support, resistance *Level) {
+	for i := range levels {
+		l := &levels[i]
+		if l.Price <= price && (support == nil || l.Price > support.Price) {
+			support = l
+		}
+		if l.Price > price && (resistance == nil || l.Price < resistance.Price) {
+			resistance = l
+		}
+	}
+	return
+}
+
+func minInt64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,62 @@
+package levels
+
+import (
+	"github.com/okharch/binance/indicators"
+	"github.com/okharch/binance/klines"
+	"testing"
+)
+
+// zigzag between 10 and 20 with swing window 1
+func zigzag(prices []float64) []klines.KLineEntry {
+	kLines := make([]klines.KLineEntry, len(prices))
+	for i, price := range prices {
+		kLines[i] = klines.KLineEntry{
+			OpenTime:   int64(i) * 60000,
+			HighPrice:  price,
+			LowPrice:   price,
+			ClosePrice: price,
+			Volume:     1,
+		}
+	}
+	return kLines
+}
+
+func TestDetect(t *testing.T) {
+	kLines := zigzag([]float64{15, 10, 15, 20, 15, 10.02, 15, 19.98, 15})
+	levels := Detect(kLines, Params{1, 0.005, 2})
+	if len(levels) != 2 {
+		t.Fatalf("expected 2 levels, got %+v", levels)
+	}
+	support, resistance := Nearest(levels, 15)
+	if support == nil || support.Kind != Support || support.Touches != 2 || support.Low != 10 || support.High != 10.02 {
+		t.Errorf("invalid support %+v", support)
+	}
+	if resistance == nil || resistance.Kind != Resistance || resistance.Touches != 2 {
+		t.Errorf("invalid resistance %+v", resistance)
+	}
+}
+
+func TestSignal(t *testing.T) {
+	p := Params{1, 0.005, 2}
+	tests := []struct {
+		expected indicators.TradeSignal
+		kind     SignalKind
+		prices   []float64
+	}{
+		{indicators.TradeBuy, Breakout, []float64{15, 10, 15, 20, 15, 10, 15, 20, 15, 19, 21}},
+		{indicators.TradeSell, Breakout, []float64{15, 10, 15, 20, 15, 10, 15, 20, 15, 11, 9}},
+		{indicators.TradeNone, NoSignal, []float64{15, 10, 15, 20, 15, 10, 15, 20, 15, 14, 16}},
+	}
+	for _, test := range tests {
+		signal, kind := Signal(zigzag(test.prices), p)
+		if signal != test.expected || kind != test.kind {
+			t.Errorf("%v: expected %v/%v, but got %v/%v", test.prices, test.expected, test.kind, signal, kind)
+		}
+	}
+	// bounce from support: low touches the zone, close is above it
+	kLines := zigzag([]float64{15, 10, 15, 20, 15, 10, 15, 20, 15, 12})
+	kLines = append(kLines, klines.KLineEntry{LowPrice: 9.99, HighPrice: 13, ClosePrice: 12.5})
+	if signal, kind := Signal(kLines, p); signal != indicators.TradeBuy || kind != Bounce {
+		t.Errorf("bounce: expected %v/%v, but got %v/%v", indicators.TradeBuy, Bounce, signal, kind)
+	}
+}
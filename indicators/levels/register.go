@@ -0,0 +1,16 @@
+package levels
+
+import "github.com/okharch/binance/indicators"
+
+func init() {
+	indicators.Register(indicators.Indicator{
+		Type: indicators.IndicatorTypeSupportResistance,
+		Name: "SupportResistance",
+		Params: []indicators.Param{
+			{Name: "lookBack", Min: 30, Max: 100, Step: 1},
+			{Name: "swingWindow", Min: 3, Max: 10, Step: 1},
+		},
+		KLinesCount: indicators.KLinesPlus(0, 0),
+		Factory:     indicators.FuncFactory(SupportResistance),
+	})
+}
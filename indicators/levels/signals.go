@@ -0,0 +1,59 @@
+package levels
+
+import (
+	"github.com/okharch/binance/indicators"
+	"github.com/okharch/binance/klines"
+)
+
+/*
+Support/resistance signals on the last kline (levels are detected on the klines before it):
+- bounce: TradeBuy when the kline touches support zone and closes above it,
+  TradeSell when it touches resistance zone and closes below it.
+- breakout: TradeBuy when close crosses above resistance zone, TradeSell when close crosses below support zone.
+*/
+
+type SignalKind int
+
+const (
+	NoSignal SignalKind = iota
+	Bounce
+	Breakout
+)
+
+// Signal returns trade signal and its kind for the last kline of kLines
+func Signal(kLines []klines.KLineEntry, p Params) (indicators.TradeSignal, SignalKind) {
+	if len(kLines) < 2*p.SwingWindow+2 {
+		return indicators.TradeNone, NoSignal
+	}
+	prev, last := &kLines[len(kLines)-2], &kLines[len(kLines)-1]
+	levels := Detect(kLines[:len(kLines)-1], p)
+	support, resistance := Nearest(levels, prev.ClosePrice)
+	if resistance != nil {
+		if prev.ClosePrice <= resistance.High && last.ClosePrice > resistance.High {
+			return indicators.TradeBuy, Breakout
+		}
+		if last.HighPrice >= resistance.Low && last.ClosePrice < resistance.Low {
+			return indicators.TradeSell, Bounce
+		}
+	}
+	if support != nil {
+		if prev.ClosePrice >= support.Low && last.ClosePrice < support.Low {
+			return indicators.TradeSell, Breakout
+		}
+		if last.LowPrice <= support.High && last.ClosePrice > support.High {
+			return indicators.TradeBuy, Bounce
+		}
+	}
+	return indicators.TradeNone, NoSignal
+}
+
+// SupportResistance uses params[0] klines as lookback and params[1] as swing window
+func SupportResistance(kLines []klines.KLineEntry, params []int) indicators.TradeSignal {
+	p := DefaultParams()
+	p.SwingWindow = params[1]
+	if len(kLines) > params[0] {
+		kLines = kLines[len(kLines)-params[0]:]
+	}
+	signal, _ := Signal(kLines, p)
+	return signal
+}
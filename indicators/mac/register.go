@@ -0,0 +1,18 @@
+package mac
+
+import "github.com/okharch/binance/indicators"
+
+func init() {
+	// we need one more element to calculate two following moving average: 0..n and 1..n+1
+	indicators.Register(indicators.Indicator{
+		Type: indicators.IndicatorTypeMAC,
+		Name: "MAC",
+		Params: []indicators.Param{
+			{Name: "longTerm", Min: 10, Max: 100, Step: 1},
+			{Name: "shortTerm", Min: 6, Max: 66, Step: 1},
+		},
+		Constraints: []indicators.Constraint{indicators.LessThan(1, 0)},
+		KLinesCount: indicators.KLinesPlus(0, 1),
+		Factory:     indicators.FuncFactory(MAC),
+	})
+}
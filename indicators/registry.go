@@ -0,0 +1,178 @@
+package indicators
+
+import (
+	"fmt"
+	"github.com/okharch/binance/klines"
+	"sort"
+	"sync"
+	"time"
+)
+
+/*
+Indicator registry.
+Each indicator package registers its indicators in init() with parameter schema:
+ranges of integer parameters and constraints between them (like shortTerm < longTerm).
+Signal generation iterates over the cartesian product of the parameter ranges (the grid)
+without knowing anything about particular indicators.
+*/
+
+// IndicatorFunc calculates signal on the last kline of kLines using params
+type IndicatorFunc func(kLines []klines.KLineEntry, params []int) TradeSignal
+
+// SignalFunc calculates signal on the last kline of kLines for already bound parameters
+type SignalFunc func(kLines []klines.KLineEntry) TradeSignal
+
+// KLinesSource provides the last n klines of any period up to the current position, e.g. ticker.Ticker.
+// it returns nil if there are not enough klines
+type KLinesSource interface {
+	KLines(period time.Duration, n int) []klines.KLineEntry
+}
+
+// SourceSignalFunc calculates signal for the period using klines of any periods from the source
+type SourceSignalFunc func(src KLinesSource, period time.Duration) TradeSignal
+
+// Param describes the range of integer indicator parameter
+type Param struct {
+	Name          string
+	Min, Max, Step int
+}
+
+// Constraint returns false for the combination of params which is not valid
+type Constraint func(params []int) bool
+
+type Indicator struct {
+	Type        IndicatorType
+	Name        string
+	Params      []Param
+	Constraints []Constraint
+	// KLinesCount returns the number of klines the indicator needs for params
+	KLinesCount func(params []int) int
+	// Factory returns signal function with bound params
+	Factory func(params []int) SignalFunc
+	// SourceFactory is used instead of KLinesCount and Factory by indicators which need klines of several periods
+	SourceFactory func(params []int) SourceSignalFunc
+	// Periods the indicator is calculated on, all periods if empty
+	Periods []time.Duration
+}
+
+var registryMu sync.RWMutex
+var registry = map[string]Indicator{}
+
+// Register adds indicator to the registry, it panics if indicator with the same name or type is registered.
+// composite indicators share IndicatorTypeComposite and are distinguished by name
+func Register(ind Indicator) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, ok := registry[ind.Name]; ok {
+		panic(fmt.Sprintf("indicator %s is already registered", ind.Name))
+	}
+	for _, r := range registry {
+		if r.Type == ind.Type && ind.Type != IndicatorTypeComposite {
+			panic(fmt.Sprintf("indicator type %d of %s is already registered by %s", ind.Type, ind.Name, r.Name))
+		}
+	}
+	registry[ind.Name] = ind
+}
+
+// Registered returns registered indicators ordered by type
+func Registered() []Indicator {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	result := make([]Indicator, 0, len(registry))
+	for _, ind := range registry {
+		result = append(result, ind)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Type != result[j].Type {
+			return result[i].Type < result[j].Type
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// Lookup returns registered indicator by name
+func Lookup(name string) (Indicator, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	ind, ok := registry[name]
+	return ind, ok
+}
+
+// LookupType returns registered indicator by type
+func LookupType(it IndicatorType) (Indicator, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	for _, ind := range registry {
+		if ind.Type == it {
+			return ind, true
+		}
+	}
+	return Indicator{}, false
+}
+
+// Grid returns all combinations of params within their ranges satisfying constraints
+func (ind Indicator) Grid() (result [][]int) {
+	if len(ind.Params) == 0 {
+		return [][]int{{}}
+	}
+	params := make([]int, len(ind.Params))
+	var iterate func(i int)
+	iterate = func(i int) {
+		if i == len(params) {
+			for _, c := range ind.Constraints {
+				if !c(params) {
+					return
+				}
+			}
+			result = append(result, append([]int(nil), params...))
+			return
+		}
+		p := ind.Params[i]
+		step := p.Step
+		if step <= 0 {
+			step = 1
+		}
+		for params[i] = p.Min; params[i] <= p.Max; params[i] += step {
+			iterate(i + 1)
+		}
+	}
+	iterate(0)
+	return
+}
+
+// MaxKLinesCount returns the maximal number of klines required over the grid
+func (ind Indicator) MaxKLinesCount() (result int) {
+	if ind.KLinesCount == nil {
+		return
+	}
+	for _, params := range ind.Grid() {
+		if n := ind.KLinesCount(params); n > result {
+			result = n
+		}
+	}
+	return
+}
+
+// FuncFactory makes factory of IndicatorFunc binding params
+func FuncFactory(f IndicatorFunc) func(params []int) SignalFunc {
+	return func(params []int) SignalFunc {
+		return func(kLines []klines.KLineEntry) TradeSignal {
+			return f(kLines, params)
+		}
+	}
+}
+
+// LessThan is a constraint params[i] < params[j]
+func LessThan(i, j int) Constraint {
+	return func(params []int) bool {
+		return params[i] < params[j]
+	}
+}
+
+// KLinesPlus returns KLinesCount func which needs params[i]+n klines
+func KLinesPlus(i, n int) func(params []int) int {
+	return func(params []int) int {
+		return params[i] + n
+	}
+}
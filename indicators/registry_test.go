@@ -0,0 +1,38 @@
+package indicators
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGrid(t *testing.T) {
+	ind := Indicator{
+		Params:      []Param{{"long", 2, 4, 1}, {"short", 1, 5, 2}},
+		Constraints: []Constraint{LessThan(1, 0)},
+	}
+	expected := [][]int{{2, 1}, {3, 1}, {4, 1}, {4, 3}}
+	if grid := ind.Grid(); !reflect.DeepEqual(grid, expected) {
+		t.Errorf("expected grid %v, got %v", expected, grid)
+	}
+}
+
+func TestParamsJSON(t *testing.T) {
+	ind := Indicator{Name: "MAC", Params: []Param{{"longTerm", 10, 100, 1}, {"shortTerm", 6, 66, 1}}}
+	paramsJSON, err := ind.ParamsJSON([]int{20, 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `{"longTerm":20,"shortTerm":10}`; paramsJSON != expected {
+		t.Errorf("expected %s, got %s", expected, paramsJSON)
+	}
+	params, err := ind.DecodeParams(paramsJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(params, []int{20, 10}) {
+		t.Errorf("expected [20 10], got %v", params)
+	}
+	if _, err := ind.DecodeParams(`{"longTerm":20}`); err == nil {
+		t.Errorf("expected error for missing param")
+	}
+}
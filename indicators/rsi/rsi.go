@@ -0,0 +1,41 @@
+package rsi
+
+import "github.com/okharch/binance/klines"
+
+/*
+Relative Strength Index:
+- average gain and average loss of close prices are smoothed by Wilder's method over period klines.
+- RSI = 100 - 100/(1 + avgGain/avgLoss), ranges 0..100, usual overbought/oversold levels are 70/30.
+*/
+
+const DefaultPeriod = 14
+
+// RSI returns RSI of the last kline, all kLines are used for smoothing,
+// at least period+1 klines are required, returns -1 otherwise
+func RSI(kLines []klines.KLineEntry, period int) float64 {
+	if period <= 0 || len(kLines) < period+1 {
+		return -1
+	}
+	var avgGain, avgLoss float64
+	for i := 1; i < len(kLines); i++ {
+		change := kLines[i].ClosePrice - kLines[i-1].ClosePrice
+		gain, loss := 0.0, 0.0
+		if change > 0 {
+			gain = change
+		} else {
+			loss = -change
+		}
+		if i <= period {
+			// simple average for the first period
+			avgGain += gain / float64(period)
+			avgLoss += loss / float64(period)
+			continue
+		}
+		avgGain = (avgGain*float64(period-1) + gain) / float64(period)
+		avgLoss = (avgLoss*float64(period-1) + loss) / float64(period)
+	}
+	if avgLoss == 0 {
+		return 100
+	}
+	return 100 - 100/(1+avgGain/avgLoss)
+}
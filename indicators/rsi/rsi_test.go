@@ -0,0 +1,22 @@
+package rsi
+
+import (
+	"github.com/okharch/binance/klines"
+	"math"
+	"testing"
+)
+
+func TestRSI(t *testing.T) {
+	prices := []float64{10, 11, 10, 11, 12}
+	kLines := make([]klines.KLineEntry, len(prices))
+	for i, price := range prices {
+		kLines[i] = klines.KLineEntry{ClosePrice: price}
+	}
+	// gains: 1, 0, 1, 1; losses: 0, 1, 0, 0
+	if v := RSI(kLines, 4); math.Abs(v-75) > 1e-9 {
+		t.Errorf("expected 75, got %v", v)
+	}
+	if v := RSI(kLines, 5); v != -1 {
+		t.Errorf("expected -1 for not enough klines, got %v", v)
+	}
+}
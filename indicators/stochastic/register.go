@@ -0,0 +1,35 @@
+package stochastic
+
+import "github.com/okharch/binance/indicators"
+
+func init() {
+	kdParams := []indicators.Param{
+		{Name: "kPeriod", Min: 5, Max: 30, Step: 1},
+		{Name: "dPeriod", Min: 1, Max: 6, Step: 1},
+	}
+	indicators.Register(indicators.Indicator{
+		Type:   indicators.IndicatorTypeStochastic,
+		Name:   "Stochastic",
+		Params: kdParams,
+		KLinesCount: func(params []int) int {
+			return Params{KPeriod: params[0], DPeriod: params[1], Slowing: 1}.KLinesCount()
+		},
+		Factory: indicators.FuncFactory(Stochastic),
+	})
+	indicators.Register(indicators.Indicator{
+		Type:   indicators.IndicatorTypeSlowStochastic,
+		Name:   "SlowStochastic",
+		Params: kdParams,
+		KLinesCount: func(params []int) int {
+			return Params{KPeriod: params[0], DPeriod: params[1], Slowing: DefaultSlowing}.KLinesCount()
+		},
+		Factory: indicators.FuncFactory(SlowStochastic),
+	})
+	indicators.Register(indicators.Indicator{
+		Type:        indicators.IndicatorTypeWilliamsR,
+		Name:        "WilliamsR",
+		Params:      []indicators.Param{{Name: "lookBack", Min: 5, Max: 30, Step: 1}},
+		KLinesCount: indicators.KLinesPlus(0, 1),
+		Factory:     indicators.FuncFactory(WilliamsR),
+	})
+}
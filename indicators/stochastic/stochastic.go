@@ -0,0 +1,125 @@
+package stochastic
+
+import (
+	"github.com/okharch/binance/indicators"
+	"github.com/okharch/binance/klines"
+)
+
+/*
+Stochastic Oscillator:
+- %K shows where the close is within the high/low range of the last KPeriod klines (0..100).
+- slow %K is %K smoothed by simple moving average over Slowing periods (Slowing=1 gives fast stochastic).
+- %D is simple moving average of %K over DPeriod periods.
+- TradeBuy when %K crosses above %D while both are below Oversold level.
+- TradeSell when %K crosses below %D while both are above Overbought level.
+*/
+
+const (
+	DefaultKPeriod    = 14
+	DefaultDPeriod    = 3
+	DefaultSlowing    = 3
+	DefaultOverbought = 80
+	DefaultOversold   = 20
+)
+
+type Params struct {
+	KPeriod, DPeriod, Slowing int
+	Overbought, Oversold      float64
+}
+
+func DefaultParams() Params {
+	return Params{DefaultKPeriod, DefaultDPeriod, DefaultSlowing, DefaultOverbought, DefaultOversold}
+}
+
+// KLinesCount returns the number of klines required to calculate %K and %D for two last klines
+func (p Params) KLinesCount() int {
+	return p.KPeriod + p.Slowing - 1 + p.DPeriod - 1 + 1
+}
+
+// highLow returns the highest high and the lowest low of kLines
+func highLow(kLines []klines.KLineEntry) (high, low float64) {
+	high, low = kLines[0].HighPrice, kLines[0].LowPrice
+	for _, kl := range kLines[1:] {
+		if kl.HighPrice > high {
+			high = kl.HighPrice
+		}
+		if kl.LowPrice < low {
+			low = kl.LowPrice
+		}
+	}
+	return
+}
+
+// PercentK returns raw %K of the last kline using the whole kLines as lookback
+func PercentK(kLines []klines.KLineEntry) float64 {
+	if len(kLines) == 0 {
+		return -1
+	}
+	high, low := highLow(kLines)
+	if high == low {
+		return 50
+	}
+	return (kLines[len(kLines)-1].ClosePrice - low) / (high - low) * 100
+}
+
+// KD returns %K and %D values for the last kLines.
+// k and d contain two values: for the previous and the last kline, nil if there are not enough klines
+func KD(kLines []klines.KLineEntry, p Params) (k, d []float64) {
+	if p.KPeriod <= 0 || p.DPeriod <= 0 || p.Slowing <= 0 || len(kLines) < p.KLinesCount() {
+		return nil, nil
+	}
+	kLines = kLines[len(kLines)-p.KLinesCount():]
+	// raw %K for every kline which has full KPeriod lookback
+	rawK := make([]float64, len(kLines)-p.KPeriod+1)
+	for i := range rawK {
+		rawK[i] = PercentK(kLines[i : i+p.KPeriod])
+	}
+	slowK := smooth(rawK, p.Slowing)
+	dValues := smooth(slowK, p.DPeriod)
+	return slowK[len(slowK)-2:], dValues
+}
+
+// smooth returns simple moving averages over n for each full window of values
+func smooth(values []float64, n int) []float64 {
+	result := make([]float64, len(values)-n+1)
+	sum := 0.0
+	for i, v := range values {
+		sum += v
+		if i >= n {
+			sum -= values[i-n]
+		}
+		if i >= n-1 {
+			result[i-n+1] = sum / float64(n)
+		}
+	}
+	return result
+}
+
+func signal(k, d []float64, p Params) indicators.TradeSignal {
+	if k == nil {
+		return indicators.TradeNone
+	}
+	if k[0] <= d[0] && k[1] > d[1] && k[1] < p.Oversold && d[1] < p.Oversold {
+		return indicators.TradeBuy
+	}
+	if k[0] >= d[0] && k[1] < d[1] && k[1] > p.Overbought && d[1] > p.Overbought {
+		return indicators.TradeSell
+	}
+	return indicators.TradeNone
+}
+
+// Signal returns %K/%D cross signal inside overbought/oversold zones
+func Signal(kLines []klines.KLineEntry, p Params) indicators.TradeSignal {
+	k, d := KD(kLines, p)
+	return signal(k, d, p)
+}
+
+// Stochastic is fast stochastic: params[0] is KPeriod, params[1] is DPeriod
+func Stochastic(kLines []klines.KLineEntry, params []int) indicators.TradeSignal {
+	return Signal(kLines, Params{params[0], params[1], 1, DefaultOverbought, DefaultOversold})
+}
+
+// SlowStochastic is stochastic with %K smoothed over DefaultSlowing: params[0] is KPeriod, params[1] is DPeriod
+func SlowStochastic(kLines []klines.KLineEntry, params []int) indicators.TradeSignal {
+	return Signal(kLines, Params{params[0], params[1], DefaultSlowing, DefaultOverbought, DefaultOversold})
+}
@@ -0,0 +1,61 @@
+package stochastic
+
+import (
+	"github.com/okharch/binance/indicators"
+	"github.com/okharch/binance/klines"
+	"math"
+	"testing"
+)
+
+func makeKLines(prices []float64) []klines.KLineEntry {
+	kLines := make([]klines.KLineEntry, len(prices))
+	for i, price := range prices {
+		kLines[i] = klines.KLineEntry{HighPrice: price, LowPrice: price, ClosePrice: price}
+	}
+	return kLines
+}
+
+func TestPercentK(t *testing.T) {
+	kLines := makeKLines([]float64{10, 20, 15})
+	if k := PercentK(kLines); math.Abs(k-50) > 1e-9 {
+		t.Errorf("%%K: expected 50, got %v", k)
+	}
+	if r := PercentR(kLines); math.Abs(r+50) > 1e-9 {
+		t.Errorf("%%R: expected -50, got %v", r)
+	}
+}
+
+func TestStochasticSignal(t *testing.T) {
+	tests := []struct {
+		expected indicators.TradeSignal
+		prices   []float64
+	}{
+		// falls to the bottom of the range and turns up a bit: %K crosses %D below 20
+		{indicators.TradeBuy, []float64{20, 19, 18, 17, 16, 15, 14, 13, 12, 11, 10, 10.5}},
+		{indicators.TradeSell, []float64{10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20, 19.5}},
+		{indicators.TradeNone, []float64{10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20, 21}},
+	}
+	for _, test := range tests {
+		signal := Stochastic(makeKLines(test.prices), []int{5, 3})
+		if signal != test.expected {
+			t.Errorf("stochastic signal error: expected %v, but got %v", test.expected, signal)
+		}
+	}
+}
+
+func TestWilliamsR(t *testing.T) {
+	tests := []struct {
+		expected indicators.TradeSignal
+		prices   []float64
+	}{
+		{indicators.TradeBuy, []float64{20, 18, 16, 14, 12, 10, 15}},
+		{indicators.TradeSell, []float64{10, 12, 14, 16, 18, 20, 15}},
+		{indicators.TradeNone, []float64{10, 12, 14, 16, 18, 20, 22}},
+	}
+	for _, test := range tests {
+		signal := WilliamsR(makeKLines(test.prices), []int{5})
+		if signal != test.expected {
+			t.Errorf("williams %%R signal error: expected %v, but got %v", test.expected, signal)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package stochastic
+
+import (
+	"github.com/okharch/binance/indicators"
+	"github.com/okharch/binance/klines"
+)
+
+/*
+Williams %R:
+- %R = (highest high - close) / (highest high - lowest low) * -100 over the lookback, ranges -100..0.
+- TradeBuy when %R leaves oversold zone (crosses above Oversold level, default -80).
+- TradeSell when %R leaves overbought zone (crosses below Overbought level, default -20).
+*/
+
+const (
+	DefaultWROverbought = -20
+	DefaultWROversold   = -80
+)
+
+// PercentR returns Williams %R of the last kline using the whole kLines as lookback
+func PercentR(kLines []klines.KLineEntry) float64 {
+	return PercentK(kLines) - 100
+}
+
+// WilliamsRSignal needs lookBack+1 klines to compare %R of the two last klines
+func WilliamsRSignal(kLines []klines.KLineEntry, lookBack int, overbought, oversold float64) indicators.TradeSignal {
+	if lookBack <= 0 || len(kLines) < lookBack+1 {
+		return indicators.TradeNone
+	}
+	kLines = kLines[len(kLines)-lookBack-1:]
+	r1 := PercentR(kLines[:lookBack])
+	r2 := PercentR(kLines[1:])
+	if r1 <= oversold && r2 > oversold {
+		return indicators.TradeBuy
+	}
+	if r1 >= overbought && r2 < overbought {
+		return indicators.TradeSell
+	}
+	return indicators.TradeNone
+}
+
+// WilliamsR uses params[0] as lookback and default overbought/oversold levels
+func WilliamsR(kLines []klines.KLineEntry, params []int) indicators.TradeSignal {
+	return WilliamsRSignal(kLines, params[0], DefaultWROverbought, DefaultWROversold)
+}
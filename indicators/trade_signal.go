@@ -7,3 +7,15 @@ const (
 	TradeBuy
 	TradeSell
 )
+
+// String returns BUY, SELL or empty string for TradeNone, as stored in indicator_signal.side
+func (ts TradeSignal) String() string {
+	switch ts {
+	case TradeBuy:
+		return "BUY"
+	case TradeSell:
+		return "SELL"
+	default:
+		return ""
+	}
+}
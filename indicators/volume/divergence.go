@@ -0,0 +1,38 @@
+package volume
+
+import (
+	"github.com/okharch/binance/indicators"
+	"github.com/okharch/binance/klines"
+)
+
+/*
+Price/CVD divergence:
+- TradeBuy when price makes a lower close over the lookback while CVD grows (buyers absorb the selling).
+- TradeSell when price makes a higher close over the lookback while CVD falls (rally without taker buying).
+*/
+
+func cvdDivergence(kLines []klines.KLineEntry) indicators.TradeSignal {
+	n := len(kLines) - 1
+	if n <= 0 {
+		return indicators.TradeNone
+	}
+	priceChange := kLines[n].ClosePrice - kLines[0].ClosePrice
+	// CVD change over the window is the sum of deltas after the first kline
+	cvdChange := CVD(kLines[1:])
+	if priceChange < 0 && cvdChange > 0 {
+		return indicators.TradeBuy
+	}
+	if priceChange > 0 && cvdChange < 0 {
+		return indicators.TradeSell
+	}
+	return indicators.TradeNone
+}
+
+// CVDDivergence compares price and CVD over params[0] klines
+func CVDDivergence(kLines []klines.KLineEntry, params []int) indicators.TradeSignal {
+	lookBack := params[0]
+	if len(kLines) < lookBack {
+		return indicators.TradeNone
+	}
+	return cvdDivergence(kLines[len(kLines)-lookBack:])
+}
@@ -0,0 +1,21 @@
+package volume
+
+import "github.com/okharch/binance/indicators"
+
+func init() {
+	indicators.Register(indicators.Indicator{
+		Type:        indicators.IndicatorTypeCVDDivergence,
+		Name:        "CVDDivergence",
+		Params:      []indicators.Param{{Name: "lookBack", Min: 10, Max: 100, Step: 1}},
+		KLinesCount: indicators.KLinesPlus(0, 0),
+		Factory:     indicators.FuncFactory(CVDDivergence),
+	})
+	// spike is confirmed by the next kline, average is taken before the previous one
+	indicators.Register(indicators.Indicator{
+		Type:        indicators.IndicatorTypeVolumeSpike,
+		Name:        "VolumeSpike",
+		Params:      []indicators.Param{{Name: "avgPeriod", Min: 10, Max: 100, Step: 1}},
+		KLinesCount: indicators.KLinesPlus(0, 3),
+		Factory:     indicators.FuncFactory(VolumeSpike),
+	})
+}
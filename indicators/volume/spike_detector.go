@@ -0,0 +1,50 @@
+package volume
+
+import (
+	"github.com/okharch/binance/klines"
+	"time"
+)
+
+// SpikeDetector detects volume spikes on a live stream of 1m klines for a single symbol.
+// 1m klines are aggregated into klines of the detector's period,
+// detection runs each time a kline of the period is closed
+type SpikeDetector struct {
+	Period time.Duration
+	Params SpikeParams
+	// closed klines of the period, at most Params.KLinesCount() are kept
+	kLines  []klines.KLineEntry
+	current klines.KLineEntry
+	started bool
+}
+
+func NewSpikeDetector(period time.Duration, p SpikeParams) *SpikeDetector {
+	return &SpikeDetector{Period: period, Params: p}
+}
+
+// Add adds closed 1m kline and returns the spike if it was detected.
+// klines must be added in order of their open time
+func (d *SpikeDetector) Add(kLine1m klines.KLineEntry) (spike Spike, ok bool) {
+	periodMs := d.Period.Milliseconds()
+	start := kLine1m.OpenTime%periodMs == 0
+	if start && d.started {
+		spike, ok = d.closeKLine()
+	}
+	if start || d.started {
+		klines.Aggregate(&d.current, &kLine1m, start)
+		d.started = true
+	}
+	// the last 1m kline of the period closes the period's kline
+	if d.started && (kLine1m.CloseTime+1)%periodMs == 0 {
+		spike, ok = d.closeKLine()
+	}
+	return
+}
+
+func (d *SpikeDetector) closeKLine() (Spike, bool) {
+	d.kLines = append(d.kLines, d.current)
+	d.started = false
+	if n := d.Params.KLinesCount(); len(d.kLines) > n {
+		d.kLines = append(d.kLines[:0], d.kLines[len(d.kLines)-n:]...)
+	}
+	return DetectSpike(d.kLines, d.Params)
+}
@@ -0,0 +1,120 @@
+package volume
+
+import (
+	"fmt"
+	"github.com/okharch/binance/indicators"
+	"github.com/okharch/binance/klines"
+	"math"
+	"strconv"
+	"strings"
+)
+
+/*
+Volume spikes (Go port of trading_indicator.volume_spikes):
+a kline is a spike when its volume
+- is at least Multiplier times the average volume of AvgPeriod klines before its previous neighbour
+- is not less than the volume of its neighbours (previous and next kline)
+- and at least one of the neighbours is also above Multiplier times the average volume.
+Since the next kline is required, the spike is detected when the kline following it is closed,
+or the last kline of its price window if the window ends later.
+Signal direction is taken from taker volume of the spike: TradeBuy if taker buyers dominate, TradeSell otherwise.
+*/
+
+const (
+	DefaultSpikeAvgPeriod  = 60
+	DefaultSpikeMultiplier = 3
+	// the window is 2 klines before the spike, the spike and 3 klines after it,
+	// as price_vol_window(symbol, period, open_time - 2 periods, 6) of volume_spikes.sql
+	DefaultSpikeWindowBefore = 2
+	DefaultSpikeWindowAfter  = 3
+)
+
+type SpikeParams struct {
+	AvgPeriod    int
+	Multiplier   float64
+	WindowBefore int // number of klines before the spike to include into PriceVolWindow
+	WindowAfter  int // number of klines after the spike to include into PriceVolWindow, at least the next one
+}
+
+func DefaultSpikeParams() SpikeParams {
+	return SpikeParams{DefaultSpikeAvgPeriod, DefaultSpikeMultiplier, DefaultSpikeWindowBefore, DefaultSpikeWindowAfter}
+}
+
+// after returns the number of klines following the spike: the next one and the rest of the window
+func (p SpikeParams) after() int {
+	if p.WindowAfter > 1 {
+		return p.WindowAfter
+	}
+	return 1
+}
+
+// KLinesCount returns the number of klines DetectSpike needs: average period, the spike, its previous neighbour
+// and the klines after it
+func (p SpikeParams) KLinesCount() int {
+	n := p.AvgPeriod + 1
+	if p.WindowBefore > n {
+		n = p.WindowBefore
+	}
+	return n + 1 + p.after()
+}
+
+type Spike struct {
+	OpenTime       int64
+	ClosePrice     float64
+	Volume         float64
+	AvgVolume      float64
+	Signal         indicators.TradeSignal
+	PriceVolWindow string // close_price(volume) pairs around the spike, like price_vol_window SQL function
+}
+
+// DetectSpike checks whether the kline followed by the klines after the spike (see SpikeParams.WindowAfter)
+// is a volume spike, the window of the spike ends with the last kline
+func DetectSpike(kLines []klines.KLineEntry, p SpikeParams) (spike Spike, ok bool) {
+	if p.AvgPeriod <= 0 || len(kLines) < p.KLinesCount() {
+		return
+	}
+	n := len(kLines) - 1 - p.after() // index of spike candidate
+	kl := &kLines[n]
+	// average volume before the spike excluding its neighbours
+	avgVolume := klines.VolumeAvg(kLines[n-1-p.AvgPeriod : n-1])
+	threshold := avgVolume * p.Multiplier
+	prevVolume, nextVolume := kLines[n-1].Volume, kLines[n+1].Volume
+	if kl.Volume < threshold || kl.Volume < prevVolume || kl.Volume < nextVolume ||
+		math.Max(prevVolume, nextVolume) <= threshold {
+		return
+	}
+	spike = Spike{
+		OpenTime:       kl.OpenTime,
+		ClosePrice:     kl.ClosePrice,
+		Volume:         kl.Volume,
+		AvgVolume:      avgVolume,
+		Signal:         indicators.TradeSell,
+		PriceVolWindow: PriceVolWindow(kLines[n-p.WindowBefore:]),
+	}
+	if Delta(kl) > 0 {
+		spike.Signal = indicators.TradeBuy
+	}
+	return spike, true
+}
+
+// PriceVolWindow formats kLines as comma separated "close_price(volume)" pairs
+func PriceVolWindow(kLines []klines.KLineEntry) string {
+	pairs := make([]string, len(kLines))
+	for i, kl := range kLines {
+		pairs[i] = fmt.Sprintf("%s(%.0f)", strconv.FormatFloat(kl.ClosePrice, 'f', -1, 64), math.Round(kl.Volume))
+	}
+	return strings.Join(pairs, ", ")
+}
+
+// VolumeSpike uses params[0] as average period and params[1] as multiplier (default if 0)
+func VolumeSpike(kLines []klines.KLineEntry, params []int) indicators.TradeSignal {
+	p := SpikeParams{params[0], DefaultSpikeMultiplier, 0, 0}
+	if len(params) > 1 && params[1] > 0 {
+		p.Multiplier = float64(params[1])
+	}
+	spike, ok := DetectSpike(kLines, p)
+	if !ok {
+		return indicators.TradeNone
+	}
+	return spike.Signal
+}
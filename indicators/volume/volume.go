@@ -0,0 +1,78 @@
+package volume
+
+import (
+	"github.com/okharch/binance/klines"
+	"time"
+)
+
+/*
+Volume based indicators.
+Binance klines carry taker buy volume, so every bar can be split into
+buy pressure (taker buy) and sell pressure (volume - taker buy).
+- OBV adds the bar volume when close goes up and subtracts it when close goes down.
+- VWAP is the volume weighted average of the typical price (high+low+close)/3.
+- CVD (cumulative volume delta) accumulates taker buy volume minus taker sell volume.
+*/
+
+// Delta returns taker buy volume minus taker sell volume of the kline
+func Delta(kl *klines.KLineEntry) float64 {
+	return 2*kl.TakerBuyBaseAssetVolume - kl.Volume
+}
+
+// TypicalPrice returns (high+low+close)/3 of the kline
+func TypicalPrice(kl *klines.KLineEntry) float64 {
+	return (kl.HighPrice + kl.LowPrice + kl.ClosePrice) / 3
+}
+
+// OBV returns on-balance volume accumulated over kLines, starting from zero at the first kline
+func OBV(kLines []klines.KLineEntry) float64 {
+	obv := 0.0
+	for i := 1; i < len(kLines); i++ {
+		switch {
+		case kLines[i].ClosePrice > kLines[i-1].ClosePrice:
+			obv += kLines[i].Volume
+		case kLines[i].ClosePrice < kLines[i-1].ClosePrice:
+			obv -= kLines[i].Volume
+		}
+	}
+	return obv
+}
+
+// CVD returns cumulative volume delta accumulated over kLines
+func CVD(kLines []klines.KLineEntry) float64 {
+	cvd := 0.0
+	for i := range kLines {
+		cvd += Delta(&kLines[i])
+	}
+	return cvd
+}
+
+// VWAP returns rolling volume weighted average price over kLines.
+// returns -1 if there is no volume
+func VWAP(kLines []klines.KLineEntry) float64 {
+	var pv, v float64
+	for i := range kLines {
+		kl := &kLines[i]
+		pv += TypicalPrice(kl) * kl.Volume
+		v += kl.Volume
+	}
+	if v == 0 {
+		return -1
+	}
+	return pv / v
+}
+
+// SessionVWAP returns VWAP anchored at the start of the UTC day of the last kline.
+// kLines have to include the whole session, klines before the session start are skipped
+func SessionVWAP(kLines []klines.KLineEntry) float64 {
+	if len(kLines) == 0 {
+		return -1
+	}
+	const dayMs = int64(24 * time.Hour / time.Millisecond)
+	sessionStart := kLines[len(kLines)-1].OpenTime / dayMs * dayMs
+	i := len(kLines)
+	for i > 0 && kLines[i-1].OpenTime >= sessionStart {
+		i--
+	}
+	return VWAP(kLines[i:])
+}
@@ -0,0 +1,14 @@
+-- volume spikes detected by cmd/volume_spikes on live kline stream
+CREATE TABLE IF NOT EXISTS binance.volume_spikes (
+    symbol VARCHAR(20) NOT NULL,
+    period VARCHAR(4) NOT NULL,
+    open_time BIGINT NOT NULL, -- open time of the spike kline
+    close_price NUMERIC(18, 8) NOT NULL,
+    volume NUMERIC(28, 8) NOT NULL,
+    avg_volume NUMERIC(28, 8) NOT NULL,
+    trade_signal varchar(4) NOT NULL, -- BUY or SELL
+    price_vol_window text, -- close_price(volume) pairs around the spike
+    created_at timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (symbol, period, open_time)
+);
+COMMENT ON TABLE binance.volume_spikes IS 'Volume spikes detected on live kline stream, Go port of trading_indicator.volume_spikes.';
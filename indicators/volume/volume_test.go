@@ -0,0 +1,116 @@
+package volume
+
+import (
+	"github.com/okharch/binance/indicators"
+	"github.com/okharch/binance/klines"
+	"math"
+	"testing"
+	"time"
+)
+
+func makeKLines(prices, volumes, takerBuy []float64) []klines.KLineEntry {
+	kLines := make([]klines.KLineEntry, len(prices))
+	for i, price := range prices {
+		kLines[i] = klines.KLineEntry{
+			OpenTime:                int64(i) * 60000,
+			HighPrice:               price,
+			LowPrice:                price,
+			ClosePrice:              price,
+			Volume:                  volumes[i],
+			TakerBuyBaseAssetVolume: takerBuy[i],
+		}
+	}
+	return kLines
+}
+
+func TestOBVAndCVD(t *testing.T) {
+	kLines := makeKLines(
+		[]float64{10, 11, 10, 10, 12},
+		[]float64{5, 10, 4, 7, 3},
+		[]float64{2, 8, 1, 3, 3},
+	)
+	if obv := OBV(kLines); obv != 10-4+3 {
+		t.Errorf("OBV: expected %v, got %v", 9, obv)
+	}
+	// deltas: -1, 6, -2, -1, 3
+	if cvd := CVD(kLines); cvd != 5 {
+		t.Errorf("CVD: expected %v, got %v", 5, cvd)
+	}
+	expected := (10*5 + 11*10 + 10*4 + 10*7 + 12*3) / 29.0
+	if vwap := VWAP(kLines); math.Abs(vwap-expected) > 1e-9 {
+		t.Errorf("VWAP: expected %v, got %v", expected, vwap)
+	}
+}
+
+func TestCVDDivergence(t *testing.T) {
+	tests := []struct {
+		expected indicators.TradeSignal
+		prices   []float64
+		takerBuy []float64
+	}{
+		{indicators.TradeBuy, []float64{10, 9, 8}, []float64{5, 8, 8}},
+		{indicators.TradeSell, []float64{8, 9, 10}, []float64{5, 2, 2}},
+		{indicators.TradeNone, []float64{8, 9, 10}, []float64{5, 8, 8}},
+	}
+	volumes := []float64{10, 10, 10}
+	for _, test := range tests {
+		signal := CVDDivergence(makeKLines(test.prices, volumes, test.takerBuy), []int{3})
+		if signal != test.expected {
+			t.Errorf("CVD divergence signal error: expected %v, but got %v", test.expected, signal)
+		}
+	}
+}
+
+func TestDetectSpike(t *testing.T) {
+	p := SpikeParams{AvgPeriod: 4, Multiplier: 3, WindowBefore: 2}
+	volumes := []float64{10, 10, 10, 10, 10, 35, 50, 40}
+	takerBuy := []float64{5, 5, 5, 5, 5, 20, 40, 10}
+	prices := []float64{1, 1.5, 1.25, 1, 1.1, 1.2, 1.3, 1.4}
+	spike, ok := DetectSpike(makeKLines(prices, volumes, takerBuy), p)
+	if !ok {
+		t.Fatalf("expected spike to be detected")
+	}
+	if spike.Volume != 50 || spike.Signal != indicators.TradeBuy {
+		t.Errorf("invalid spike %+v", spike)
+	}
+	if expected := "1.1(10), 1.2(35), 1.3(50), 1.4(40)"; spike.PriceVolWindow != expected {
+		t.Errorf("price window: expected %q, got %q", expected, spike.PriceVolWindow)
+	}
+	// the window of 3 klines after the spike as in volume_spikes.sql
+	p.WindowAfter = 3
+	spike, ok = DetectSpike(makeKLines(append(prices, 1.5, 1.6), append(volumes, 20, 15),
+		append(takerBuy, 10, 10)), p)
+	if !ok || spike.Volume != 50 {
+		t.Fatalf("expected spike to be detected with the window after it, got %+v", spike)
+	}
+	if expected := "1.1(10), 1.2(35), 1.3(50), 1.4(40), 1.5(20), 1.6(15)"; spike.PriceVolWindow != expected {
+		t.Errorf("price window: expected %q, got %q", expected, spike.PriceVolWindow)
+	}
+	p.WindowAfter = 0
+	// the next kline volume is greater than the candidate one
+	volumes[7] = 60
+	if _, ok := DetectSpike(makeKLines(prices, volumes, takerBuy), p); ok {
+		t.Errorf("expected no spike")
+	}
+}
+
+func TestSpikeDetector(t *testing.T) {
+	d := NewSpikeDetector(time.Minute*5, SpikeParams{AvgPeriod: 4, Multiplier: 3})
+	spikes := 0
+	// 5m klines volumes
+	for i, v := range []float64{10, 10, 10, 10, 10, 70, 100, 40} {
+		for m := 0; m < 5; m++ {
+			openTime := int64(i*5+m) * 60000
+			kl := klines.KLineEntry{OpenTime: openTime, CloseTime: openTime + 59999, ClosePrice: 1, Volume: v / 5}
+			if spike, ok := d.Add(kl); ok {
+				spikes++
+				if spike.OpenTime != 6*5*60000 {
+					t.Errorf("invalid spike open time %d", spike.OpenTime)
+				}
+			}
+		}
+	}
+	if spikes != 1 {
+		t.Errorf("expected 1 spike, got %d", spikes)
+	}
+}
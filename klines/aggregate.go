@@ -0,0 +1,53 @@
+package klines
+
+import (
+	"math"
+	"time"
+)
+
+// Aggregate merges 1m kline into the kline of a larger period.
+// start should be true for the first 1m kline of the period
+func Aggregate(kLine, kLine1m *KLineEntry, start bool) {
+	if start {
+		// initialize the open price and time and other aggregate values for the new period
+		kLine.OpenPrice = kLine1m.OpenPrice
+		kLine.OpenTime = kLine1m.OpenTime
+		kLine.HighPrice = kLine1m.HighPrice
+		kLine.LowPrice = kLine1m.LowPrice
+		kLine.Volume = kLine1m.Volume
+		kLine.QuoteAssetVolume = kLine1m.QuoteAssetVolume
+		kLine.NumTrades = kLine1m.NumTrades
+		kLine.TakerBuyBaseAssetVolume = kLine1m.TakerBuyBaseAssetVolume
+		kLine.TakerBuyQuoteAssetVolume = kLine1m.TakerBuyQuoteAssetVolume
+	} else {
+		// aggregate the kLine data if we're not at the start of a new period
+		kLine.HighPrice = math.Max(kLine.HighPrice, kLine1m.HighPrice)
+		kLine.LowPrice = math.Min(kLine.LowPrice, kLine1m.LowPrice)
+		kLine.Volume += kLine1m.Volume
+		kLine.QuoteAssetVolume += kLine1m.QuoteAssetVolume
+		kLine.NumTrades += kLine1m.NumTrades
+		kLine.TakerBuyBaseAssetVolume += kLine1m.TakerBuyBaseAssetVolume
+		kLine.TakerBuyQuoteAssetVolume += kLine1m.TakerBuyQuoteAssetVolume
+	}
+
+	// update the close price and time for the kLine
+	kLine.ClosePrice = kLine1m.ClosePrice
+	kLine.CloseTime = kLine1m.CloseTime
+}
+
+// AggregatePeriod aggregates 1m klines into klines of the period.
+// the period is aligned to the open time of 1m klines by PeriodStart, i.e. 1h klines start at the beginning
+// of the hour and 1w klines on Monday
+func AggregatePeriod(data1m []KLineEntry, period time.Duration) (result []KLineEntry) {
+	var periodStart int64
+	for i := range data1m {
+		kl := &data1m[i]
+		start := len(result) == 0 || PeriodStart(kl.OpenTime, period) != periodStart
+		if start {
+			periodStart = PeriodStart(kl.OpenTime, period)
+			result = append(result, KLineEntry{})
+		}
+		Aggregate(&result[len(result)-1], kl, start)
+	}
+	return
+}
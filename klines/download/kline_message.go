@@ -0,0 +1,73 @@
+package download
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"github.com/okharch/binance/klines"
+	"strconv"
+)
+
+// KlineMessage is kline event of combined web socket stream
+type KlineMessage struct {
+	Symbol string
+	Period string
+	Closed bool // kline is final
+	KLine  klines.KLineEntry
+}
+
+type wsKlineEvent struct {
+	Data struct {
+		K struct {
+			Symbol    string `json:"s"`
+			Period    string `json:"i"`
+			OpenTime  int64  `json:"t"`
+			CloseTime int64  `json:"T"`
+			Open      string `json:"o"`
+			High      string `json:"h"`
+			Low       string `json:"l"`
+			Close     string `json:"c"`
+			Volume    string `json:"v"`
+			NumTrades int64  `json:"n"`
+			Closed    bool   `json:"x"`
+			QuoteVol  string `json:"q"`
+			TakerBase string `json:"V"`
+			TakerQuot string `json:"Q"`
+		} `json:"k"`
+	} `json:"data"`
+}
+
+// ParseKlineMessage parses message received from kline web socket stream
+func ParseKlineMessage(msg []byte) (km KlineMessage, err error) {
+	var event wsKlineEvent
+	if err = json.Unmarshal(msg, &event); err != nil {
+		return km, fmt.Errorf("failed to parse kline message: %w", err)
+	}
+	k := &event.Data.K
+	km.Symbol, km.Period, km.Closed = k.Symbol, k.Period, k.Closed
+	kl := &km.KLine
+	kl.OpenTime, kl.CloseTime, kl.NumTrades = k.OpenTime, k.CloseTime, k.NumTrades
+	for _, f := range []struct {
+		dst *float64
+		src string
+	}{
+		{&kl.OpenPrice, k.Open}, {&kl.HighPrice, k.High}, {&kl.LowPrice, k.Low}, {&kl.ClosePrice, k.Close},
+		{&kl.Volume, k.Volume}, {&kl.QuoteAssetVolume, k.QuoteVol},
+		{&kl.TakerBuyBaseAssetVolume, k.TakerBase}, {&kl.TakerBuyQuoteAssetVolume, k.TakerQuot},
+	} {
+		if *f.dst, err = strconv.ParseFloat(f.src, 64); err != nil {
+			return km, fmt.Errorf("failed to parse kline message %s: %w", msg, err)
+		}
+	}
+	return km, nil
+}
+
+// WatchKlines returns web socket stream of 1m klines for watched symbols
+func WatchKlines(ctx context.Context, db *sqlx.DB) (<-chan []byte, error) {
+	symbols, err := fetchWatchedSymbols(db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch symbols: %v", err)
+	}
+	return getBinanceWebSocketKlines(ctx, symbols)
+}
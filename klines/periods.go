@@ -78,3 +78,21 @@ func Duration2Period(duration time.Duration) string {
 		return "1m"
 	}
 }
+
+// weekOffset is the offset of weekly klines from the Unix epoch: it is Thursday, Binance weeks start on Monday
+const weekOffset = 4 * 24 * time.Hour
+
+// PeriodStart returns the open time of the kline of the period containing time t, times are in milliseconds.
+// the klines are aligned to the Unix epoch, except the weekly ones which start on Monday like the ones of Binance
+func PeriodStart(t int64, period time.Duration) int64 {
+	ms := period.Milliseconds()
+	var offset int64
+	if period == 7*24*time.Hour {
+		offset = weekOffset.Milliseconds()
+	}
+	start := (t-offset)/ms*ms + offset
+	if start > t {
+		start -= ms // t is before the first week after the epoch
+	}
+	return start
+}
@@ -0,0 +1,27 @@
+package klines
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAggregatePeriodWeeks(t *testing.T) {
+	// 2023-03-01 is Wednesday, the week started on Monday 2023-02-27
+	monday := time.Date(2023, 2, 27, 0, 0, 0, 0, time.UTC).UnixMilli()
+	var data1m []KLineEntry
+	for _, day := range []int{2, 6, 7, 8} {
+		openTime := monday + int64(day)*24*time.Hour.Milliseconds()
+		data1m = append(data1m, KLineEntry{OpenTime: openTime, CloseTime: openTime + 59999, Volume: 1})
+	}
+	week := 7 * 24 * time.Hour
+	result := AggregatePeriod(data1m, week)
+	if len(result) != 2 || result[0].Volume != 2 || result[1].Volume != 2 {
+		t.Fatalf("expected 2 weeks of 2 klines, got %+v", result)
+	}
+	if start := PeriodStart(data1m[0].OpenTime, week); start != monday {
+		t.Errorf("expected the week to start on %v, got %v", time.UnixMilli(monday).UTC(), time.UnixMilli(start).UTC())
+	}
+	if start := PeriodStart(0, week); start != -3*24*time.Hour.Milliseconds() {
+		t.Errorf("expected the week of the epoch to start on Monday 1969-12-29, got %v", time.UnixMilli(start).UTC())
+	}
+}
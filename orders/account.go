@@ -0,0 +1,105 @@
+package orders
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Trade is the trade of the account as it is returned by /api/v3/myTrades
+type Trade struct {
+	Symbol          string  `json:"symbol"`
+	Id              int64   `json:"id"`
+	OrderId         int64   `json:"orderId"`
+	OrderListId     int64   `json:"orderListId"`
+	Price           float64 `json:"price,string"`
+	Qty             float64 `json:"qty,string"`
+	QuoteQty        float64 `json:"quoteQty,string"`
+	Commission      float64 `json:"commission,string"`
+	CommissionAsset string  `json:"commissionAsset"`
+	Time            int64   `json:"time"`
+	IsBuyer         bool    `json:"isBuyer"`
+	IsMaker         bool    `json:"isMaker"`
+}
+
+// MyTrades returns up to limit trades of the symbol starting from trade id fromId
+func (c *Client) MyTrades(ctx context.Context, symbol string, fromId int64, limit int) ([]Trade, error) {
+	params := url.Values{"symbol": {symbol}, "fromId": {strconv.FormatInt(fromId, 10)},
+		"limit": {strconv.Itoa(limit)}}
+	var result []Trade
+	err := c.do(ctx, http.MethodGet, "/api/v3/myTrades", params, &result)
+	return result, err
+}
+
+// Deposit statuses: 0 pending, 6 credited but cannot withdraw, 1 success
+const DepositSuccess = 1
+
+type Deposit struct {
+	Id         string  `json:"id"`
+	Amount     float64 `json:"amount,string"`
+	Coin       string  `json:"coin"`
+	Network    string  `json:"network"`
+	Status     int     `json:"status"`
+	TxId       string  `json:"txId"`
+	InsertTime int64   `json:"insertTime"`
+}
+
+// Withdrawal statuses: 0 email sent, 1 cancelled, 2 awaiting approval, 3 rejected, 4 processing, 5 failure, 6 completed
+const WithdrawalCompleted = 6
+
+type Withdrawal struct {
+	Id             string  `json:"id"`
+	Amount         float64 `json:"amount,string"`
+	TransactionFee float64 `json:"transactionFee,string"`
+	Coin           string  `json:"coin"`
+	Network        string  `json:"network"`
+	Status         int     `json:"status"`
+	TxId           string  `json:"txId"`
+	ApplyTime      string  `json:"applyTime"` // UTC like 2019-10-12 11:12:02
+}
+
+// MaxHistoryWindow is the longest period of deposit and withdrawal history requests
+const MaxHistoryWindow = 90 * 24 * time.Hour
+
+// Deposits returns up to limit deposits from offset inserted between start and end, see MaxHistoryWindow
+func (c *Client) Deposits(ctx context.Context, start, end time.Time, offset, limit int) ([]Deposit, error) {
+	var result []Deposit
+	err := c.do(ctx, http.MethodGet, "/sapi/v1/capital/deposit/hisrec", historyParams(start, end, offset, limit), &result)
+	return result, err
+}
+
+// Withdrawals returns up to limit withdrawals from offset applied between start and end, see MaxHistoryWindow
+func (c *Client) Withdrawals(ctx context.Context, start, end time.Time, offset, limit int) ([]Withdrawal, error) {
+	var result []Withdrawal
+	err := c.do(ctx, http.MethodGet, "/sapi/v1/capital/withdraw/history", historyParams(start, end, offset, limit), &result)
+	return result, err
+}
+
+func historyParams(start, end time.Time, offset, limit int) url.Values {
+	return url.Values{"startTime": {strconv.FormatInt(start.UnixMilli(), 10)},
+		"endTime": {strconv.FormatInt(end.UnixMilli(), 10)}, "offset": {strconv.Itoa(offset)},
+		"limit": {strconv.Itoa(limit)}}
+}
+
+type AccountBalance struct {
+	Asset  string  `json:"asset"`
+	Free   float64 `json:"free,string"`
+	Locked float64 `json:"locked,string"`
+}
+
+// Account is the spot account as it is returned by /api/v3/account
+type Account struct {
+	CanTrade    bool             `json:"canTrade"`
+	AccountType string           `json:"accountType"`
+	UpdateTime  int64            `json:"updateTime"`
+	Balances    []AccountBalance `json:"balances"`
+}
+
+// Account returns the account with non-zero balances
+func (c *Client) Account(ctx context.Context) (Account, error) {
+	var result Account
+	err := c.do(ctx, http.MethodGet, "/api/v3/account", url.Values{"omitZeroBalances": {"true"}}, &result)
+	return result, err
+}
@@ -0,0 +1,321 @@
+package orders
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/okharch/binance/request"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const DefaultBaseURL = "https://api.binance.com"
+
+// Client calls signed and api key endpoints of Binance spot REST API
+type Client struct {
+	BaseURL    string
+	APIKey     string
+	SecretKey  string
+	RecvWindow time.Duration
+	HTTP       *http.Client
+	// TimeOffset is added to the local time of signed requests, it is server time minus local one
+	TimeOffset time.Duration
+}
+
+func NewClient(apiKey, secretKey string) *Client {
+	return &Client{BaseURL: DefaultBaseURL, APIKey: apiKey, SecretKey: secretKey, RecvWindow: 5 * time.Second,
+		HTTP: http.DefaultClient}
+}
+
+// NewClientFromEnv returns the client with keys of BINANCE_API_KEY and BINANCE_SECRET_KEY environment variables
+func NewClientFromEnv() (*Client, error) {
+	apiKey, secretKey := os.Getenv("BINANCE_API_KEY"), os.Getenv("BINANCE_SECRET_KEY")
+	if apiKey == "" || secretKey == "" {
+		return nil, errors.New("BINANCE_API_KEY and BINANCE_SECRET_KEY environment variables are not set")
+	}
+	return NewClient(apiKey, secretKey), nil
+}
+
+// APIError is the error returned by the exchange
+type APIError struct {
+	StatusCode int    `json:"-"`
+	Code       int    `json:"code"`
+	Msg        string `json:"msg"`
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("binance error %d (http %d): %s", e.Code, e.StatusCode, e.Msg)
+}
+
+// Rejected is true if the request was rejected, otherwise (5xx) its execution status is unknown
+func (e *APIError) Rejected() bool {
+	return e.StatusCode < 500
+}
+
+const (
+	codeCancelRejected = -2011
+	codeNoSuchOrder    = -2013
+)
+
+// IsUnknownOrder returns true if the exchange does not know the order
+func IsUnknownOrder(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return apiErr.Code == codeNoSuchOrder || apiErr.Code == codeCancelRejected && strings.Contains(apiErr.Msg, "Unknown order")
+}
+
+// OrderRequest is the new order, the params required depend on the type:
+//
+//	LIMIT                                - Quantity, Price
+//	MARKET                               - Quantity or QuoteOrderQty
+//	STOP_LOSS_LIMIT, TAKE_PROFIT_LIMIT   - Quantity, Price, StopPrice
+type OrderRequest struct {
+	Symbol        string
+	Side          Side
+	Type          Type
+	TimeInForce   TimeInForce // GTC if it is required and empty
+	Quantity      float64
+	QuoteOrderQty float64
+	Price         float64
+	StopPrice     float64
+	ClientOrderId string
+}
+
+func (r OrderRequest) params() (url.Values, error) {
+	params := url.Values{}
+	params.Set("symbol", r.Symbol)
+	params.Set("side", string(r.Side))
+	params.Set("type", string(r.Type))
+	params.Set("newClientOrderId", r.ClientOrderId)
+	params.Set("newOrderRespType", "RESULT")
+	switch r.Type {
+	case Market:
+		if (r.Quantity > 0) == (r.QuoteOrderQty > 0) {
+			return nil, errors.New("market order requires either quantity or quote order quantity")
+		}
+		if r.Quantity > 0 {
+			params.Set("quantity", formatFloat(r.Quantity))
+		} else {
+			params.Set("quoteOrderQty", formatFloat(r.QuoteOrderQty))
+		}
+		return params, nil
+	case Limit, StopLossLimit, TakeProfitLimit:
+	default:
+		return nil, fmt.Errorf("unsupported order type %s", r.Type)
+	}
+	if r.Quantity <= 0 || r.Price <= 0 {
+		return nil, fmt.Errorf("%s order requires quantity and price", r.Type)
+	}
+	if r.Type != Limit {
+		if r.StopPrice <= 0 {
+			return nil, fmt.Errorf("%s order requires stop price", r.Type)
+		}
+		params.Set("stopPrice", formatFloat(r.StopPrice))
+	}
+	timeInForce := r.TimeInForce
+	if timeInForce == "" {
+		timeInForce = GTC
+	}
+	params.Set("timeInForce", string(timeInForce))
+	params.Set("quantity", formatFloat(r.Quantity))
+	params.Set("price", formatFloat(r.Price))
+	return params, nil
+}
+
+// OCORequest is the pair of a limit maker order at Price and a stop loss limit order at StopPrice and StopLimitPrice
+type OCORequest struct {
+	Symbol               string
+	Side                 Side
+	Quantity             float64
+	Price                float64
+	StopPrice            float64
+	StopLimitPrice       float64
+	StopLimitTimeInForce TimeInForce // GTC if empty
+	ListClientOrderId    string
+	LimitClientOrderId   string
+	StopClientOrderId    string
+}
+
+func (r OCORequest) params() (url.Values, error) {
+	if r.Quantity <= 0 || r.Price <= 0 || r.StopPrice <= 0 || r.StopLimitPrice <= 0 {
+		return nil, errors.New("OCO order requires quantity, price, stop price and stop limit price")
+	}
+	timeInForce := r.StopLimitTimeInForce
+	if timeInForce == "" {
+		timeInForce = GTC
+	}
+	params := url.Values{}
+	params.Set("symbol", r.Symbol)
+	params.Set("side", string(r.Side))
+	params.Set("quantity", formatFloat(r.Quantity))
+	params.Set("price", formatFloat(r.Price))
+	params.Set("stopPrice", formatFloat(r.StopPrice))
+	params.Set("stopLimitPrice", formatFloat(r.StopLimitPrice))
+	params.Set("stopLimitTimeInForce", string(timeInForce))
+	params.Set("listClientOrderId", r.ListClientOrderId)
+	params.Set("limitClientOrderId", r.LimitClientOrderId)
+	params.Set("stopClientOrderId", r.StopClientOrderId)
+	params.Set("newOrderRespType", "RESULT")
+	return params, nil
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// NewOrder places the order
+func (c *Client) NewOrder(ctx context.Context, r OrderRequest) (Order, error) {
+	params, err := r.params()
+	if err != nil {
+		return Order{}, err
+	}
+	var o Order
+	err = c.do(ctx, http.MethodPost, "/api/v3/order", params, &o)
+	o.normalize()
+	return o, err
+}
+
+// NewOCO places OCO order
+func (c *Client) NewOCO(ctx context.Context, r OCORequest) (OrderList, error) {
+	params, err := r.params()
+	if err != nil {
+		return OrderList{}, err
+	}
+	var list OrderList
+	err = c.do(ctx, http.MethodPost, "/api/v3/order/oco", params, &list)
+	for i := range list.Orders {
+		list.Orders[i].normalize()
+	}
+	return list, err
+}
+
+// CancelOrder cancels the order by its client order id
+func (c *Client) CancelOrder(ctx context.Context, symbol, clientOrderId string) (Order, error) {
+	params := url.Values{"symbol": {symbol}, "origClientOrderId": {clientOrderId}}
+	var o Order
+	err := c.do(ctx, http.MethodDelete, "/api/v3/order", params, &o)
+	o.normalize()
+	return o, err
+}
+
+// CancelOCO cancels both orders of OCO by its list client order id
+func (c *Client) CancelOCO(ctx context.Context, symbol, listClientOrderId string) (OrderList, error) {
+	params := url.Values{"symbol": {symbol}, "listClientOrderId": {listClientOrderId}}
+	var list OrderList
+	err := c.do(ctx, http.MethodDelete, "/api/v3/orderList", params, &list)
+	for i := range list.Orders {
+		list.Orders[i].normalize()
+	}
+	return list, err
+}
+
+// QueryOrder returns the order by its client order id
+func (c *Client) QueryOrder(ctx context.Context, symbol, clientOrderId string) (Order, error) {
+	params := url.Values{"symbol": {symbol}, "origClientOrderId": {clientOrderId}}
+	var o Order
+	err := c.do(ctx, http.MethodGet, "/api/v3/order", params, &o)
+	o.normalize()
+	return o, err
+}
+
+// OpenOrders returns open orders of the symbol, of all symbols if it is empty
+func (c *Client) OpenOrders(ctx context.Context, symbol string) ([]Order, error) {
+	params := url.Values{}
+	if symbol != "" {
+		params.Set("symbol", symbol)
+	}
+	var result []Order
+	err := c.do(ctx, http.MethodGet, "/api/v3/openOrders", params, &result)
+	for i := range result {
+		result[i].normalize()
+	}
+	return result, err
+}
+
+// errRateLimited is returned by sendOnce if the request was rejected by 429 and has to be sent again
+var errRateLimited = errors.New("rate limited")
+
+// do sends the signed request and decodes json response into result,
+// the request is signed again with the new timestamp when it is retried after the rate limit
+func (c *Client) do(ctx context.Context, method, path string, params url.Values, result any) error {
+	if c.RecvWindow > 0 {
+		params.Set("recvWindow", strconv.FormatInt(c.RecvWindow.Milliseconds(), 10))
+	}
+	for {
+		params.Set("timestamp", strconv.FormatInt(time.Now().Add(c.TimeOffset).UnixMilli(), 10))
+		query := params.Encode()
+		err := c.sendOnce(ctx, method, path, query+"&signature="+Sign(c.SecretKey, query), result)
+		if !errors.Is(err, errRateLimited) {
+			return err
+		}
+	}
+}
+
+// send sends the request with the api key only and decodes json response into result
+func (c *Client) send(ctx context.Context, method, path, query string, result any) error {
+	for {
+		err := c.sendOnce(ctx, method, path, query, result)
+		if !errors.Is(err, errRateLimited) {
+			return err
+		}
+	}
+}
+
+// sendOnce sends the request within the rate limit shared with the request package:
+// it waits for the weight used by all requests and adjusts it by the response
+func (c *Client) sendOnce(ctx context.Context, method, path, query string, result any) error {
+	if !request.WaitApiLimit(ctx) {
+		return ctx.Err()
+	}
+	target := c.BaseURL + path
+	if query != "" {
+		target += "?" + query
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", request.UserAgent)
+	req.Header.Set("X-MBX-APIKEY", c.APIKey)
+	res, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if request.AdjustApiLimit(res) {
+		return errRateLimited
+	}
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode != http.StatusOK {
+		apiErr := &APIError{StatusCode: res.StatusCode}
+		if err := json.Unmarshal(body, apiErr); err != nil || apiErr.Code == 0 {
+			return fmt.Errorf("%s %s failed with status %s: %s", method, path, res.Status, body)
+		}
+		return apiErr
+	}
+	if err := json.Unmarshal(body, result); err != nil {
+		return fmt.Errorf("failed to decode response of %s %s: %w", method, path, err)
+	}
+	return nil
+}
+
+// Sign returns hex encoded HMAC SHA256 signature of the payload
+func Sign(secretKey, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secretKey))
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,30 @@
+package orders
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// listen key of the user data stream expires in 60 minutes unless it is kept alive
+
+// CreateListenKey starts a new user data stream and returns its listen key
+func (c *Client) CreateListenKey(ctx context.Context) (string, error) {
+	var result struct {
+		ListenKey string `json:"listenKey"`
+	}
+	err := c.send(ctx, http.MethodPost, "/api/v3/userDataStream", "", &result)
+	return result.ListenKey, err
+}
+
+// KeepAliveListenKey extends validity of the listen key for 60 minutes
+func (c *Client) KeepAliveListenKey(ctx context.Context, listenKey string) error {
+	var result struct{}
+	return c.send(ctx, http.MethodPut, "/api/v3/userDataStream", url.Values{"listenKey": {listenKey}}.Encode(), &result)
+}
+
+// CloseListenKey closes the user data stream
+func (c *Client) CloseListenKey(ctx context.Context, listenKey string) error {
+	var result struct{}
+	return c.send(ctx, http.MethodDelete, "/api/v3/userDataStream", url.Values{"listenKey": {listenKey}}.Encode(), &result)
+}
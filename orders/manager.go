@@ -0,0 +1,411 @@
+package orders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Manager places orders and tracks their state, every change is persisted to the store
+type Manager struct {
+	client    *Client
+	store     Store
+	prefix    string
+	validator Validator
+	onChange  func(o Order)
+	mu        sync.Mutex
+	orders    map[string]*Order // by client order id
+	seq       int64
+}
+
+// Validator checks orders before they are sent, openOrders is the number of open orders of the symbol
+type Validator interface {
+	ValidateOrder(r OrderRequest, openOrders int) error
+	ValidateOCO(r OCORequest, openOrders int) error
+}
+
+// Releaser is implemented by the validators which reserve something for the accepted orders, e.g. the risk limits,
+// Release is called with the client order id of the accepted order which is not sent
+type Releaser interface {
+	Release(clientOrderId string)
+}
+
+// Validators checks orders by every validator, the first error is returned,
+// the order rejected by a validator is released by the validators which accepted it
+type Validators []Validator
+
+func (vs Validators) ValidateOrder(r OrderRequest, openOrders int) error {
+	for i, v := range vs {
+		if err := v.ValidateOrder(r, openOrders); err != nil {
+			vs[:i].Release(r.ClientOrderId)
+			return err
+		}
+	}
+	return nil
+}
+
+func (vs Validators) ValidateOCO(r OCORequest, openOrders int) error {
+	for i, v := range vs {
+		if err := v.ValidateOCO(r, openOrders); err != nil {
+			vs[:i].release(r)
+			return err
+		}
+	}
+	return nil
+}
+
+// Release releases the order by every validator which is Releaser
+func (vs Validators) Release(clientOrderId string) {
+	for _, v := range vs {
+		if r, ok := v.(Releaser); ok {
+			r.Release(clientOrderId)
+		}
+	}
+}
+
+// release releases both orders of OCO
+func (vs Validators) release(r OCORequest) {
+	vs.Release(r.LimitClientOrderId)
+	vs.Release(r.StopClientOrderId)
+}
+
+// NewManager returns the manager, prefix distinguishes client order ids of the application,
+// call Reconcile to load the orders of the previous run
+func NewManager(client *Client, store Store, prefix string) *Manager {
+	return &Manager{client: client, store: store, prefix: prefix, orders: map[string]*Order{}}
+}
+
+// SetValidator sets the validator of orders, the orders it rejects are not sent nor persisted.
+// if the validator is Releaser the accepted orders which are not sent are released by it
+func (m *Manager) SetValidator(v Validator) {
+	m.validator = v
+}
+
+// release releases the accepted order which is not sent by the validator
+func (m *Manager) release(clientOrderId string) {
+	if r, ok := m.validator.(Releaser); ok {
+		r.Release(clientOrderId)
+	}
+}
+
+// SetOnChange sets the function called with every change of an order after it is persisted, e.g. fills and cancels
+func (m *Manager) SetOnChange(f func(o Order)) {
+	m.onChange = f
+}
+
+// NewClientOrderId returns unique client order id: prefix, time in base 36 and the sequence number
+func (m *Manager) NewClientOrderId() string {
+	m.mu.Lock()
+	m.seq++
+	seq := m.seq
+	m.mu.Unlock()
+	return fmt.Sprintf("%s%s%s", m.prefix, strconv.FormatInt(time.Now().UnixMilli(), 36), strconv.FormatInt(seq, 36))
+}
+
+// Place persists the order as PENDING_NEW and sends it.
+// if the request fails without the answer of the exchange the order stays PENDING_NEW until Reconcile
+func (m *Manager) Place(ctx context.Context, r OrderRequest) (Order, error) {
+	if r.ClientOrderId == "" {
+		r.ClientOrderId = m.NewClientOrderId()
+	}
+	if _, err := r.params(); err != nil {
+		return Order{}, err
+	}
+	if m.validator != nil {
+		if err := m.validator.ValidateOrder(r, m.openOrders(r.Symbol)); err != nil {
+			return Order{}, err
+		}
+	}
+	pending := Order{Symbol: r.Symbol, OrderListId: -1, ClientOrderId: r.ClientOrderId, Side: r.Side, Type: r.Type,
+		TimeInForce: r.TimeInForce, Price: r.Price, StopPrice: r.StopPrice, OrigQty: r.Quantity,
+		Status: StatusPendingNew, UpdateTime: time.Now().UnixMilli()}
+	if _, err := m.Apply(ctx, pending); err != nil {
+		m.release(r.ClientOrderId)
+		return pending, err
+	}
+	o, err := m.client.NewOrder(ctx, r)
+	if err != nil {
+		return m.rejected(ctx, pending, err)
+	}
+	o.Price, o.StopPrice = r.Price, r.StopPrice // not returned by RESULT response
+	_, err = m.Apply(ctx, o)
+	return m.current(o), err
+}
+
+// PlaceOCO persists both orders of OCO as PENDING_NEW and sends it
+func (m *Manager) PlaceOCO(ctx context.Context, r OCORequest) (OrderList, error) {
+	if r.ListClientOrderId == "" {
+		r.ListClientOrderId = m.NewClientOrderId()
+	}
+	if r.LimitClientOrderId == "" {
+		r.LimitClientOrderId = m.NewClientOrderId()
+	}
+	if r.StopClientOrderId == "" {
+		r.StopClientOrderId = m.NewClientOrderId()
+	}
+	if _, err := r.params(); err != nil {
+		return OrderList{}, err
+	}
+	if m.validator != nil {
+		if err := m.validator.ValidateOCO(r, m.openOrders(r.Symbol)); err != nil {
+			return OrderList{}, err
+		}
+	}
+	now := time.Now().UnixMilli()
+	legs := []Order{
+		{Symbol: r.Symbol, OrderListId: -1, ClientOrderId: r.LimitClientOrderId, Side: r.Side, Type: LimitMaker,
+			Price: r.Price, OrigQty: r.Quantity, Status: StatusPendingNew, UpdateTime: now},
+		{Symbol: r.Symbol, OrderListId: -1, ClientOrderId: r.StopClientOrderId, Side: r.Side, Type: StopLossLimit,
+			TimeInForce: r.StopLimitTimeInForce, Price: r.StopLimitPrice, StopPrice: r.StopPrice, OrigQty: r.Quantity,
+			Status: StatusPendingNew, UpdateTime: now},
+	}
+	for _, o := range legs {
+		if _, err := m.Apply(ctx, o); err != nil {
+			m.release(r.LimitClientOrderId)
+			m.release(r.StopClientOrderId)
+			return OrderList{}, err
+		}
+	}
+	list, err := m.client.NewOCO(ctx, r)
+	if err != nil {
+		for _, o := range legs {
+			if _, rejectErr := m.rejected(ctx, o, err); rejectErr != err {
+				return list, rejectErr
+			}
+		}
+		return list, err
+	}
+	for i, o := range list.Orders {
+		if _, err := m.Apply(ctx, o); err != nil {
+			return list, err
+		}
+		list.Orders[i] = m.current(o)
+	}
+	return list, nil
+}
+
+// rejected marks the pending order rejected and releases it if the exchange rejected it, err is returned
+func (m *Manager) rejected(ctx context.Context, pending Order, err error) (Order, error) {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || !apiErr.Rejected() {
+		return pending, err
+	}
+	pending.Status, pending.Reason, pending.UpdateTime = StatusRejected, apiErr.Msg, time.Now().UnixMilli()
+	m.release(pending.ClientOrderId)
+	if _, saveErr := m.Apply(ctx, pending); saveErr != nil {
+		return pending, saveErr
+	}
+	return pending, err
+}
+
+// Cancel cancels the order
+func (m *Manager) Cancel(ctx context.Context, symbol, clientOrderId string) (Order, error) {
+	o, err := m.client.CancelOrder(ctx, symbol, clientOrderId)
+	if err != nil {
+		if IsUnknownOrder(err) {
+			// it is filled or cancelled already, get its final state
+			if _, refreshErr := m.Refresh(ctx, symbol, clientOrderId); refreshErr != nil {
+				log.Printf("failed to refresh order %s: %v", clientOrderId, refreshErr)
+			}
+		}
+		return m.current(Order{ClientOrderId: clientOrderId}), err
+	}
+	_, err = m.Apply(ctx, o)
+	return m.current(o), err
+}
+
+// CancelAll cancels all open orders, the orders which are not open on the exchange anymore are refreshed.
+// it returns the first error, the other orders are cancelled anyway
+func (m *Manager) CancelAll(ctx context.Context) error {
+	var firstErr error
+	for _, o := range m.OpenOrders() {
+		if current, ok := m.Order(o.ClientOrderId); ok && current.Status.Final() {
+			continue // the other leg of OCO cancelled before
+		}
+		if _, err := m.Cancel(ctx, o.Symbol, o.ClientOrderId); err != nil && !IsUnknownOrder(err) {
+			log.Printf("failed to cancel order %s: %v", o.ClientOrderId, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// CancelOCO cancels both orders of OCO
+func (m *Manager) CancelOCO(ctx context.Context, symbol, listClientOrderId string) (OrderList, error) {
+	list, err := m.client.CancelOCO(ctx, symbol, listClientOrderId)
+	if err != nil {
+		return list, err
+	}
+	for _, o := range list.Orders {
+		if _, err := m.Apply(ctx, o); err != nil {
+			return list, err
+		}
+	}
+	return list, nil
+}
+
+// Refresh queries the order from the exchange and applies its state
+func (m *Manager) Refresh(ctx context.Context, symbol, clientOrderId string) (Order, error) {
+	o, err := m.client.QueryOrder(ctx, symbol, clientOrderId)
+	if err != nil {
+		return Order{}, err
+	}
+	_, err = m.Apply(ctx, o)
+	return m.current(o), err
+}
+
+// Apply updates the tracked order by the state received from the exchange and persists it,
+// it returns false if the update is stale: the transition from the current status is not valid
+func (m *Manager) Apply(ctx context.Context, o Order) (bool, error) {
+	applied, err := m.apply(ctx, &o)
+	if applied && m.onChange != nil {
+		m.onChange(o)
+	}
+	return applied, err
+}
+
+func (m *Manager) apply(ctx context.Context, o *Order) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	current, ok := m.orders[o.ClientOrderId]
+	var previous Status
+	if ok {
+		previous = current.Status
+		if !m.accept(current, *o) {
+			return false, nil
+		}
+		merge(o, current)
+	}
+	if err := m.store.SaveOrder(ctx, *o, previous); err != nil {
+		return false, err
+	}
+	saved := *o
+	m.orders[o.ClientOrderId] = &saved
+	return true, nil
+}
+
+// accept returns true if the update o is newer than current
+func (m *Manager) accept(current *Order, o Order) bool {
+	if current.Status == o.Status {
+		return o.ExecutedQty > current.ExecutedQty || o.ExecutedQty == current.ExecutedQty && o.UpdateTime > current.UpdateTime
+	}
+	return current.Status.CanTransition(o.Status)
+}
+
+// merge keeps fields of the current order which are missing in some responses
+func merge(o, current *Order) {
+	if o.OrderId == 0 {
+		o.OrderId = current.OrderId
+	}
+	if o.Price == 0 {
+		o.Price = current.Price
+	}
+	if o.StopPrice == 0 {
+		o.StopPrice = current.StopPrice
+	}
+	if o.TimeInForce == "" {
+		o.TimeInForce = current.TimeInForce
+	}
+	if o.Reason == "" {
+		o.Reason = current.Reason
+	}
+}
+
+// current returns the tracked state of the order or o if it is not tracked
+func (m *Manager) current(o Order) Order {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if current, ok := m.orders[o.ClientOrderId]; ok {
+		return *current
+	}
+	return o
+}
+
+// Order returns the tracked order by client order id
+func (m *Manager) Order(clientOrderId string) (Order, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	o, ok := m.orders[clientOrderId]
+	if !ok {
+		return Order{}, false
+	}
+	return *o, true
+}
+
+// OpenOrders returns orders whose status is not final
+func (m *Manager) OpenOrders() []Order {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var result []Order
+	for _, o := range m.orders {
+		if !o.Status.Final() {
+			result = append(result, *o)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ClientOrderId < result[j].ClientOrderId })
+	return result
+}
+
+// openOrders returns the number of open orders of the symbol
+func (m *Manager) openOrders(symbol string) (n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, o := range m.orders {
+		if o.Symbol == symbol && !o.Status.Final() {
+			n++
+		}
+	}
+	return n
+}
+
+// Reconcile loads open orders from the store and brings them in line with the exchange:
+// orders open on the exchange are applied (unknown ones are adopted), the stored ones
+// which are not open anymore are queried for their final state.
+// PENDING_NEW orders unknown to the exchange never reached it and are marked rejected
+func (m *Manager) Reconcile(ctx context.Context) error {
+	stored, err := m.store.LoadOpenOrders(ctx)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	for i := range stored {
+		o := stored[i]
+		m.orders[o.ClientOrderId] = &o
+	}
+	m.mu.Unlock()
+	open, err := m.client.OpenOrders(ctx, "")
+	if err != nil {
+		return fmt.Errorf("failed to get open orders: %w", err)
+	}
+	isOpen := map[string]bool{}
+	for _, o := range open {
+		isOpen[o.ClientOrderId] = true
+		if _, err := m.Apply(ctx, o); err != nil {
+			return err
+		}
+	}
+	for _, o := range m.OpenOrders() {
+		if isOpen[o.ClientOrderId] {
+			continue
+		}
+		queried, err := m.client.QueryOrder(ctx, o.Symbol, o.ClientOrderId)
+		if IsUnknownOrder(err) && o.Status == StatusPendingNew {
+			o.Status, o.Reason, o.UpdateTime = StatusRejected, "not found on the exchange", time.Now().UnixMilli()
+			queried, err = o, nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to query order %s: %w", o.ClientOrderId, err)
+		}
+		if _, err := m.Apply(ctx, queried); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package orders
+
+/*
+Orders of Binance spot trading.
+an order is identified by its client order id which is generated before the order is sent,
+so the order is persisted as PENDING_NEW before the request and can be found on the exchange
+after the response is lost or the process is restarted.
+the status of the order is changed only by the valid transitions, stale updates are ignored:
+
+	PENDING_NEW -> any
+	NEW -> PARTIALLY_FILLED, FILLED, PENDING_CANCEL, CANCELED, EXPIRED
+	PARTIALLY_FILLED -> PARTIALLY_FILLED, FILLED, PENDING_CANCEL, CANCELED, EXPIRED
+	PENDING_CANCEL -> FILLED, CANCELED, EXPIRED
+	FILLED, CANCELED, REJECTED, EXPIRED -> none
+*/
+
+type Side string
+
+const (
+	Buy  Side = "BUY"
+	Sell Side = "SELL"
+)
+
+type Type string
+
+const (
+	Limit           Type = "LIMIT"
+	Market          Type = "MARKET"
+	StopLossLimit   Type = "STOP_LOSS_LIMIT"
+	TakeProfitLimit Type = "TAKE_PROFIT_LIMIT"
+	LimitMaker      Type = "LIMIT_MAKER" // the limit leg of OCO orders
+)
+
+type TimeInForce string
+
+const (
+	GTC TimeInForce = "GTC" // good till cancelled
+	IOC TimeInForce = "IOC" // immediate or cancel
+	FOK TimeInForce = "FOK" // fill or kill
+)
+
+type Status string
+
+const (
+	StatusPendingNew      Status = "PENDING_NEW" // persisted before it is sent to the exchange
+	StatusNew             Status = "NEW"
+	StatusPartiallyFilled Status = "PARTIALLY_FILLED"
+	StatusFilled          Status = "FILLED"
+	StatusPendingCancel   Status = "PENDING_CANCEL"
+	StatusCanceled        Status = "CANCELED"
+	StatusRejected        Status = "REJECTED"
+	StatusExpired         Status = "EXPIRED"
+)
+
+// Final is true if the status of the order can't be changed anymore
+func (s Status) Final() bool {
+	switch s {
+	case StatusFilled, StatusCanceled, StatusRejected, StatusExpired:
+		return true
+	}
+	return false
+}
+
+// CanTransition returns true if the order status may be changed from s to next
+func (s Status) CanTransition(next Status) bool {
+	switch s {
+	case StatusPendingNew:
+		return true
+	case StatusNew:
+		return next != StatusPendingNew && next != StatusNew && next != StatusRejected
+	case StatusPartiallyFilled:
+		return next != StatusPendingNew && next != StatusNew && next != StatusRejected
+	case StatusPendingCancel:
+		return next == StatusFilled || next == StatusCanceled || next == StatusExpired
+	}
+	return false
+}
+
+// Order is the state of the order on the exchange as it is returned by Binance REST API
+type Order struct {
+	Symbol            string      `json:"symbol"`
+	OrderId           int64       `json:"orderId"`     // 0 until it is acknowledged by the exchange
+	OrderListId       int64       `json:"orderListId"` // -1 if it is not a part of OCO
+	ClientOrderId     string      `json:"clientOrderId"`
+	OrigClientOrderId string      `json:"origClientOrderId,omitempty"` // returned by cancel
+	Side              Side        `json:"side"`
+	Type              Type        `json:"type"`
+	TimeInForce       TimeInForce `json:"timeInForce,omitempty"`
+	Price             float64     `json:"price,string"`
+	StopPrice         float64     `json:"stopPrice,string"`
+	OrigQty           float64     `json:"origQty,string"`
+	ExecutedQty       float64     `json:"executedQty,string"`
+	QuoteQty          float64     `json:"cummulativeQuoteQty,string"`
+	Status            Status      `json:"status"`
+	TransactTime      int64       `json:"transactTime,omitempty"` // returned by new order and cancel
+	UpdateTime        int64       `json:"updateTime,omitempty"`
+	// Reason is the error of the exchange if the order was rejected
+	Reason string `json:"-"`
+}
+
+// normalize fills fields which are missing in responses of some endpoints
+func (o *Order) normalize() {
+	if o.OrigClientOrderId != "" {
+		// cancel returns the id of the cancel request as clientOrderId
+		o.ClientOrderId, o.OrigClientOrderId = o.OrigClientOrderId, ""
+	}
+	if o.UpdateTime == 0 {
+		o.UpdateTime = o.TransactTime
+	}
+}
+
+// OrderList is OCO order: a limit maker order and a stop loss limit one, when one of them is filled the other expires
+type OrderList struct {
+	OrderListId       int64   `json:"orderListId"`
+	ContingencyType   string  `json:"contingencyType"`
+	ListStatusType    string  `json:"listStatusType"`
+	ListOrderStatus   string  `json:"listOrderStatus"`
+	ListClientOrderId string  `json:"listClientOrderId"`
+	TransactionTime   int64   `json:"transactionTime"`
+	Symbol            string  `json:"symbol"`
+	Orders            []Order `json:"orderReports"`
+}
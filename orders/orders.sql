@@ -0,0 +1,33 @@
+-- orders placed on Binance spot by orders.Manager, the row is created before the order is sent
+CREATE TABLE IF NOT EXISTS binance.spot_order (
+    client_order_id text PRIMARY KEY,
+    symbol          text NOT NULL,
+    order_id        int8 NOT NULL DEFAULT 0, -- 0 until the order is acknowledged by the exchange
+    order_list_id   int8 NOT NULL DEFAULT -1, -- OCO order list
+    side            text NOT NULL,
+    type            text NOT NULL,
+    time_in_force   text NOT NULL DEFAULT '',
+    price           float8 NOT NULL DEFAULT 0,
+    stop_price      float8 NOT NULL DEFAULT 0,
+    orig_qty        float8 NOT NULL DEFAULT 0,
+    executed_qty    float8 NOT NULL DEFAULT 0,
+    quote_qty       float8 NOT NULL DEFAULT 0,
+    status          text NOT NULL, -- PENDING_NEW, NEW, PARTIALLY_FILLED, FILLED, PENDING_CANCEL, CANCELED, REJECTED, EXPIRED
+    reason          text NOT NULL DEFAULT '',
+    update_time     int8 NOT NULL DEFAULT 0, -- of the exchange
+    created_at      timestamptz NOT NULL DEFAULT now(),
+    updated_at      timestamptz NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS spot_order_open ON binance.spot_order (symbol)
+    WHERE status IN ('PENDING_NEW', 'NEW', 'PARTIALLY_FILLED', 'PENDING_CANCEL');
+
+-- status transitions of the orders
+CREATE TABLE IF NOT EXISTS binance.spot_order_transition (
+    client_order_id text NOT NULL REFERENCES binance.spot_order ON DELETE CASCADE,
+    from_status     text NOT NULL, -- empty for new orders
+    to_status       text NOT NULL,
+    executed_qty    float8 NOT NULL,
+    update_time     int8 NOT NULL,
+    created_at      timestamptz NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS spot_order_transition_order ON binance.spot_order_transition (client_order_id);
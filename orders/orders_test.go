@@ -0,0 +1,203 @@
+package orders
+
+import (
+	"context"
+	"github.com/okharch/binance/orders/stub"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func newTestManager(t *testing.T) (*Manager, *MemStore, *stub.Exchange) {
+	exchange := stub.NewExchange("key", "secret")
+	t.Cleanup(exchange.Close)
+	client := NewClient("key", "secret")
+	client.BaseURL = exchange.URL
+	store := NewMemStore()
+	return NewManager(client, store, "test"), store, exchange
+}
+
+func TestPlaceAndCancel(t *testing.T) {
+	ctx := context.Background()
+	m, store, exchange := newTestManager(t)
+	exchange.SetPrice("BTCUSDT", 20000)
+
+	market, err := m.Place(ctx, OrderRequest{Symbol: "BTCUSDT", Side: Buy, Type: Market, QuoteOrderQty: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if market.Status != StatusFilled || market.ExecutedQty != 0.005 || market.QuoteQty != 100 || market.OrderId == 0 {
+		t.Errorf("unexpected market order %+v", market)
+	}
+	if got := store.Transitions(market.ClientOrderId); len(got) != 2 || got[0] != StatusPendingNew || got[1] != StatusFilled {
+		t.Errorf("unexpected transitions %v", got)
+	}
+
+	limit, err := m.Place(ctx, OrderRequest{Symbol: "BTCUSDT", Side: Sell, Type: Limit, Quantity: 0.005, Price: 21000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limit.Status != StatusNew || limit.TimeInForce != GTC || len(m.OpenOrders()) != 1 {
+		t.Fatalf("unexpected limit order %+v", limit)
+	}
+	canceled, err := m.Cancel(ctx, "BTCUSDT", limit.ClientOrderId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if canceled.ClientOrderId != limit.ClientOrderId || canceled.Status != StatusCanceled || len(m.OpenOrders()) != 0 {
+		t.Errorf("unexpected cancelled order %+v", canceled)
+	}
+	if _, err := m.Cancel(ctx, "BTCUSDT", limit.ClientOrderId); !IsUnknownOrder(err) {
+		t.Errorf("expected unknown order error, got %v", err)
+	}
+
+	if _, err := m.Place(ctx, OrderRequest{Symbol: "BTCUSDT", Side: Sell, Type: StopLossLimit, Quantity: 1, Price: 19000}); err == nil {
+		t.Error("expected the stop loss order without stop price to be invalid")
+	}
+	exchange.RejectNext(400, -2010, "Account has insufficient balance for requested action.")
+	rejected, err := m.Place(ctx, OrderRequest{Symbol: "BTCUSDT", Side: Buy, Type: Limit, Quantity: 100, Price: 19000})
+	if saved, _ := store.Order(rejected.ClientOrderId); err == nil || rejected.Status != StatusRejected || saved.Reason == "" {
+		t.Errorf("expected rejected order, got %+v, %v", rejected, err)
+	}
+}
+
+func TestRateLimited(t *testing.T) {
+	ctx := context.Background()
+	m, _, exchange := newTestManager(t)
+	exchange.SetPrice("BTCUSDT", 20000)
+	exchange.RejectNext(http.StatusTooManyRequests, -1003, "Too many requests.")
+	start := time.Now()
+	o, err := m.Place(ctx, OrderRequest{Symbol: "BTCUSDT", Side: Buy, Type: Market, QuoteOrderQty: 100})
+	if err != nil || o.Status != StatusFilled {
+		t.Fatalf("expected the order to be sent again, got %+v, %v", o, err)
+	}
+	if exchange.Requests() != 2 || time.Since(start) < time.Second {
+		t.Errorf("expected the second request after Retry-After, got %d requests in %v", exchange.Requests(),
+			time.Since(start))
+	}
+}
+
+func TestStatusTransitions(t *testing.T) {
+	ctx := context.Background()
+	m, store, _ := newTestManager(t)
+	o := Order{Symbol: "ETHUSDT", ClientOrderId: "x", Status: StatusNew, OrigQty: 2, UpdateTime: 1}
+	for _, update := range []struct {
+		status   Status
+		executed float64
+		applied  bool
+	}{
+		{StatusNew, 0, true},
+		{StatusPartiallyFilled, 1, true},
+		{StatusNew, 0, false},               // stale
+		{StatusPartiallyFilled, 0.5, false}, // stale
+		{StatusPartiallyFilled, 1.5, true},
+		{StatusFilled, 2, true},
+		{StatusCanceled, 2, false},
+	} {
+		o.Status, o.ExecutedQty = update.status, update.executed
+		o.UpdateTime++
+		applied, err := m.Apply(ctx, o)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if applied != update.applied {
+			t.Errorf("%s %v: expected applied %v", update.status, update.executed, update.applied)
+		}
+	}
+	saved, _ := store.Order("x")
+	if got := store.Transitions("x"); len(got) != 3 || saved.Status != StatusFilled {
+		t.Errorf("unexpected transitions %v", got)
+	}
+	if StatusFilled.CanTransition(StatusCanceled) || !StatusPendingCancel.CanTransition(StatusFilled) {
+		t.Error("unexpected transitions of final and pending cancel statuses")
+	}
+}
+
+func TestOCO(t *testing.T) {
+	ctx := context.Background()
+	m, _, exchange := newTestManager(t)
+	list, err := m.PlaceOCO(ctx, OCORequest{Symbol: "BTCUSDT", Side: Sell, Quantity: 1, Price: 22000, StopPrice: 19000,
+		StopLimitPrice: 18900})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Orders) != 2 || len(m.OpenOrders()) != 2 {
+		t.Fatalf("expected 2 open orders, got %+v", list)
+	}
+	for _, o := range list.Orders {
+		if o.OrderListId != list.OrderListId || o.Status != StatusNew {
+			t.Errorf("unexpected OCO order %+v", o)
+		}
+	}
+	// the stop is triggered and filled, the limit maker expires
+	stop := list.Orders[0]
+	if stop.Type != StopLossLimit || !exchange.Fill(stop.ClientOrderId, 1, 18950) {
+		t.Fatalf("failed to fill %+v", stop)
+	}
+	if err := m.Reconcile(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.OpenOrders()) != 0 {
+		t.Errorf("expected no open orders after reconcile, got %+v", m.OpenOrders())
+	}
+
+	list, err = m.PlaceOCO(ctx, OCORequest{Symbol: "BTCUSDT", Side: Sell, Quantity: 1, Price: 22000, StopPrice: 19000,
+		StopLimitPrice: 18900})
+	if err != nil {
+		t.Fatal(err)
+	}
+	canceled, err := m.CancelOCO(ctx, "BTCUSDT", list.ListClientOrderId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if canceled.ListOrderStatus != "ALL_DONE" || len(m.OpenOrders()) != 0 {
+		t.Errorf("unexpected cancelled OCO %+v", canceled)
+	}
+}
+
+func TestReconcileAfterRestart(t *testing.T) {
+	ctx := context.Background()
+	m, store, exchange := newTestManager(t)
+	filled, err := m.Place(ctx, OrderRequest{Symbol: "BTCUSDT", Side: Buy, Type: Limit, Quantity: 1, Price: 19000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	open, err := m.Place(ctx, OrderRequest{Symbol: "BTCUSDT", Side: Buy, Type: Limit, Quantity: 1, Price: 18000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the response is lost, the order is on the exchange but it is PENDING_NEW locally
+	exchange.DropNextResponse()
+	lost, err := m.Place(ctx, OrderRequest{Symbol: "BTCUSDT", Side: Buy, Type: Limit, Quantity: 1, Price: 17000})
+	if err == nil || lost.Status != StatusPendingNew {
+		t.Fatalf("expected the order to stay PENDING_NEW, got %+v, %v", lost, err)
+	}
+	// the order was saved but the process died before sending it
+	neverSent := Order{Symbol: "BTCUSDT", OrderListId: -1, ClientOrderId: "never-sent", Side: Buy, Type: Limit,
+		OrigQty: 1, Price: 16000, Status: StatusPendingNew}
+	if err := store.SaveOrder(ctx, neverSent, ""); err != nil {
+		t.Fatal(err)
+	}
+	exchange.Fill(filled.ClientOrderId, 1, 19000)
+	exchange.Place("BTCUSDT", "manual", "BUY", 1, 15000)
+
+	restarted := NewManager(m.client, store, "test")
+	if err := restarted.Reconcile(ctx); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]Status{
+		filled.ClientOrderId: StatusFilled,
+		open.ClientOrderId:   StatusNew,
+		lost.ClientOrderId:   StatusNew,
+		"never-sent":         StatusRejected,
+		"manual":             StatusNew,
+	}
+	for id, status := range expected {
+		if got, _ := store.Order(id); got.Status != status {
+			t.Errorf("%s: expected %s, got %+v", id, status, got)
+		}
+	}
+	if n := len(restarted.OpenOrders()); n != 3 {
+		t.Errorf("expected 3 open orders, got %d", n)
+	}
+}
@@ -0,0 +1,116 @@
+package orders
+
+import (
+	"context"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"sync"
+)
+
+// Store persists orders of the Manager
+type Store interface {
+	// SaveOrder saves the order, previous is its status before the update, empty for new orders
+	SaveOrder(ctx context.Context, o Order, previous Status) error
+	// LoadOpenOrders returns orders whose status is not final
+	LoadOpenOrders(ctx context.Context) ([]Order, error)
+}
+
+// DBStore is the Store of binance.spot_order and binance.spot_order_transition, see orders.sql
+type DBStore struct {
+	DB *sqlx.DB
+}
+
+func (s DBStore) SaveOrder(ctx context.Context, o Order, previous Status) error {
+	tx, err := s.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, `INSERT INTO binance.spot_order (client_order_id, symbol, order_id, order_list_id,
+		side, type, time_in_force, price, stop_price, orig_qty, executed_qty, quote_qty, status, reason, update_time)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+	ON CONFLICT (client_order_id) DO UPDATE SET order_id = EXCLUDED.order_id, order_list_id = EXCLUDED.order_list_id,
+		orig_qty = EXCLUDED.orig_qty, executed_qty = EXCLUDED.executed_qty, quote_qty = EXCLUDED.quote_qty,
+		status = EXCLUDED.status, reason = EXCLUDED.reason, update_time = EXCLUDED.update_time, updated_at = now()`,
+		o.ClientOrderId, o.Symbol, o.OrderId, o.OrderListId, o.Side, o.Type, o.TimeInForce, o.Price, o.StopPrice,
+		o.OrigQty, o.ExecutedQty, o.QuoteQty, o.Status, o.Reason, o.UpdateTime); err != nil {
+		return fmt.Errorf("failed to save order %s: %w", o.ClientOrderId, err)
+	}
+	if previous != o.Status {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO binance.spot_order_transition (client_order_id, from_status,
+			to_status, executed_qty, update_time)
+		VALUES ($1, $2, $3, $4, $5)`, o.ClientOrderId, previous, o.Status, o.ExecutedQty, o.UpdateTime); err != nil {
+			return fmt.Errorf("failed to save transition of order %s: %w", o.ClientOrderId, err)
+		}
+	}
+	return tx.Commit()
+}
+
+func (s DBStore) LoadOpenOrders(ctx context.Context) ([]Order, error) {
+	var result []Order
+	rows, err := s.DB.QueryContext(ctx, `SELECT client_order_id, symbol, order_id, order_list_id, side, type,
+		time_in_force, price, stop_price, orig_qty, executed_qty, quote_qty, status, reason, update_time
+	FROM binance.spot_order
+	WHERE status IN ('PENDING_NEW', 'NEW', 'PARTIALLY_FILLED', 'PENDING_CANCEL')
+	ORDER BY created_at`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load open orders: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var o Order
+		if err := rows.Scan(&o.ClientOrderId, &o.Symbol, &o.OrderId, &o.OrderListId, &o.Side, &o.Type, &o.TimeInForce,
+			&o.Price, &o.StopPrice, &o.OrigQty, &o.ExecutedQty, &o.QuoteQty, &o.Status, &o.Reason, &o.UpdateTime); err != nil {
+			return nil, err
+		}
+		result = append(result, o)
+	}
+	return result, rows.Err()
+}
+
+// MemStore keeps orders and their transitions in memory, e.g. for tests
+type MemStore struct {
+	mu          sync.Mutex
+	orders      map[string]Order
+	transitions map[string][]Status
+}
+
+func NewMemStore() *MemStore {
+	return &MemStore{orders: map[string]Order{}, transitions: map[string][]Status{}}
+}
+
+func (s *MemStore) SaveOrder(_ context.Context, o Order, previous Status) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.orders[o.ClientOrderId] = o
+	if previous != o.Status {
+		s.transitions[o.ClientOrderId] = append(s.transitions[o.ClientOrderId], o.Status)
+	}
+	return nil
+}
+
+func (s *MemStore) LoadOpenOrders(_ context.Context) (result []Order, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, o := range s.orders {
+		if !o.Status.Final() {
+			result = append(result, o)
+		}
+	}
+	return result, nil
+}
+
+// Order returns the saved order by client order id
+func (s *MemStore) Order(clientOrderId string) (Order, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	o, ok := s.orders[clientOrderId]
+	return o, ok
+}
+
+// Transitions returns the statuses the order went through
+func (s *MemStore) Transitions(clientOrderId string) []Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Status(nil), s.transitions[clientOrderId]...)
+}
@@ -0,0 +1,166 @@
+package stub
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// trade is the trade of the fill in /api/v3/myTrades format
+type trade struct {
+	Symbol          string `json:"symbol"`
+	Id              int64  `json:"id"`
+	OrderId         int64  `json:"orderId"`
+	OrderListId     int64  `json:"orderListId"`
+	Price           string `json:"price"`
+	Qty             string `json:"qty"`
+	QuoteQty        string `json:"quoteQty"`
+	Commission      string `json:"commission"`
+	CommissionAsset string `json:"commissionAsset"`
+	Time            int64  `json:"time"`
+	IsBuyer         bool   `json:"isBuyer"`
+	IsMaker         bool   `json:"isMaker"`
+}
+
+type deposit struct {
+	Id         string `json:"id"`
+	Amount     string `json:"amount"`
+	Coin       string `json:"coin"`
+	Network    string `json:"network"`
+	Status     int    `json:"status"`
+	TxId       string `json:"txId"`
+	InsertTime int64  `json:"insertTime"`
+}
+
+type withdrawal struct {
+	Id             string `json:"id"`
+	Amount         string `json:"amount"`
+	TransactionFee string `json:"transactionFee"`
+	Coin           string `json:"coin"`
+	Network        string `json:"network"`
+	Status         int    `json:"status"`
+	TxId           string `json:"txId"`
+	ApplyTime      string `json:"applyTime"`
+	applyTime      int64
+}
+
+// AddDeposit adds successful deposit of the coin to the history
+func (e *Exchange) AddDeposit(coin string, amount float64, at time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.deposits = append(e.deposits, deposit{Id: "d" + strconv.Itoa(len(e.deposits)+1), Amount: format(amount),
+		Coin: coin, Network: coin, Status: 1, TxId: "tx" + strconv.FormatInt(at.UnixNano(), 36),
+		InsertTime: at.UnixMilli()})
+}
+
+// AddWithdrawal adds completed withdrawal of the coin to the history
+func (e *Exchange) AddWithdrawal(coin string, amount, fee float64, at time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.withdrawals = append(e.withdrawals, withdrawal{Id: "w" + strconv.Itoa(len(e.withdrawals)+1),
+		Amount: format(amount), TransactionFee: format(fee), Coin: coin, Network: coin, Status: 6,
+		TxId: "tx" + strconv.FormatInt(at.UnixNano(), 36), ApplyTime: at.UTC().Format("2006-01-02 15:04:05"),
+		applyTime: at.UnixMilli()})
+}
+
+// SetBalance sets the balance of the asset returned by /api/v3/account
+func (e *Exchange) SetBalance(asset string, free, locked float64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.balances[asset] = [2]float64{free, locked}
+}
+
+type balance struct {
+	Asset  string `json:"asset"`
+	Free   string `json:"free"`
+	Locked string `json:"locked"`
+}
+
+func (e *Exchange) handleAccount(r *http.Request) (any, *Error) {
+	result := struct {
+		CanTrade    bool      `json:"canTrade"`
+		AccountType string    `json:"accountType"`
+		UpdateTime  int64     `json:"updateTime"`
+		Balances    []balance `json:"balances"`
+	}{CanTrade: true, AccountType: "SPOT", UpdateTime: now(), Balances: []balance{}}
+	for asset, b := range e.balances {
+		if b[0] != 0 || b[1] != 0 || r.URL.Query().Get("omitZeroBalances") != "true" {
+			result.Balances = append(result.Balances, balance{Asset: asset, Free: format(b[0]), Locked: format(b[1])})
+		}
+	}
+	sort.Slice(result.Balances, func(i, j int) bool { return result.Balances[i].Asset < result.Balances[j].Asset })
+	return result, nil
+}
+
+func (e *Exchange) handleMyTrades(r *http.Request) (any, *Error) {
+	q := r.URL.Query()
+	fromId, _ := strconv.ParseInt(q.Get("fromId"), 10, 64)
+	limit := limitParam(q.Get("limit"), 500)
+	result := []trade{}
+	for _, t := range e.trades {
+		if t.Symbol == q.Get("symbol") && t.Id >= fromId && len(result) < limit {
+			result = append(result, t)
+		}
+	}
+	return result, nil
+}
+
+func (e *Exchange) handleDeposits(r *http.Request) (any, *Error) {
+	start, end, offset, limit, err := historyWindow(r)
+	if err != nil {
+		return nil, err
+	}
+	result := []deposit{}
+	for _, d := range e.deposits {
+		if d.InsertTime >= start && d.InsertTime <= end {
+			result = append(result, d)
+		}
+	}
+	return page(result, offset, limit), nil
+}
+
+func (e *Exchange) handleWithdrawals(r *http.Request) (any, *Error) {
+	start, end, offset, limit, err := historyWindow(r)
+	if err != nil {
+		return nil, err
+	}
+	result := []withdrawal{}
+	for _, w := range e.withdrawals {
+		if w.applyTime >= start && w.applyTime <= end {
+			result = append(result, w)
+		}
+	}
+	return page(result, offset, limit), nil
+}
+
+// historyWindow returns params of deposit and withdrawal history requests, the window is limited to 90 days
+func historyWindow(r *http.Request) (start, end int64, offset, limit int, err *Error) {
+	q := r.URL.Query()
+	start, _ = strconv.ParseInt(q.Get("startTime"), 10, 64)
+	end, _ = strconv.ParseInt(q.Get("endTime"), 10, 64)
+	if end-start > (90 * 24 * time.Hour).Milliseconds() {
+		return 0, 0, 0, 0, &Error{Status: http.StatusBadRequest, Code: -1127,
+			Msg: "More than 90 days between startTime and endTime."}
+	}
+	offset, _ = strconv.Atoi(q.Get("offset"))
+	return start, end, offset, limitParam(q.Get("limit"), 1000), nil
+}
+
+func limitParam(s string, defaultLimit int) int {
+	if limit, _ := strconv.Atoi(s); limit > 0 && limit <= 1000 {
+		return limit
+	}
+	return defaultLimit
+}
+
+func page[T any](items []T, offset, limit int) []T {
+	if offset >= len(items) {
+		return []T{}
+	}
+	items = items[offset:]
+	if len(items) > limit {
+		items = items[:limit]
+	}
+	return items
+}
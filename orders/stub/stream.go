@@ -0,0 +1,177 @@
+package stub
+
+import (
+	"github.com/gorilla/websocket"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// executionReport is the event of the user data stream sent on every change of the order
+type executionReport struct {
+	EventType         string  `json:"e"`
+	EventTime         int64   `json:"E"`
+	Symbol            string  `json:"s"`
+	ClientOrderId     string  `json:"c"`
+	Side              string  `json:"S"`
+	Type              string  `json:"o"`
+	TimeInForce       string  `json:"f"`
+	Quantity          string  `json:"q"`
+	Price             string  `json:"p"`
+	StopPrice         string  `json:"P"`
+	OrderListId       int64   `json:"g"`
+	OrigClientOrderId string  `json:"C"`
+	ExecutionType     string  `json:"x"`
+	Status            string  `json:"X"`
+	RejectReason      string  `json:"r"`
+	OrderId           int64   `json:"i"`
+	LastQty           string  `json:"l"`
+	CumulativeQty     string  `json:"z"`
+	LastPrice         string  `json:"L"`
+	Commission        string  `json:"n"`
+	CommissionAsset   *string `json:"N"`
+	TransactionTime   int64   `json:"T"`
+	TradeId           int64   `json:"t"`
+	IsMaker           bool    `json:"m"`
+	CreationTime      int64   `json:"O"`
+	CumulativeQuote   string  `json:"Z"`
+	LastQuote         string  `json:"Y"`
+}
+
+// StreamURL returns the web socket endpoint of the user data stream, the listen key is appended to it
+func (e *Exchange) StreamURL() string {
+	return "ws" + strings.TrimPrefix(e.URL, "http") + "/ws/"
+}
+
+// Push sends the event to the connected user data streams
+func (e *Exchange) Push(event any) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.push(event)
+}
+
+// ExpireListenKeys sends listenKeyExpired events and invalidates the listen keys
+func (e *Exchange) ExpireListenKeys() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for conn, listenKey := range e.streams {
+		conn.WriteJSON(map[string]any{"e": "listenKeyExpired", "E": now(), "listenKey": listenKey})
+	}
+	e.listenKeys = map[string]bool{}
+}
+
+// DropStreams closes connections of the user data streams
+func (e *Exchange) DropStreams() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for conn := range e.streams {
+		conn.Close()
+		delete(e.streams, conn)
+	}
+}
+
+// KeepAlives returns the number of keep alive requests of listen keys
+func (e *Exchange) KeepAlives() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.keepAlives
+}
+
+// ListenKeys returns the number of valid listen keys
+func (e *Exchange) ListenKeys() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return len(e.listenKeys)
+}
+
+func (e *Exchange) push(event any) {
+	for conn := range e.streams {
+		conn.WriteJSON(event)
+	}
+}
+
+// report sends execution report of the order, cancelId is the client id of the cancel request,
+// t is the trade of TRADE execution type
+func (e *Exchange) report(o *Order, execType, cancelId string, t *trade) {
+	r := executionReport{EventType: "executionReport", EventTime: now(), Symbol: o.Symbol,
+		ClientOrderId: o.ClientOrderId, Side: o.Side, Type: o.Type, TimeInForce: o.TimeInForce, Quantity: o.OrigQty,
+		Price: o.Price, StopPrice: o.StopPrice, OrderListId: o.OrderListId, ExecutionType: execType,
+		Status: o.Status, RejectReason: "NONE", OrderId: o.OrderId, LastQty: format(0),
+		CumulativeQty: o.ExecutedQty, LastPrice: format(0), Commission: format(0),
+		TransactionTime: o.UpdateTime, TradeId: -1, CreationTime: o.TransactTime,
+		CumulativeQuote: o.CummulativeQuoteQty, LastQuote: format(0)}
+	if cancelId != "" {
+		r.ClientOrderId, r.OrigClientOrderId = cancelId, o.ClientOrderId
+	}
+	if t != nil {
+		r.LastQty, r.LastPrice, r.LastQuote, r.Commission = t.Qty, t.Price, t.QuoteQty, t.Commission
+		r.CommissionAsset, r.TradeId, r.IsMaker = &t.CommissionAsset, t.Id, t.IsMaker
+	}
+	e.push(r)
+}
+
+func (e *Exchange) handleUserDataStream(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-MBX-APIKEY") != e.APIKey {
+		writeJSON(w, http.StatusUnauthorized, Error{Code: -2015, Msg: "Invalid API-key, IP, or permissions for action."})
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.requests++
+	listenKey := r.URL.Query().Get("listenKey")
+	switch r.Method {
+	case http.MethodPost:
+		listenKey = "key" + strconv.FormatInt(e.nextId, 10) + strconv.FormatInt(now(), 36)
+		e.nextId++
+		e.listenKeys[listenKey] = true
+		writeJSON(w, http.StatusOK, map[string]string{"listenKey": listenKey})
+		return
+	case http.MethodPut, http.MethodDelete:
+		if !e.listenKeys[listenKey] {
+			writeJSON(w, http.StatusBadRequest, Error{Code: -1125, Msg: "This listenKey does not exist."})
+			return
+		}
+		if r.Method == http.MethodPut {
+			e.keepAlives++
+		} else {
+			delete(e.listenKeys, listenKey)
+		}
+		writeJSON(w, http.StatusOK, struct{}{})
+		return
+	}
+	writeJSON(w, http.StatusMethodNotAllowed, Error{Code: -1000, Msg: "method not allowed"})
+}
+
+var upgrader = websocket.Upgrader{}
+
+// handleStream sends the events to the connection until it is closed
+func (e *Exchange) handleStream(w http.ResponseWriter, r *http.Request) {
+	listenKey := strings.TrimPrefix(r.URL.Path, "/ws/")
+	e.mu.Lock()
+	valid := e.listenKeys[listenKey]
+	e.mu.Unlock()
+	if !valid {
+		writeJSON(w, http.StatusBadRequest, Error{Code: -1125, Msg: "This listenKey does not exist."})
+		return
+	}
+	// the stream is registered under the lock so the events pushed after the client is connected are not lost
+	e.mu.Lock()
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		e.mu.Unlock()
+		return
+	}
+	e.streams[conn] = listenKey
+	e.mu.Unlock()
+	defer func() {
+		e.mu.Lock()
+		delete(e.streams, conn)
+		e.mu.Unlock()
+		conn.Close()
+	}()
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,426 @@
+package stub
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/gorilla/websocket"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+Exchange is a local stub of Binance spot order endpoints for tests:
+
+	POST   /api/v3/order       new LIMIT, MARKET, STOP_LOSS_LIMIT, TAKE_PROFIT_LIMIT order
+	DELETE /api/v3/order       cancel order
+	GET    /api/v3/order       query order
+	GET    /api/v3/openOrders  open orders
+	POST   /api/v3/order/oco   new OCO order
+	DELETE /api/v3/orderList   cancel OCO order
+	GET    /api/v3/account     balances set by SetBalance
+	GET    /api/v3/myTrades    trades of the fills
+	GET    /sapi/v1/capital/deposit/hisrec     deposits added by AddDeposit
+	GET    /sapi/v1/capital/withdraw/history   withdrawals added by AddWithdrawal
+	POST, PUT, DELETE /api/v3/userDataStream  create, keep alive and close listen key
+	GET    /ws/{listenKey}     user data stream, see StreamURL
+
+requests must be signed by the secret key, user data stream ones require the api key only.
+market orders are filled at the price set by SetPrice, other orders stay NEW until they are filled by Fill.
+numbers are encoded as strings like Binance does.
+*/
+type Exchange struct {
+	*httptest.Server
+	APIKey, SecretKey string
+
+	mu          sync.Mutex
+	prices      map[string]float64
+	orders      map[string]*Order // by client order id
+	nextId      int64
+	nextListId  int64
+	dropNext    bool
+	requests    int
+	rejectNext  *Error
+	orderLists  map[string][]string // client order ids by list client order id
+	listsByLeg  map[string]string
+	listIdByKey map[string]int64
+	nextTradeId int64
+	listenKeys  map[string]bool
+	keepAlives  int
+	streams     map[*websocket.Conn]string // listen keys of connected streams
+	trades      []trade
+	deposits    []deposit
+	withdrawals []withdrawal
+	balances    map[string][2]float64 // free and locked by asset
+}
+
+// Order is the order in Binance json format
+type Order struct {
+	Symbol              string `json:"symbol"`
+	OrderId             int64  `json:"orderId"`
+	OrderListId         int64  `json:"orderListId"`
+	ClientOrderId       string `json:"clientOrderId"`
+	OrigClientOrderId   string `json:"origClientOrderId,omitempty"`
+	Price               string `json:"price"`
+	OrigQty             string `json:"origQty"`
+	ExecutedQty         string `json:"executedQty"`
+	CummulativeQuoteQty string `json:"cummulativeQuoteQty"`
+	Status              string `json:"status"`
+	TimeInForce         string `json:"timeInForce"`
+	Type                string `json:"type"`
+	Side                string `json:"side"`
+	StopPrice           string `json:"stopPrice"`
+	TransactTime        int64  `json:"transactTime,omitempty"`
+	UpdateTime          int64  `json:"updateTime"`
+}
+
+type orderList struct {
+	OrderListId       int64   `json:"orderListId"`
+	ContingencyType   string  `json:"contingencyType"`
+	ListStatusType    string  `json:"listStatusType"`
+	ListOrderStatus   string  `json:"listOrderStatus"`
+	ListClientOrderId string  `json:"listClientOrderId"`
+	TransactionTime   int64   `json:"transactionTime"`
+	Symbol            string  `json:"symbol"`
+	OrderReports      []Order `json:"orderReports"`
+}
+
+// Error is the error response of Binance
+type Error struct {
+	Status int    `json:"-"`
+	Code   int    `json:"code"`
+	Msg    string `json:"msg"`
+}
+
+// NewExchange starts the stub server, Close it after the test
+func NewExchange(apiKey, secretKey string) *Exchange {
+	e := &Exchange{APIKey: apiKey, SecretKey: secretKey, prices: map[string]float64{}, orders: map[string]*Order{},
+		nextId: 1, nextListId: 1, orderLists: map[string][]string{}, listsByLeg: map[string]string{},
+		listIdByKey: map[string]int64{}, nextTradeId: 1, listenKeys: map[string]bool{},
+		streams: map[*websocket.Conn]string{}, balances: map[string][2]float64{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v3/order", e.signed(e.handleOrder))
+	mux.HandleFunc("/api/v3/openOrders", e.signed(e.handleOpenOrders))
+	mux.HandleFunc("/api/v3/order/oco", e.signed(e.handleNewOCO))
+	mux.HandleFunc("/api/v3/orderList", e.signed(e.handleCancelOCO))
+	mux.HandleFunc("/api/v3/account", e.signed(e.handleAccount))
+	mux.HandleFunc("/api/v3/myTrades", e.signed(e.handleMyTrades))
+	mux.HandleFunc("/sapi/v1/capital/deposit/hisrec", e.signed(e.handleDeposits))
+	mux.HandleFunc("/sapi/v1/capital/withdraw/history", e.signed(e.handleWithdrawals))
+	mux.HandleFunc("/api/v3/userDataStream", e.handleUserDataStream)
+	mux.HandleFunc("/ws/", e.handleStream)
+	e.Server = httptest.NewServer(mux)
+	return e
+}
+
+// SetPrice sets the price market orders of the symbol are filled at
+func (e *Exchange) SetPrice(symbol string, price float64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.prices[symbol] = price
+}
+
+// DropNextResponse closes the connection instead of the response to the next request after it is processed
+func (e *Exchange) DropNextResponse() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.dropNext = true
+}
+
+// RejectNext rejects the next request with the error, 429 responses ask to retry after a second
+func (e *Exchange) RejectNext(status, code int, msg string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.rejectNext = &Error{Status: status, Code: code, Msg: msg}
+}
+
+// Requests returns the number of requests received
+func (e *Exchange) Requests() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.requests
+}
+
+// Fill executes quantity of the open order at price, the other order of OCO expires
+func (e *Exchange) Fill(clientOrderId string, quantity, price float64) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	o, ok := e.orders[clientOrderId]
+	if !ok || !open(o.Status) {
+		return false
+	}
+	e.fill(o, quantity, price)
+	if list, ok := e.listsByLeg[clientOrderId]; ok {
+		for _, id := range e.orderLists[list] {
+			if leg := e.orders[id]; id != clientOrderId && open(leg.Status) {
+				leg.Status, leg.UpdateTime = "EXPIRED", now()
+				e.report(leg, "EXPIRED", "", nil)
+			}
+		}
+	}
+	return true
+}
+
+// Place places the limit order as if it was done by another client
+func (e *Exchange) Place(symbol, clientOrderId, side string, quantity, price float64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.newOrder(symbol, side, "LIMIT", "GTC", format(quantity), "", format(price), "", clientOrderId, -1)
+}
+
+// Cancel cancels the order on the exchange as if it was done by another client
+func (e *Exchange) Cancel(clientOrderId string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if o, ok := e.orders[clientOrderId]; ok && open(o.Status) {
+		o.Status, o.UpdateTime = "CANCELED", now()
+		e.report(o, "CANCELED", "cancel"+strconv.FormatInt(now(), 36), nil)
+	}
+}
+
+// Order returns the order by client order id
+func (e *Exchange) Order(clientOrderId string) (Order, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	o, ok := e.orders[clientOrderId]
+	if !ok {
+		return Order{}, false
+	}
+	return *o, true
+}
+
+func (e *Exchange) fill(o *Order, quantity, price float64) {
+	orig, executed, quote := parse(o.OrigQty), parse(o.ExecutedQty), parse(o.CummulativeQuoteQty)
+	if quantity > orig-executed {
+		quantity = orig - executed
+	}
+	executed += quantity
+	quote += quantity * price
+	o.ExecutedQty, o.CummulativeQuoteQty = format(executed), format(quote)
+	o.Status = "PARTIALLY_FILLED"
+	if executed >= orig {
+		o.Status = "FILLED"
+	}
+	o.UpdateTime = now()
+	t := trade{Symbol: o.Symbol, Id: e.nextTradeId, OrderId: o.OrderId, OrderListId: o.OrderListId,
+		Price: format(price), Qty: format(quantity), QuoteQty: format(quantity * price), Commission: format(0),
+		CommissionAsset: "BNB", Time: o.UpdateTime, IsBuyer: o.Side == "BUY", IsMaker: o.Type != "MARKET"}
+	e.nextTradeId++
+	e.trades = append(e.trades, t)
+	e.report(o, "TRADE", "", &t)
+}
+
+func open(status string) bool {
+	return status == "NEW" || status == "PARTIALLY_FILLED"
+}
+
+func now() int64 {
+	return time.Now().UnixMilli()
+}
+
+func parse(s string) float64 {
+	v, _ := strconv.ParseFloat(s, 64)
+	return v
+}
+
+func format(v float64) string {
+	return strconv.FormatFloat(v, 'f', 8, 64)
+}
+
+// signed checks the api key and the signature of the request
+func (e *Exchange) signed(handler func(r *http.Request) (any, *Error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		e.mu.Lock()
+		e.requests++
+		reject := e.rejectNext
+		e.rejectNext = nil
+		e.mu.Unlock()
+		query := r.URL.RawQuery
+		i := strings.LastIndex(query, "&signature=")
+		if r.Header.Get("X-MBX-APIKEY") != e.APIKey || i < 0 || !validSignature(e.SecretKey, query[:i], query[i+len("&signature="):]) {
+			writeJSON(w, http.StatusUnauthorized, Error{Code: -1022, Msg: "Signature for this request is not valid."})
+			return
+		}
+		if r.URL.Query().Get("timestamp") == "" {
+			writeJSON(w, http.StatusBadRequest, Error{Code: -1102, Msg: "Mandatory parameter 'timestamp' was not sent."})
+			return
+		}
+		if reject != nil {
+			if reject.Status == http.StatusTooManyRequests {
+				w.Header().Set("Retry-After", "1")
+			}
+			writeJSON(w, reject.Status, reject)
+			return
+		}
+		e.mu.Lock()
+		result, err := handler(r)
+		drop := e.dropNext
+		e.dropNext = false
+		e.mu.Unlock()
+		if drop {
+			if hj, ok := w.(http.Hijacker); ok {
+				if conn, _, hjErr := hj.Hijack(); hjErr == nil {
+					conn.Close()
+					return
+				}
+			}
+		}
+		if err != nil {
+			writeJSON(w, err.Status, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, result)
+	}
+}
+
+func validSignature(secretKey, payload, signature string) bool {
+	mac := hmac.New(sha256.New, []byte(secretKey))
+	mac.Write([]byte(payload))
+	expected := hex.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+var errUnknownOrder = &Error{Status: http.StatusBadRequest, Code: -2013, Msg: "Order does not exist."}
+
+func (e *Exchange) handleOrder(r *http.Request) (any, *Error) {
+	q := r.URL.Query()
+	switch r.Method {
+	case http.MethodPost:
+		o, err := e.newOrder(q.Get("symbol"), q.Get("side"), q.Get("type"), q.Get("timeInForce"), q.Get("quantity"),
+			q.Get("quoteOrderQty"), q.Get("price"), q.Get("stopPrice"), q.Get("newClientOrderId"), -1)
+		if err != nil {
+			return nil, err
+		}
+		return *o, nil
+	case http.MethodGet:
+		o, ok := e.orders[q.Get("origClientOrderId")]
+		if !ok || o.Symbol != q.Get("symbol") {
+			return nil, errUnknownOrder
+		}
+		return *o, nil
+	case http.MethodDelete:
+		o, ok := e.orders[q.Get("origClientOrderId")]
+		if !ok || o.Symbol != q.Get("symbol") || !open(o.Status) {
+			return nil, &Error{Status: http.StatusBadRequest, Code: -2011, Msg: "Unknown order sent."}
+		}
+		o.Status, o.UpdateTime = "CANCELED", now()
+		result := *o
+		result.OrigClientOrderId, result.ClientOrderId = o.ClientOrderId, "cancel"+strconv.FormatInt(now(), 36)
+		result.TransactTime = o.UpdateTime
+		e.report(o, "CANCELED", result.ClientOrderId, nil)
+		return result, nil
+	}
+	return nil, &Error{Status: http.StatusMethodNotAllowed, Code: -1000, Msg: "method not allowed"}
+}
+
+func (e *Exchange) newOrder(symbol, side, orderType, timeInForce, quantity, quoteQty, price, stopPrice,
+	clientOrderId string, listId int64) (*Order, *Error) {
+	if o, ok := e.orders[clientOrderId]; ok && open(o.Status) {
+		return nil, &Error{Status: http.StatusBadRequest, Code: -2010, Msg: "Duplicate order sent."}
+	}
+	if symbol == "" || side == "" || orderType == "" {
+		return nil, &Error{Status: http.StatusBadRequest, Code: -1102, Msg: "Mandatory parameter was not sent."}
+	}
+	if clientOrderId == "" {
+		clientOrderId = "stub" + strconv.FormatInt(e.nextId, 10)
+	}
+	o := &Order{Symbol: symbol, OrderId: e.nextId, OrderListId: listId, ClientOrderId: clientOrderId, Side: side,
+		Type: orderType, TimeInForce: timeInForce, Price: format(parse(price)), StopPrice: format(parse(stopPrice)),
+		OrigQty: format(parse(quantity)), ExecutedQty: format(0), CummulativeQuoteQty: format(0), Status: "NEW",
+		TransactTime: now()}
+	o.UpdateTime = o.TransactTime
+	marketPrice, ok := e.prices[symbol]
+	if orderType == "MARKET" && !ok {
+		return nil, &Error{Status: http.StatusBadRequest, Code: -1121, Msg: "Invalid symbol."}
+	}
+	e.nextId++
+	if orderType == "MARKET" && quoteQty != "" {
+		o.OrigQty = format(parse(quoteQty) / marketPrice)
+	}
+	e.report(o, "NEW", "", nil)
+	if orderType == "MARKET" {
+		e.fill(o, parse(o.OrigQty), marketPrice)
+	}
+	e.orders[clientOrderId] = o
+	return o, nil
+}
+
+func (e *Exchange) handleOpenOrders(r *http.Request) (any, *Error) {
+	symbol := r.URL.Query().Get("symbol")
+	result := []Order{}
+	for _, o := range e.orders {
+		if open(o.Status) && (symbol == "" || o.Symbol == symbol) {
+			result = append(result, *o)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].OrderId < result[j].OrderId })
+	return result, nil
+}
+
+func (e *Exchange) handleNewOCO(r *http.Request) (any, *Error) {
+	if r.Method != http.MethodPost {
+		return nil, &Error{Status: http.StatusMethodNotAllowed, Code: -1000, Msg: "method not allowed"}
+	}
+	q := r.URL.Query()
+	listKey := q.Get("listClientOrderId")
+	if _, ok := e.orderLists[listKey]; ok {
+		return nil, &Error{Status: http.StatusBadRequest, Code: -2010, Msg: "Duplicate order sent."}
+	}
+	listId := e.nextListId
+	e.nextListId++
+	limit, err := e.newOrder(q.Get("symbol"), q.Get("side"), "LIMIT_MAKER", "", q.Get("quantity"), "", q.Get("price"),
+		"", q.Get("limitClientOrderId"), listId)
+	if err != nil {
+		return nil, err
+	}
+	stop, err := e.newOrder(q.Get("symbol"), q.Get("side"), "STOP_LOSS_LIMIT", q.Get("stopLimitTimeInForce"),
+		q.Get("quantity"), "", q.Get("stopLimitPrice"), q.Get("stopPrice"), q.Get("stopClientOrderId"), listId)
+	if err != nil {
+		return nil, err
+	}
+	e.orderLists[listKey] = []string{stop.ClientOrderId, limit.ClientOrderId}
+	e.listsByLeg[stop.ClientOrderId], e.listsByLeg[limit.ClientOrderId] = listKey, listKey
+	e.listIdByKey[listKey] = listId
+	return e.orderList(listKey, "EXEC_STARTED", "EXECUTING"), nil
+}
+
+func (e *Exchange) handleCancelOCO(r *http.Request) (any, *Error) {
+	if r.Method != http.MethodDelete {
+		return nil, &Error{Status: http.StatusMethodNotAllowed, Code: -1000, Msg: "method not allowed"}
+	}
+	listKey := r.URL.Query().Get("listClientOrderId")
+	ids, ok := e.orderLists[listKey]
+	if !ok {
+		return nil, &Error{Status: http.StatusBadRequest, Code: -2011, Msg: "Unknown order list sent."}
+	}
+	for _, id := range ids {
+		if o := e.orders[id]; open(o.Status) {
+			o.Status, o.UpdateTime = "CANCELED", now()
+			e.report(o, "CANCELED", "", nil)
+		}
+	}
+	return e.orderList(listKey, "ALL_DONE", "ALL_DONE"), nil
+}
+
+func (e *Exchange) orderList(listKey, listStatus, orderStatus string) orderList {
+	list := orderList{OrderListId: e.listIdByKey[listKey], ContingencyType: "OCO", ListStatusType: listStatus,
+		ListOrderStatus: orderStatus, ListClientOrderId: listKey, TransactionTime: now()}
+	for _, id := range e.orderLists[listKey] {
+		o := e.orders[id]
+		list.Symbol = o.Symbol
+		list.OrderReports = append(list.OrderReports, *o)
+	}
+	return list
+}
@@ -0,0 +1,180 @@
+package paper
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/okharch/binance/backtest"
+	"time"
+)
+
+// Account of paper trading, see paper_trading.sql
+type Account struct {
+	Id          int32
+	Name        string
+	Symbol      string
+	SymbolId    int32
+	Indicator   string
+	Params      []int
+	Period      string
+	InitialCash float64
+	PayWithBNB  bool
+}
+
+// LoadAccounts loads active accounts from binance.paper_account
+func LoadAccounts(ctx context.Context, db *sqlx.DB) ([]Account, error) {
+	rows, err := db.QueryContext(ctx, `SELECT account_id, name, symbol, binance.get_symbol_id(symbol), indicator, params,
+		period, initial_cash, pay_with_bnb
+	FROM binance.paper_account
+	WHERE active
+	ORDER BY account_id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load paper accounts: %w", err)
+	}
+	defer rows.Close()
+	var accounts []Account
+	for rows.Next() {
+		var a Account
+		var symbolId sql.NullInt32
+		var params pq.Int64Array
+		if err := rows.Scan(&a.Id, &a.Name, &a.Symbol, &symbolId, &a.Indicator, &params, &a.Period, &a.InitialCash,
+			&a.PayWithBNB); err != nil {
+			return nil, err
+		}
+		if !symbolId.Valid {
+			return nil, fmt.Errorf("unknown symbol %s of paper account %s", a.Symbol, a.Name)
+		}
+		a.SymbolId = symbolId.Int32
+		for _, p := range params {
+			a.Params = append(a.Params, int(p))
+		}
+		accounts = append(accounts, a)
+	}
+	return accounts, rows.Err()
+}
+
+// loadState loads the portfolio and pending orders of the account, ok is false if it has not traded yet
+func loadState(ctx context.Context, db *sqlx.DB, accountId int32) (state backtest.State, lastOpenTime int64, ok bool, err error) {
+	err = db.QueryRowContext(ctx, `SELECT cash, position, avg_price, entry_fees, entry_time, next_order_id, last_open_time
+	FROM binance.paper_position WHERE account_id = $1`, accountId).Scan(&state.Cash, &state.Position, &state.AvgPrice,
+		&state.EntryFees, &state.EntryTime, &state.NextId, &lastOpenTime)
+	if errors.Is(err, sql.ErrNoRows) {
+		return state, 0, false, nil
+	}
+	if err != nil {
+		return state, 0, false, fmt.Errorf("failed to load paper position: %w", err)
+	}
+	rows, err := db.QueryContext(ctx, `SELECT order_id, side, type, quantity, quote_quantity, price, stop_price,
+		submit_time, active_time, status
+	FROM binance.paper_order
+	WHERE account_id = $1 AND status = 'PENDING'
+	ORDER BY order_id`, accountId)
+	if err != nil {
+		return state, 0, false, fmt.Errorf("failed to load paper orders: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var o backtest.Order
+		var side, orderType, status string
+		if err = rows.Scan(&o.Id, &side, &orderType, &o.Quantity, &o.QuoteQuantity, &o.Price, &o.StopPrice,
+			&o.SubmitTime, &o.ActiveTime, &status); err != nil {
+			return state, 0, false, err
+		}
+		if err = o.Side.UnmarshalText([]byte(side)); err != nil {
+			return state, 0, false, err
+		}
+		if err = o.Type.UnmarshalText([]byte(orderType)); err != nil {
+			return state, 0, false, err
+		}
+		if err = o.Status.UnmarshalText([]byte(status)); err != nil {
+			return state, 0, false, err
+		}
+		state.Pending = append(state.Pending, o)
+	}
+	return state, lastOpenTime, true, rows.Err()
+}
+
+// saveUpdates persists changes of the engine after the kline in a single transaction
+func saveUpdates(ctx context.Context, db *sqlx.DB, accountId int32, u backtest.Updates, state backtest.State,
+	lastOpenTime int64) error {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, o := range u.Orders {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO binance.paper_order (account_id, order_id, side, type, quantity,
+			quote_quantity, price, stop_price, submit_time, active_time, status, reason)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		ON CONFLICT (account_id, order_id) DO UPDATE SET status = EXCLUDED.status, reason = EXCLUDED.reason,
+			quantity = EXCLUDED.quantity, updated_at = now()`,
+			accountId, o.Id, o.Side.String(), o.Type.String(), o.Quantity, o.QuoteQuantity, o.Price, o.StopPrice,
+			o.SubmitTime, o.ActiveTime, o.Status.String(), o.Reason); err != nil {
+			return fmt.Errorf("failed to save paper order: %w", err)
+		}
+	}
+	for _, f := range u.Fills {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO binance.paper_fill (account_id, order_id, time, side, price,
+			quantity, fee, maker)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT DO NOTHING`,
+			accountId, f.OrderId, f.Time, f.Side.String(), f.Price, f.Quantity, f.Fee, f.Maker); err != nil {
+			return fmt.Errorf("failed to save paper fill: %w", err)
+		}
+	}
+	for _, p := range u.Equity {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO binance.paper_equity (account_id, time, equity, cash, position, price)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT DO NOTHING`,
+			accountId, p.Time, p.Equity, p.Cash, p.Position, p.Price); err != nil {
+			return fmt.Errorf("failed to save paper equity: %w", err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO binance.paper_position (account_id, cash, position, avg_price,
+		entry_fees, entry_time, next_order_id, last_open_time)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	ON CONFLICT (account_id) DO UPDATE SET cash = EXCLUDED.cash, position = EXCLUDED.position,
+		avg_price = EXCLUDED.avg_price, entry_fees = EXCLUDED.entry_fees, entry_time = EXCLUDED.entry_time,
+		next_order_id = EXCLUDED.next_order_id, last_open_time = EXCLUDED.last_open_time, updated_at = now()`,
+		accountId, state.Cash, state.Position, state.AvgPrice, state.EntryFees, state.EntryTime, state.NextId,
+		lastOpenTime); err != nil {
+		return fmt.Errorf("failed to save paper position: %w", err)
+	}
+	return tx.Commit()
+}
+
+// BookQuotes is the QuoteSource of the order book collected into binance.bid_book and binance.ask_book
+type BookQuotes struct {
+	DB *sqlx.DB
+	// MaxAge of the book updates, quotes are not known if the book was not updated for longer
+	MaxAge time.Duration
+}
+
+// Quotes returns the best bid and ask of the latest volumes of prices updated within MaxAge
+func (b BookQuotes) Quotes(ctx context.Context, symbol string) (bid, ask float64, ok bool, err error) {
+	maxAge := b.MaxAge
+	if maxAge == 0 {
+		maxAge = time.Minute
+	}
+	var quotes [2]sql.NullFloat64
+	for i, side := range []struct{ table, best string }{{"bid_book", "max"}, {"ask_book", "min"}} {
+		query := fmt.Sprintf(`SELECT %s(price)::float8 FROM (
+			SELECT DISTINCT ON (price) price, volume
+			FROM binance.%s
+			WHERE symbol_id = (SELECT id FROM binance.symbol_prices WHERE symbol = upper($1))
+				AND ptime > now() - $2::interval
+			ORDER BY price, ptime DESC
+		) t WHERE volume > 0`, side.best, side.table)
+		if err = b.DB.QueryRowContext(ctx, query, symbol, fmt.Sprintf("%d milliseconds", maxAge.Milliseconds())).
+			Scan(&quotes[i]); err != nil {
+			return 0, 0, false, fmt.Errorf("failed to get %s quote of %s: %w", side.best, symbol, err)
+		}
+	}
+	if !quotes[0].Valid || !quotes[1].Valid || quotes[0].Float64 >= quotes[1].Float64 {
+		return 0, 0, false, nil
+	}
+	return quotes[0].Float64, quotes[1].Float64, true, nil
+}
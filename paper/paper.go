@@ -0,0 +1,219 @@
+package paper
+
+import (
+	"context"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"github.com/okharch/binance/backtest"
+	"github.com/okharch/binance/klines"
+	"github.com/okharch/binance/klines/download"
+	"github.com/okharch/binance/orders"
+	"github.com/okharch/binance/risk"
+	"log"
+	"time"
+)
+
+/*
+Paper trading forward-tests strategies on the live 1m kline stream without real money.
+every account runs a backtest engine which is stepped by closed klines of its symbol,
+orders are filled at the best bid and ask of the order book as soon as the kline is processed,
+or against the next klines if the book is not available.
+orders, fills, equity and the portfolio are persisted after every kline, so the service
+continues from the saved state after restart: the ticker is warmed up by klines from binance.klines.
+*/
+
+// QuoteSource returns the best bid and ask of the symbol, ok is false if they are not known
+type QuoteSource interface {
+	Quotes(ctx context.Context, symbol string) (bid, ask float64, ok bool, err error)
+}
+
+// trader runs the strategy of the account
+type trader struct {
+	account      Account
+	engine       *backtest.Engine
+	strategy     backtest.Strategy
+	lastOpenTime int64 // the last 1m kline processed
+}
+
+// step processes closed 1m kline, false if it was processed already
+func (t *trader) step(k klines.KLineEntry) bool {
+	if k.OpenTime <= t.lastOpenTime {
+		return false
+	}
+	t.engine.Step(t.strategy, k)
+	t.lastOpenTime = k.OpenTime
+	return true
+}
+
+// book of the account for the risk manager
+func (t *trader) book() string {
+	return "paper " + t.account.Name
+}
+
+// exposure returns the position of the account and the value of its open buy orders
+func (t *trader) exposure() risk.Exposure {
+	price := t.engine.Price()
+	e := risk.Exposure{Position: t.engine.Position(), Price: price}
+	for _, o := range t.engine.OpenOrders() {
+		if o.Side == backtest.Buy {
+			e.OpenBuys += orderValue(o, price)
+		}
+	}
+	return e
+}
+
+// orderValue returns the quote value of the order, market orders by quantity are valued at the price
+func orderValue(o backtest.Order, price float64) float64 {
+	switch {
+	case o.QuoteQuantity > 0:
+		return o.QuoteQuantity
+	case o.Price > 0:
+		return o.Quantity * o.Price
+	}
+	return o.Quantity * price
+}
+
+type Service struct {
+	db      *sqlx.DB
+	quotes  QuoteSource
+	traders map[string][]*trader // by symbol
+	risk    *risk.Manager
+}
+
+// NewService loads active accounts and restores their state, quotes may be nil
+func NewService(ctx context.Context, db *sqlx.DB, quotes QuoteSource) (*Service, error) {
+	accounts, err := LoadAccounts(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	s := &Service{db: db, quotes: quotes, traders: map[string][]*trader{}}
+	for _, account := range accounts {
+		t, err := s.newTrader(ctx, account)
+		if err != nil {
+			return nil, fmt.Errorf("failed to start paper account %s: %w", account.Name, err)
+		}
+		s.traders[account.Symbol] = append(s.traders[account.Symbol], t)
+		log.Printf("paper account %s: %s %s%v %s, equity %.2f", account.Name, account.Symbol,
+			account.Indicator, account.Params, account.Period, t.engine.Equity())
+	}
+	return s, nil
+}
+
+func (s *Service) newTrader(ctx context.Context, account Account) (*trader, error) {
+	period, err := klines.Period2Duration(account.Period)
+	if err != nil {
+		return nil, err
+	}
+	strategy, err := backtest.NewSignalStrategy(account.Indicator, account.Params, period)
+	if err != nil {
+		return nil, err
+	}
+	cfg := backtest.DefaultConfig()
+	cfg.InitialCash = account.InitialCash
+	cfg.Fees = backtest.BinanceFees(account.PayWithBNB)
+	cfg.CloseAtEnd = false
+	engine := backtest.New(cfg)
+
+	history, err := klines.FetchKLineDataFromDBSincePeriodsBefore(s.db, account.SymbolId, int64(strategy.HistoryMinutes()))
+	if err != nil {
+		return nil, err
+	}
+	engine.Start(history.Data)
+	t := &trader{account: account, engine: engine, strategy: strategy}
+	if n := len(history.Data); n > 0 {
+		t.lastOpenTime = history.Data[n-1].OpenTime
+	}
+	state, lastOpenTime, ok, err := loadState(ctx, s.db, account.Id)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		engine.Restore(state)
+		if lastOpenTime > t.lastOpenTime {
+			t.lastOpenTime = lastOpenTime
+		}
+	}
+	return t, nil
+}
+
+// SetRisk checks the orders of all accounts by the risk manager,
+// the open orders are cancelled on the next kline after the kill switch is engaged
+func (s *Service) SetRisk(ctx context.Context, r *risk.Manager) {
+	s.risk = r
+	for _, traders := range s.traders {
+		for _, t := range traders {
+			t := t
+			t.engine.SetOrderCheck(func(e *backtest.Engine, o backtest.Order) error {
+				side := orders.Buy
+				if o.Side == backtest.Sell {
+					side = orders.Sell
+				}
+				price := e.Price()
+				return r.CheckOrder(ctx, risk.Order{Book: t.book(), Symbol: t.account.Symbol, Side: side,
+					QuoteQty: orderValue(o, price)})
+			})
+			s.updateRisk(t)
+		}
+	}
+}
+
+// updateRisk sets the exposure and equity of the account to the risk manager
+func (s *Service) updateRisk(t *trader) {
+	if s.risk == nil {
+		return
+	}
+	s.risk.SetExposure(t.book(), t.account.Symbol, t.exposure())
+	s.risk.SetEquity(t.book(), t.engine.Equity())
+}
+
+// Run processes messages of web socket kline stream until it is closed or ctx is cancelled
+func (s *Service) Run(ctx context.Context, messages <-chan []byte) error {
+	for {
+		var msg []byte
+		var ok bool
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg, ok = <-messages:
+			if !ok {
+				return ctx.Err()
+			}
+		}
+		km, err := download.ParseKlineMessage(msg)
+		if err != nil {
+			log.Print(err)
+			continue
+		}
+		if !km.Closed || km.Period != "1m" {
+			continue
+		}
+		for _, t := range s.traders[km.Symbol] {
+			if err := s.process(ctx, t, km.KLine); err != nil {
+				log.Printf("paper account %s: %v", t.account.Name, err)
+			}
+		}
+	}
+}
+
+// process steps the trader by the kline, fills its orders at the current quotes and persists the changes
+func (s *Service) process(ctx context.Context, t *trader, k klines.KLineEntry) error {
+	if k.OpenTime <= t.lastOpenTime {
+		return nil
+	}
+	if s.risk != nil && s.risk.Killed() {
+		for _, o := range t.engine.OpenOrders() {
+			t.engine.Cancel(o.Id)
+		}
+	}
+	t.step(k)
+	if s.quotes != nil && len(t.engine.OpenOrders()) > 0 {
+		bid, ask, ok, err := s.quotes.Quotes(ctx, t.account.Symbol)
+		if err != nil {
+			log.Printf("paper account %s: failed to get quotes: %v", t.account.Name, err)
+		} else if ok {
+			t.engine.FillAtQuotes(time.Now().UnixMilli(), bid, ask)
+		}
+	}
+	s.updateRisk(t)
+	return saveUpdates(ctx, s.db, t.account.Id, t.engine.TakeUpdates(), t.engine.State(), t.lastOpenTime)
+}
@@ -0,0 +1,57 @@
+package paper
+
+import (
+	"context"
+	"github.com/okharch/binance/backtest"
+	"github.com/okharch/binance/klines"
+	"github.com/okharch/binance/risk"
+	"testing"
+)
+
+func TestTraderSkipsProcessedKLines(t *testing.T) {
+	var calls int
+	tr := &trader{
+		engine:       backtest.New(backtest.DefaultConfig()),
+		strategy:     backtest.StrategyFunc(func(e *backtest.Engine, k klines.KLineEntry) { calls++ }),
+		lastOpenTime: 60000,
+	}
+	tr.engine.Start(nil)
+	for _, openTime := range []int64{0, 60000, 120000, 120000, 180000} {
+		tr.step(klines.KLineEntry{OpenTime: openTime, CloseTime: openTime + 59999, OpenPrice: 100, HighPrice: 100,
+			LowPrice: 100, ClosePrice: 100})
+	}
+	if calls != 2 || tr.lastOpenTime != 180000 {
+		t.Errorf("expected 2 klines processed up to 180000, got %d up to %d", calls, tr.lastOpenTime)
+	}
+}
+
+func TestRiskChecksOrders(t *testing.T) {
+	r := risk.NewManager(risk.Limits{MaxPosition: map[string]float64{"BTCUSDT": 250}})
+	tr := &trader{
+		account: Account{Name: "test", Symbol: "BTCUSDT"},
+		engine:  backtest.New(backtest.DefaultConfig()),
+		strategy: backtest.StrategyFunc(func(e *backtest.Engine, k klines.KLineEntry) {
+			e.Buy(2)
+		}),
+	}
+	tr.engine.Start(nil)
+	s := &Service{traders: map[string][]*trader{"BTCUSDT": {tr}}}
+	s.SetRisk(context.Background(), r)
+	for _, openTime := range []int64{60000, 120000, 180000} {
+		tr.step(klines.KLineEntry{OpenTime: openTime, CloseTime: openTime + 59999, OpenPrice: 100, HighPrice: 100,
+			LowPrice: 100, ClosePrice: 100})
+		s.updateRisk(tr)
+	}
+	// the first order of 200 is within the limit, the next ones would exceed it
+	updates := tr.engine.TakeUpdates()
+	var rejected int
+	for _, o := range updates.Orders {
+		if o.Status == backtest.Rejected {
+			rejected++
+		}
+	}
+	if rejected != 2 || tr.engine.Position() != 2 {
+		t.Errorf("expected the orders over the limit to be rejected, position %v, orders %+v", tr.engine.Position(),
+			updates.Orders)
+	}
+}
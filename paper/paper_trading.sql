@@ -0,0 +1,69 @@
+-- paper trading accounts: each one trades signals of the indicator with params on the period of the symbol
+CREATE TABLE IF NOT EXISTS binance.paper_account (
+    account_id   serial PRIMARY KEY,
+    name         text NOT NULL UNIQUE,
+    symbol       text NOT NULL,
+    indicator    text NOT NULL,
+    params       int[] NOT NULL DEFAULT '{}',
+    period       text NOT NULL DEFAULT '1h',
+    initial_cash float8 NOT NULL DEFAULT 10000,
+    pay_with_bnb bool NOT NULL DEFAULT false,
+    active       bool NOT NULL DEFAULT true,
+    created_at   timestamptz NOT NULL DEFAULT now()
+);
+
+-- the current portfolio of the account, it is reloaded after restarts
+CREATE TABLE IF NOT EXISTS binance.paper_position (
+    account_id     int PRIMARY KEY REFERENCES binance.paper_account ON DELETE CASCADE,
+    cash           float8 NOT NULL,
+    position       float8 NOT NULL,
+    avg_price      float8 NOT NULL,
+    entry_fees     float8 NOT NULL,
+    entry_time     int8 NOT NULL,
+    next_order_id  int NOT NULL,
+    last_open_time int8 NOT NULL, -- the last 1m kline processed
+    updated_at     timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS binance.paper_order (
+    account_id     int NOT NULL REFERENCES binance.paper_account ON DELETE CASCADE,
+    order_id       int NOT NULL,
+    side           text NOT NULL, -- BUY, SELL
+    type           text NOT NULL, -- MARKET, LIMIT, STOP
+    quantity       float8 NOT NULL,
+    quote_quantity float8 NOT NULL,
+    price          float8 NOT NULL,
+    stop_price     float8 NOT NULL,
+    submit_time    int8 NOT NULL,
+    active_time    int8 NOT NULL,
+    status         text NOT NULL, -- PENDING, FILLED, CANCELLED, REJECTED
+    reason         text NOT NULL DEFAULT '',
+    updated_at     timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (account_id, order_id)
+);
+CREATE INDEX IF NOT EXISTS paper_order_pending ON binance.paper_order (account_id) WHERE status = 'PENDING';
+
+CREATE TABLE IF NOT EXISTS binance.paper_fill (
+    account_id int NOT NULL REFERENCES binance.paper_account ON DELETE CASCADE,
+    order_id   int NOT NULL,
+    time       int8 NOT NULL,
+    side       text NOT NULL,
+    price      float8 NOT NULL,
+    quantity   float8 NOT NULL,
+    fee        float8 NOT NULL,
+    maker      bool NOT NULL,
+    PRIMARY KEY (account_id, order_id, time)
+);
+
+CREATE TABLE IF NOT EXISTS binance.paper_equity (
+    account_id int NOT NULL REFERENCES binance.paper_account ON DELETE CASCADE,
+    time       int8 NOT NULL,
+    equity     float8 NOT NULL,
+    cash       float8 NOT NULL,
+    position   float8 NOT NULL,
+    price      float8 NOT NULL,
+    PRIMARY KEY (account_id, time)
+);
+
+-- example:
+-- INSERT INTO binance.paper_account (name, symbol, indicator, params, period) VALUES ('btc-mac-1h', 'BTCUSDT', 'MAC', '{10,20}', '1h');
@@ -0,0 +1,322 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"github.com/okharch/binance/indicators"
+	"github.com/okharch/binance/klines"
+	"github.com/okharch/binance/ticker"
+	"log"
+	"runtime"
+	"sync"
+	"time"
+)
+
+/*
+Signal generation pipeline:
+
+	symbols -> workers (one streaming ticker per symbol) -> writer (batched inserts, checkpoints)
+
+besides signals, the workers calculate values of multi-line indicators on the completed klines, see ticker.CalculateValues.
+
+each worker streams 1m klines of its symbol from the store in chunks, prefetching the next chunk
+while the current one is processed, so the memory does not depend on the history length.
+only the history the registered indicators need on their periods is loaded before the checkpoints,
+see ticker.HistoryMinutes, the values of the longer periods are calculated once the ticker has enough klines of them.
+the single writer saves checkpoints of a symbol only after all the signals emitted before them are inserted.
+*/
+
+// Config of the signal generation pipeline
+type Config struct {
+	Workers         int  // number of symbols processed concurrently, runtime.NumCPU() if 0
+	ChunkMinutes    int  // number of 1m klines fetched from the store at once
+	BatchSize       int  // number of signals or values inserted at once
+	CheckpointTicks int  // checkpoints are saved every CheckpointTicks processed 1m klines
+	Full            bool // ignore checkpoints and generate signals for all the klines
+}
+
+// DefaultConfig returns config with the week chunks and a checkpoint per day of klines
+func DefaultConfig() Config {
+	return Config{
+		Workers:         runtime.NumCPU(),
+		ChunkMinutes:    1440 * 7,
+		BatchSize:       5000,
+		CheckpointTicks: 1440,
+	}
+}
+
+// writeItem is either a batch of signals, a batch of values or checkpoints of the symbol
+type writeItem struct {
+	signals     []indicators.IndicatorSignal
+	values      []indicators.IndicatorValueRecord
+	symbolId    int32
+	checkpoints ticker.Checkpoints
+}
+
+type pipeline struct {
+	cfg       Config
+	store     Store
+	catalogue *indicators.Catalogue
+	stats     *Stats
+	writes    chan writeItem
+}
+
+// Run generates signals for the symbols using cfg.Workers workers and a single writer.
+// checkpoints reached before ctx is cancelled are saved, stats may be nil.
+func Run(ctx context.Context, store Store, catalogue *indicators.Catalogue, symbols []int32, cfg Config, stats *Stats) error {
+	def := DefaultConfig()
+	if cfg.Workers <= 0 {
+		cfg.Workers = def.Workers
+	}
+	if cfg.ChunkMinutes <= 0 {
+		cfg.ChunkMinutes = def.ChunkMinutes
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = def.BatchSize
+	}
+	if cfg.CheckpointTicks <= 0 {
+		cfg.CheckpointTicks = def.CheckpointTicks
+	}
+	if stats == nil {
+		stats = &Stats{}
+	}
+	p := &pipeline{cfg: cfg, store: store, catalogue: catalogue, stats: stats,
+		writes: make(chan writeItem, cfg.Workers)}
+
+	// the writer cancels workers when it fails
+	workersCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	writerErr := make(chan error, 1)
+	go func() {
+		writerErr <- p.write(cancel)
+	}()
+
+	symbolsCh := make(chan int32)
+	var wg sync.WaitGroup
+	for i := 0; i < cfg.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for symbol := range symbolsCh {
+				if err := p.processSymbol(workersCtx, symbol); err != nil && workersCtx.Err() == nil {
+					log.Printf("failed to generate signals for symbol %d: %v", symbol, err)
+				}
+				stats.add(&stats.Symbols, 1)
+			}
+		}()
+	}
+feed:
+	for _, symbol := range symbols {
+		select {
+		case <-workersCtx.Done():
+			break feed
+		case symbolsCh <- symbol:
+		}
+	}
+	close(symbolsCh)
+	wg.Wait()
+	close(p.writes)
+	if err := <-writerErr; err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// write inserts signals and values in batches and saves checkpoints in the order they were sent by workers.
+// after a failure it keeps draining writes, so the workers are never blocked.
+func (p *pipeline) write(cancel context.CancelFunc) error {
+	// the writes are completed even if the pipeline is cancelled, to save the progress
+	ctx := context.Background()
+	var batch []indicators.IndicatorSignal
+	var valuesBatch []indicators.IndicatorValueRecord
+	var failed error
+	flushSignals := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := p.store.InsertSignals(ctx, batch); err != nil {
+			return fmt.Errorf("failed to insert %d signals: %w", len(batch), err)
+		}
+		p.stats.add(&p.stats.Inserted, int64(len(batch)))
+		batch = batch[:0]
+		return nil
+	}
+	flushValues := func() error {
+		if len(valuesBatch) == 0 {
+			return nil
+		}
+		if err := p.store.InsertValues(ctx, valuesBatch); err != nil {
+			return fmt.Errorf("failed to insert %d indicator values: %w", len(valuesBatch), err)
+		}
+		p.stats.add(&p.stats.Values, int64(len(valuesBatch)))
+		valuesBatch = valuesBatch[:0]
+		return nil
+	}
+	flush := func() error {
+		if err := flushSignals(); err != nil {
+			return err
+		}
+		return flushValues()
+	}
+	for item := range p.writes {
+		if failed != nil {
+			continue
+		}
+		var err error
+		if item.checkpoints == nil {
+			batch = append(batch, item.signals...)
+			valuesBatch = append(valuesBatch, item.values...)
+			if len(batch) >= p.cfg.BatchSize {
+				err = flushSignals()
+			}
+			if len(valuesBatch) >= p.cfg.BatchSize && err == nil {
+				err = flushValues()
+			}
+		} else if err = flush(); err == nil {
+			err = p.store.SaveCheckpoints(ctx, item.symbolId, item.checkpoints)
+			p.stats.add(&p.stats.Checkpoints, 1)
+		}
+		if err != nil {
+			failed = err
+			cancel()
+		}
+	}
+	if failed != nil {
+		return failed
+	}
+	return flush()
+}
+
+// processSymbol streams klines of the symbol through a streaming ticker and sends signals to the writer
+func (p *pipeline) processSymbol(ctx context.Context, symbol int32) error {
+	checkpoints := ticker.Checkpoints{}
+	if !p.cfg.Full {
+		var err error
+		if checkpoints, err = p.store.LoadCheckpoints(ctx, symbol); err != nil {
+			return err
+		}
+	}
+	// klines required by indicators before the checkpoint and new ones
+	since := checkpoints.Min()
+	history := int64(ticker.HistoryMinutes()) * 60000
+	endTime := time.Now().UnixNano() / int64(time.Millisecond)
+	startTime := endTime - history
+	if since > 0 && since-history < startTime {
+		startTime = since - history
+	}
+
+	t := ticker.NewStreamingTicker(ticker.KLinesToKeep())
+	var signals []indicators.IndicatorSignal
+	var values []indicators.IndicatorValueRecord
+	emit := func(signal indicators.IndicatorSignal) {
+		signals = append(signals, signal)
+	}
+	emitValues := func(records []indicators.IndicatorValueRecord) {
+		values = append(values, records...)
+	}
+	flushSignals := func() {
+		if len(signals) == 0 {
+			return
+		}
+		p.stats.add(&p.stats.Signals, int64(len(signals)))
+		p.writes <- writeItem{signals: signals}
+		signals = nil
+	}
+	flushValues := func() {
+		if len(values) == 0 {
+			return
+		}
+		p.writes <- writeItem{values: values}
+		values = nil
+	}
+	saveCheckpoints := func(openTime int64) {
+		flushSignals()
+		flushValues()
+		checkpoints.Advance(openTime)
+		saved := make(ticker.Checkpoints, len(checkpoints))
+		for name, last := range checkpoints {
+			saved[name] = last
+		}
+		p.writes <- writeItem{symbolId: symbol, checkpoints: saved}
+	}
+
+	processed := 0
+	var lastOpenTime int64
+	var err error
+	chunks := p.fetchChunks(ctx, symbol, startTime, endTime)
+chunks:
+	for chunk := range chunks {
+		if chunk.err != nil {
+			err = chunk.err
+			break
+		}
+		for _, kLine := range chunk.kLines {
+			t.Push(kLine)
+			p.stats.add(&p.stats.KLines, 1)
+			if kLine.OpenTime <= since {
+				// history required by indicators
+				continue
+			}
+			t.CalculateSignals(symbol, p.catalogue, checkpoints, emit)
+			t.CalculateValues(symbol, emitValues)
+			if len(signals) >= p.cfg.BatchSize {
+				flushSignals()
+			}
+			if len(values) >= p.cfg.BatchSize {
+				flushValues()
+			}
+			lastOpenTime = kLine.OpenTime
+			processed++
+			if processed%p.cfg.CheckpointTicks == 0 {
+				saveCheckpoints(lastOpenTime)
+			}
+			if ctx.Err() != nil {
+				break chunks
+			}
+		}
+	}
+	// let the prefetching goroutine finish
+	for range chunks {
+	}
+	if processed > 0 {
+		// save the progress even if the context is cancelled
+		saveCheckpoints(lastOpenTime)
+	}
+	if err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+type chunk struct {
+	kLines []klines.KLineEntry
+	err    error
+}
+
+// fetchChunks fetches 1m klines of the symbol in chunks of cfg.ChunkMinutes one chunk ahead of the consumer
+func (p *pipeline) fetchChunks(ctx context.Context, symbol int32, startTime, endTime int64) <-chan chunk {
+	chunks := make(chan chunk, 1)
+	go func() {
+		defer close(chunks)
+		step := int64(p.cfg.ChunkMinutes) * 60000
+		for from := startTime; from <= endTime && ctx.Err() == nil; from += step {
+			to := from + step - 1
+			if to > endTime {
+				to = endTime
+			}
+			kLines, err := p.store.FetchKLines(ctx, symbol, from, to)
+			if err == nil && len(kLines) == 0 {
+				continue
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case chunks <- chunk{kLines, err}:
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return chunks
+}
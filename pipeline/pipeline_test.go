@@ -0,0 +1,239 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"github.com/okharch/binance/indicators"
+	"github.com/okharch/binance/indicators/composite"
+	"github.com/okharch/binance/klines"
+	"github.com/okharch/binance/ticker"
+	"io"
+	"log"
+	"os"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// signalKey is the unique key of binance.indicator_signal
+type signalKey struct {
+	symbolId, indicatorId int32
+	period                int8
+	openTime              int64
+}
+
+type memStore struct {
+	mu          sync.Mutex
+	kLines      map[int32][]klines.KLineEntry
+	checkpoints map[int32]ticker.Checkpoints
+	saves       int
+	signals     int
+	batches     []int // the sizes of inserted batches
+	rows        map[signalKey]bool
+	values      int
+	insertErr   error
+}
+
+func (s *memStore) LoadCheckpoints(_ context.Context, symbolId int32) (ticker.Checkpoints, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := ticker.Checkpoints{}
+	for name, last := range s.checkpoints[symbolId] {
+		result[name] = last
+	}
+	return result, nil
+}
+
+func (s *memStore) FetchKLines(_ context.Context, symbolId int32, startTime, endTime int64) (result []klines.KLineEntry, err error) {
+	for _, kl := range s.kLines[symbolId] {
+		if kl.OpenTime >= startTime && kl.OpenTime <= endTime {
+			result = append(result, kl)
+		}
+	}
+	return
+}
+
+func (s *memStore) InsertSignals(_ context.Context, signals []indicators.IndicatorSignal) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.insertErr != nil {
+		return s.insertErr
+	}
+	s.signals += len(signals)
+	s.batches = append(s.batches, len(signals))
+	for _, signal := range signals {
+		s.rows[signalKey{signal.SymbolId, signal.IndicatorId, signal.Period, signal.OpenTime}] = true
+	}
+	return nil
+}
+
+func (s *memStore) InsertValues(_ context.Context, values []indicators.IndicatorValueRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values += len(values)
+	return nil
+}
+
+func (s *memStore) SaveCheckpoints(_ context.Context, symbolId int32, checkpoints ticker.Checkpoints) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.checkpoints[symbolId] = checkpoints
+	s.saves++
+	return nil
+}
+
+func newMemStore(symbols []int32, n int) *memStore {
+	s := &memStore{kLines: map[int32][]klines.KLineEntry{}, checkpoints: map[int32]ticker.Checkpoints{},
+		rows: map[signalKey]bool{}}
+	now := time.Now().Truncate(time.Minute).UnixMilli()
+	for _, symbol := range symbols {
+		data := make([]klines.KLineEntry, n)
+		for i := range data {
+			price := 100 + float64(i%50)
+			data[i] = klines.KLineEntry{
+				OpenTime:  now - int64(n-i)*60000,
+				CloseTime: now - int64(n-i-1)*60000 - 1,
+				OpenPrice: price, HighPrice: price + 1, LowPrice: price - 1, ClosePrice: price,
+				Volume: 10,
+			}
+		}
+		s.kLines[symbol] = data
+	}
+	return s
+}
+
+// catalogue of the tests has only the rule registered by TestMain, it buys while the 5m kline closes above 120,
+// the prices of memStore klines cycle from 100 to 149
+var catalogue = indicators.NewCatalogue(nil)
+
+func TestMain(m *testing.M) {
+	rule := composite.Rule{Name: "close_5m", Period: "5m", Side: "BUY",
+		Condition: composite.Condition{Value: "close", Op: ">", Threshold: 120}}
+	if err := composite.Register([]composite.Rule{rule}); err != nil {
+		panic(err)
+	}
+	ind, _ := indicators.Lookup(rule.Name)
+	if err := catalogue.Register(context.Background(), []indicators.Indicator{ind}); err != nil {
+		panic(err)
+	}
+	// the signals of other indicators are reported as missing in the catalogue
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+func TestRunCheckpoints(t *testing.T) {
+	symbols := []int32{1, 2, 3}
+	const n = 400
+	store := newMemStore(symbols, n)
+	cfg := Config{Workers: 2, ChunkMinutes: 250, CheckpointTicks: 100}
+	stats := &Stats{}
+	if err := Run(context.Background(), store, catalogue, symbols, cfg, stats); err != nil {
+		t.Fatal(err)
+	}
+	s := stats.Snapshot()
+	if s.Symbols != 3 || s.KLines != 3*n {
+		t.Errorf("expected 3 symbols and %d klines, got %+v", 3*n, s)
+	}
+	if s.Signals == 0 || s.Inserted != s.Signals || store.signals != int(s.Signals) {
+		t.Errorf("expected all the signals to be inserted, got %d inserted of %+v", store.signals, s)
+	}
+	if s.Values == 0 || store.values != int(s.Values) {
+		t.Errorf("expected indicator values to be inserted, got %d inserted of %+v", store.values, s)
+	}
+	// 4 periodic checkpoints and the final one per symbol
+	if store.saves != 3*5 {
+		t.Errorf("expected 15 saved checkpoints, got %d", store.saves)
+	}
+	for _, symbol := range symbols {
+		last := store.kLines[symbol][n-1].OpenTime
+		if got := store.checkpoints[symbol].Min(); got != last {
+			t.Errorf("symbol %d: expected checkpoint %d, got %d", symbol, last, got)
+		}
+	}
+
+	// nothing is processed after the checkpoints, only the history the indicators need is loaded
+	store.saves = 0
+	stats = &Stats{}
+	if err := Run(context.Background(), store, catalogue, symbols, cfg, stats); err != nil {
+		t.Fatal(err)
+	}
+	if store.saves != 0 {
+		t.Errorf("expected no checkpoints after resume, got %d", store.saves)
+	}
+	history := ticker.HistoryMinutes() + 1
+	if history > n {
+		history = n
+	}
+	if s := stats.Snapshot(); s.KLines != int64(3*history) {
+		t.Errorf("expected %d klines of history per symbol, got %+v", history, s)
+	}
+}
+
+func TestRunBatches(t *testing.T) {
+	const batchSize = 20
+	store := newMemStore([]int32{1}, 400)
+	// the only checkpoint is the final one, so only the last batch is flushed before it is full
+	cfg := Config{Workers: 1, ChunkMinutes: 100, BatchSize: batchSize, CheckpointTicks: 1000}
+	stats := &Stats{}
+	if err := Run(context.Background(), store, catalogue, []int32{1}, cfg, stats); err != nil {
+		t.Fatal(err)
+	}
+	s := stats.Snapshot()
+	if s.Signals <= batchSize || s.Inserted != s.Signals || store.signals != int(s.Signals) {
+		t.Fatalf("expected more than %d signals to be inserted, got %d inserted of %+v", batchSize, store.signals, s)
+	}
+	n := len(store.batches)
+	if n != int(s.Signals+batchSize-1)/batchSize {
+		t.Errorf("expected %d signals in batches of %d, got %v", s.Signals, batchSize, store.batches)
+	}
+	for i, size := range store.batches {
+		if size > batchSize || size < batchSize && i < n-1 {
+			t.Errorf("unexpected size %d of batch %d: %v", size, i, store.batches)
+		}
+	}
+}
+
+func TestRunAlignsPeriods(t *testing.T) {
+	// the same klines without the first 3 minutes, so the second run starts at another time
+	store := newMemStore([]int32{1}, 400)
+	late := newMemStore(nil, 0)
+	late.kLines[1] = store.kLines[1][3:]
+	for _, s := range []*memStore{store, late} {
+		if err := Run(context.Background(), s, catalogue, []int32{1}, Config{Workers: 1}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the rows of both runs are the same after the warm-up of the second run:
+	// its first 5m kline is incomplete and the rule requires 4 more klines
+	from := late.kLines[1][0].OpenTime + 5*5*60000
+	rows := func(s *memStore) map[signalKey]bool {
+		result := map[signalKey]bool{}
+		for row := range s.rows {
+			if row.openTime%(5*60000) != 0 {
+				t.Errorf("open time %d of 5m signal is not aligned", row.openTime)
+			}
+			if row.openTime >= from {
+				result[row] = true
+			}
+		}
+		return result
+	}
+	expected, got := rows(store), rows(late)
+	if len(expected) == 0 {
+		t.Fatal("expected signals")
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected the same %d signals of both runs, got %d", len(expected), len(got))
+	}
+}
+
+func TestRunCancelled(t *testing.T) {
+	store := newMemStore([]int32{1}, 100)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := Run(ctx, store, catalogue, []int32{1}, Config{}, nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
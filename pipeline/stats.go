@@ -0,0 +1,57 @@
+package pipeline
+
+import (
+	"context"
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+// Stats are progress counters of the pipeline, they are safe to read while it runs
+type Stats struct {
+	Symbols     int64 // symbols processed
+	KLines      int64 // 1m klines pushed to tickers
+	Signals     int64 // signals generated
+	Inserted    int64 // signals passed to the store
+	Values      int64 // indicator values passed to the store
+	Checkpoints int64 // checkpoints saved
+}
+
+func (s *Stats) add(counter *int64, n int64) {
+	atomic.AddInt64(counter, n)
+}
+
+// Snapshot returns a consistent enough copy of the counters
+func (s *Stats) Snapshot() Stats {
+	return Stats{
+		Symbols:     atomic.LoadInt64(&s.Symbols),
+		KLines:      atomic.LoadInt64(&s.KLines),
+		Signals:     atomic.LoadInt64(&s.Signals),
+		Inserted:    atomic.LoadInt64(&s.Inserted),
+		Values:      atomic.LoadInt64(&s.Values),
+		Checkpoints: atomic.LoadInt64(&s.Checkpoints),
+	}
+}
+
+// Report logs progress and throughput every interval until ctx is done
+func (s *Stats) Report(ctx context.Context, interval time.Duration, totalSymbols int) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	last := s.Snapshot()
+	lastTime := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			cur := s.Snapshot()
+			seconds := now.Sub(lastTime).Seconds()
+			log.Printf("symbols %d/%d, klines %d (%.0f/s), signals %d (%.0f/s), inserted %d, values %d",
+				cur.Symbols, totalSymbols,
+				cur.KLines, float64(cur.KLines-last.KLines)/seconds,
+				cur.Signals, float64(cur.Signals-last.Signals)/seconds,
+				cur.Inserted, cur.Values)
+			last, lastTime = cur, now
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package pipeline
+
+import (
+	"context"
+	"github.com/jmoiron/sqlx"
+	"github.com/okharch/binance/indicators"
+	"github.com/okharch/binance/klines"
+	"github.com/okharch/binance/ticker"
+)
+
+// Store is the source of klines and the destination of signals, indicator values and checkpoints of the pipeline
+type Store interface {
+	LoadCheckpoints(ctx context.Context, symbolId int32) (ticker.Checkpoints, error)
+	FetchKLines(ctx context.Context, symbolId int32, startTime, endTime int64) ([]klines.KLineEntry, error)
+	InsertSignals(ctx context.Context, signals []indicators.IndicatorSignal) error
+	InsertValues(ctx context.Context, values []indicators.IndicatorValueRecord) error
+	SaveCheckpoints(ctx context.Context, symbolId int32, checkpoints ticker.Checkpoints) error
+}
+
+// DBStore is the Store of binance database
+type DBStore struct {
+	DB *sqlx.DB
+}
+
+func (s DBStore) LoadCheckpoints(ctx context.Context, symbolId int32) (ticker.Checkpoints, error) {
+	return ticker.LoadCheckpoints(ctx, s.DB, symbolId)
+}
+
+func (s DBStore) FetchKLines(_ context.Context, symbolId int32, startTime, endTime int64) ([]klines.KLineEntry, error) {
+	kd, err := klines.FetchKLineDataFromDB(s.DB, symbolId, startTime, endTime)
+	if err != nil {
+		return nil, err
+	}
+	return kd.Data, nil
+}
+
+func (s DBStore) InsertSignals(_ context.Context, signals []indicators.IndicatorSignal) error {
+	return ticker.InsertIndicatorSignals(s.DB, signals)
+}
+
+func (s DBStore) InsertValues(_ context.Context, values []indicators.IndicatorValueRecord) error {
+	return indicators.InsertIndicatorValues(s.DB, values)
+}
+
+func (s DBStore) SaveCheckpoints(ctx context.Context, symbolId int32, checkpoints ticker.Checkpoints) error {
+	return ticker.SaveCheckpoints(ctx, s.DB, symbolId, checkpoints)
+}
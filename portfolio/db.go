@@ -0,0 +1,119 @@
+package portfolio
+
+import (
+	"context"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"log"
+	"time"
+)
+
+// LoadRates returns the rates of the latest prices of trading symbols of binance.exchange_symbols
+func LoadRates(ctx context.Context, db *sqlx.DB) (*Rates, error) {
+	var pairs []Pair
+	if err := db.SelectContext(ctx, &pairs, `SELECT e.symbol AS "symbol", e.base_asset AS "base",
+		e.quote_asset AS "quote", p.price::float8 AS "price"
+	FROM binance.exchange_symbols e
+	JOIN binance.symbol_prices p ON p.symbol = e.symbol
+	WHERE e.status = 'TRADING' AND p.price > 0`); err != nil {
+		return nil, fmt.Errorf("failed to load prices: %w", err)
+	}
+	return NewRates(pairs), nil
+}
+
+// StreamBalances are the balances of binance.account_balance updated by the user data stream
+type StreamBalances struct {
+	DB *sqlx.DB
+}
+
+func (s StreamBalances) Balances(ctx context.Context) ([]Balance, error) {
+	var result []Balance
+	err := s.DB.SelectContext(ctx, &result, `SELECT asset AS "asset", free + locked AS "qty"
+	FROM binance.account_balance WHERE free + locked > 0`)
+	return result, err
+}
+
+// ManualHoldings are the holdings of the portfolio entered into binance.portfolio_holding
+type ManualHoldings struct {
+	DB        *sqlx.DB
+	Portfolio string
+}
+
+func (s ManualHoldings) Balances(ctx context.Context) ([]Balance, error) {
+	var result []Balance
+	err := s.DB.SelectContext(ctx, &result, `SELECT asset AS "asset", qty AS "qty"
+	FROM binance.portfolio_holding WHERE portfolio = $1 AND qty > 0`, s.Portfolio)
+	return result, err
+}
+
+// SaveSnapshot stores the snapshot with its assets into binance.portfolio_snapshot and binance.portfolio_snapshot_asset
+func SaveSnapshot(ctx context.Context, db *sqlx.DB, s Snapshot) (snapshotId int64, err error) {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	if err = tx.GetContext(ctx, &snapshotId, `INSERT INTO binance.portfolio_snapshot (portfolio, taken_at,
+		total_usdt, total_btc)
+	VALUES ($1, $2, $3, $4)
+	RETURNING snapshot_id`, s.Portfolio, s.Time, s.TotalUSDT, s.TotalBTC); err != nil {
+		return 0, fmt.Errorf("failed to save snapshot of %s: %w", s.Portfolio, err)
+	}
+	for _, a := range s.Assets {
+		if _, err = tx.ExecContext(ctx, `INSERT INTO binance.portfolio_snapshot_asset (snapshot_id, asset, qty,
+			price_usdt, value_usdt, value_btc, allocation, route)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`, snapshotId, a.Asset, a.Qty, a.PriceUSDT, a.ValueUSDT, a.ValueBTC,
+			a.Allocation, a.Route); err != nil {
+			return 0, fmt.Errorf("failed to save %s of snapshot of %s: %w", a.Asset, s.Portfolio, err)
+		}
+	}
+	return snapshotId, tx.Commit()
+}
+
+// TakeSnapshot values the balances of the source by the latest prices and stores the snapshot
+func TakeSnapshot(ctx context.Context, db *sqlx.DB, portfolio string, source Source) (Snapshot, error) {
+	balances, err := source.Balances(ctx)
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("failed to get balances of %s: %w", portfolio, err)
+	}
+	rates, err := LoadRates(ctx, db)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	s := Valuate(portfolio, balances, rates, time.Now())
+	if _, err := SaveSnapshot(ctx, db, s); err != nil {
+		return s, err
+	}
+	return s, nil
+}
+
+// Run takes snapshots every interval until ctx is cancelled, the errors are logged
+func Run(ctx context.Context, db *sqlx.DB, portfolio string, source Source, interval time.Duration) {
+	for {
+		s, err := TakeSnapshot(ctx, db, portfolio, source)
+		if err != nil {
+			log.Printf("failed to take snapshot: %v", err)
+		} else {
+			LogSnapshot(s)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// LogSnapshot logs the totals, the allocation and the assets which could not be valued
+func LogSnapshot(s Snapshot) {
+	for _, a := range s.Assets {
+		if a.Route != "" {
+			log.Printf("%s %s: %v = %.2f USDT (%.2f%%) via %s", s.Portfolio, a.Asset, a.Qty, a.ValueUSDT,
+				a.Allocation, a.Route)
+		}
+	}
+	if unpriced := s.Unpriced(); len(unpriced) > 0 {
+		log.Printf("%s: no price of %v", s.Portfolio, unpriced)
+	}
+	log.Printf("%s: %.2f USDT, %.8f BTC", s.Portfolio, s.TotalUSDT, s.TotalBTC)
+}
@@ -0,0 +1,112 @@
+package portfolio
+
+import (
+	"context"
+	"github.com/okharch/binance/orders"
+	"sort"
+	"strings"
+	"time"
+)
+
+/*
+Portfolio is the set of balances valued in USDT and BTC by the latest prices of binance.symbol_prices.
+the balances are taken from the account API, binance.account_balance updated by the user data stream
+or binance.portfolio_holding filled manually. the snapshots of the valuation with allocation percentages
+are stored periodically into binance.portfolio_snapshot, see portfolio.sql
+*/
+
+type Balance struct {
+	Asset string  `db:"asset"`
+	Qty   float64 `db:"qty"`
+}
+
+// Source returns the balances of the portfolio
+type Source interface {
+	Balances(ctx context.Context) ([]Balance, error)
+}
+
+// AccountBalances are free and locked balances of the account of the client
+type AccountBalances struct {
+	Client *orders.Client
+}
+
+func (s AccountBalances) Balances(ctx context.Context) ([]Balance, error) {
+	account, err := s.Client.Account(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]Balance, 0, len(account.Balances))
+	for _, b := range account.Balances {
+		if qty := b.Free + b.Locked; qty > 0 {
+			result = append(result, Balance{Asset: b.Asset, Qty: qty})
+		}
+	}
+	return result, nil
+}
+
+const (
+	USDT = "USDT"
+	BTC  = "BTC"
+)
+
+type AssetValue struct {
+	Asset      string
+	Qty        float64
+	PriceUSDT  float64
+	ValueUSDT  float64
+	ValueBTC   float64
+	Allocation float64 // percent of the total USDT value
+	Route      string  // of the conversion to USDT like XYZ>BTC>USDT, empty if the asset could not be valued
+}
+
+type Snapshot struct {
+	Portfolio string
+	Time      time.Time
+	TotalUSDT float64
+	TotalBTC  float64
+	Assets    []AssetValue // sorted by value descending
+}
+
+// Valuate values the balances by the rates, the assets without a route to USDT have zero value
+func Valuate(portfolio string, balances []Balance, rates *Rates, at time.Time) Snapshot {
+	s := Snapshot{Portfolio: portfolio, Time: at}
+	quantities := map[string]float64{}
+	for _, b := range balances {
+		quantities[b.Asset] += b.Qty
+	}
+	for asset, qty := range quantities {
+		a := AssetValue{Asset: asset, Qty: qty}
+		if price, route, ok := rates.Rate(asset, USDT); ok {
+			a.PriceUSDT, a.ValueUSDT, a.Route = price, qty*price, strings.Join(route, ">")
+		}
+		if price, _, ok := rates.Rate(asset, BTC); ok {
+			a.ValueBTC = qty * price
+		}
+		s.TotalUSDT += a.ValueUSDT
+		s.TotalBTC += a.ValueBTC
+		s.Assets = append(s.Assets, a)
+	}
+	for i := range s.Assets {
+		if s.TotalUSDT > 0 {
+			s.Assets[i].Allocation = 100 * s.Assets[i].ValueUSDT / s.TotalUSDT
+		}
+	}
+	sort.Slice(s.Assets, func(i, j int) bool {
+		if s.Assets[i].ValueUSDT != s.Assets[j].ValueUSDT {
+			return s.Assets[i].ValueUSDT > s.Assets[j].ValueUSDT
+		}
+		return s.Assets[i].Asset < s.Assets[j].Asset
+	})
+	return s
+}
+
+// Unpriced returns the assets which could not be valued
+func (s Snapshot) Unpriced() (result []string) {
+	for _, a := range s.Assets {
+		if a.Route == "" {
+			result = append(result, a.Asset)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,36 @@
+-- holdings of the portfolios which are not on the account, e.g. cold wallets
+CREATE TABLE IF NOT EXISTS binance.portfolio_holding (
+    portfolio  text NOT NULL,
+    asset      text NOT NULL,
+    qty        float8 NOT NULL,
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (portfolio, asset)
+);
+
+-- valuations of the portfolios taken by portfolio.TakeSnapshot
+CREATE TABLE IF NOT EXISTS binance.portfolio_snapshot (
+    snapshot_id serial PRIMARY KEY,
+    portfolio   text NOT NULL,
+    taken_at    timestamptz NOT NULL,
+    total_usdt  float8 NOT NULL,
+    total_btc   float8 NOT NULL
+);
+CREATE INDEX IF NOT EXISTS portfolio_snapshot_taken ON binance.portfolio_snapshot (portfolio, taken_at);
+
+CREATE TABLE IF NOT EXISTS binance.portfolio_snapshot_asset (
+    snapshot_id int NOT NULL REFERENCES binance.portfolio_snapshot ON DELETE CASCADE,
+    asset       text NOT NULL,
+    qty         float8 NOT NULL,
+    price_usdt  float8 NOT NULL, -- 0 if there is no route to USDT
+    value_usdt  float8 NOT NULL,
+    value_btc   float8 NOT NULL,
+    allocation  float8 NOT NULL, -- percent of total_usdt
+    route       text NOT NULL DEFAULT '', -- of the conversion to USDT like XYZ>BTC>USDT
+    PRIMARY KEY (snapshot_id, asset)
+);
+
+-- daily value history of the portfolios: the last snapshot of each day
+CREATE OR REPLACE VIEW binance.portfolio_daily AS
+SELECT DISTINCT ON (portfolio, taken_at::date) portfolio, taken_at::date AS day, total_usdt, total_btc, snapshot_id
+FROM binance.portfolio_snapshot
+ORDER BY portfolio, taken_at::date, taken_at DESC;
@@ -0,0 +1,87 @@
+package portfolio
+
+import (
+	"context"
+	"github.com/okharch/binance/orders"
+	"github.com/okharch/binance/orders/stub"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testPairs = []Pair{
+	{"BTCUSDT", "BTC", "USDT", 20000},
+	{"ETHBTC", "ETH", "BTC", 0.05},
+	{"ETHUSDT", "ETH", "USDT", 1000},
+	{"XYZBTC", "XYZ", "BTC", 0.0001},
+	{"XYZETH", "XYZ", "ETH", 0.002},
+	{"USDTDAI", "USDT", "DAI", 1.25},
+	{"ABCDEF", "ABC", "DEF", 1},
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestRates(t *testing.T) {
+	rates := NewRates(testPairs)
+	for _, c := range []struct {
+		asset, currency string
+		rate            float64
+		route           string
+	}{
+		{"BTC", "USDT", 20000, "BTC>USDT"},
+		{"USDT", "BTC", 1.0 / 20000, "USDT>BTC"},
+		{"XYZ", "USDT", 2, "XYZ>BTC>USDT"}, // BTC is preferred to ETH
+		{"DAI", "BTC", 0.8 / 20000, "DAI>USDT>BTC"},
+		{"USDT", "USDT", 1, "USDT"},
+	} {
+		rate, route, ok := rates.Rate(c.asset, c.currency)
+		if !ok || !near(rate, c.rate) || strings.Join(route, ">") != c.route {
+			t.Errorf("%s in %s: expected %v via %s, got %v via %v", c.asset, c.currency, c.rate, c.route, rate, route)
+		}
+	}
+	if _, _, ok := rates.Rate("ABC", "USDT"); ok {
+		t.Error("expected no route from ABC")
+	}
+}
+
+func TestValuate(t *testing.T) {
+	balances := []Balance{{"BTC", 0.5}, {"ETH", 2}, {"XYZ", 1000}, {"USDT", 1000}, {"ABC", 5}, {"USDT", 1000}}
+	s := Valuate("main", balances, NewRates(testPairs), time.Now())
+	if !near(s.TotalUSDT, 10000+2000+2000+2000) || !near(s.TotalBTC, 0.8) {
+		t.Errorf("unexpected totals %v USDT %v BTC", s.TotalUSDT, s.TotalBTC)
+	}
+	if len(s.Assets) != 5 || s.Assets[0].Asset != "BTC" || !near(s.Assets[0].Allocation, 62.5) ||
+		s.Assets[1].Asset != "ETH" || s.Assets[4].Asset != "ABC" || s.Assets[4].Allocation != 0 {
+		t.Errorf("unexpected assets %+v", s.Assets)
+	}
+	var total float64
+	for _, a := range s.Assets {
+		total += a.Allocation
+	}
+	if !near(total, 100) {
+		t.Errorf("expected allocations to sum up to 100, got %v", total)
+	}
+	if unpriced := s.Unpriced(); len(unpriced) != 1 || unpriced[0] != "ABC" {
+		t.Errorf("unexpected unpriced assets %v", unpriced)
+	}
+}
+
+func TestAccountBalances(t *testing.T) {
+	exchange := stub.NewExchange("key", "secret")
+	defer exchange.Close()
+	client := orders.NewClient("key", "secret")
+	client.BaseURL = exchange.URL
+	exchange.SetBalance("BTC", 0.4, 0.1)
+	exchange.SetBalance("ETH", 0, 0)
+	exchange.SetBalance("USDT", 100, 0)
+	balances, err := AccountBalances{Client: client}.Balances(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(balances) != 2 || balances[0] != (Balance{"BTC", 0.5}) || balances[1] != (Balance{"USDT", 100}) {
+		t.Errorf("unexpected balances %+v", balances)
+	}
+}
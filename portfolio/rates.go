@@ -0,0 +1,92 @@
+package portfolio
+
+import (
+	"sort"
+)
+
+// Pair is the symbol with its last price: the price of Base in Quote
+type Pair struct {
+	Symbol string  `db:"symbol"`
+	Base   string  `db:"base"`
+	Quote  string  `db:"quote"`
+	Price  float64 `db:"price"`
+}
+
+// bridges are the assets preferred for the routes between assets without a direct pair
+var bridges = []string{"USDT", "BTC", "BNB", "ETH", "BUSD", "FDUSD"}
+
+// maxHops is the maximal number of pairs in the route
+const maxHops = 3
+
+type edge struct {
+	to   string
+	rate float64
+}
+
+// Rates converts the assets by the prices of the pairs, the route through other assets is used
+// when there is no direct pair, e.g. XYZ -> BTC -> USDT
+type Rates struct {
+	edges map[string][]edge
+}
+
+func NewRates(pairs []Pair) *Rates {
+	r := &Rates{edges: map[string][]edge{}}
+	for _, p := range pairs {
+		if p.Price <= 0 || p.Base == "" || p.Quote == "" {
+			continue
+		}
+		r.edges[p.Base] = append(r.edges[p.Base], edge{to: p.Quote, rate: p.Price})
+		r.edges[p.Quote] = append(r.edges[p.Quote], edge{to: p.Base, rate: 1 / p.Price})
+	}
+	priority := map[string]int{}
+	for i, b := range bridges {
+		priority[b] = len(bridges) - i
+	}
+	// the search visits bridges first, so the routes are stable and go through the liquid assets
+	for _, edges := range r.edges {
+		sort.Slice(edges, func(i, j int) bool {
+			if pi, pj := priority[edges[i].to], priority[edges[j].to]; pi != pj {
+				return pi > pj
+			}
+			return edges[i].to < edges[j].to
+		})
+	}
+	return r
+}
+
+// Rate returns the price of the asset in currency and the route of the conversion, the shortest one is used
+func (r *Rates) Rate(asset, currency string) (rate float64, route []string, ok bool) {
+	if asset == currency {
+		return 1, []string{asset}, true
+	}
+	type node struct {
+		asset string
+		rate  float64
+		prev  *node
+		hops  int
+	}
+	visited := map[string]bool{asset: true}
+	queue := []*node{{asset: asset, rate: 1}}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		if n.hops == maxHops {
+			continue
+		}
+		for _, e := range r.edges[n.asset] {
+			if visited[e.to] {
+				continue
+			}
+			visited[e.to] = true
+			next := &node{asset: e.to, rate: n.rate * e.rate, prev: n, hops: n.hops + 1}
+			if e.to == currency {
+				for p := next; p != nil; p = p.prev {
+					route = append([]string{p.asset}, route...)
+				}
+				return next.rate, route, true
+			}
+			queue = append(queue, next)
+		}
+	}
+	return 0, nil, false
+}
@@ -109,3 +109,32 @@ func waitUntilTimeOrCancelled(ctx context.Context, t time.Time) bool {
 		return true
 	}
 }
+
+// WaitApiLimit waits as GetRequest does before the request which is sent by other clients, e.g. signed ones.
+// returns true if context was not cancelled
+func WaitApiLimit(ctx context.Context) bool {
+	return waitApiLimit(ctx)
+}
+
+// AdjustApiLimit updates the used weight by x-mbx-used-weight-1m header of the response of other clients,
+// the next requests wait for Retry-After of 429 and 418 responses (a minute if it is missing).
+// returns true if the request was rejected by 429 and has to be retried after WaitApiLimit
+func AdjustApiLimit(res *http.Response) bool {
+	waitMu.Lock()
+	defer waitMu.Unlock()
+	if used, err := strconv.Atoi(res.Header.Get("x-mbx-used-weight-1m")); err == nil {
+		usedWeight1m = used
+	}
+	if res.StatusCode != http.StatusTooManyRequests && res.StatusCode != http.StatusTeapot {
+		return false
+	}
+	retryAfter, err := strconv.Atoi(res.Header.Get("Retry-After"))
+	if err != nil || retryAfter <= 0 {
+		retryAfter = 60
+	}
+	if until := time.Now().Add(time.Duration(retryAfter) * time.Second); until.After(waitUntil) {
+		waitUntil = until
+	}
+	log.Printf("%s is rate limited, retry after %ds", res.Request.URL.Path, retryAfter)
+	return res.StatusCode == http.StatusTooManyRequests
+}
@@ -0,0 +1,70 @@
+package risk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"github.com/okharch/binance/request"
+	"net/http"
+)
+
+// Alerter sends the breach to the operators
+type Alerter interface {
+	Alert(ctx context.Context, b *Breach) error
+}
+
+// DBAlerter inserts the breach into binance.risk_breach and notifies the listeners of risk_breach channel
+type DBAlerter struct {
+	DB *sqlx.DB
+}
+
+func (a DBAlerter) Alert(ctx context.Context, b *Breach) error {
+	payload, err := json.Marshal(b)
+	if err != nil {
+		return err
+	}
+	if _, err := a.DB.ExecContext(ctx, `WITH b AS (
+		INSERT INTO binance.risk_breach (time, rule, book, symbol, msg) VALUES ($1, $2, $3, $4, $5)
+	)
+	SELECT pg_notify('risk_breach', $6)`, b.Time, b.Rule, b.Book, b.Symbol, b.Msg, string(payload)); err != nil {
+		return fmt.Errorf("failed to save risk breach: %w", err)
+	}
+	return nil
+}
+
+// WebhookAlerter posts the breach as json to the URL, e.g. of a chat integration
+type WebhookAlerter struct {
+	URL  string
+	HTTP *http.Client // http.DefaultClient if nil
+}
+
+func (a WebhookAlerter) Alert(ctx context.Context, b *Breach) error {
+	payload, err := json.Marshal(struct {
+		*Breach
+		Text string `json:"text"`
+	}{b, b.Error()})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.URL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", request.UserAgent)
+	client := a.HTTP
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post risk alert: %w", err)
+	}
+	res.Body.Close()
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("risk alert webhook returned %s", res.Status)
+	}
+	return nil
+}
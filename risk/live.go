@@ -0,0 +1,143 @@
+package risk
+
+import (
+	"context"
+	"errors"
+	"github.com/okharch/binance/orders"
+	"log"
+	"sync"
+	"time"
+)
+
+// LiveBook keeps the exposure and equity of the live trading book up to date:
+// Update sets them from the account balances and reserves the open buy orders of the orders manager,
+// between the updates the fills and cancels of the orders are applied as they are reported by the manager
+type LiveBook struct {
+	risk   *Manager
+	book   string
+	client *orders.Client
+	orders *orders.Manager
+	quote  string
+	price  func(symbol string) float64
+
+	mu       sync.Mutex
+	executed map[string]float64 // executed quantity of the open orders by client order id
+}
+
+// NewLiveBook returns the book of the orders placed by live, the symbols are quoted in the quote asset, e.g. USDT,
+// price returns the last price of the symbol. the changes of the orders of live are applied to the risk manager
+func NewLiveBook(r *Manager, book string, client *orders.Client, live *orders.Manager, quote string,
+	price func(symbol string) float64) *LiveBook {
+	b := &LiveBook{risk: r, book: book, client: client, orders: live, quote: quote, price: price,
+		executed: map[string]float64{}}
+	live.SetOnChange(b.orderChanged)
+	return b
+}
+
+// NewLiveManager returns the orders manager of live trading whose orders are checked by the risk manager
+// before the other validators, its open orders are cancelled when the kill switch is engaged.
+// the exposure of the book is kept by the returned LiveBook, see Run. price returns the last price of the symbol,
+// it values the market orders by quantity and the assets of the account
+func NewLiveManager(r *Manager, book string, client *orders.Client, store orders.Store, prefix, quote string,
+	price func(symbol string) float64, validators ...orders.Validator) (*orders.Manager, *LiveBook, error) {
+	if price == nil {
+		return nil, nil, errors.New("the last price is required by the risk check of live orders")
+	}
+	live := orders.NewManager(client, store, prefix)
+	live.SetValidator(append(orders.Validators{Validator{Risk: r, Book: book, Price: price}}, validators...))
+	r.OnKill(live.CancelAll)
+	return live, NewLiveBook(r, book, client, live, quote, price), nil
+}
+
+// Update sets the exposure of the book from the balances of the account, the open buy orders which are not
+// reserved yet, e.g. placed by the previous run, are reserved at their price or the last one, the worst price of OCO.
+// the equity is the quote balance and the value of the assets priced in the quote asset
+func (b *LiveBook) Update(ctx context.Context) error {
+	account, err := b.client.Account(ctx)
+	if err != nil {
+		return err
+	}
+	openOrders := b.orders.OpenOrders()
+	exposure := map[string]Exposure{}
+	var equity float64
+	for _, balance := range account.Balances {
+		total := balance.Free + balance.Locked
+		if balance.Asset == b.quote {
+			equity += total
+			continue
+		}
+		symbol := balance.Asset + b.quote
+		price := b.price(symbol)
+		if price <= 0 {
+			continue // the asset is not traded against the quote one
+		}
+		exposure[symbol] = Exposure{Position: total, Price: price}
+		equity += total * price
+	}
+	listPrice := map[int64]float64{} // the worst price of the buy OCO
+	for _, o := range openOrders {
+		if o.OrderListId >= 0 && o.Price > listPrice[o.OrderListId] {
+			listPrice[o.OrderListId] = o.Price
+		}
+	}
+	executed := make(map[string]float64, len(openOrders))
+	for _, o := range openOrders {
+		executed[o.ClientOrderId] = o.ExecutedQty
+		// the pending orders are reserved by the validator, OCO is reserved by its limit order
+		if o.Side != orders.Buy || o.Status == orders.StatusPendingNew ||
+			o.OrderListId >= 0 && o.Type != orders.LimitMaker {
+			continue
+		}
+		price := o.Price
+		if o.OrderListId >= 0 {
+			price = listPrice[o.OrderListId]
+		}
+		if price == 0 {
+			price = b.price(o.Symbol)
+		}
+		b.risk.Reserve(b.book, o.Symbol, o.ClientOrderId, (o.OrigQty-o.ExecutedQty)*price)
+	}
+	b.mu.Lock()
+	b.executed = executed
+	b.mu.Unlock()
+	b.risk.SetBookExposure(b.book, exposure)
+	b.risk.SetEquity(b.book, equity)
+	return nil
+}
+
+// Run updates the book every interval until ctx is cancelled
+func (b *LiveBook) Run(ctx context.Context, interval time.Duration) {
+	for {
+		if err := b.Update(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("risk: failed to update %s: %v", b.book, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// orderChanged moves the new fills of the order to the position and releases the rest of the value reserved
+// by the buy order when it is final
+func (b *LiveBook) orderChanged(o orders.Order) {
+	b.mu.Lock()
+	filled := o.ExecutedQty - b.executed[o.ClientOrderId]
+	if o.Status.Final() {
+		delete(b.executed, o.ClientOrderId)
+	} else {
+		b.executed[o.ClientOrderId] = o.ExecutedQty
+	}
+	b.mu.Unlock()
+	if filled > 0 {
+		price := o.Price
+		if o.QuoteQty > 0 {
+			price = o.QuoteQty / o.ExecutedQty
+		}
+		b.risk.Filled(b.book, o.Symbol, o.ClientOrderId, o.Side, filled, price)
+	}
+	if o.Side == orders.Buy && o.Status.Final() {
+		b.risk.Release(o.ClientOrderId)
+	}
+}
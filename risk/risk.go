@@ -0,0 +1,456 @@
+package risk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/okharch/binance/orders"
+	"log"
+	"math"
+	"os"
+	"sync"
+	"time"
+)
+
+/*
+Risk manager is the single place every order path (paper and live) passes through before an order is sent.
+it rejects the orders which break the limits:
+  - forbidden symbols
+  - max orders per minute
+  - max daily loss, the loss is measured from the first equity of the UTC day
+  - max position per symbol and in total, the position is the quote value of the base asset held and bought
+    by the open orders, summed over all books (paper accounts or live trading)
+
+the value of an accepted buy order is added to the open buys of its book at once, so the orders sent before
+the exposure is set again are limited too. the value of the order with the client order id is reserved by the id
+until its fills and Release, it is kept by SetExposure. the value of the order without the id is replaced
+by SetExposure.
+
+orders which reduce the position (sells) are not limited by the loss and the position limits,
+the buys whose value is not known, e.g. market orders by quantity without the last price, are rejected.
+the kill switch blocks all new orders and cancels the open ones, it is engaged manually over http,
+by a file or by a database flag, see Watch.
+every breach is logged and sent to the alerters.
+*/
+
+// AnySymbol is the key of MaxPosition applied to the symbols without their own limit
+const AnySymbol = "*"
+
+// Limits of the risk manager, zero value of a limit means no limit
+type Limits struct {
+	MaxPosition        map[string]float64 `json:"max_position"` // quote value by symbol or AnySymbol
+	MaxTotalPosition   float64            `json:"max_total_position"`
+	MaxDailyLoss       float64            `json:"max_daily_loss"`
+	MaxOrdersPerMinute int                `json:"max_orders_per_minute"`
+	ForbiddenSymbols   []string           `json:"forbidden_symbols"`
+}
+
+// LoadLimits loads json limits from the file
+func LoadLimits(fileName string) (Limits, error) {
+	var limits Limits
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return limits, err
+	}
+	if err := json.Unmarshal(data, &limits); err != nil {
+		return limits, fmt.Errorf("failed to parse risk limits %s: %w", fileName, err)
+	}
+	return limits, nil
+}
+
+func (l Limits) maxPosition(symbol string) float64 {
+	if v, ok := l.MaxPosition[symbol]; ok {
+		return v
+	}
+	return l.MaxPosition[AnySymbol]
+}
+
+// rules of the breaches
+const (
+	RuleKillSwitch      = "kill_switch"
+	RuleForbiddenSymbol = "forbidden_symbol"
+	RuleOrderRate       = "order_rate"
+	RuleDailyLoss       = "daily_loss"
+	RulePosition        = "position"
+	RuleTotalPosition   = "total_position"
+	RuleUnknownValue    = "unknown_value"
+)
+
+// Breach is the rejected order or the engaged kill switch, it is the error returned by CheckOrder
+type Breach struct {
+	Time   time.Time `json:"time"`
+	Rule   string    `json:"rule"`
+	Book   string    `json:"book,omitempty"`
+	Symbol string    `json:"symbol,omitempty"`
+	Msg    string    `json:"msg"`
+}
+
+func (b *Breach) Error() string {
+	return fmt.Sprintf("risk %s: %s", b.Rule, b.Msg)
+}
+
+// Order to check, the value of the order is QuoteQty if it is set, otherwise Quantity times Price
+type Order struct {
+	Id       string // client order id of live orders, the value of the accepted buy is reserved by it
+	Book     string // paper account or live trading
+	Symbol   string
+	Side     orders.Side
+	Quantity float64
+	QuoteQty float64
+	Price    float64 // limit price or the last price of market orders
+}
+
+func (o Order) value() float64 {
+	if o.QuoteQty > 0 {
+		return o.QuoteQty
+	}
+	return o.Quantity * o.Price
+}
+
+// Exposure of the book to the symbol
+type Exposure struct {
+	Position float64 // base asset held
+	Price    float64 // the last price
+	OpenBuys float64 // quote value of the open buy orders
+}
+
+func (e Exposure) value() float64 {
+	return e.Position*e.Price + e.OpenBuys
+}
+
+type bookSymbol struct {
+	book, symbol string
+}
+
+// reservation is the value of the accepted buy order which is not filled yet
+type reservation struct {
+	bookSymbol
+	value float64
+}
+
+// dayEquity is the equity of the book at the start of the UTC day and the current one
+type dayEquity struct {
+	day            int64
+	start, current float64
+}
+
+// Status of the risk manager
+type Status struct {
+	Killed     bool      `json:"killed"`
+	KilledBy   string    `json:"killed_by,omitempty"`
+	Reason     string    `json:"reason,omitempty"`
+	KilledAt   time.Time `json:"killed_at,omitempty"`
+	DailyLoss  float64   `json:"daily_loss"`
+	Position   float64   `json:"position"`
+	LastMinute int       `json:"orders_last_minute"`
+	Limits     Limits    `json:"limits"`
+}
+
+// Manager checks the orders against the limits, it is safe for concurrent use
+type Manager struct {
+	// AlertInterval is the minimal interval between the alerts of the same rule, book and symbol,
+	// the breaches are logged anyway
+	AlertInterval time.Duration
+	// Token is required by the http requests engaging and releasing the kill switch as the bearer token,
+	// ServeHTTP serves the status only if it is empty
+	Token string
+
+	mu        sync.Mutex
+	limits    Limits
+	forbidden map[string]bool
+	exposure  map[bookSymbol]Exposure
+	reserved  map[string]*reservation // by client order id
+	equity    map[string]*dayEquity
+	sent      []time.Time // accepted orders within the last minute
+	killed    bool
+	killedBy  string
+	reason    string
+	killedAt  time.Time
+	onKill    []func(ctx context.Context) error
+	alerters  []Alerter
+	alerted   map[string]time.Time
+	now       func() time.Time
+}
+
+func NewManager(limits Limits, alerters ...Alerter) *Manager {
+	m := &Manager{AlertInterval: 5 * time.Minute, exposure: map[bookSymbol]Exposure{},
+		reserved: map[string]*reservation{}, equity: map[string]*dayEquity{},
+		alerters: alerters, alerted: map[string]time.Time{}, now: time.Now}
+	m.SetLimits(limits)
+	return m
+}
+
+// SetLimits replaces the limits
+func (m *Manager) SetLimits(limits Limits) {
+	forbidden := make(map[string]bool, len(limits.ForbiddenSymbols))
+	for _, s := range limits.ForbiddenSymbols {
+		forbidden[s] = true
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.limits, m.forbidden = limits, forbidden
+}
+
+// SetExposure sets the exposure of the book to the symbol, the open buys added by CheckOrder are replaced,
+// the values reserved by the client order ids are kept
+func (m *Manager) SetExposure(book, symbol string, e Exposure) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.exposure[bookSymbol{book, symbol}] = e
+}
+
+// SetBookExposure replaces the exposure of the book to all symbols, the values reserved by the client order ids
+// are kept
+func (m *Manager) SetBookExposure(book string, exposure map[string]Exposure) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for k := range m.exposure {
+		if k.book == book {
+			delete(m.exposure, k)
+		}
+	}
+	for symbol, e := range exposure {
+		m.exposure[bookSymbol{book, symbol}] = e
+	}
+}
+
+// Reserve reserves the value of the open buy order which was not checked by CheckOrder,
+// e.g. placed by the previous run, the order reserved before keeps its value
+func (m *Manager) Reserve(book, symbol, id string, value float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.reserved[id]; !ok {
+		m.reserved[id] = &reservation{bookSymbol{book, symbol}, value}
+	}
+}
+
+// Release releases the rest of the value reserved by the buy order when it is final or it was not sent
+func (m *Manager) Release(id string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.reserved, id)
+}
+
+// Filled moves the filled quantity of the buy order from the reserved value or the open buys to the position,
+// the position is reduced by the filled quantity of the sell order
+func (m *Manager) Filled(book, symbol, id string, side orders.Side, quantity, price float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	k := bookSymbol{book, symbol}
+	e := m.exposure[k]
+	if side == orders.Buy {
+		e.Position += quantity
+		if r, ok := m.reserved[id]; ok {
+			r.value = math.Max(0, r.value-quantity*price)
+		} else {
+			e.OpenBuys = math.Max(0, e.OpenBuys-quantity*price)
+		}
+	} else {
+		e.Position = math.Max(0, e.Position-quantity)
+	}
+	if price > 0 {
+		e.Price = price
+	}
+	m.exposure[k] = e
+}
+
+// SetEquity sets the current equity of the book, the first one of the UTC day is the base of the daily loss
+func (m *Manager) SetEquity(book string, equity float64) {
+	day := m.now().UTC().Truncate(24 * time.Hour).Unix()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.equity[book]
+	if !ok || e.day != day {
+		e = &dayEquity{day: day, start: equity}
+		m.equity[book] = e
+	}
+	e.current = equity
+}
+
+// dailyLoss returns the loss of all books since the start of the day, the books not updated today are skipped
+func (m *Manager) dailyLoss(now time.Time) (loss float64) {
+	day := now.UTC().Truncate(24 * time.Hour).Unix()
+	for _, e := range m.equity {
+		if e.day == day {
+			loss += e.start - e.current
+		}
+	}
+	return loss
+}
+
+// position returns the exposure to the symbol and the total one over all books
+func (m *Manager) position(symbol string) (symbolValue, total float64) {
+	for k, e := range m.exposure {
+		v := e.value()
+		total += v
+		if k.symbol == symbol {
+			symbolValue += v
+		}
+	}
+	for _, r := range m.reserved {
+		total += r.value
+		if r.symbol == symbol {
+			symbolValue += r.value
+		}
+	}
+	return symbolValue, total
+}
+
+// recentOrders drops the orders sent before the last minute and returns the number of the rest
+func (m *Manager) recentOrders(now time.Time) int {
+	i := 0
+	for i < len(m.sent) && now.Sub(m.sent[i]) >= time.Minute {
+		i++
+	}
+	m.sent = m.sent[i:]
+	return len(m.sent)
+}
+
+// CheckOrder returns *Breach if the order breaks the limits, otherwise the order is counted as sent
+// and the value of the buy order is reserved by its id or added to the open buys of the book
+func (m *Manager) CheckOrder(ctx context.Context, o Order) error {
+	now := m.now()
+	m.mu.Lock()
+	b := m.check(o, now)
+	if b == nil {
+		m.sent = append(m.sent, now)
+		if o.Side == orders.Buy && o.Id != "" {
+			m.reserved[o.Id] = &reservation{bookSymbol{o.Book, o.Symbol}, o.value()}
+		} else if o.Side == orders.Buy {
+			k := bookSymbol{o.Book, o.Symbol}
+			e := m.exposure[k]
+			e.OpenBuys += o.value()
+			m.exposure[k] = e
+		}
+	}
+	m.mu.Unlock()
+	if b != nil {
+		m.breach(ctx, b)
+		return b
+	}
+	return nil
+}
+
+// check must be called under the lock
+func (m *Manager) check(o Order, now time.Time) *Breach {
+	l := m.limits
+	breach := func(rule, format string, args ...any) *Breach {
+		return &Breach{Time: now, Rule: rule, Book: o.Book, Symbol: o.Symbol, Msg: fmt.Sprintf(format, args...)}
+	}
+	if m.killed {
+		return breach(RuleKillSwitch, "trading is stopped by %s: %s", m.killedBy, m.reason)
+	}
+	if m.forbidden[o.Symbol] {
+		return breach(RuleForbiddenSymbol, "%s is forbidden", o.Symbol)
+	}
+	if l.MaxOrdersPerMinute > 0 && m.recentOrders(now) >= l.MaxOrdersPerMinute {
+		return breach(RuleOrderRate, "%d orders sent within the last minute", len(m.sent))
+	}
+	if o.Side != orders.Buy {
+		return nil
+	}
+	if loss := m.dailyLoss(now); l.MaxDailyLoss > 0 && loss >= l.MaxDailyLoss {
+		return breach(RuleDailyLoss, "daily loss %.2f reached the limit %.2f", loss, l.MaxDailyLoss)
+	}
+	value := o.value()
+	if value <= 0 {
+		return breach(RuleUnknownValue, "the value of the order is unknown: quantity %g, price %g", o.Quantity, o.Price)
+	}
+	symbolValue, total := m.position(o.Symbol)
+	if limit := l.maxPosition(o.Symbol); limit > 0 && symbolValue+value > limit {
+		return breach(RulePosition, "position %.2f plus order %.2f exceeds the limit %.2f of %s", symbolValue, value,
+			limit, o.Symbol)
+	}
+	if l.MaxTotalPosition > 0 && total+value > l.MaxTotalPosition {
+		return breach(RuleTotalPosition, "total position %.2f plus order %.2f exceeds the limit %.2f", total, value,
+			l.MaxTotalPosition)
+	}
+	return nil
+}
+
+// breach logs the breach and sends it to the alerters unless the same one was alerted within AlertInterval
+func (m *Manager) breach(ctx context.Context, b *Breach) {
+	log.Printf("%s book=%s symbol=%s", b.Error(), b.Book, b.Symbol)
+	key := b.Rule + "/" + b.Book + "/" + b.Symbol
+	m.mu.Lock()
+	if last, ok := m.alerted[key]; ok && b.Time.Sub(last) < m.AlertInterval {
+		m.mu.Unlock()
+		return
+	}
+	m.alerted[key] = b.Time
+	m.mu.Unlock()
+	m.alert(ctx, b)
+}
+
+func (m *Manager) alert(ctx context.Context, b *Breach) {
+	for _, a := range m.alerters {
+		if err := a.Alert(ctx, b); err != nil {
+			log.Printf("failed to send risk alert: %v", err)
+		}
+	}
+}
+
+// OnKill adds the function called when the kill switch is engaged, e.g. to cancel open orders
+func (m *Manager) OnKill(f func(ctx context.Context) error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onKill = append(m.onKill, f)
+}
+
+// Kill engages the kill switch: new orders are rejected and the functions of OnKill are called,
+// the first error of them is returned
+func (m *Manager) Kill(ctx context.Context, by, reason string) error {
+	now := m.now()
+	m.mu.Lock()
+	if m.killed {
+		m.mu.Unlock()
+		return nil
+	}
+	m.killed, m.killedBy, m.reason, m.killedAt = true, by, reason, now
+	onKill := m.onKill
+	m.mu.Unlock()
+	// every engagement is alerted
+	b := &Breach{Time: now, Rule: RuleKillSwitch, Msg: fmt.Sprintf("engaged by %s: %s", by, reason)}
+	log.Print(b.Error())
+	m.alert(ctx, b)
+	var firstErr error
+	for _, f := range onKill {
+		if err := f(ctx); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Resume releases the kill switch, false if it was not engaged
+func (m *Manager) Resume(by string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.killed {
+		return false
+	}
+	log.Printf("risk kill switch released by %s, it was engaged by %s: %s", by, m.killedBy, m.reason)
+	m.killed, m.killedBy, m.reason = false, "", ""
+	return true
+}
+
+// Killed returns true if the kill switch is engaged
+func (m *Manager) Killed() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.killed
+}
+
+// Status returns the current state of the manager
+func (m *Manager) Status() Status {
+	now := m.now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := Status{Killed: m.killed, KilledBy: m.killedBy, Reason: m.reason, DailyLoss: m.dailyLoss(now),
+		LastMinute: m.recentOrders(now), Limits: m.limits}
+	if m.killed {
+		s.KilledAt = m.killedAt
+	}
+	_, s.Position = m.position("")
+	return s
+}
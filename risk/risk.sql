@@ -0,0 +1,21 @@
+-- the kill switch of the risk manager: trading is stopped while it is engaged
+CREATE TABLE IF NOT EXISTS binance.risk_kill_switch (
+    id         bool PRIMARY KEY DEFAULT true CHECK (id), -- the single row
+    engaged    bool NOT NULL DEFAULT false,
+    reason     text NOT NULL DEFAULT '',
+    updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+INSERT INTO binance.risk_kill_switch (id) VALUES (true) ON CONFLICT DO NOTHING;
+
+-- the rejected orders and engaged kill switches, they are notified on risk_breach channel as well
+CREATE TABLE IF NOT EXISTS binance.risk_breach (
+    breach_id  bigserial PRIMARY KEY,
+    time       timestamptz NOT NULL,
+    rule       text NOT NULL,
+    book       text NOT NULL DEFAULT '',
+    symbol     text NOT NULL DEFAULT '',
+    msg        text NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS risk_breach_time_idx ON binance.risk_breach (time);
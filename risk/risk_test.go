@@ -0,0 +1,442 @@
+package risk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/okharch/binance/orders"
+	"github.com/okharch/binance/orders/stub"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testAlerter collects the breaches
+type testAlerter struct {
+	mu       sync.Mutex
+	breaches []*Breach
+}
+
+func (a *testAlerter) Alert(ctx context.Context, b *Breach) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.breaches = append(a.breaches, b)
+	return nil
+}
+
+func (a *testAlerter) rules() (rules []string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, b := range a.breaches {
+		rules = append(rules, b.Rule)
+	}
+	return rules
+}
+
+func testManager(limits Limits, alerters ...Alerter) (*Manager, *time.Time) {
+	now := time.Date(2023, 3, 1, 10, 0, 0, 0, time.UTC)
+	m := NewManager(limits, alerters...)
+	m.now = func() time.Time { return now }
+	return m, &now
+}
+
+func rule(err error) string {
+	var b *Breach
+	if !errors.As(err, &b) {
+		return ""
+	}
+	return b.Rule
+}
+
+func TestCheckOrder(t *testing.T) {
+	ctx := context.Background()
+	m, now := testManager(Limits{MaxPosition: map[string]float64{"BTCUSDT": 1000, AnySymbol: 500},
+		MaxTotalPosition: 1500, MaxDailyLoss: 100, MaxOrdersPerMinute: 10, ForbiddenSymbols: []string{"LUNAUSDT"}})
+	m.SetExposure("a", "BTCUSDT", Exposure{Position: 0.02, Price: 20000, OpenBuys: 200}) // 600
+	m.SetExposure("b", "BTCUSDT", Exposure{Position: 0.01, Price: 20000})                // 200
+	m.SetExposure("b", "ETHUSDT", Exposure{Position: 0.25, Price: 1600})                 // 400
+	buy := func(symbol string, value float64) Order {
+		return Order{Book: "a", Symbol: symbol, Side: orders.Buy, QuoteQty: value}
+	}
+	for _, c := range []struct {
+		o    Order
+		rule string
+	}{
+		{buy("BTCUSDT", 200), ""},
+		{buy("BTCUSDT", 201), RulePosition},
+		{buy("ETHUSDT", 101), RulePosition},      // the limit of any symbol
+		{buy("BNBUSDT", 101), RuleTotalPosition}, // the first buy makes the total 1400
+		{Order{Book: "a", Symbol: "BTCUSDT", Side: orders.Buy, Quantity: 0.01, Price: 20000}, RulePosition},
+		{Order{Book: "a", Symbol: "BTCUSDT", Side: orders.Sell, Quantity: 1, Price: 20000}, ""},
+		{Order{Book: "a", Symbol: "LUNAUSDT", Side: orders.Sell, Quantity: 1, Price: 1}, RuleForbiddenSymbol},
+	} {
+		if err := m.CheckOrder(ctx, c.o); rule(err) != c.rule {
+			t.Errorf("%+v: expected %q, got %v", c.o, c.rule, err)
+		}
+	}
+
+	m.SetEquity("a", 10000)
+	m.SetEquity("b", 5000)
+	m.SetEquity("a", 9940)
+	if err := m.CheckOrder(ctx, buy("BNBUSDT", 10)); err != nil {
+		t.Errorf("unexpected error within the daily loss: %v", err)
+	}
+	m.SetEquity("b", 4960)
+	if err := m.CheckOrder(ctx, buy("BNBUSDT", 10)); rule(err) != RuleDailyLoss {
+		t.Errorf("expected daily loss, got %v", err)
+	}
+	if err := m.CheckOrder(ctx, Order{Book: "b", Symbol: "BTCUSDT", Side: orders.Sell, QuoteQty: 10}); err != nil {
+		t.Errorf("expected sells to be allowed after the daily loss, got %v", err)
+	}
+	// the loss is counted from the first equity of the next day
+	*now = now.Add(24 * time.Hour)
+	m.SetEquity("a", 9900)
+	if err := m.CheckOrder(ctx, buy("BNBUSDT", 10)); err != nil {
+		t.Errorf("unexpected error on the next day: %v", err)
+	}
+
+	// sells are counted by the order rate too
+	sell := Order{Book: "a", Symbol: "BNBUSDT", Side: orders.Sell, QuoteQty: 10}
+	for i := m.Status().LastMinute; i < 10; i++ {
+		if err := m.CheckOrder(ctx, sell); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := m.CheckOrder(ctx, buy("BNBUSDT", 10)); rule(err) != RuleOrderRate {
+		t.Errorf("expected order rate, got %v", err)
+	}
+	*now = now.Add(time.Minute)
+	if err := m.CheckOrder(ctx, buy("BNBUSDT", 10)); err != nil {
+		t.Errorf("unexpected error after a minute: %v", err)
+	}
+}
+
+func TestOpenBuys(t *testing.T) {
+	ctx := context.Background()
+	m, _ := testManager(Limits{MaxPosition: map[string]float64{AnySymbol: 250}})
+	buy := func(id string) Order {
+		return Order{Id: id, Book: "a", Symbol: "BTCUSDT", Side: orders.Buy, QuoteQty: 200}
+	}
+	if err := m.CheckOrder(ctx, buy("1")); err != nil {
+		t.Fatal(err)
+	}
+	// the first buy is not reflected by the exposure set by the caller yet
+	if err := m.CheckOrder(ctx, buy("2")); rule(err) != RulePosition {
+		t.Errorf("expected the second buy to exceed the position, got %v", err)
+	}
+	m.SetExposure("a", "BTCUSDT", Exposure{})
+	if s := m.Status(); s.Position != 200 {
+		t.Errorf("expected the exposure to keep the reserved buy, got %v", s.Position)
+	}
+	m.Release("1")
+	if err := m.CheckOrder(ctx, buy("3")); err != nil {
+		t.Errorf("expected the buy after the release to pass, got %v", err)
+	}
+	// the fill at the lower price leaves 20 reserved until the order is final
+	m.Filled("a", "BTCUSDT", "3", orders.Buy, 0.01, 18000)
+	if s := m.Status(); s.Position != 200 {
+		t.Errorf("expected the filled buy to stay in the position, got %v", s.Position)
+	}
+	m.Release("3")
+	if s := m.Status(); s.Position != 180 {
+		t.Errorf("expected the rest of the buy to be released, got %v", s.Position)
+	}
+	m.Filled("a", "BTCUSDT", "4", orders.Sell, 0.01, 18000)
+	if s := m.Status(); s.Position != 0 {
+		t.Errorf("expected the sell to reduce the position, got %v", s.Position)
+	}
+	// the open buys of the order without the id are replaced by the exposure
+	if err := m.CheckOrder(ctx, buy("")); err != nil {
+		t.Fatal(err)
+	}
+	m.SetExposure("a", "BTCUSDT", Exposure{})
+	if s := m.Status(); s.Position != 0 {
+		t.Errorf("expected the exposure to replace the open buys, got %v", s.Position)
+	}
+}
+
+func TestValidatorMarketBuy(t *testing.T) {
+	m, _ := testManager(Limits{MaxPosition: map[string]float64{AnySymbol: 1000}})
+	buy := orders.OrderRequest{Symbol: "BTCUSDT", Side: orders.Buy, Type: orders.Market, Quantity: 0.1}
+	for _, c := range []struct {
+		price func(symbol string) float64
+		rule  string
+	}{
+		{nil, RuleUnknownValue},
+		{func(string) float64 { return 0 }, RuleUnknownValue},
+		{func(string) float64 { return 20000 }, RulePosition}, // 2000 by the last price
+		{func(string) float64 { return 5000 }, ""},
+	} {
+		v := Validator{Risk: m, Book: "live", Price: c.price}
+		if err := v.ValidateOrder(buy, 0); rule(err) != c.rule {
+			t.Errorf("expected %q, got %v", c.rule, err)
+		}
+	}
+	// the market sell by quantity reduces the position, it is not limited
+	sell := buy
+	sell.Side = orders.Sell
+	if err := (Validator{Risk: m, Book: "live"}).ValidateOrder(sell, 0); err != nil {
+		t.Errorf("expected the sell to pass, got %v", err)
+	}
+}
+
+func TestLiveBook(t *testing.T) {
+	ctx := context.Background()
+	exchange := stub.NewExchange("key", "secret")
+	defer exchange.Close()
+	exchange.SetBalance("USDT", 800, 0)
+	exchange.SetBalance("BTC", 0.01, 0)
+	client := orders.NewClient("key", "secret")
+	client.BaseURL = exchange.URL
+	m, _ := testManager(Limits{MaxPosition: map[string]float64{AnySymbol: 450}})
+	price := func(string) float64 { return 20000 }
+	live, book, err := NewLiveManager(m, "live", client, orders.NewMemStore(), "test", "USDT", price)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := book.Update(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if s := m.Status(); s.Position != 200 {
+		t.Fatalf("expected the position of the balance, got %+v", s)
+	}
+	place := func(price float64) (orders.Order, error) {
+		return live.Place(ctx, orders.OrderRequest{Symbol: "BTCUSDT", Side: orders.Buy, Type: orders.Limit,
+			Quantity: 0.01, Price: price})
+	}
+	first, err := place(19000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := place(19000); rule(err) != RulePosition {
+		t.Errorf("expected the second buy to exceed the position, got %v", err)
+	}
+	// the cancelled buy is released
+	if _, err := live.Cancel(ctx, "BTCUSDT", first.ClientOrderId); err != nil {
+		t.Fatal(err)
+	}
+	second, err := place(20000)
+	if err != nil {
+		t.Fatalf("expected the buy after the cancel to pass, got %v", err)
+	}
+	// the fill moves the buy to the position: 0.01 held and 0.01 bought
+	exchange.Fill(second.ClientOrderId, 0.01, 20000)
+	if _, err := live.Refresh(ctx, "BTCUSDT", second.ClientOrderId); err != nil {
+		t.Fatal(err)
+	}
+	if s := m.Status(); s.Position != 400 {
+		t.Errorf("expected the filled buy in the position, got %+v", s)
+	}
+	// the stub does not change the balances by fills, the update replaces the exposure by them
+	if err := book.Update(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if s := m.Status(); s.Position != 200 {
+		t.Errorf("expected the position of the balance after the update, got %+v", s)
+	}
+
+	// the market buys by quote quantity are released when they are final
+	marketBuy := func() (orders.Order, error) {
+		return live.Place(ctx, orders.OrderRequest{Symbol: "BTCUSDT", Side: orders.Buy, Type: orders.Market,
+			QuoteOrderQty: 200})
+	}
+	if o, err := marketBuy(); err == nil || o.Status != orders.StatusRejected {
+		t.Fatalf("expected the market buy without the price to be rejected by the exchange, got %+v %v", o, err)
+	}
+	exchange.SetPrice("BTCUSDT", 19000)
+	o, err := marketBuy()
+	if err != nil || o.Status != orders.StatusFilled {
+		t.Fatalf("expected the market buy to be filled, got %+v %v", o, err)
+	}
+	// 0.01 held and 200/19000 bought, the fill sets the last price
+	if s := m.Status(); math.Abs(s.Position-390) > 1e-3 {
+		t.Errorf("expected the filled market buy in the position, got %+v", s)
+	}
+	if err := book.Update(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// the buy rejected by the next validator and the one which failed to be saved are released
+	rejecting, _, err := NewLiveManager(m, "live", client, orders.NewMemStore(), "reject", "USDT", price, rejectAll{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rejecting.Place(ctx, orders.OrderRequest{Symbol: "BTCUSDT", Side: orders.Buy, Type: orders.Market,
+		QuoteOrderQty: 200}); err == nil {
+		t.Fatal("expected the buy to be rejected")
+	}
+	failing, _, err := NewLiveManager(m, "live", client, failingStore{}, "fail", "USDT", price)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := failing.Place(ctx, orders.OrderRequest{Symbol: "BTCUSDT", Side: orders.Buy, Type: orders.Market,
+		QuoteOrderQty: 200}); err == nil {
+		t.Fatal("expected the buy to fail")
+	}
+	if s := m.Status(); s.Position != 200 {
+		t.Errorf("expected the buys which were not sent to be released, got %+v", s)
+	}
+}
+
+type rejectAll struct{}
+
+func (rejectAll) ValidateOrder(r orders.OrderRequest, openOrders int) error {
+	return errors.New("rejected")
+}
+
+func (rejectAll) ValidateOCO(r orders.OCORequest, openOrders int) error {
+	return errors.New("rejected")
+}
+
+type failingStore struct{}
+
+func (failingStore) SaveOrder(ctx context.Context, o orders.Order, previous orders.Status) error {
+	return errors.New("failed to save")
+}
+
+func (failingStore) LoadOpenOrders(ctx context.Context) ([]orders.Order, error) {
+	return nil, nil
+}
+
+func TestAlertInterval(t *testing.T) {
+	ctx := context.Background()
+	alerter := &testAlerter{}
+	m, now := testManager(Limits{ForbiddenSymbols: []string{"LUNAUSDT"}}, alerter)
+	o := Order{Book: "a", Symbol: "LUNAUSDT", Side: orders.Buy, QuoteQty: 10}
+	for i := 0; i < 3; i++ {
+		if err := m.CheckOrder(ctx, o); err == nil {
+			t.Fatal("expected the order to be rejected")
+		}
+	}
+	*now = now.Add(m.AlertInterval)
+	if err := m.CheckOrder(ctx, o); err == nil {
+		t.Fatal("expected the order to be rejected")
+	}
+	if rules := alerter.rules(); len(rules) != 2 {
+		t.Errorf("expected 2 alerts, got %v", rules)
+	}
+}
+
+func TestKillSwitch(t *testing.T) {
+	ctx := context.Background()
+	exchange := stub.NewExchange("key", "secret")
+	defer exchange.Close()
+	client := orders.NewClient("key", "secret")
+	client.BaseURL = exchange.URL
+	alerter := &testAlerter{}
+	m := NewManager(Limits{}, alerter)
+	live, _, err := NewLiveManager(m, "live", client, orders.NewMemStore(), "test", "USDT",
+		func(string) float64 { return 20000 })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := live.Place(ctx, orders.OrderRequest{Symbol: "BTCUSDT", Side: orders.Buy, Type: orders.Limit,
+		Quantity: 1, Price: 19000}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := live.PlaceOCO(ctx, orders.OCORequest{Symbol: "BTCUSDT", Side: orders.Sell, Quantity: 1,
+		Price: 22000, StopPrice: 19000, StopLimitPrice: 18900}); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "kill")
+	if err := os.WriteFile(path, []byte("exchange outage\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	watchCtx, stop := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		m.Watch(watchCtx, time.Millisecond, FileSwitch{Path: path})
+		close(done)
+	}()
+	// the orders are blocked before the open ones are cancelled
+	waitFor(t, func() bool { return len(live.OpenOrders()) == 0 })
+	_, err = live.Place(ctx, orders.OrderRequest{Symbol: "BTCUSDT", Side: orders.Sell, Type: orders.Limit,
+		Quantity: 1, Price: 21000})
+	if rule(err) != RuleKillSwitch {
+		t.Errorf("expected the order to be blocked, got %v", err)
+	}
+	if s := m.Status(); s.Reason != "exchange outage" || s.KilledBy != "file "+path {
+		t.Errorf("unexpected status %+v", s)
+	}
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool { return !m.Killed() })
+	stop()
+	<-done
+
+	// http
+	m.Token = "token"
+	server := httptest.NewServer(m)
+	defer server.Close()
+	request := func(path, token string) *http.Response {
+		req, err := http.NewRequest(http.MethodPost, server.URL+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+	for _, token := range []string{"", "wrong"} {
+		res := request("/kill", token)
+		res.Body.Close()
+		if res.StatusCode != http.StatusUnauthorized || m.Killed() {
+			t.Errorf("expected the request with token %q to be unauthorized, got %s", token, res.Status)
+		}
+	}
+	post := func(path string) Status {
+		res := request(path, m.Token)
+		defer res.Body.Close()
+		var s Status
+		if err := json.NewDecoder(res.Body).Decode(&s); err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	if s := post("/kill?reason=drawdown"); !s.Killed || s.Reason != "drawdown" {
+		t.Errorf("unexpected status after kill %+v", s)
+	}
+	if s := post("/resume"); s.Killed {
+		t.Errorf("unexpected status after resume %+v", s)
+	}
+	if rules := alerter.rules(); len(rules) < 3 || rules[0] != RuleKillSwitch {
+		t.Errorf("expected kill switch alerts, got %v", rules)
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); !cond(); time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("timeout")
+		}
+	}
+}
+
+func TestWebhookAlerter(t *testing.T) {
+	var received map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer server.Close()
+	b := &Breach{Time: time.Now(), Rule: RuleDailyLoss, Book: "a", Msg: "daily loss 100 reached the limit 100"}
+	if err := (WebhookAlerter{URL: server.URL}).Alert(context.Background(), b); err != nil {
+		t.Fatal(err)
+	}
+	if received["rule"] != RuleDailyLoss || received["text"] != b.Error() {
+		t.Errorf("unexpected alert %v", received)
+	}
+}
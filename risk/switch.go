@@ -0,0 +1,136 @@
+package risk
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Switch is the external kill switch polled by Watch
+type Switch interface {
+	Name() string
+	// Engaged returns true and the reason if trading must be stopped
+	Engaged(ctx context.Context) (engaged bool, reason string, err error)
+}
+
+// FileSwitch is engaged while the file exists, its content is the reason
+type FileSwitch struct {
+	Path string
+}
+
+func (s FileSwitch) Name() string {
+	return "file " + s.Path
+}
+
+func (s FileSwitch) Engaged(ctx context.Context) (bool, string, error) {
+	data, err := os.ReadFile(s.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, "", nil
+	}
+	if err != nil {
+		return false, "", err
+	}
+	return true, strings.TrimSpace(string(data)), nil
+}
+
+// DBSwitch is the flag of binance.risk_kill_switch, see risk.sql
+type DBSwitch struct {
+	DB *sqlx.DB
+}
+
+func (s DBSwitch) Name() string {
+	return "binance.risk_kill_switch"
+}
+
+func (s DBSwitch) Engaged(ctx context.Context) (bool, string, error) {
+	var row struct {
+		Engaged bool   `db:"engaged"`
+		Reason  string `db:"reason"`
+	}
+	err := s.DB.GetContext(ctx, &row, "SELECT engaged, reason FROM binance.risk_kill_switch WHERE id")
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, "", nil
+	}
+	if err != nil {
+		return false, "", fmt.Errorf("failed to check kill switch: %w", err)
+	}
+	return row.Engaged, row.Reason, nil
+}
+
+// Watch polls the switches every interval until ctx is cancelled: the kill switch is engaged by the first engaged one
+// and released when it is off, unless the switch was engaged by someone else, e.g. over http
+func (m *Manager) Watch(ctx context.Context, interval time.Duration, switches ...Switch) {
+	for {
+		for _, s := range switches {
+			engaged, reason, err := s.Engaged(ctx)
+			if err != nil {
+				log.Printf("risk: %v", err)
+				continue
+			}
+			if engaged {
+				if err := m.Kill(ctx, s.Name(), reason); err != nil {
+					log.Printf("risk: failed to stop trading: %v", err)
+				}
+				break
+			}
+			m.mu.Lock()
+			ownKill := m.killed && m.killedBy == s.Name()
+			m.mu.Unlock()
+			if ownKill {
+				m.Resume(s.Name())
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// ServeHTTP returns the status by GET, engages the kill switch by POST /kill?reason= and releases it by POST /resume,
+// POST requests must be authorized by "Authorization: Bearer <Token>"
+func (m *Manager) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost && !m.authorized(r) {
+		http.Error(w, "invalid or missing token", http.StatusUnauthorized)
+		return
+	}
+	switch {
+	case r.Method == http.MethodGet:
+	case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/kill"):
+		reason := r.URL.Query().Get("reason")
+		if reason == "" {
+			reason = "manual stop"
+		}
+		if err := m.Kill(r.Context(), "http "+r.RemoteAddr, reason); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/resume"):
+		m.Resume("http " + r.RemoteAddr)
+	default:
+		http.Error(w, "GET status, POST /kill?reason= or POST /resume", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(m.Status()); err != nil {
+		log.Printf("risk: failed to write status: %v", err)
+	}
+}
+
+func (m *Manager) authorized(r *http.Request) bool {
+	auth := r.Header.Get("Authorization")
+	if m.Token == "" || !strings.HasPrefix(auth, "Bearer ") {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(auth, "Bearer ")), []byte(m.Token)) == 1
+}
@@ -0,0 +1,45 @@
+package risk
+
+import (
+	"context"
+	"github.com/okharch/binance/orders"
+)
+
+// Validator checks the orders of live trading by the risk manager, see NewLiveManager,
+// the exposure and equity of the book are kept by LiveBook of the same book.
+// the value of the accepted buy is reserved by its client order id, the limit order of OCO reserves the value of OCO
+type Validator struct {
+	Risk  *Manager
+	Book  string
+	Price func(symbol string) float64 // the last price of market orders by quantity, they are rejected without it
+}
+
+// ValidateOrder checks the order at its limit price or at the last price of market orders,
+// market buys by quantity are rejected while the last price is not known
+
+func (v Validator) ValidateOrder(r orders.OrderRequest, openOrders int) error {
+	price := r.Price
+	if r.Type == orders.Market {
+		price = 0
+		if v.Price != nil {
+			price = v.Price(r.Symbol)
+		}
+	}
+	return v.Risk.CheckOrder(context.Background(), Order{Id: r.ClientOrderId, Book: v.Book, Symbol: r.Symbol,
+		Side: r.Side, Quantity: r.Quantity, QuoteQty: r.QuoteOrderQty, Price: price})
+}
+
+// ValidateOCO checks OCO at the worst price of its orders
+func (v Validator) ValidateOCO(r orders.OCORequest, openOrders int) error {
+	price := r.Price
+	if r.Side == orders.Buy && r.StopLimitPrice > price {
+		price = r.StopLimitPrice
+	}
+	return v.Risk.CheckOrder(context.Background(), Order{Id: r.LimitClientOrderId, Book: v.Book, Symbol: r.Symbol,
+		Side: r.Side, Quantity: r.Quantity, Price: price})
+}
+
+// Release releases the value reserved by the order which was not sent
+func (v Validator) Release(clientOrderId string) {
+	v.Risk.Release(clientOrderId)
+}
@@ -0,0 +1,23 @@
+package sizing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"github.com/okharch/binance/exchange_info"
+)
+
+// LoadRules returns the rules of the symbol of binance.exchange_symbols imported by exchange_info
+func LoadRules(ctx context.Context, db *sqlx.DB, symbol string, market bool) (Rules, error) {
+	var filters []byte
+	if err := db.GetContext(ctx, &filters, "SELECT filters FROM binance.exchange_symbols WHERE symbol = $1",
+		symbol); err != nil {
+		return Rules{}, fmt.Errorf("failed to load filters of %s: %w", symbol, err)
+	}
+	s := &exchange_info.Symbol{Symbol: symbol}
+	if err := json.Unmarshal(filters, &s.Filters); err != nil {
+		return Rules{}, fmt.Errorf("failed to parse filters of %s: %w", symbol, err)
+	}
+	return RulesOf(s, market), nil
+}
@@ -0,0 +1,190 @@
+package sizing
+
+import (
+	"errors"
+	"github.com/okharch/binance/klines"
+	"math"
+	"time"
+)
+
+const year = 365 * 24 * time.Hour // crypto markets trade every day
+
+// FixedFractional buys for Fraction of equity
+type FixedFractional struct {
+	Fraction float64
+}
+
+func (p FixedFractional) validate() error {
+	if p.Fraction <= 0 {
+		return errors.New("fraction must be positive")
+	}
+	return nil
+}
+
+func (p FixedFractional) KLines() int {
+	return 0
+}
+
+func (p FixedFractional) Size(in Input) float64 {
+	return in.Equity * p.Fraction / in.Price
+}
+
+// FixedRisk buys the quantity losing Risk fraction of equity if the stop at ATRMultiple of ATR below the price is hit
+type FixedRisk struct {
+	Risk        float64
+	ATRPeriod   int
+	ATRMultiple float64
+}
+
+func (p FixedRisk) validate() error {
+	if p.Risk <= 0 || p.ATRPeriod <= 0 || p.ATRMultiple <= 0 {
+		return errors.New("risk, ATR period and multiple must be positive")
+	}
+	return nil
+}
+
+func (p FixedRisk) KLines() int {
+	return p.ATRPeriod + 1
+}
+
+// Stop returns the stop price the size is based on
+func (p FixedRisk) Stop(in Input) float64 {
+	return in.Price - p.ATRMultiple*ATR(in.KLines, p.ATRPeriod)
+}
+
+func (p FixedRisk) Size(in Input) float64 {
+	distance := p.ATRMultiple * ATR(in.KLines, p.ATRPeriod)
+	if distance <= 0 {
+		return 0
+	}
+	return in.Equity * p.Risk / distance
+}
+
+// ATR returns Wilder's average true range of period: the first period true ranges are averaged,
+// the next ones are smoothed into it. it is 0 if there are less than period+1 klines
+func ATR(kLines []klines.KLineEntry, period int) float64 {
+	if period <= 0 || len(kLines) < period+1 {
+		return 0
+	}
+	var atr float64
+	for i := 1; i < len(kLines); i++ {
+		k, prevClose := kLines[i], kLines[i-1].ClosePrice
+		tr := math.Max(k.HighPrice-k.LowPrice, math.Max(math.Abs(k.HighPrice-prevClose), math.Abs(k.LowPrice-prevClose)))
+		if i <= period {
+			atr += tr / float64(period)
+		} else {
+			atr = (atr*float64(period-1) + tr) / float64(period)
+		}
+	}
+	return atr
+}
+
+// Kelly buys for Scale of Kelly fraction of equity capped by Cap, e.g. 0.5 and 0.25 are the half Kelly up to 25%.
+// WinRate is the probability of a winning trade, Payoff is the average win divided by the average loss
+type Kelly struct {
+	WinRate float64
+	Payoff  float64
+	Scale   float64
+	Cap     float64
+}
+
+// EstimateKelly returns Kelly policy of win rate and payoff of the returns of past trades
+func EstimateKelly(returns []float64, scale, cap float64) Kelly {
+	var wins, losses int
+	var won, lost float64
+	for _, r := range returns {
+		if r > 0 {
+			wins++
+			won += r
+		} else if r < 0 {
+			losses++
+			lost -= r
+		}
+	}
+	k := Kelly{Scale: scale, Cap: cap}
+	if wins+losses > 0 {
+		k.WinRate = float64(wins) / float64(wins+losses)
+	}
+	if wins > 0 && losses > 0 {
+		k.Payoff = (won / float64(wins)) / (lost / float64(losses))
+	}
+	return k
+}
+
+func (p Kelly) validate() error {
+	if p.WinRate < 0 || p.WinRate > 1 {
+		return errors.New("win rate must be within 0 and 1")
+	}
+	if p.Payoff <= 0 || p.Scale <= 0 || p.Cap <= 0 {
+		return errors.New("payoff, scale and cap must be positive")
+	}
+	return nil
+}
+
+// Fraction returns the fraction of equity, 0 if the edge is negative
+func (p Kelly) Fraction() float64 {
+	if p.Payoff <= 0 {
+		return 0
+	}
+	f := (p.WinRate - (1-p.WinRate)/p.Payoff) * p.Scale
+	return math.Max(0, math.Min(f, p.Cap))
+}
+
+func (p Kelly) KLines() int {
+	return 0
+}
+
+func (p Kelly) Size(in Input) float64 {
+	return in.Equity * p.Fraction() / in.Price
+}
+
+// VolatilityTarget buys for the fraction of equity which annualised volatility is Target,
+// the volatility is calculated of Lookback close to close returns of the klines, the fraction is capped by MaxFraction
+type VolatilityTarget struct {
+	Target      float64
+	Lookback    int
+	MaxFraction float64
+}
+
+func (p VolatilityTarget) validate() error {
+	if p.Target <= 0 || p.Lookback < 2 || p.MaxFraction <= 0 {
+		return errors.New("target and max fraction must be positive, lookback must be at least 2")
+	}
+	return nil
+}
+
+func (p VolatilityTarget) KLines() int {
+	return p.Lookback + 1
+}
+
+func (p VolatilityTarget) Size(in Input) float64 {
+	vol := Volatility(in.KLines, p.Lookback)
+	if vol <= 0 {
+		return 0
+	}
+	return in.Equity * math.Min(p.Target/vol, p.MaxFraction) / in.Price
+}
+
+// Volatility returns annualised standard deviation of the last n log returns of close prices,
+// the klines must be of the same period. it is 0 if there are not enough klines
+func Volatility(kLines []klines.KLineEntry, n int) float64 {
+	if n < 2 || len(kLines) < n+1 {
+		return 0
+	}
+	kLines = kLines[len(kLines)-n-1:]
+	returns := make([]float64, n)
+	var mean float64
+	for i := range returns {
+		returns[i] = math.Log(kLines[i+1].ClosePrice / kLines[i].ClosePrice)
+		mean += returns[i] / float64(n)
+	}
+	var variance float64
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean) / float64(n-1)
+	}
+	period := kLines[n].OpenTime - kLines[n-1].OpenTime
+	if period <= 0 {
+		return 0
+	}
+	return math.Sqrt(variance * float64(year.Milliseconds()) / float64(period))
+}
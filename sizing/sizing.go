@@ -0,0 +1,185 @@
+package sizing
+
+import (
+	"fmt"
+	"github.com/okharch/binance/exchange_info"
+	"github.com/okharch/binance/klines"
+	"math"
+	"strconv"
+	"strings"
+)
+
+/*
+Position sizing decides how much of the base asset to buy. the policies are
+  - FixedFractional: the fraction of equity
+  - FixedRisk: the fraction of equity is lost if the stop at ATR multiple below the price is hit
+  - Kelly: the Kelly fraction of the win rate and payoff, scaled and capped
+  - VolatilityTarget: the fraction of equity giving the target annualised volatility of the position
+
+the size of the policy is rounded down to the lot step and limited by the quantity and notional filters of the symbol
+by Quantity, so the result can be sent as is. the same policies are used by backtest strategies and live trading:
+the input is the equity, the cash available, the current price and the recent klines of the period the strategy trades.
+*/
+
+// Input of the sizing policy
+type Input struct {
+	Equity float64 // in quote asset
+	Cash   float64 // quote asset available, the quantity is limited by it if it is positive
+	Price  float64
+	// KLines of the period, the last one is the current kline, at least Sizer.KLines of them
+	KLines []klines.KLineEntry
+}
+
+// Sizer is the position sizing policy
+type Sizer interface {
+	// KLines returns the number of klines required by Size, 0 if they are not used
+	KLines() int
+	// Size returns the quantity to buy before it is adjusted to the rules of the symbol
+	Size(in Input) float64
+}
+
+// Rules are the quantity and notional filters of the symbol, zero value of a rule means no limit
+type Rules struct {
+	StepSize    float64
+	MinQty      float64
+	MaxQty      float64
+	MinNotional float64
+	MaxNotional float64
+}
+
+// RulesOf returns the rules of LOT_SIZE, MARKET_LOT_SIZE, MIN_NOTIONAL and NOTIONAL filters of the symbol,
+// market lot and notional filters applied to market orders are used if market is true
+func RulesOf(s *exchange_info.Symbol, market bool) Rules {
+	var r Rules
+	f := s.Filters
+	if f.LotSize != nil {
+		r.StepSize, r.MinQty, r.MaxQty = f.LotSize.StepSize, f.LotSize.MinQty, f.LotSize.MaxQty
+	}
+	if market && f.MarketLotSize != nil {
+		if f.MarketLotSize.StepSize > 0 {
+			r.StepSize = f.MarketLotSize.StepSize
+		}
+		r.MinQty = math.Max(r.MinQty, f.MarketLotSize.MinQty)
+		if f.MarketLotSize.MaxQty > 0 && (r.MaxQty == 0 || f.MarketLotSize.MaxQty < r.MaxQty) {
+			r.MaxQty = f.MarketLotSize.MaxQty
+		}
+	}
+	if f.MinNotional != nil && (!market || f.MinNotional.ApplyToMarket) {
+		r.MinNotional = f.MinNotional.MinNotional
+	}
+	if f.Notional != nil {
+		if !market || f.Notional.ApplyMinToMarket {
+			r.MinNotional = math.Max(r.MinNotional, f.Notional.MinNotional)
+		}
+		if !market || f.Notional.ApplyMaxToMarket {
+			r.MaxNotional = f.Notional.MaxNotional
+		}
+	}
+	return r
+}
+
+// TooSmallError is returned by Quantity if the size is below the minimal quantity or notional of the symbol
+type TooSmallError struct {
+	Quantity float64 // the size rounded down to the lot step
+	Rule     string
+	Min      float64
+}
+
+func (e *TooSmallError) Error() string {
+	return fmt.Sprintf("quantity %s is below %s %s", format(e.Quantity), e.Rule, format(e.Min))
+}
+
+func format(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// Quantity returns the size of the policy limited by the cash, max quantity and max notional and rounded down
+// to the lot step, *TooSmallError is returned if the result does not pass min quantity or min notional.
+// 0 without error means the policy does not buy
+func Quantity(s Sizer, in Input, rules Rules) (float64, error) {
+	if in.Price <= 0 {
+		return 0, fmt.Errorf("invalid price %v", in.Price)
+	}
+	if n := s.KLines(); len(in.KLines) < n {
+		return 0, fmt.Errorf("%d klines required, got %d", n, len(in.KLines))
+	}
+	q := s.Size(in)
+	if q <= 0 || math.IsNaN(q) {
+		return 0, nil
+	}
+	if in.Cash > 0 {
+		q = math.Min(q, in.Cash/in.Price)
+	}
+	if rules.MaxQty > 0 {
+		q = math.Min(q, rules.MaxQty)
+	}
+	if rules.MaxNotional > 0 {
+		q = math.Min(q, rules.MaxNotional/in.Price)
+	}
+	q = exchange_info.FloorToStep(q, rules.StepSize)
+	if q < rules.MinQty || q == 0 {
+		return 0, &TooSmallError{Quantity: q, Rule: "min quantity", Min: rules.MinQty}
+	}
+	if q*in.Price < rules.MinNotional {
+		return 0, &TooSmallError{Quantity: q, Rule: "min notional", Min: rules.MinNotional}
+	}
+	return q, nil
+}
+
+// Parse returns the policy of the spec, the params are comma separated:
+//
+//	fixed:fraction
+//	risk:fraction,atr_period,atr_multiple
+//	kelly:win_rate,payoff,scale,cap
+//	volatility:target,lookback,max_fraction
+func Parse(spec string) (Sizer, error) {
+	name, paramsSpec, _ := strings.Cut(spec, ":")
+	var params []float64
+	if paramsSpec != "" {
+		for _, p := range strings.Split(paramsSpec, ",") {
+			v, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid param %q of sizing %s", p, spec)
+			}
+			params = append(params, v)
+		}
+	}
+	expect := func(n int) error {
+		if len(params) != n {
+			return fmt.Errorf("sizing %s expects %d params, got %d", name, n, len(params))
+		}
+		return nil
+	}
+	var s interface {
+		Sizer
+		validate() error
+	}
+	switch name {
+	case "fixed":
+		if err := expect(1); err != nil {
+			return nil, err
+		}
+		s = FixedFractional{Fraction: params[0]}
+	case "risk":
+		if err := expect(3); err != nil {
+			return nil, err
+		}
+		s = FixedRisk{Risk: params[0], ATRPeriod: int(params[1]), ATRMultiple: params[2]}
+	case "kelly":
+		if err := expect(4); err != nil {
+			return nil, err
+		}
+		s = Kelly{WinRate: params[0], Payoff: params[1], Scale: params[2], Cap: params[3]}
+	case "volatility":
+		if err := expect(3); err != nil {
+			return nil, err
+		}
+		s = VolatilityTarget{Target: params[0], Lookback: int(params[1]), MaxFraction: params[2]}
+	default:
+		return nil, fmt.Errorf("unknown sizing %s", name)
+	}
+	if err := s.validate(); err != nil {
+		return nil, fmt.Errorf("sizing %s: %w", spec, err)
+	}
+	return s, nil
+}
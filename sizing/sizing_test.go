@@ -0,0 +1,147 @@
+package sizing
+
+import (
+	"errors"
+	"github.com/okharch/binance/exchange_info"
+	"github.com/okharch/binance/klines"
+	"math"
+	"testing"
+)
+
+// hourly returns hourly klines of the close prices with the range of high and low around them
+func hourly(spread float64, closes ...float64) []klines.KLineEntry {
+	result := make([]klines.KLineEntry, len(closes))
+	for i, c := range closes {
+		openTime := int64(i) * 3600000
+		result[i] = klines.KLineEntry{OpenTime: openTime, CloseTime: openTime + 3599999, OpenPrice: c,
+			HighPrice: c + spread/2, LowPrice: c - spread/2, ClosePrice: c}
+	}
+	return result
+}
+
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9*math.Max(1, math.Abs(b))
+}
+
+func TestATR(t *testing.T) {
+	kLines := hourly(10, 100, 100, 100, 100)
+	if atr := ATR(kLines, 3); !almostEqual(atr, 10) {
+		t.Errorf("expected ATR 10, got %v", atr)
+	}
+	// the gap up is the true range: 130 + 5 - 100
+	kLines = append(kLines, hourly(10, 130)...)
+	if atr := ATR(kLines, 3); !almostEqual(atr, (10*2+35)/3.0) {
+		t.Errorf("expected smoothed ATR, got %v", atr)
+	}
+	if atr := ATR(kLines[:3], 3); atr != 0 {
+		t.Errorf("expected 0 without enough klines, got %v", atr)
+	}
+}
+
+func TestPolicies(t *testing.T) {
+	in := Input{Equity: 10000, Price: 100, KLines: hourly(4, 100, 100, 100, 100, 100)}
+	for _, c := range []struct {
+		name     string
+		s        Sizer
+		expected float64
+	}{
+		{"fixed", FixedFractional{Fraction: 0.5}, 50},
+		// the stop is 2 ATR = 8 below, 1% of equity is lost if it is hit
+		{"risk", FixedRisk{Risk: 0.01, ATRPeriod: 3, ATRMultiple: 2}, 12.5},
+		// 0.55 - 0.45 / 1.5 = 0.25, the half is 0.125
+		{"kelly", Kelly{WinRate: 0.55, Payoff: 1.5, Scale: 0.5, Cap: 0.2}, 12.5},
+		{"kelly cap", Kelly{WinRate: 0.55, Payoff: 1.5, Scale: 1, Cap: 0.2}, 20},
+		{"kelly negative edge", Kelly{WinRate: 0.3, Payoff: 1.5, Scale: 1, Cap: 1}, 0},
+		{"volatility of flat prices", VolatilityTarget{Target: 0.2, Lookback: 3, MaxFraction: 1}, 0},
+	} {
+		if size := c.s.Size(in); !almostEqual(size, c.expected) {
+			t.Errorf("%s: expected %v, got %v", c.name, c.expected, size)
+		}
+	}
+	if stop := (FixedRisk{Risk: 0.01, ATRPeriod: 3, ATRMultiple: 2}).Stop(in); !almostEqual(stop, 92) {
+		t.Errorf("expected stop 92, got %v", stop)
+	}
+
+	// the hourly returns alternate +-1%, the annualised volatility is about 0.01 * sqrt(8760) = 0.94
+	in.KLines = hourly(0, 100, 101, 100, 101, 100)
+	vol := Volatility(in.KLines, 4)
+	if vol < 0.9 || vol > 1.2 {
+		t.Fatalf("unexpected volatility %v", vol)
+	}
+	if size := (VolatilityTarget{Target: 0.2, Lookback: 4, MaxFraction: 1}).Size(in); !almostEqual(size, 10000*0.2/vol/100) {
+		t.Errorf("unexpected size of volatility target %v", size)
+	}
+	if size := (VolatilityTarget{Target: 2, Lookback: 4, MaxFraction: 1}).Size(in); !almostEqual(size, 100) {
+		t.Errorf("expected the fraction to be capped, got %v", size)
+	}
+
+	k := EstimateKelly([]float64{0.1, 0.2, -0.1, 0, 0.3, -0.1}, 0.5, 0.25)
+	if k.WinRate != 0.6 || !almostEqual(k.Payoff, 2) {
+		t.Errorf("unexpected estimated Kelly %+v", k)
+	}
+}
+
+func TestQuantity(t *testing.T) {
+	rules := Rules{StepSize: 0.001, MinQty: 0.001, MaxQty: 2, MinNotional: 30, MaxNotional: 50000}
+	in := Input{Equity: 100000, Price: 27000}
+	for _, c := range []struct {
+		fraction float64
+		cash     float64
+		expected float64
+		rule     string
+	}{
+		{0.1, 0, 0.37, ""},              // 10000 / 27000 = 0.37037
+		{0.5, 0, 1.851, ""},             // max notional 50000
+		{1, 0, 1.851, ""},               // max notional is less than max quantity
+		{0.1, 5000, 0.185, ""},          // limited by the cash
+		{0.1, 20, 0, "min quantity"},    // 20 / 27000 = 0.00074
+		{0.00028, 0, 0, "min notional"}, // 0.001 * 27000 = 27
+	} {
+		in.Cash = c.cash
+		q, err := Quantity(FixedFractional{Fraction: c.fraction}, in, rules)
+		var tooSmall *TooSmallError
+		switch {
+		case c.rule == "" && (err != nil || !almostEqual(q, c.expected)):
+			t.Errorf("%v of %v: expected %v, got %v, %v", c.fraction, c.cash, c.expected, q, err)
+		case c.rule != "" && (!errors.As(err, &tooSmall) || tooSmall.Rule != c.rule):
+			t.Errorf("%v of %v: expected %s error, got %v, %v", c.fraction, c.cash, c.rule, q, err)
+		}
+	}
+	if q, err := Quantity(Kelly{WinRate: 0.3, Payoff: 1, Scale: 1, Cap: 1}, in, rules); q != 0 || err != nil {
+		t.Errorf("expected no size without edge, got %v, %v", q, err)
+	}
+	if _, err := Quantity(FixedRisk{Risk: 0.01, ATRPeriod: 14, ATRMultiple: 2}, in, rules); err == nil {
+		t.Error("expected error without klines")
+	}
+}
+
+func TestRulesOf(t *testing.T) {
+	s := &exchange_info.Symbol{Symbol: "BTCUSDT", Filters: exchange_info.Filters{
+		LotSize:       &exchange_info.LotSizeFilter{MinQty: 0.00001, MaxQty: 9000, StepSize: 0.00001},
+		MarketLotSize: &exchange_info.LotSizeFilter{MaxQty: 100},
+		Notional: &exchange_info.NotionalFilter{MinNotional: 5, ApplyMinToMarket: true, MaxNotional: 9000000,
+			ApplyMaxToMarket: false},
+	}}
+	limit, market := RulesOf(s, false), RulesOf(s, true)
+	if limit != (Rules{StepSize: 0.00001, MinQty: 0.00001, MaxQty: 9000, MinNotional: 5, MaxNotional: 9000000}) {
+		t.Errorf("unexpected rules of limit orders %+v", limit)
+	}
+	if market != (Rules{StepSize: 0.00001, MinQty: 0.00001, MaxQty: 100, MinNotional: 5}) {
+		t.Errorf("unexpected rules of market orders %+v", market)
+	}
+}
+
+func TestParse(t *testing.T) {
+	s, err := Parse("risk:0.01,14,2")
+	if err != nil || s != (FixedRisk{Risk: 0.01, ATRPeriod: 14, ATRMultiple: 2}) {
+		t.Errorf("unexpected policy %+v, %v", s, err)
+	}
+	if s, err = Parse("volatility:0.2,30,1"); err != nil || s.KLines() != 31 {
+		t.Errorf("unexpected policy %+v, %v", s, err)
+	}
+	for _, spec := range []string{"fixed", "fixed:0", "kelly:0.5,1,1", "kelly:1.5,1,1,1", "martingale:2", "fixed:x"} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("expected %s to be invalid", spec)
+		}
+	}
+}
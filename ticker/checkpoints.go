@@ -0,0 +1,77 @@
+package ticker
+
+import (
+	"context"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"github.com/okharch/binance/indicators"
+)
+
+// Checkpoints contains open time of the last 1m kline processed by indicator name
+type Checkpoints map[string]int64
+
+// LoadCheckpoints loads checkpoints of signal generation for the symbol
+func LoadCheckpoints(ctx context.Context, db *sqlx.DB, symbolId int32) (Checkpoints, error) {
+	var rows []struct {
+		Indicator    string `db:"indicator"`
+		LastOpenTime int64  `db:"last_open_time"`
+	}
+	err := db.SelectContext(ctx, &rows,
+		`SELECT indicator, last_open_time FROM binance.signal_checkpoint WHERE symbol_id = $1`, symbolId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load checkpoints of symbol %d: %w", symbolId, err)
+	}
+	checkpoints := make(Checkpoints, len(rows))
+	for _, row := range rows {
+		checkpoints[row.Indicator] = row.LastOpenTime
+	}
+	return checkpoints, nil
+}
+
+// SaveCheckpoints saves open time of the last processed 1m kline for all the indicators
+func SaveCheckpoints(ctx context.Context, db *sqlx.DB, symbolId int32, checkpoints Checkpoints) error {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for indicator, lastOpenTime := range checkpoints {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO binance.signal_checkpoint (symbol_id, indicator, last_open_time)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (symbol_id, indicator) DO UPDATE
+			SET last_open_time = EXCLUDED.last_open_time, updated_at = now()`,
+			symbolId, indicator, lastOpenTime)
+		if err != nil {
+			return fmt.Errorf("failed to save checkpoint of %s for symbol %d: %w", indicator, symbolId, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// Min returns the earliest checkpoint among the registered indicators, 0 if any of them was never processed
+func (c Checkpoints) Min() int64 {
+	var result int64 = -1
+	for _, indicator := range indicators.Registered() {
+		last, ok := c[indicator.Name]
+		if !ok {
+			return 0
+		}
+		if result < 0 || last < result {
+			result = last
+		}
+	}
+	if result < 0 {
+		return 0
+	}
+	return result
+}
+
+// Advance sets checkpoints of all the registered indicators to openTime
+func (c Checkpoints) Advance(openTime int64) {
+	for _, indicator := range indicators.Registered() {
+		if c[indicator.Name] < openTime {
+			c[indicator.Name] = openTime
+		}
+	}
+}
@@ -0,0 +1,13 @@
+package ticker
+
+import "github.com/okharch/binance/indicators/fibonacci"
+
+// FibonacciSwing returns the swing of lookback klines of the period with periodIndex
+// before and including the current position of the ticker, its levels are swing.Values()
+func (t *Ticker) FibonacciSwing(periodIndex, lookback int, p fibonacci.Params) (swing fibonacci.Swing, ok bool) {
+	kLines := t.GetKLines(periodIndex, PeriodMinutes[periodIndex], lookback)
+	if kLines == nil {
+		return
+	}
+	return fibonacci.LastSwing(kLines, p)
+}
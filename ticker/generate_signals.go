@@ -4,42 +4,72 @@ import (
 	"context"
 	"github.com/jmoiron/sqlx"
 	"github.com/okharch/binance/indicators"
-	"github.com/okharch/binance/indicators/mac"
+	_ "github.com/okharch/binance/indicators/all"
 	"github.com/okharch/binance/klines"
 	"log"
-	"runtime"
 	"sync"
+	"time"
 )
 
+// TGenIndicatorSignal is a registered indicator to be calculated on the period of the ticker
 type TGenIndicatorSignal struct {
-	IndicatorType indicators.IndicatorType
-	IndicatorFunc func(kLines []klines.KLineEntry, params []int) indicators.TradeSignal
-	PeriodIndex, PeriodMinutes,
-	MinLongTerm, MaxLongTerm int
-	ShortTermMul, ShortTermDiv int
+	Indicator                  indicators.Indicator
+	Grid                       [][]int
+	PeriodIndex, PeriodMinutes int
 }
 
-func getIndicatorsList() []TGenIndicatorSignal {
-	return macIndicatorsList()
+func getIndicatorsList() (result []TGenIndicatorSignal) {
+	for _, indicator := range indicators.Registered() {
+		grid := indicator.Grid()
+		for i, pm := range PeriodMinutes {
+			if !calculatedOnPeriod(indicator, pm) {
+				continue
+			}
+			result = append(result, TGenIndicatorSignal{indicator, grid, i, pm})
+		}
+	}
+	return
 }
 
-const MaxLongTerm = 100
+func calculatedOnPeriod(indicator indicators.Indicator, periodMinutes int) bool {
+	if len(indicator.Periods) == 0 {
+		return true
+	}
+	for _, period := range indicator.Periods {
+		if period == time.Duration(periodMinutes)*time.Minute {
+			return true
+		}
+	}
+	return false
+}
 
-func macIndicatorsList() (result []TGenIndicatorSignal) {
-	for i, pm := range PeriodMinutes {
-		result = append(result, TGenIndicatorSignal{
-			indicators.IndicatorTypeMAC, mac.MAC,
-			i, pm, 10, MaxLongTerm,
-			2, 3,
-		})
+var catalogues = map[*sqlx.DB]*indicators.Catalogue{}
+var cataloguesMu sync.Mutex
+
+// GetCatalogue returns indicator catalogue of the db with all registered indicators in it
+func GetCatalogue(ctx context.Context, db *sqlx.DB) (*indicators.Catalogue, error) {
+	cataloguesMu.Lock()
+	defer cataloguesMu.Unlock()
+	if c, ok := catalogues[db]; ok {
+		return c, nil
 	}
-	return
+	c := indicators.NewCatalogue(db)
+	if err := c.Load(ctx); err != nil {
+		return nil, err
+	}
+	if err := c.Register(ctx, indicators.Registered()); err != nil {
+		return nil, err
+	}
+	catalogues[db] = c
+	return c, nil
 }
 
-func insertIndicatorSignals(db *sqlx.DB, signals []indicators.IndicatorSignal) error {
+// InsertIndicatorSignals inserts signals skipping the ones which are already stored
+func InsertIndicatorSignals(db *sqlx.DB, signals []indicators.IndicatorSignal) error {
 	query := `
-        INSERT INTO binance.indicator_signal (open_time, symbol_id, indicator_id, period, volume)
-        VALUES (:open_time, :symbol_id, :indicator_id, :period, :volume)
+        INSERT INTO binance.indicator_signal (open_time, symbol_id, indicator_id, side, period, volume)
+        VALUES (:open_time, :symbol_id, :indicator_id, :side, :period, :volume)
+        ON CONFLICT (symbol_id, indicator_id, period, open_time) DO NOTHING
     `
 	_, err := db.NamedExec(query, signals)
 	return err
@@ -48,106 +78,127 @@ func insertIndicatorSignals(db *sqlx.DB, signals []indicators.IndicatorSignal) e
 // GenerateSignals generates indicator signals for a given symbol using the provided
 // TGenIndicatorSignals, and inserts them into a database using a given context and SQLx database pointer.
 func (t *Ticker) GenerateSignals(ctx context.Context, db *sqlx.DB, symbolId int32) error {
+	return t.GenerateSignalsSince(ctx, db, symbolId, nil)
+}
+
+// GenerateSignalsSince generates signals at the current position for the indicators
+// whose checkpoints are before the current 1m kline
+func (t *Ticker) GenerateSignalsSince(ctx context.Context, db *sqlx.DB, symbolId int32, checkpoints Checkpoints) error {
+	catalogue, err := GetCatalogue(ctx, db)
+	if err != nil {
+		return err
+	}
 	var signals []indicators.IndicatorSignal
-	insertSignals := func() error {
-		if len(signals) == 0 {
-			return nil
-		}
-		err := insertIndicatorSignals(db, signals)
-		if err != nil {
-			return err
-		}
-		signals = signals[:0] // reset slice
+	t.CalculateSignals(symbolId, catalogue, checkpoints, func(signal indicators.IndicatorSignal) {
+		signals = append(signals, signal)
+	})
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if len(signals) == 0 {
 		return nil
 	}
-	// this server handles concurrent access to signals slice using signalChannel
-	var waitSignals sync.WaitGroup
-	signalChannel := make(chan indicators.IndicatorSignal, 1024)
-	waitSignals.Add(1)
-	go func() {
-		defer waitSignals.Done()
-		for signal := range signalChannel {
-			signals = append(signals, signal)
-		}
-	}()
+	return InsertIndicatorSignals(db, signals)
+}
 
-	concurrentRoutines := make(chan struct{}, runtime.NumCPU())
-	var wg sync.WaitGroup
-	generateSignals := func(indSignal TGenIndicatorSignal) {
-		defer wg.Done()
-		// check if input ranges valid
-		if int(indSignal.IndicatorType) > 1024 {
-			log.Fatalf("invalid indicator type exceeds 1024: %s", indSignal.IndicatorType)
+// CalculateSignals calculates signals of all registered indicators at the current position
+// for the indicators whose checkpoints are before the current 1m kline, and passes them to emit.
+// it runs in the caller's goroutine, parallelism is achieved by processing several symbols at once
+func (t *Ticker) CalculateSignals(symbolId int32, catalogue *indicators.Catalogue, checkpoints Checkpoints,
+	emit func(signal indicators.IndicatorSignal)) {
+	openTime := t.periods[0][t.position-t.offsets[0]].OpenTime
+	for _, indSignal := range getIndicatorsList() {
+		if last, ok := checkpoints[indSignal.Indicator.Name]; ok && last >= openTime {
+			continue
 		}
-		if indSignal.MaxLongTerm >= 1<<11 {
-			log.Fatalf("invalid longTerm value: %d exceeds 2048", indSignal.MaxLongTerm)
-		}
-		if indSignal.ShortTermDiv != 0 {
-			maxShortTerm := indSignal.MaxLongTerm * indSignal.ShortTermMul / indSignal.ShortTermDiv
-			if maxShortTerm >= 1<<10 {
-				log.Fatalf("invalid shortTerm value: %d exceeds 1024", maxShortTerm)
-			}
-		}
-		// wait free slot for execution or cancelling event
-		select {
-		case <-ctx.Done():
-			return
-		case concurrentRoutines <- struct{}{}: // take a slot
-		}
-		defer func() {
-			<-concurrentRoutines // release the slot
-		}()
-		// calculate shortTerm range before loop
-		var minShortTerm, maxShortTerm int
-		if indSignal.ShortTermDiv != 0 {
-			minShortTerm = indSignal.MinLongTerm * indSignal.ShortTermMul / indSignal.ShortTermDiv
-			maxShortTerm = indSignal.MaxLongTerm * indSignal.ShortTermMul / indSignal.ShortTermDiv
-		}
-		for longTerm := indSignal.MinLongTerm; longTerm <= indSignal.MaxLongTerm; longTerm++ {
-			// check min..max < longTerm
-			// we need one more element to calculate two following moving average: 0..n and 1..n+1
-			longTermKLines := t.GetKLines(indSignal.PeriodIndex, indSignal.PeriodMinutes, longTerm+1)
-			if longTermKLines == nil {
+		t.calculateIndicatorSignals(indSignal, symbolId, catalogue, emit)
+	}
+}
+
+func (t *Ticker) calculateIndicatorSignals(indSignal TGenIndicatorSignal, symbolId int32, catalogue *indicators.Catalogue,
+	emit func(signal indicators.IndicatorSignal)) {
+	indicator := indSignal.Indicator
+	period := time.Duration(indSignal.PeriodMinutes) * time.Minute
+	for _, params := range indSignal.Grid {
+		var signal indicators.TradeSignal
+		var kLines []klines.KLineEntry
+		if indicator.SourceFactory != nil {
+			// the current kline of the period is used for volume values
+			kLines = t.GetKLines(indSignal.PeriodIndex, indSignal.PeriodMinutes, 4)
+			if kLines == nil {
 				continue
 			}
-			kl := &longTermKLines[longTerm]
-			openTime := kl.OpenTime
-			volume := kl.Volume
-			volAvg := klines.VolumeAvg(longTermKLines[1:])
-			vol3Avg := klines.VolumeAvg(longTermKLines[longTerm-3+1 : longTerm+1])
-			for shortTerm := minShortTerm; shortTerm <= maxShortTerm; shortTerm++ {
-				signal := indSignal.IndicatorFunc(longTermKLines, []int{longTerm, shortTerm})
-				if signal != indicators.TradeNone {
-					indicatorId := int32((int(indSignal.IndicatorType)<<10+longTerm)<<12 + shortTerm)
-					if signal == indicators.TradeSell {
-						indicatorId = -indicatorId
-					}
-					signalChannel <- indicators.IndicatorSignal{
-						OpenTime:    openTime,
-						SymbolId:    symbolId,
-						IndicatorId: indicatorId,
-						Period:      int8(indSignal.PeriodIndex),
-						Volume:      volume,
-						VolAvg:      volAvg,
-						Vol3Avg:     vol3Avg,
-					}
-				}
+			signal = indicator.SourceFactory(params)(t, period)
+		} else {
+			kLines = t.GetKLines(indSignal.PeriodIndex, indSignal.PeriodMinutes, indicator.KLinesCount(params))
+			if kLines == nil {
+				continue
 			}
+			signal = indicator.Factory(params)(kLines)
 		}
+		if signal == indicators.TradeNone {
+			continue
+		}
+		n := len(kLines)
+		id, ok := catalogue.Id(indicator.Name, params)
+		if !ok {
+			log.Printf("indicator %s%v is missing in catalogue", indicator.Name, params)
+			continue
+		}
+		kl := &kLines[n-1]
+		emit(indicators.IndicatorSignal{
+			OpenTime:    kl.OpenTime,
+			SymbolId:    symbolId,
+			IndicatorId: id,
+			Side:        signal.String(),
+			Period:      int8(indSignal.PeriodIndex),
+			Volume:      kl.Volume,
+			VolAvg:      klines.VolumeAvg(kLines[1:]),
+			Vol3Avg:     klines.VolumeAvg(kLines[n-3:]),
+		})
 	}
-	for _, indicator := range getIndicatorsList() {
-		wg.Add(1)
-		go generateSignals(indicator)
+}
+
+// MaxKLines returns the maximal number of klines of a period required by registered indicators
+func MaxKLines() int {
+	maxKLines := 0
+	for _, indicator := range indicators.Registered() {
+		if n := indicator.MaxKLinesCount(); n > maxKLines {
+			maxKLines = n
+		}
 	}
-	wg.Wait()
-	close(signalChannel)
-	waitSignals.Wait()
-	if err := ctx.Err(); err != nil {
-		return err
+	return maxKLines
+}
+
+// KLinesToKeep returns the number of klines of each period a streaming ticker has to keep,
+// composite rules report their lookbacks by KLinesCount, so they must be registered before the ticker is created
+func KLinesToKeep() int {
+	const minKeep = 256
+	if n := MaxKLines() + 1; n > minKeep {
+		return n
 	}
-	return insertSignals()
+	return minKeep
 }
 
+// GetMaxMinutes returns the number of 1m klines enough to calculate all registered indicators on all periods
 func GetMaxMinutes() int {
-	return MaxLongTerm * PeriodMinutes[len(PeriodMinutes)-1]
+	return MaxKLines() * PeriodMinutes[len(PeriodMinutes)-1]
+}
+
+// HistoryMinutes returns the number of 1m klines the registered indicators need before the first signal
+// on the periods they are calculated on: their klines and the first one of the period which may be incomplete,
+// a streaming ticker does not keep more than KLinesToKeep klines of the period
+func HistoryMinutes() int {
+	keep := KLinesToKeep()
+	result := 0
+	for _, indSignal := range getIndicatorsList() {
+		n := indSignal.Indicator.MaxKLinesCount() + 1
+		if n > keep {
+			n = keep
+		}
+		if minutes := n * indSignal.PeriodMinutes; minutes > result {
+			result = minutes
+		}
+	}
+	return result
 }
@@ -0,0 +1,13 @@
+package ticker
+
+import "github.com/okharch/binance/indicators/ichimoku"
+
+// Ichimoku returns Ichimoku lines for the current position of the ticker on the period with periodIndex.
+// ok is false if there are not enough klines before the current position
+func (t *Ticker) Ichimoku(periodIndex int, p ichimoku.Params) (v ichimoku.Values, ok bool) {
+	kLines := t.GetKLines(periodIndex, PeriodMinutes[periodIndex], p.KLinesCount())
+	if kLines == nil {
+		return
+	}
+	return ichimoku.Calculate(kLines, p)
+}
@@ -0,0 +1,13 @@
+package ticker
+
+import "github.com/okharch/binance/indicators/levels"
+
+// SupportResistance returns levels detected on lookback klines of the period with periodIndex
+// before and including the current position of the ticker
+func (t *Ticker) SupportResistance(periodIndex, lookback int, p levels.Params) []levels.Level {
+	kLines := t.GetKLines(periodIndex, PeriodMinutes[periodIndex], lookback)
+	if kLines == nil {
+		return nil
+	}
+	return levels.Detect(kLines, p)
+}
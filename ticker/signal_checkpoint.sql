@@ -0,0 +1,18 @@
+-- progress of signal generation: the last 1m kline processed by indicator for the symbol
+CREATE TABLE IF NOT EXISTS binance.signal_checkpoint (
+    symbol_id INT NOT NULL,
+    indicator VARCHAR(40) NOT NULL, -- registered name of the indicator
+    last_open_time BIGINT NOT NULL, -- open time of the last processed 1m kline
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (symbol_id, indicator)
+);
+COMMENT ON TABLE binance.signal_checkpoint IS 'Checkpoints of incremental signal generation by cmd/generate_signals.';
+
+-- signals are generated on each 1m tick for the current kline of the period,
+-- the first signal of the indicator on the kline is kept, so generation can be safely repeated
+DELETE FROM binance.indicator_signal a
+USING binance.indicator_signal b
+WHERE a.ctid > b.ctid AND a.symbol_id = b.symbol_id AND a.indicator_id = b.indicator_id
+  AND a.period = b.period AND a.open_time = b.open_time;
+CREATE UNIQUE INDEX IF NOT EXISTS indicator_signal_uk
+    ON binance.indicator_signal (symbol_id, indicator_id, period, open_time);
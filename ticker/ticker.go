@@ -1,9 +1,8 @@
 package ticker
 
 import (
-	"context"
 	"github.com/okharch/binance/klines"
-	"math"
+	"time"
 )
 
 var PeriodMinutes = []int{1, 5, 10, 15, 30, 60, 240, 720, 1440, 1440 * 3, 1440 * 7}
@@ -11,99 +10,121 @@ var PeriodMinutes = []int{1, 5, 10, 15, 30, 60, 240, 720, 1440, 1440 * 3, 1440 *
 type Ticker struct {
 	position int
 	periods  [][]klines.KLineEntry
+	// streaming ticker keeps only the last klines of each period,
+	// offsets are absolute indexes of the first kept klines of the periods
+	offsets []int
+	keep    int
+	// current are absolute indexes of the klines of the periods containing the current 1m kline,
+	// closed is true for the periods whose klines are completed by it
+	current []int
+	closed  []bool
 }
 
-func (t *Ticker) allocatePeriods() {
-}
-
+// NewTicker creates ticker of 1m klines, the position is before the first one, see Next
 func NewTicker(data1m []klines.KLineEntry) *Ticker {
-	t := &Ticker{}
-	// calculate the maximum number of klines to store for this period
-	t.periods = make([][]klines.KLineEntry, len(PeriodMinutes))
+	t := newTicker()
 	t.periods[0] = data1m
-	minutes := len(data1m)
 	for i, m := range PeriodMinutes[1:] {
-		t.periods[i+1] = make([]klines.KLineEntry, (minutes+m-1)/m)
+		t.periods[i+1] = make([]klines.KLineEntry, 0, len(data1m)/m+2)
 	}
+	return t
+}
 
+// NewStreamingTicker creates ticker which is fed by Push and keeps only the last keep klines of each period,
+// so the memory does not depend on the number of klines passed through it
+func NewStreamingTicker(keep int) *Ticker {
+	t := newTicker()
+	t.keep = keep
 	return t
 }
 
-// Get a channel of klines starting from the beginning
-func (t *Ticker) GetTicksChannel(ctx context.Context) <-chan klines.KLineEntry {
-	channel := make(chan klines.KLineEntry)
-
-	// Start a goroutine to send klines to the channel
-	go func() {
-		defer close(channel)
-		// loop over minute bars
-		for i, kline := range t.periods[0] {
-			select {
-			case <-ctx.Done():
-				return
-			case channel <- kline:
-				t.updatePosition(i)
-			}
+func newTicker() *Ticker {
+	return &Ticker{
+		position: -1,
+		periods:  make([][]klines.KLineEntry, len(PeriodMinutes)),
+		offsets:  make([]int, len(PeriodMinutes)),
+		current:  make([]int, len(PeriodMinutes)),
+		closed:   make([]bool, len(PeriodMinutes)),
+	}
+}
+
+// Push appends 1m kline to the streaming ticker and moves the position to it
+func (t *Ticker) Push(kLine1m klines.KLineEntry) {
+	t.periods[0] = append(t.periods[0], kLine1m)
+	t.updatePosition(t.position + 1)
+	// drop old klines when twice as many as required are stored, so copying is amortized
+	for i, kLines := range t.periods {
+		if len(kLines) > 2*t.keep {
+			drop := len(kLines) - t.keep
+			t.periods[i] = append(kLines[:0], kLines[drop:]...)
+			t.offsets[i] += drop
 		}
-	}()
+	}
+}
 
-	return channel
+// Next moves the ticker created by NewTicker to the next 1m kline, false if there are no more klines
+func (t *Ticker) Next() bool {
+	if t.position+1 >= len(t.periods[0]) {
+		return false
+	}
+	t.updatePosition(t.position + 1)
+	return true
 }
 
-// is used internally when iterating over 1m candles to update/append new candles for larger periods
+// is used internally when iterating over 1m candles to update/append new candles for larger periods.
+// the klines of the periods are aligned to the open time of 1m klines by klines.PeriodStart,
+// so the gaps of 1m klines do not shift the following klines of the periods
 func (t *Ticker) updatePosition(new1mposition int) {
 	// update the current position to the new 1 minute position
 	t.position = new1mposition
+	t.current[0] = new1mposition
+	t.closed[0] = true
 
 	// get the new 1 minute kline
 	kLines1m := t.periods[0]
-	kLine1m := &kLines1m[new1mposition]
+	kLine1m := &kLines1m[new1mposition-t.offsets[0]]
 
 	// iterate over each period in the pdata map
 	for i, count1mPeriods := range PeriodMinutes[1:] {
-		// calculate the index of the current kLine for the current period
-		idx := new1mposition / count1mPeriods
-
-		// get the current kLine for the current period
-		kLines := t.periods[i+1]
-		kLine := &kLines[idx]
-
-		// check if we just started a new period
-		if t.position%count1mPeriods == 0 {
-			// initialize the open price and time and other aggregate values for the new period
-			kLine.OpenPrice = kLine1m.OpenPrice
-			kLine.OpenTime = kLine1m.OpenTime
-			kLine.HighPrice = kLine1m.HighPrice
-			kLine.LowPrice = kLine1m.LowPrice
-			kLine.Volume = kLine1m.Volume
-			kLine.QuoteAssetVolume = kLine1m.QuoteAssetVolume
-			kLine.NumTrades = kLine1m.NumTrades
-			kLine.TakerBuyBaseAssetVolume = kLine1m.TakerBuyBaseAssetVolume
-			kLine.TakerBuyQuoteAssetVolume = kLine1m.TakerBuyQuoteAssetVolume
-		} else {
-			// aggregate the kLine data if we're not at the start of a new period
-			kLine.HighPrice = math.Max(kLine.HighPrice, kLine1m.HighPrice)
-			kLine.LowPrice = math.Min(kLine.LowPrice, kLine1m.LowPrice)
-			kLine.Volume += kLine1m.Volume
-			kLine.QuoteAssetVolume += kLine1m.QuoteAssetVolume
-			kLine.NumTrades += kLine1m.NumTrades
-			kLine.TakerBuyBaseAssetVolume += kLine1m.TakerBuyBaseAssetVolume
-			kLine.TakerBuyQuoteAssetVolume += kLine1m.TakerBuyQuoteAssetVolume
+		periodIndex := i + 1
+		period := time.Duration(count1mPeriods) * time.Minute
+		openTime := klines.PeriodStart(kLine1m.OpenTime, period)
+
+		// the first 1m kline or the one of the next kline of the period starts it, the first one may be incomplete
+		start := new1mposition == 0
+		if !start && t.periods[periodIndex][t.current[periodIndex]-t.offsets[periodIndex]].OpenTime != openTime {
+			t.current[periodIndex]++
+			start = true
+		}
+
+		// get the current kLine for the current period, new klines are appended
+		idx := t.current[periodIndex] - t.offsets[periodIndex]
+		if idx == len(t.periods[periodIndex]) {
+			t.periods[periodIndex] = append(t.periods[periodIndex], klines.KLineEntry{})
 		}
+		kLine := &t.periods[periodIndex][idx]
 
-		// update the close price and time for the kLine
-		kLine.ClosePrice = kLine1m.ClosePrice
-		kLine.CloseTime = kLine1m.CloseTime
+		// start or aggregate the kLine of the current period
+		klines.Aggregate(kLine, kLine1m, start)
+		kLine.OpenTime = openTime
+		closeTime := kLine1m.CloseTime + 1
+		t.closed[periodIndex] = klines.PeriodStart(closeTime, period) == closeTime
 	}
 }
 
 // Get specified number of candlesticks before and including current position
 func (t *Ticker) GetKLines(periodIndex, count1mPeriods, n int) []klines.KLineEntry {
-	// calculate the index of the current kLine for the current period
-	idx := t.position / count1mPeriods
+	// the index of the current kLine for the current period,
+	// the first kline of the period may be incomplete, so it is not counted
+	idx := t.current[periodIndex]
 	if idx <= n {
 		return nil
 	}
+	idx -= t.offsets[periodIndex]
+	if idx < n-1 {
+		// streaming ticker does not keep that many klines
+		return nil
+	}
 	return t.periods[periodIndex][idx-n+1 : idx+1]
 }
 
@@ -114,3 +135,15 @@ func max(a, b int) int {
 	}
 	return b
 }
+
+// KLines returns n klines of the period before and including current position,
+// nil if the period is not one of PeriodMinutes or there are not enough klines
+func (t *Ticker) KLines(period time.Duration, n int) []klines.KLineEntry {
+	minutes := int(period / time.Minute)
+	for i, pm := range PeriodMinutes {
+		if pm == minutes {
+			return t.GetKLines(i, pm, n)
+		}
+	}
+	return nil
+}
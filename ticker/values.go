@@ -0,0 +1,28 @@
+package ticker
+
+import (
+	"github.com/okharch/binance/indicators"
+	"github.com/okharch/binance/indicators/fibonacci"
+	"github.com/okharch/binance/indicators/ichimoku"
+)
+
+// fibonacciLookBack is the number of klines the swing of the stored Fibonacci levels is found in
+const fibonacciLookBack = 50
+
+// CalculateValues calculates values of multi-line indicators (Ichimoku lines, Fibonacci levels)
+// on the klines of the periods completed by the current 1m kline and passes them to emit.
+// 1m klines are skipped, their values would outnumber the klines themselves
+func (t *Ticker) CalculateValues(symbolId int32, emit func(values []indicators.IndicatorValueRecord)) {
+	for periodIndex := 1; periodIndex < len(PeriodMinutes); periodIndex++ {
+		if !t.closed[periodIndex] {
+			continue
+		}
+		openTime := t.periods[periodIndex][t.current[periodIndex]-t.offsets[periodIndex]].OpenTime
+		if v, ok := t.Ichimoku(periodIndex, ichimoku.DefaultParams()); ok {
+			emit(indicators.NewIndicatorValueRecords(openTime, symbolId, int8(periodIndex), "Ichimoku", v.Lines()))
+		}
+		if swing, ok := t.FibonacciSwing(periodIndex, fibonacciLookBack, fibonacci.DefaultParams()); ok {
+			emit(indicators.NewIndicatorValueRecords(openTime, symbolId, int8(periodIndex), "Fibonacci", swing.Values()))
+		}
+	}
+}
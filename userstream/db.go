@@ -0,0 +1,65 @@
+package userstream
+
+import (
+	"context"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"github.com/okharch/binance/orders"
+)
+
+// DBHandler records the events into the tables of userstream.sql, the orders are updated by Orders if it is set
+type DBHandler struct {
+	DB     *sqlx.DB
+	Orders *orders.Manager
+}
+
+// AccountPosition updates binance.account_balance, the balances older than the stored ones are ignored
+func (h DBHandler) AccountPosition(ctx context.Context, e *AccountPosition) error {
+	tx, err := h.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, b := range e.Balances {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO binance.account_balance (asset, free, locked, update_time)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (asset) DO UPDATE SET free = EXCLUDED.free, locked = EXCLUDED.locked,
+			update_time = EXCLUDED.update_time, updated_at = now()
+		WHERE binance.account_balance.update_time <= EXCLUDED.update_time`,
+			b.Asset, b.Free, b.Locked, e.LastUpdateTime); err != nil {
+			return fmt.Errorf("failed to save balance of %s: %w", b.Asset, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// BalanceUpdate inserts the update into binance.balance_update
+func (h DBHandler) BalanceUpdate(ctx context.Context, e *BalanceUpdate) error {
+	if _, err := h.DB.ExecContext(ctx, `INSERT INTO binance.balance_update (asset, delta, clear_time, event_time)
+	VALUES ($1, $2, $3, $4)
+	ON CONFLICT DO NOTHING`, e.Asset, e.Delta, e.ClearTime, e.EventTime); err != nil {
+		return fmt.Errorf("failed to save balance update of %s: %w", e.Asset, err)
+	}
+	return nil
+}
+
+// ExecutionReport applies the order to Orders and inserts the fill into binance.spot_fill
+func (h DBHandler) ExecutionReport(ctx context.Context, e *ExecutionReport) error {
+	if h.Orders != nil {
+		if _, err := h.Orders.Apply(ctx, e.Order()); err != nil {
+			return err
+		}
+	}
+	f, ok := e.Fill()
+	if !ok {
+		return nil
+	}
+	if _, err := h.DB.ExecContext(ctx, `INSERT INTO binance.spot_fill (symbol, trade_id, order_id, client_order_id,
+		side, price, quantity, quote_qty, commission, commission_asset, is_maker, trade_time)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+	ON CONFLICT DO NOTHING`, f.Symbol, f.TradeId, f.OrderId, f.ClientOrderId, f.Side, f.Price, f.Quantity,
+		f.QuoteQty, f.Commission, f.CommissionAsset, f.Maker, f.Time); err != nil {
+		return fmt.Errorf("failed to save fill %s %d: %w", f.Symbol, f.TradeId, err)
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package userstream
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/okharch/binance/orders"
+)
+
+/*
+Events of Binance user data stream, numbers are sent as strings.
+encoding/json matches keys case-insensitively when there is no exact match, so every key of an event
+which differs from another one by case only is declared: otherwise "C" would be decoded into "c".
+*/
+
+const (
+	EventAccountPosition  = "outboundAccountPosition"
+	EventBalanceUpdate    = "balanceUpdate"
+	EventExecutionReport  = "executionReport"
+	EventListenKeyExpired = "listenKeyExpired"
+)
+
+type Balance struct {
+	Asset  string  `json:"a"`
+	Free   float64 `json:"f,string"`
+	Locked float64 `json:"l,string"`
+}
+
+// AccountPosition is sent when the balances change, it contains the changed assets only
+type AccountPosition struct {
+	EventType      string    `json:"e"`
+	EventTime      int64     `json:"E"`
+	LastUpdateTime int64     `json:"u"`
+	Balances       []Balance `json:"B"`
+}
+
+// BalanceUpdate is sent on deposits, withdrawals and transfers between accounts
+type BalanceUpdate struct {
+	EventType string  `json:"e"`
+	EventTime int64   `json:"E"`
+	Asset     string  `json:"a"`
+	Delta     float64 `json:"d,string"`
+	ClearTime int64   `json:"T"`
+}
+
+// ExecutionReport is sent on every change of the order
+type ExecutionReport struct {
+	EventType         string             `json:"e"`
+	EventTime         int64              `json:"E"`
+	Symbol            string             `json:"s"`
+	ClientOrderId     string             `json:"c"` // the id of the cancel request if the order is cancelled
+	Side              orders.Side        `json:"S"`
+	Type              orders.Type        `json:"o"`
+	TimeInForce       orders.TimeInForce `json:"f"`
+	Quantity          float64            `json:"q,string"`
+	Price             float64            `json:"p,string"`
+	StopPrice         float64            `json:"P,string"`
+	IcebergQty        float64            `json:"F,string"`
+	OrderListId       int64              `json:"g"`
+	OrigClientOrderId string             `json:"C"` // the id of the cancelled order
+	ExecutionType     string             `json:"x"` // NEW, CANCELED, REPLACED, REJECTED, TRADE, EXPIRED
+	Status            string             `json:"X"`
+	RejectReason      string             `json:"r"`
+	OrderId           int64              `json:"i"`
+	LastQty           float64            `json:"l,string"`
+	CumulativeQty     float64            `json:"z,string"`
+	LastPrice         float64            `json:"L,string"`
+	Commission        float64            `json:"n,string"`
+	CommissionAsset   string             `json:"N"`
+	TransactionTime   int64              `json:"T"`
+	TradeId           int64              `json:"t"`
+	Ignore1           int64              `json:"I"`
+	IsOnBook          bool               `json:"w"`
+	IsMaker           bool               `json:"m"`
+	Ignore2           bool               `json:"M"`
+	CreationTime      int64              `json:"O"`
+	CumulativeQuote   float64            `json:"Z,string"`
+	LastQuote         float64            `json:"Y,string"`
+	QuoteOrderQty     float64            `json:"Q,string"`
+	WorkingTime       int64              `json:"W"`
+	SelfTradeMode     string             `json:"V"`
+	PreventedMatchId  int64              `json:"v"`
+	TradeGroupId      int64              `json:"u"`
+	CounterOrderId    int64              `json:"U"`
+}
+
+// Fill is the trade of the order
+type Fill struct {
+	Symbol          string
+	TradeId         int64
+	OrderId         int64
+	ClientOrderId   string
+	Side            orders.Side
+	Price           float64
+	Quantity        float64
+	QuoteQty        float64
+	Commission      float64
+	CommissionAsset string
+	Maker           bool
+	Time            int64
+}
+
+// Order returns the state of the order after the event
+func (r ExecutionReport) Order() orders.Order {
+	o := orders.Order{Symbol: r.Symbol, OrderId: r.OrderId, OrderListId: r.OrderListId, ClientOrderId: r.ClientOrderId,
+		Side: r.Side, Type: r.Type, TimeInForce: r.TimeInForce, Price: r.Price, StopPrice: r.StopPrice,
+		OrigQty: r.Quantity, ExecutedQty: r.CumulativeQty, QuoteQty: r.CumulativeQuote, Status: orders.Status(r.Status),
+		UpdateTime: r.TransactionTime}
+	if r.OrigClientOrderId != "" {
+		o.ClientOrderId = r.OrigClientOrderId
+	}
+	if o.Status == "EXPIRED_IN_MATCH" {
+		// expired by self trade prevention
+		o.Status = orders.StatusExpired
+	}
+	if r.RejectReason != "" && r.RejectReason != "NONE" {
+		o.Reason = r.RejectReason
+	}
+	return o
+}
+
+// Fill returns the trade if the order was filled by the event
+func (r ExecutionReport) Fill() (Fill, bool) {
+	if r.ExecutionType != "TRADE" {
+		return Fill{}, false
+	}
+	return Fill{Symbol: r.Symbol, TradeId: r.TradeId, OrderId: r.OrderId, ClientOrderId: r.ClientOrderId, Side: r.Side,
+		Price: r.LastPrice, Quantity: r.LastQty, QuoteQty: r.LastQuote, Commission: r.Commission,
+		CommissionAsset: r.CommissionAsset, Maker: r.IsMaker, Time: r.TransactionTime}, true
+}
+
+// ListenKeyExpired is sent when the listen key was not kept alive, the stream stops after it
+type ListenKeyExpired struct {
+	EventType string `json:"e"`
+	EventTime int64  `json:"E"`
+	ListenKey string `json:"listenKey"`
+}
+
+// ParseEvent returns *AccountPosition, *BalanceUpdate, *ExecutionReport or *ListenKeyExpired,
+// nil for the events which are not known
+func ParseEvent(message []byte) (any, error) {
+	var head struct {
+		EventType string `json:"e"`
+		EventTime int64  `json:"E"`
+	}
+	if err := json.Unmarshal(message, &head); err != nil {
+		return nil, fmt.Errorf("invalid event %s: %w", message, err)
+	}
+	var event any
+	switch head.EventType {
+	case EventAccountPosition:
+		event = &AccountPosition{}
+	case EventBalanceUpdate:
+		event = &BalanceUpdate{}
+	case EventExecutionReport:
+		event = &ExecutionReport{}
+	case EventListenKeyExpired:
+		event = &ListenKeyExpired{}
+	default:
+		return nil, nil
+	}
+	if err := json.Unmarshal(message, event); err != nil {
+		return nil, fmt.Errorf("invalid %s event %s: %w", head.EventType, message, err)
+	}
+	return event, nil
+}
@@ -0,0 +1,178 @@
+package userstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gorilla/websocket"
+	"github.com/okharch/binance/orders"
+	"log"
+	"sync"
+	"time"
+)
+
+const DefaultURL = "wss://stream.binance.com:9443/ws/"
+
+// Handler processes events of the stream, the errors are logged and the stream goes on
+type Handler interface {
+	AccountPosition(ctx context.Context, e *AccountPosition) error
+	BalanceUpdate(ctx context.Context, e *BalanceUpdate) error
+	ExecutionReport(ctx context.Context, e *ExecutionReport) error
+}
+
+/*
+Stream is the user data stream of the account of the client:
+the listen key is created and kept alive via REST, the events are read from the web socket.
+the stream is reconnected with a new listen key when the connection is lost, the listen key
+has expired or could not be kept alive. the events sent while it was disconnected are lost,
+OnConnect is called to recover the state, e.g. by orders.Manager.Reconcile
+*/
+type Stream struct {
+	Client  *orders.Client
+	Handler Handler
+	// URL is the web socket endpoint the listen key is appended to
+	URL string
+	// KeepAlive is the interval of keep alive requests, the listen key expires in 60 minutes without them
+	KeepAlive time.Duration
+	// ReadTimeout is the time without messages or pings after which the connection is considered lost
+	ReadTimeout time.Duration
+	// Backoff is the delay before the first reconnect, it is doubled on subsequent failures up to MaxBackoff
+	Backoff, MaxBackoff time.Duration
+	// OnConnect is called when the stream is connected before its events are handled
+	OnConnect func(ctx context.Context) error
+}
+
+func NewStream(client *orders.Client, handler Handler) *Stream {
+	return &Stream{Client: client, Handler: handler, URL: DefaultURL, KeepAlive: 30 * time.Minute,
+		ReadTimeout: 10 * time.Minute, Backoff: time.Second, MaxBackoff: time.Minute}
+}
+
+var errListenKeyExpired = errors.New("listen key expired")
+
+// Run handles events of the stream until ctx is cancelled
+func (s *Stream) Run(ctx context.Context) {
+	backoff := s.Backoff
+	for {
+		connected, err := s.session(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if connected {
+			backoff = s.Backoff
+		}
+		log.Printf("user data stream disconnected: %v, reconnecting in %v", err, backoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > s.MaxBackoff {
+			backoff = s.MaxBackoff
+		}
+	}
+}
+
+// session connects the stream with a new listen key and handles its events until it is disconnected,
+// connected is true if it was connected and OnConnect succeeded
+func (s *Stream) session(ctx context.Context) (connected bool, err error) {
+	listenKey, err := s.Client.CreateListenKey(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to create listen key: %w", err)
+	}
+	expired := false
+	defer func() {
+		if expired {
+			return
+		}
+		closeCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := s.Client.CloseListenKey(closeCtx, listenKey); err != nil {
+			log.Printf("failed to close listen key: %v", err)
+		}
+	}()
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, s.URL+listenKey, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to connect to user data stream: %w", err)
+	}
+	sessionCtx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	defer func() {
+		cancel()
+		conn.Close()
+		wg.Wait()
+	}()
+	// the connection is closed to interrupt reading when the listen key can't be kept alive or ctx is cancelled
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		s.keepAlive(sessionCtx, listenKey)
+		conn.Close()
+	}()
+	s.extendDeadline(conn)
+	conn.SetPingHandler(func(data string) error {
+		s.extendDeadline(conn)
+		return conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(10*time.Second))
+	})
+	if s.OnConnect != nil {
+		if err := s.OnConnect(ctx); err != nil {
+			return false, fmt.Errorf("failed to handle connect: %w", err)
+		}
+	}
+	connected = true
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			return connected, err
+		}
+		s.extendDeadline(conn)
+		event, err := ParseEvent(message)
+		if err != nil {
+			log.Print(err)
+			continue
+		}
+		if _, ok := event.(*ListenKeyExpired); ok {
+			expired = true
+			return connected, errListenKeyExpired
+		}
+		if err := s.handle(ctx, event); err != nil {
+			log.Printf("failed to handle user data event %s: %v", message, err)
+		}
+	}
+}
+
+func (s *Stream) extendDeadline(conn *websocket.Conn) {
+	if s.ReadTimeout > 0 {
+		conn.SetReadDeadline(time.Now().Add(s.ReadTimeout))
+	}
+}
+
+// keepAlive extends the listen key every KeepAlive interval until ctx is cancelled or it fails
+func (s *Stream) keepAlive(ctx context.Context, listenKey string) {
+	ticker := time.NewTicker(s.KeepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Client.KeepAliveListenKey(ctx, listenKey); err != nil {
+				if ctx.Err() == nil {
+					log.Printf("failed to keep alive listen key: %v", err)
+				}
+				return
+			}
+		}
+	}
+}
+
+func (s *Stream) handle(ctx context.Context, event any) error {
+	switch e := event.(type) {
+	case *AccountPosition:
+		return s.Handler.AccountPosition(ctx, e)
+	case *BalanceUpdate:
+		return s.Handler.BalanceUpdate(ctx, e)
+	case *ExecutionReport:
+		return s.Handler.ExecutionReport(ctx, e)
+	}
+	return nil
+}
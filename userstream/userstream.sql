@@ -0,0 +1,37 @@
+-- balances of the spot account as they are sent by outboundAccountPosition events of the user data stream
+CREATE TABLE IF NOT EXISTS binance.account_balance (
+    asset       text PRIMARY KEY,
+    free        float8 NOT NULL,
+    locked      float8 NOT NULL,
+    update_time int8 NOT NULL, -- of the exchange
+    updated_at  timestamptz NOT NULL DEFAULT now()
+);
+
+-- deposits, withdrawals and transfers: balanceUpdate events
+CREATE TABLE IF NOT EXISTS binance.balance_update (
+    asset      text NOT NULL,
+    delta      float8 NOT NULL,
+    clear_time int8 NOT NULL,
+    event_time int8 NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (asset, clear_time, delta)
+);
+
+-- trades of the orders: executionReport events of TRADE execution type
+CREATE TABLE IF NOT EXISTS binance.spot_fill (
+    symbol           text NOT NULL,
+    trade_id         int8 NOT NULL,
+    order_id         int8 NOT NULL,
+    client_order_id  text NOT NULL,
+    side             text NOT NULL,
+    price            float8 NOT NULL,
+    quantity         float8 NOT NULL,
+    quote_qty        float8 NOT NULL,
+    commission       float8 NOT NULL,
+    commission_asset text NOT NULL DEFAULT '',
+    is_maker         bool NOT NULL,
+    trade_time       int8 NOT NULL,
+    created_at       timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (symbol, trade_id)
+);
+CREATE INDEX IF NOT EXISTS spot_fill_order ON binance.spot_fill (client_order_id);
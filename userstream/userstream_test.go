@@ -0,0 +1,164 @@
+package userstream
+
+import (
+	"context"
+	"github.com/okharch/binance/orders"
+	"github.com/okharch/binance/orders/stub"
+	"testing"
+	"time"
+)
+
+func TestParseEvent(t *testing.T) {
+	event, err := ParseEvent([]byte(`{"e":"executionReport","E":1499405658658,"s":"ETHBTC","c":"cancel1",
+	"S":"BUY","o":"LIMIT","f":"GTC","q":"1.00000000","p":"0.10264410","P":"0.00000000","F":"0.00000000","g":-1,
+	"C":"order1","x":"CANCELED","X":"CANCELED","r":"NONE","i":4293153,"l":"0.00000000","z":"0.50000000",
+	"L":"0.00000000","n":"0","N":null,"T":1499405658657,"t":-1,"I":8641984,"w":false,"m":false,"M":false,
+	"O":1499405658600,"Z":"0.05132205","Y":"0.00000000","Q":"0.00000000","W":1499405658657,"V":"NONE"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, ok := event.(*ExecutionReport)
+	if !ok {
+		t.Fatalf("unexpected event %T", event)
+	}
+	o := r.Order()
+	if o.ClientOrderId != "order1" || o.Status != orders.StatusCanceled || o.Price != 0.1026441 || o.StopPrice != 0 ||
+		o.OrigQty != 1 || o.ExecutedQty != 0.5 || o.UpdateTime != 1499405658657 || o.OrderListId != -1 || o.Reason != "" {
+		t.Errorf("unexpected order %+v", o)
+	}
+	if _, ok := r.Fill(); ok {
+		t.Error("cancel is not a fill")
+	}
+
+	event, err = ParseEvent([]byte(`{"e":"executionReport","E":2,"s":"ETHBTC","c":"order1","S":"SELL","o":"LIMIT",
+	"f":"GTC","q":"1","p":"0.1","P":"0","g":-1,"C":"","x":"TRADE","X":"PARTIALLY_FILLED","r":"NONE","i":7,
+	"l":"0.3","z":"0.3","L":"0.11","n":"0.0001","N":"BNB","T":3,"t":42,"m":true,"Z":"0.033","Y":"0.033"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, ok := event.(*ExecutionReport).Fill()
+	if !ok || f.TradeId != 42 || f.Quantity != 0.3 || f.Price != 0.11 || f.QuoteQty != 0.033 || !f.Maker ||
+		f.CommissionAsset != "BNB" || f.ClientOrderId != "order1" || f.Side != orders.Sell {
+		t.Errorf("unexpected fill %+v", f)
+	}
+
+	event, err = ParseEvent([]byte(`{"e":"outboundAccountPosition","E":1564034571105,"u":1564034571073,
+	"B":[{"a":"ETH","f":"10000.000000","l":"0.500000"}]}`))
+	if p, ok := event.(*AccountPosition); err != nil || !ok || p.LastUpdateTime != 1564034571073 ||
+		len(p.Balances) != 1 || p.Balances[0] != (Balance{"ETH", 10000, 0.5}) {
+		t.Errorf("unexpected account position %+v: %v", event, err)
+	}
+	event, err = ParseEvent([]byte(`{"e":"balanceUpdate","E":1573200697110,"a":"BTC","d":"-100.00000000","T":1573200697068}`))
+	if b, ok := event.(*BalanceUpdate); err != nil || !ok || b.Delta != -100 || b.ClearTime != 1573200697068 {
+		t.Errorf("unexpected balance update %+v: %v", event, err)
+	}
+	if event, err = ParseEvent([]byte(`{"e":"outboundAccountInfo","E":1}`)); event != nil || err != nil {
+		t.Errorf("expected unknown event to be skipped, got %v %v", event, err)
+	}
+	if _, err = ParseEvent([]byte(`{"e":"balanceUpdate","d":1}`)); err == nil {
+		t.Error("expected error for invalid event")
+	}
+}
+
+// recorder sends the events to the channel
+type recorder chan any
+
+func (r recorder) AccountPosition(ctx context.Context, e *AccountPosition) error {
+	r <- e
+	return nil
+}
+
+func (r recorder) BalanceUpdate(ctx context.Context, e *BalanceUpdate) error {
+	r <- e
+	return nil
+}
+
+func (r recorder) ExecutionReport(ctx context.Context, e *ExecutionReport) error {
+	r <- e
+	return nil
+}
+
+func TestStream(t *testing.T) {
+	exchange := stub.NewExchange("key", "secret")
+	defer exchange.Close()
+	client := orders.NewClient("key", "secret")
+	client.BaseURL = exchange.URL
+	events := make(recorder, 100)
+	s := NewStream(client, events)
+	s.URL, s.KeepAlive, s.Backoff, s.MaxBackoff = exchange.StreamURL(), 10*time.Millisecond, time.Millisecond, 10*time.Millisecond
+	connected := make(chan bool, 10)
+	s.OnConnect = func(ctx context.Context) error {
+		connected <- true
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan bool)
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+	wait := func(what string, c <-chan bool) {
+		t.Helper()
+		select {
+		case <-c:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout waiting for %s", what)
+		}
+	}
+	next := func() any {
+		t.Helper()
+		select {
+		case e := <-events:
+			return e
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for event")
+		}
+		return nil
+	}
+	expectOrder := func(clientOrderId string, status orders.Status, executed float64) *ExecutionReport {
+		t.Helper()
+		r, ok := next().(*ExecutionReport)
+		if !ok {
+			t.Fatal("expected execution report")
+		}
+		if o := r.Order(); o.ClientOrderId != clientOrderId || o.Status != status || o.ExecutedQty != executed {
+			t.Fatalf("expected %s %s %v, got %+v", clientOrderId, status, executed, o)
+		}
+		return r
+	}
+
+	wait("connect", connected)
+	exchange.Place("BTCUSDT", "o1", "BUY", 1, 27000)
+	expectOrder("o1", orders.StatusNew, 0)
+	exchange.Fill("o1", 0.4, 26990)
+	r := expectOrder("o1", orders.StatusPartiallyFilled, 0.4)
+	if f, ok := r.Fill(); !ok || f.Quantity != 0.4 || f.Price != 26990 || f.TradeId != 1 {
+		t.Errorf("unexpected fill %+v", f)
+	}
+	exchange.Push(map[string]any{"e": "outboundAccountPosition", "E": 1, "u": 1,
+		"B": []map[string]string{{"a": "BTC", "f": "0.4", "l": "0"}}})
+	if p, ok := next().(*AccountPosition); !ok || p.Balances[0].Free != 0.4 {
+		t.Errorf("unexpected account position %+v", p)
+	}
+	for start := time.Now(); exchange.KeepAlives() == 0; time.Sleep(time.Millisecond) {
+		if time.Since(start) > 5*time.Second {
+			t.Fatal("listen key is not kept alive")
+		}
+	}
+
+	// the stream reconnects with a new listen key when it expires and when the connection is lost
+	exchange.ExpireListenKeys()
+	wait("reconnect after listen key expired", connected)
+	exchange.Cancel("o1")
+	expectOrder("o1", orders.StatusCanceled, 0.4)
+	exchange.DropStreams()
+	wait("reconnect after connection is lost", connected)
+	exchange.Place("BTCUSDT", "o2", "SELL", 1, 28000)
+	expectOrder("o2", orders.StatusNew, 0)
+
+	cancel()
+	wait("stop", done)
+	if n := exchange.ListenKeys(); n != 0 {
+		t.Errorf("expected listen key to be closed, got %d", n)
+	}
+}